@@ -0,0 +1,125 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	"github.com/bklimczak/tanks/engine/input"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/sim"
+	"github.com/bklimczak/tanks/engine/ui"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// useAbilitySlot picks the ability behind an ability button's hotkey
+func (g *Game) useAbilitySlot(slot int) {
+	abilities := ui.SelectedAbilities(g.world.Units)
+	if slot >= 0 && slot < len(abilities) {
+		g.selectAbility(entity.AbilityDefs[abilities[slot]])
+	}
+}
+
+// selectAbility uses an ability the selected units aim at themselves right
+// away, and starts aiming the others
+func (g *Game) selectAbility(def *entity.AbilityDef) {
+	if def.Target == entity.AbilityTargetSelf {
+		g.castAbility(def, emath.Vec2{}, 0)
+		return
+	}
+	g.placementMode = false
+	g.placementDef = nil
+	g.pendingAbility = def
+}
+
+// updateAbilityTargeting aims the pending ability: a left click uses it at
+// the cursor, a right click cancels. Unit abilities wait for a click on a
+// unit. Holding shift keeps aiming after the ability is used.
+func (g *Game) updateAbilityTargeting(inputState input.State) {
+	if inputState.RightJustPressed || len(g.selectedUnitIDs()) == 0 {
+		g.pendingAbility = nil
+		return
+	}
+	if !inputState.LeftJustPressed {
+		return
+	}
+	def := g.pendingAbility
+	pos := g.engine.Camera.ScreenToWorld(inputState.MousePos)
+	var targetID uint64
+	if def.Target == entity.AbilityTargetUnit {
+		target := g.unitAt(pos)
+		if target == nil {
+			return
+		}
+		targetID = target.ID
+	}
+	g.castAbility(def, pos, targetID)
+	if !inputState.ShiftHeld {
+		g.pendingAbility = nil
+	}
+}
+
+// castAbility orders the selected units to use an ability
+func (g *Game) castAbility(def *entity.AbilityDef, pos emath.Vec2, targetID uint64) {
+	cmd := sim.Command{
+		Type:     sim.CmdUseAbility,
+		Faction:  entity.FactionPlayer,
+		UnitIDs:  g.selectedUnitIDs(),
+		TargetX:  pos.X,
+		TargetY:  pos.Y,
+		TargetID: targetID,
+		Ability:  def.Type,
+	}
+	if g.state == StateMultiplayerPlaying {
+		if g.networkClient != nil {
+			g.networkClient.SendCommand(gameCommand(cmd))
+		}
+		return
+	}
+	g.world.Submit(cmd)
+}
+
+// drawEffects draws the ability areas on the map: smoke for everyone to
+// see, sweeps only for the player who made them
+func (g *Game) drawEffects(screen *ebiten.Image) {
+	cam := g.engine.Camera
+	zoom := float32(cam.GetZoom())
+	for _, e := range g.world.Effects {
+		center := cam.WorldToScreen(e.Center)
+		radius := float32(e.Def.Radius) * zoom
+		// Fade out over the last second
+		fade := min(1, e.Remaining)
+		switch {
+		case e.Def.Conceals:
+			vector.FillCircle(screen, float32(center.X), float32(center.Y), radius, color.NRGBA{120, 120, 125, uint8(170 * fade)}, true)
+		case e.Def.Reveals && e.Faction == entity.FactionPlayer:
+			vector.StrokeCircle(screen, float32(center.X), float32(center.Y), radius, 2, color.NRGBA{80, 200, 255, uint8(200 * fade)}, true)
+		}
+	}
+}
+
+// drawAbilityAim shows the reach of the selected units that can use the
+// pending ability, so the player sees where a click will land
+func (g *Game) drawAbilityAim(screen *ebiten.Image) {
+	def := g.pendingAbility
+	if def == nil || def.Range <= 0 {
+		return
+	}
+	cam := g.engine.Camera
+	zoom := float32(cam.GetZoom())
+	for _, u := range g.world.Units {
+		if u.Selected && u.Faction == entity.FactionPlayer && u.AbilityReady(def.Type) {
+			center := cam.WorldToScreen(u.Center())
+			vector.StrokeCircle(screen, float32(center.X), float32(center.Y), float32(def.Range)*zoom, 1, color.NRGBA{80, 200, 255, 120}, true)
+		}
+	}
+}
+
+// abilityInstructions is the instructions bar while an ability is aimed
+func abilityInstructions(def *entity.AbilityDef) string {
+	click := "Left Click: Target Point"
+	if def.Target == entity.AbilityTargetUnit {
+		click = "Left Click: Target Unit"
+	}
+	return def.Name + " - " + click + " | Shift+Click: Keep Aiming | Right Click/ESC: Cancel"
+}
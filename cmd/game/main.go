@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -16,8 +17,10 @@ import (
 	"github.com/bklimczak/tanks/engine/input"
 	emath "github.com/bklimczak/tanks/engine/math"
 	"github.com/bklimczak/tanks/engine/network"
+	"github.com/bklimczak/tanks/engine/protocol"
 	"github.com/bklimczak/tanks/engine/render"
 	"github.com/bklimczak/tanks/engine/resource"
+	"github.com/bklimczak/tanks/engine/sim"
 	"github.com/bklimczak/tanks/engine/terrain"
 	"github.com/bklimczak/tanks/engine/ui"
 	"github.com/hajimehoshi/ebiten/v2"
@@ -38,27 +41,23 @@ const (
 	StateMultiplayerPlaying
 )
 const (
-	unitSize         = 20.0
-	selectionMargin  = 2.0
-	tickRate         = 1.0 / 60.0
-	worldMultiplier  = 5.0
-	baseWidth        = 1280.0
-	baseHeight       = 720.0
-	worldWidth       = baseWidth * worldMultiplier
-	worldHeight      = baseHeight * worldMultiplier
-	minimapWidth     = 160.0
-	minimapHeight    = 120.0
-	minimapMargin    = 10.0
-	buildingGridSize = terrain.TileSize
+	unitSize        = 20.0
+	selectionMargin = 2.0
+	tickRate        = sim.TickRate
+	worldMultiplier = 5.0
+	baseWidth       = 1280.0
+	baseHeight      = 720.0
+	worldWidth      = baseWidth * worldMultiplier
+	worldHeight     = baseHeight * worldMultiplier
+	minimapWidth    = 160.0
+	minimapHeight   = 120.0
+	minimapMargin   = 10.0
 )
 
 type Game struct {
 	engine             *engine.Engine
-	units              []*entity.Unit
-	buildings          []*entity.Building
+	world              *sim.World
 	playerNexus        *entity.Building
-	wreckages          []*entity.Wreckage
-	projectiles        []*entity.Projectile
 	terrainMap         *terrain.Map
 	mapConfig          *terrain.MapConfig
 	fogOfWar           *fog.FogOfWar
@@ -84,18 +83,20 @@ type Game struct {
 	debugTerrainTime   time.Duration
 	debugMinimapTime   time.Duration
 	debugFogTiles      int
-	nextUnitID         uint64
-	nextBuildingID     uint64
-	nextWreckageID     uint64
-	nextProjectileID   uint64
 	state              GameState
 	placementMode      bool
 	placementDef       *entity.BuildingDef
+	pendingAbility     *entity.AbilityDef // Ability waiting for a target on the map
 	placementValid     bool
 	elapsedTime        float64
 	mpPlayerSlot       int
 	mpIsReady          bool
 	mpCameraPositioned bool
+	mpReconnectAt      time.Time
+	mpReconnecting     bool
+	formation          sim.Formation // Layout used for group move orders
+	orderStart         emath.Vec2    // World point where the right button went down
+	orderPending       bool          // Right button is held for an order
 }
 
 func NewGame() *Game {
@@ -115,18 +116,11 @@ func NewGame() *Game {
 	}
 
 	// Load map configuration
-	var mapConfig *terrain.MapConfig
-	var terrainMap *terrain.Map
-
-	mapConfig, err = terrain.LoadMapConfig("maps/skirmish.yaml")
+	mapConfig, err := terrain.LoadMapConfig("maps/skirmish.yaml")
 	if err != nil {
 		log.Printf("Map config not found, using default: %v", err)
-		terrainMap = terrain.NewMap(worldWidth, worldHeight)
-		terrainMap.Generate(42)
-		terrainMap.PlaceMetalDeposit(400, 150)
-	} else {
-		terrainMap = mapConfig.ToMap()
 	}
+	terrainMap := skirmishTerrain(mapConfig)
 
 	actualWorldWidth := terrainMap.PixelWidth
 	actualWorldHeight := terrainMap.PixelHeight
@@ -162,8 +156,10 @@ func NewGame() *Game {
 		screenWidth:       int(baseWidth),
 		screenHeight:      int(baseHeight),
 		mpPlayerSlot:      -1,
+		formation:         sim.DefaultFormation,
 	}
 	g.engine.Collision.SetTerrain(terrainMap)
+	g.newWorld()
 
 	// Load entities from map config or use legacy setup
 	if mapConfig != nil {
@@ -180,77 +176,44 @@ func (g *Game) setupPlayerBase() {
 	startX, startY := g.findPassablePosition(300, 200)
 
 	nexusDef := entity.BuildingDefs[entity.BuildingCommandNexus]
-	commandNexus := entity.NewBuilding(g.nextBuildingID, startX, startY, nexusDef)
-	commandNexus.Faction = entity.FactionPlayer
-	commandNexus.Completed = true
-	commandNexus.BuildProgress = 1.0
-	g.buildings = append(g.buildings, commandNexus)
-	g.playerNexus = commandNexus
-	g.nextBuildingID++
-	g.applyBuildingEffects(nexusDef)
+	g.playerNexus = g.world.SpawnBuilding(nexusDef, startX, startY, entity.FactionPlayer)
 
 	solarDef := entity.BuildingDefs[entity.BuildingSolarArray]
 	solarX, solarY := g.findPassablePosition(startX+nexusDef.Size+20, startY)
-	solarArray := entity.NewBuilding(g.nextBuildingID, solarX, solarY, solarDef)
-	solarArray.Faction = entity.FactionPlayer
-	solarArray.Completed = true
-	solarArray.BuildProgress = 1.0
-	g.buildings = append(g.buildings, solarArray)
-	g.nextBuildingID++
-	g.applyBuildingEffects(solarDef)
+	g.world.SpawnBuilding(solarDef, solarX, solarY, entity.FactionPlayer)
 
 	tankDef := entity.UnitDefs[entity.UnitTypeTank]
 	for i := 0; i < 2; i++ {
 		tx, ty := g.findPassablePosition(startX+float64(i)*60, startY+nexusDef.Size+60)
-		tank := entity.NewUnitFromDef(g.nextUnitID, tx, ty, tankDef, entity.FactionPlayer)
-		g.units = append(g.units, tank)
-		g.nextUnitID++
+		g.world.SpawnUnit(tankDef, tx, ty, entity.FactionPlayer)
 	}
 
 	scoutDef := entity.UnitDefs[entity.UnitTypeScout]
 	scoutX, scoutY := g.findPassablePosition(startX+100, startY+nexusDef.Size+60)
-	scout := entity.NewUnitFromDef(g.nextUnitID, scoutX, scoutY, scoutDef, entity.FactionPlayer)
-	g.units = append(g.units, scout)
-	g.nextUnitID++
+	g.world.SpawnUnit(scoutDef, scoutX, scoutY, entity.FactionPlayer)
 }
 
 func (g *Game) setupEnemyBase() {
 	enemyBaseX, enemyBaseY := 3500.0, 2700.0
-	g.enemyAI = ai.NewEnemyAI(enemyBaseX, enemyBaseY)
+	g.setEnemyAI(ai.NewEnemyAI(enemyBaseX, enemyBaseY))
 
 	nexusDef := entity.BuildingDefs[entity.BuildingCommandNexus]
 	enemyNexusX, enemyNexusY := g.findPassablePosition(enemyBaseX, enemyBaseY)
-	enemyNexus := entity.NewBuilding(g.nextBuildingID, enemyNexusX, enemyNexusY, nexusDef)
-	enemyNexus.Faction = entity.FactionEnemy
-	enemyNexus.Color = entity.GetFactionTintedColor(nexusDef.Color, entity.FactionEnemy)
-	enemyNexus.Completed = true
-	enemyNexus.BuildProgress = 1.0
-	g.buildings = append(g.buildings, enemyNexus)
-	g.nextBuildingID++
+	g.world.SpawnBuilding(nexusDef, enemyNexusX, enemyNexusY, entity.FactionEnemy)
 
 	hoverBayDef := entity.BuildingDefs[entity.BuildingHoverBay]
 	hoverBayX, hoverBayY := g.findPassablePosition(enemyBaseX+nexusDef.Size+20, enemyBaseY)
-	enemyHoverBay := entity.NewBuilding(g.nextBuildingID, hoverBayX, hoverBayY, hoverBayDef)
-	enemyHoverBay.Faction = entity.FactionEnemy
-	enemyHoverBay.Color = entity.GetFactionTintedColor(hoverBayDef.Color, entity.FactionEnemy)
-	enemyHoverBay.Completed = true
-	enemyHoverBay.BuildProgress = 1.0
-	g.buildings = append(g.buildings, enemyHoverBay)
-	g.nextBuildingID++
+	g.world.SpawnBuilding(hoverBayDef, hoverBayX, hoverBayY, entity.FactionEnemy)
 
 	tankDef := entity.UnitDefs[entity.UnitTypeTank]
 	for i := 0; i < 2; i++ {
 		ex, ey := g.findPassablePosition(enemyBaseX-50+float64(i)*60, enemyBaseY+nexusDef.Size+40)
-		tank := entity.NewUnitFromDef(g.nextUnitID, ex, ey, tankDef, entity.FactionEnemy)
-		g.units = append(g.units, tank)
-		g.nextUnitID++
+		g.world.SpawnUnit(tankDef, ex, ey, entity.FactionEnemy)
 	}
 
 	scoutDef := entity.UnitDefs[entity.UnitTypeScout]
 	enemyScoutX, enemyScoutY := g.findPassablePosition(enemyBaseX+50, enemyBaseY+nexusDef.Size+80)
-	enemyScout := entity.NewUnitFromDef(g.nextUnitID, enemyScoutX, enemyScoutY, scoutDef, entity.FactionEnemy)
-	g.units = append(g.units, enemyScout)
-	g.nextUnitID++
+	g.world.SpawnUnit(scoutDef, enemyScoutX, enemyScoutY, entity.FactionEnemy)
 }
 
 func (g *Game) loadEntitiesFromConfig(config *terrain.MapConfig) {
@@ -263,22 +226,25 @@ func (g *Game) loadEntitiesFromConfig(config *terrain.MapConfig) {
 			g.engine.Resources.Get(resource.Energy).Current = factionConfig.Resources.Energy
 		}
 
+		// Setup AI for enemy factions before their buildings, so building
+		// effects go to the AI's economy
+		if factionConfig.Type == "ai" && len(factionConfig.Buildings) > 0 {
+			// Find the Command Nexus position for AI base
+			for _, b := range factionConfig.Buildings {
+				if b.Type == "CommandNexus" {
+					g.setEnemyAI(ai.NewEnemyAI(b.X, b.Y))
+					break
+				}
+			}
+		}
+
 		// Load buildings
 		for _, buildingConfig := range factionConfig.Buildings {
 			building := g.createBuildingFromConfig(buildingConfig, faction)
-			if building != nil {
-				g.buildings = append(g.buildings, building)
-				g.nextBuildingID++
-
-				// Track player's Command Nexus
-				if faction == entity.FactionPlayer && building.Type == entity.BuildingCommandNexus {
-					g.playerNexus = building
-				}
 
-				// Apply building effects if completed
-				if building.Completed {
-					g.applyBuildingEffects(building.Def)
-				}
+			// Track player's Command Nexus
+			if building != nil && faction == entity.FactionPlayer && building.Type == entity.BuildingCommandNexus {
+				g.playerNexus = building
 			}
 		}
 
@@ -291,25 +257,35 @@ func (g *Game) loadEntitiesFromConfig(config *terrain.MapConfig) {
 			for i := 0; i < count; i++ {
 				offsetX := float64(i%3) * 40
 				offsetY := float64(i/3) * 40
-				unit := g.createUnitFromConfig(unitConfig, faction, offsetX, offsetY)
-				if unit != nil {
-					g.units = append(g.units, unit)
-					g.nextUnitID++
-				}
+				g.createUnitFromConfig(unitConfig, faction, offsetX, offsetY)
 			}
 		}
+	}
+}
 
-		// Setup AI for enemy factions
-		if factionConfig.Type == "ai" && len(factionConfig.Buildings) > 0 {
-			// Find the Command Nexus position for AI base
-			for _, b := range factionConfig.Buildings {
-				if b.Type == "CommandNexus" {
-					g.enemyAI = ai.NewEnemyAI(b.X, b.Y)
-					break
-				}
-			}
-		}
+// skirmishTerrain builds a fresh single player map from its config, or the
+// default map when there is none
+func skirmishTerrain(mapConfig *terrain.MapConfig) *terrain.Map {
+	if mapConfig != nil {
+		return mapConfig.ToMap()
 	}
+	terrainMap := terrain.NewMap(worldWidth, worldHeight)
+	terrainMap.Generate(42)
+	terrainMap.PlaceMetalDeposit(400, 150)
+	return terrainMap
+}
+
+// newWorld replaces the simulation with an empty one on the current terrain
+// and hands it the player's resource manager
+func (g *Game) newWorld() {
+	g.world = sim.NewWorld(g.terrainMap)
+	g.world.SetResources(entity.FactionPlayer, g.engine.Resources)
+}
+
+// setEnemyAI installs the computer opponent and lets the world run its economy
+func (g *Game) setEnemyAI(enemyAI *ai.EnemyAI) {
+	g.enemyAI = enemyAI
+	g.world.SetResources(enemyAI.Faction, enemyAI.Resources)
 }
 
 func (g *Game) getFactionFromConfig(factionType string) entity.Faction {
@@ -332,24 +308,16 @@ func (g *Game) createBuildingFromConfig(config terrain.BuildingConfig, faction e
 	}
 
 	x, y := g.findPassablePosition(config.X, config.Y)
-	building := entity.NewBuilding(g.nextBuildingID, x, y, def)
-	building.Faction = faction
-
-	if faction == entity.FactionEnemy {
-		building.Color = entity.GetFactionTintedColor(def.Color, faction)
-	}
 
 	// Buildings from config default to completed unless specified otherwise
 	completed := config.Completed
 	if !config.Completed && config.Type != "" {
 		completed = true // Default to completed for pre-placed buildings
 	}
-	if completed {
-		building.Completed = true
-		building.BuildProgress = 1.0
+	if !completed {
+		return g.world.StartBuilding(def, x, y, faction)
 	}
-
-	return building
+	return g.world.SpawnBuilding(def, x, y, faction)
 }
 
 func (g *Game) createUnitFromConfig(config terrain.UnitConfig, faction entity.Faction, offsetX, offsetY float64) *entity.Unit {
@@ -369,7 +337,7 @@ func (g *Game) createUnitFromConfig(config terrain.UnitConfig, faction entity.Fa
 	}
 
 	x, y := g.findPassablePosition(config.X+offsetX, config.Y+offsetY)
-	return entity.NewUnitFromDef(g.nextUnitID, x, y, unitDef, faction)
+	return g.world.SpawnUnit(unitDef, x, y, faction)
 }
 
 func (g *Game) getBuildingTypeFromString(typeName string) entity.BuildingType {
@@ -400,6 +368,8 @@ func (g *Game) getBuildingTypeFromString(typeName string) entity.BuildingType {
 		return entity.BuildingMissileBattery
 	case "LaserTower":
 		return entity.BuildingLaserTower
+	case "Airfield":
+		return entity.BuildingAirfield
 	default:
 		return entity.BuildingCommandNexus
 	}
@@ -423,6 +393,20 @@ func (g *Game) getUnitTypeFromString(typeName string) entity.UnitType {
 		return entity.UnitTypeFlameTank
 	case "AAVehicle":
 		return entity.UnitTypeAAVehicle
+	case "Gunship":
+		return entity.UnitTypeGunship
+	case "Bomber":
+		return entity.UnitTypeBomber
+	case "HoverScout":
+		return entity.UnitTypeHoverScout
+	case "HoverTank":
+		return entity.UnitTypeHoverTank
+	case "HoverTransport":
+		return entity.UnitTypeHoverTransport
+	case "Skylifter":
+		return entity.UnitTypeAirTransport
+	case "PhantomTank":
+		return entity.UnitTypePhantomTank
 	case "Constructor":
 		return entity.UnitTypeConstructor
 	default:
@@ -432,14 +416,14 @@ func (g *Game) getUnitTypeFromString(typeName string) entity.UnitType {
 
 func (g *Game) findPassablePosition(x, y float64) (float64, float64) {
 	bounds := emath.NewRect(x, y, unitSize, unitSize)
-	if g.terrainMap.IsPassable(bounds) {
+	if g.terrainMap.IsPassable(bounds, entity.MovementGround) {
 		return x, y
 	}
 	for radius := 1.0; radius < 500; radius += terrain.TileSize {
 		for dx := -radius; dx <= radius; dx += terrain.TileSize {
 			for dy := -radius; dy <= radius; dy += terrain.TileSize {
 				testBounds := emath.NewRect(x+dx, y+dy, unitSize, unitSize)
-				if g.terrainMap.IsPassable(testBounds) {
+				if g.terrainMap.IsPassable(testBounds, entity.MovementGround) {
 					return x + dx, y + dy
 				}
 			}
@@ -480,24 +464,24 @@ func (g *Game) updateEndScreen(inputState input.State) error {
 }
 
 func (g *Game) resetGame() {
-	g.units = nil
-	g.buildings = nil
 	g.playerNexus = nil
-	g.wreckages = nil
-	g.projectiles = nil
-	g.nextUnitID = 0
-	g.nextBuildingID = 0
-	g.nextWreckageID = 0
-	g.nextProjectileID = 0
 	g.placementMode = false
 	g.placementDef = nil
-	g.terrainCache = nil
+	g.pendingAbility = nil
 	g.enemyAI = nil
 
+	// Mining and multiplayer change the map, so start from a fresh one
+	g.terrainMap = skirmishTerrain(g.mapConfig)
+	g.engine.Collision.SetTerrain(g.terrainMap)
+	g.terrainCache = nil
+	g.minimap.InvalidateTerrain()
+
 	g.engine.Resources = resource.NewManager()
+	g.newWorld()
 
 	actualWorldWidth := g.terrainMap.PixelWidth
 	actualWorldHeight := g.terrainMap.PixelHeight
+	g.minimap.SetWorldSize(actualWorldWidth, actualWorldHeight)
 	g.fogOfWar = fog.New(actualWorldWidth, actualWorldHeight, terrain.TileSize)
 
 	// Load entities from map config or use legacy setup
@@ -547,6 +531,9 @@ func (g *Game) enterMultiplayerLobby() {
 	// Create network client if not exists
 	if g.networkClient == nil {
 		g.networkClient = network.NewClient("Player")
+		if *netJSON {
+			g.networkClient.SetEncoding(protocol.EncodingJSON)
+		}
 	}
 }
 
@@ -762,6 +749,8 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 		if g.placementMode {
 			g.placementMode = false
 			g.placementDef = nil
+		} else if g.pendingAbility != nil {
+			g.pendingAbility = nil
 		} else {
 			if g.networkClient != nil {
 				g.networkClient.LeaveLobby()
@@ -775,12 +764,22 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 	// Update game state from server
 	if g.networkClient != nil && g.networkClient.IsConnected() {
 		gameState := g.networkClient.GetGameState()
+
+		// P pauses the game, or votes to resume it when already paused
+		if inputState.PausePressed {
+			if gameState != nil && gameState.Pause != nil {
+				g.networkClient.RequestResume()
+			} else {
+				g.networkClient.RequestPause()
+			}
+		}
+
 		if gameState != nil {
 			g.updateFromServerState(gameState)
 			g.updateFogOfWar()
 
 			// Position camera on player's base once we have units
-			if !g.mpCameraPositioned && len(g.buildings) > 0 {
+			if !g.mpCameraPositioned && len(g.world.Buildings) > 0 {
 				g.positionCameraOnPlayerBase()
 				g.mpCameraPositioned = true
 			}
@@ -798,6 +797,8 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 			}
 			return nil
 		}
+	} else if g.networkClient != nil {
+		g.reconnectToMatch()
 	}
 
 	// Handle camera and input
@@ -824,6 +825,16 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 		}
 	}
 
+	if g.resourceBar.UpdatePower(inputState.MousePos, inputState.LeftJustPressed) || inputState.PowerPressed {
+		g.cyclePowerPriority()
+	}
+	if inputState.RecyclePressed {
+		g.recycleSelected()
+	}
+	if inputState.AbilitySlot > 0 {
+		g.useAbilitySlot(inputState.AbilitySlot - 1)
+	}
+
 	// Handle minimap clicks
 	if g.minimap.Contains(inputState.MousePos) {
 		if inputState.LeftJustPressed || inputState.LeftPressed {
@@ -863,23 +874,33 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 
 	// Update command panel based on selection
 	factory := g.getSelectedFactory()
+	lab := g.getSelectedLab()
 	buildingWithStructures := g.getSelectedBuildingWithStructures()
-	if factory != nil {
-		g.commandPanel.SetFactoryOptions(factory)
-		g.commandPanel.UpdateQueueCounts()
-	} else if buildingWithStructures != nil {
-		g.commandPanel.SetBuildingBuildOptions(buildingWithStructures)
-	} else {
-		g.commandPanel.SetVisible(false)
-	}
+	g.updateCommandPanelOptions(factory, lab, buildingWithStructures)
 
 	// Handle command panel interactions
 	if g.commandPanel.Contains(inputState.MousePos) {
 		if inputState.LeftJustPressed {
-			if clickedUnit := g.commandPanel.UpdateUnit(inputState.MousePos, true); clickedUnit != nil {
+			if ability := g.commandPanel.UpdateAbility(inputState.MousePos, true); ability != nil {
+				g.selectAbility(ability)
+			} else if g.commandPanel.UpdateRecycle(inputState.MousePos, true) {
+				g.recycleSelected()
+			} else if priority, ok := g.commandPanel.UpdatePriority(inputState.MousePos, true); ok {
+				if building := g.getSelectedBuilding(); building != nil && g.networkClient != nil {
+					g.networkClient.SendSetPriorityCommand(building.ID, int(priority))
+				}
+			} else if stance, ok := g.commandPanel.UpdateStance(inputState.MousePos, true); ok {
+				if g.networkClient != nil {
+					g.networkClient.SendSetStanceCommand(g.selectedUnitIDs(), int(stance))
+				}
+			} else if clickedUnit := g.commandPanel.UpdateUnit(inputState.MousePos, true); clickedUnit != nil {
 				if factory != nil && g.networkClient != nil {
 					g.networkClient.SendProduceUnitCommand(factory.ID, int(clickedUnit.Type))
 				}
+			} else if research := g.commandPanel.UpdateResearch(inputState.MousePos, true); research != nil {
+				if lab != nil && g.networkClient != nil {
+					g.networkClient.SendResearchCommand(lab.ID, int(research.Type))
+				}
 			} else if clickedDef := g.commandPanel.Update(inputState.MousePos, true); clickedDef != nil {
 				g.placementMode = true
 				g.placementDef = clickedDef
@@ -889,6 +910,10 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 				if factory != nil && g.networkClient != nil {
 					g.networkClient.SendCancelProductionCommand(factory.ID, int(clickedUnit.Type))
 				}
+			} else if research := g.commandPanel.UpdateResearchRightClick(inputState.MousePos, true); research != nil {
+				if lab != nil && g.networkClient != nil {
+					g.networkClient.SendCancelResearchCommand(lab.ID, int(research.Type))
+				}
 			}
 		} else {
 			g.commandPanel.Update(inputState.MousePos, false)
@@ -905,6 +930,11 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 			if bounds != nil {
 				g.tooltip.ShowUnit(hoveredUnit, bounds.Pos.X+bounds.Size.X+5, bounds.Pos.Y)
 			}
+		} else if hoveredResearch := g.commandPanel.GetHoveredResearch(inputState.MousePos); hoveredResearch != nil {
+			bounds := g.commandPanel.GetHoveredButtonBounds(inputState.MousePos)
+			if bounds != nil {
+				g.tooltip.ShowResearch(hoveredResearch, bounds.Pos.X+bounds.Size.X+5, bounds.Pos.Y)
+			}
 		} else {
 			g.tooltip.Hide()
 		}
@@ -913,20 +943,46 @@ func (g *Game) updateMultiplayerPlaying(inputState input.State) error {
 
 	g.tooltip.Hide()
 
+	if g.pendingAbility != nil {
+		g.updateAbilityTargeting(inputState)
+		return nil
+	}
+
 	// Handle unit selection and commands
 	g.handleMultiplayerSelection(inputState)
-	if inputState.RightJustPressed {
-		worldPos := cam.ScreenToWorld(inputState.MousePos)
-		g.handleMultiplayerCommand(worldPos)
-	}
+	g.updateRightOrder(inputState, func(start, end emath.Vec2, dragged bool) {
+		if g.networkClient == nil || !g.networkClient.IsConnected() {
+			return
+		}
+		if cmd, ok := g.rightClickOrder(inputState, start, end, dragged); ok {
+			g.networkClient.SendCommand(gameCommand(cmd))
+		}
+	})
 
 	return nil
 }
 
-func (g *Game) updateFromServerState(state *network.GameStatePayload) {
+// reconnectToMatch retries the connection every few seconds after it drops
+// mid-game; the server puts us back into our slot
+func (g *Game) reconnectToMatch() {
+	if g.mpReconnecting || time.Now().Before(g.mpReconnectAt) {
+		return
+	}
+	g.mpReconnecting = true
+	g.mpReconnectAt = time.Now().Add(3 * time.Second)
+
+	go func() {
+		if err := g.networkClient.Reconnect(); err != nil {
+			log.Printf("Reconnect failed: %v", err)
+		}
+		g.mpReconnecting = false
+	}()
+}
+
+func (g *Game) updateFromServerState(state *protocol.GameStatePayload) {
 	// Preserve selected unit IDs before rebuilding
 	selectedUnitIDs := make(map[uint64]bool)
-	for _, u := range g.units {
+	for _, u := range g.world.Units {
 		if u.Selected {
 			selectedUnitIDs[u.ID] = true
 		}
@@ -934,14 +990,14 @@ func (g *Game) updateFromServerState(state *network.GameStatePayload) {
 
 	// Preserve selected building IDs before rebuilding
 	selectedBuildingIDs := make(map[uint64]bool)
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if b.Selected {
 			selectedBuildingIDs[b.ID] = true
 		}
 	}
 
 	// Clear and rebuild units from server state
-	g.units = make([]*entity.Unit, 0, len(state.Units))
+	g.world.Units = make([]*entity.Unit, 0, len(state.Units))
 	for _, u := range state.Units {
 		unitType := entity.UnitType(u.Type)
 		unitDef := entity.UnitDefs[unitType]
@@ -949,16 +1005,32 @@ func (g *Game) updateFromServerState(state *network.GameStatePayload) {
 			continue
 		}
 		faction := g.getFactionFromSlot(u.OwnerSlot)
-		unit := entity.NewUnitFromDef(u.ID, u.X, u.Y, unitDef, faction)
+		unit := entity.NewUnitFromDef(u.ID, u.PosX, u.PosY, unitDef, faction)
 		unit.Angle = u.Angle
+		unit.TurretAngle = u.TurretAngle
+		unit.Stance = entity.Stance(u.Stance)
+		unit.Rank = entity.Rank(u.Rank)
+		for _, o := range u.Orders {
+			unit.Orders = append(unit.Orders, entity.Order{Type: entity.OrderType(o.Type), Pos: emath.Vec2{X: o.X, Y: o.Y}})
+		}
 		unit.Health = u.Health
 		unit.MaxHealth = u.MaxHealth
 		unit.Selected = selectedUnitIDs[u.ID]
-		g.units = append(g.units, unit)
+		copy(unit.Ability.Cooldowns[:], u.Cooldowns)
+		unit.Ability.BoostLeft = u.BoostLeft
+		for _, c := range u.Cargo {
+			if cargoDef := entity.UnitDefs[entity.UnitType(c.Type)]; cargoDef != nil {
+				cargo := entity.NewUnitFromDef(c.ID, u.PosX, u.PosY, cargoDef, faction)
+				cargo.Health = c.Health
+				cargo.MaxHealth = c.MaxHealth
+				unit.Load(cargo)
+			}
+		}
+		g.world.Units = append(g.world.Units, unit)
 	}
 
 	// Clear and rebuild buildings from server state
-	g.buildings = make([]*entity.Building, 0, len(state.Buildings))
+	g.world.Buildings = make([]*entity.Building, 0, len(state.Buildings))
 	for _, b := range state.Buildings {
 		buildingType := entity.BuildingType(b.Type)
 		buildingDef := entity.BuildingDefs[buildingType]
@@ -966,39 +1038,104 @@ func (g *Game) updateFromServerState(state *network.GameStatePayload) {
 			continue
 		}
 		faction := g.getFactionFromSlot(b.OwnerSlot)
-		building := entity.NewBuilding(b.ID, b.X, b.Y, buildingDef)
+		building := entity.NewBuilding(b.ID, b.PosX, b.PosY, buildingDef)
 		building.Faction = faction
 		building.Health = b.Health
 		building.MaxHealth = b.MaxHealth
 		building.Completed = b.Completed
 		building.BuildProgress = b.BuildProgress
+		building.Yield = b.Yield
+		building.DepositLeft = b.Deposit
+		building.Power = 1 - b.PowerDeficit
+		building.Priority = entity.Priority(b.Priority)
+		building.Recycling = b.Recycling
+		building.RecycleProgress = b.RecycleProg
+		if b.Researching {
+			building.CurrentResearch = entity.ResearchDefs[entity.ResearchType(b.ResType)]
+			building.Researching = building.CurrentResearch != nil
+			building.ResearchProgress = b.ResProgress
+		}
 		building.Selected = selectedBuildingIDs[b.ID]
 		if faction != entity.FactionPlayer {
 			building.Color = entity.GetFactionTintedColor(buildingDef.Color, faction)
 		}
-		g.buildings = append(g.buildings, building)
+		g.world.Buildings = append(g.world.Buildings, building)
 	}
 
 	// Clear and rebuild projectiles from server state
-	g.projectiles = make([]*entity.Projectile, 0, len(state.Projectiles))
+	g.world.Projectiles = make([]*entity.Projectile, 0, len(state.Projectiles))
 	for _, p := range state.Projectiles {
 		faction := g.getFactionFromSlot(p.OwnerSlot)
+		weapon := entity.WeaponType(p.Weapon)
+		style := weapon.Style()
+		position := emath.Vec2{X: p.PosX, Y: p.PosY}
+		// No target is sent for shots homing in on a unit hidden from us
+		var direction emath.Vec2
+		if p.TargetX != 0 || p.TargetY != 0 {
+			direction = emath.Vec2{X: p.TargetX, Y: p.TargetY}.Sub(position).Normalize()
+		}
 		projectile := &entity.Projectile{
 			Entity: entity.Entity{
 				ID:       p.ID,
-				Position: emath.Vec2{X: p.X, Y: p.Y},
-				Size:     emath.Vec2{X: 4, Y: 4},
-				Color:    color.RGBA{255, 200, 50, 255},
+				Position: position,
+				Size:     emath.Vec2{X: style.Size, Y: style.Size},
+				Color:    style.Color,
 				Active:   true,
 				Faction:  faction,
 			},
+			Direction: direction,
+			Weapon:    weapon,
+		}
+		g.world.Projectiles = append(g.world.Projectiles, projectile)
+	}
+
+	// Clear and rebuild wreckage from server state
+	g.world.Wreckages = make([]*entity.Wreckage, 0, len(state.Wreckages))
+	for _, w := range state.Wreckages {
+		g.world.Wreckages = append(g.world.Wreckages, &entity.Wreckage{
+			Entity: entity.Entity{
+				ID:       w.ID,
+				Position: emath.Vec2{X: w.PosX, Y: w.PosY},
+				Size:     emath.Vec2{X: w.Width, Y: w.Height},
+				Color:    entity.WreckageColor,
+				Active:   true,
+				Faction:  entity.FactionNeutral,
+			},
+			MetalValue: w.Metal,
+		})
+	}
+
+	g.world.Effects = g.world.Effects[:0]
+	for _, e := range state.Effects {
+		if def := entity.AbilityDefs[entity.AbilityType(e.Ability)]; def != nil {
+			effect := entity.NewAreaEffect(def, g.getFactionFromSlot(e.OwnerSlot), emath.Vec2{X: e.X, Y: e.Y})
+			effect.Remaining = e.Remaining
+			g.world.Effects = append(g.world.Effects, effect)
 		}
-		g.projectiles = append(g.projectiles, projectile)
 	}
 
-	// Update resources for our player
+	// Deposits the server no longer lists are mined out
+	deposits := make([]terrain.Deposit, len(state.Deposits))
+	for i, d := range state.Deposits {
+		deposits[i] = terrain.Deposit{X: d.X, Y: d.Y, Amount: d.Amount}
+	}
+	g.terrainMap.SetDeposits(deposits)
+
+	// Fog, radar and detection queries go through the world's indexes
+	g.world.RebuildIndexes()
+
+	// Update resources and research for our player
 	for _, p := range state.Players {
 		if p.Slot == g.mpPlayerSlot {
+			research := make([]entity.ResearchType, len(p.Research))
+			for i, rt := range p.Research {
+				research[i] = entity.ResearchType(rt)
+			}
+			g.world.Tech(entity.FactionPlayer).SetCompleted(research)
+			// The server sends only the share of demand the grid covers
+			grid := g.world.PowerGrid(entity.FactionPlayer)
+			grid.First = entity.PowerClass(p.PowerFirst)
+			grid.Supply, grid.Demand = p.PowerLevel, 1
 			g.engine.Resources.Get(resource.Metal).Current = p.Resources.Metal
 			g.engine.Resources.Get(resource.Metal).Capacity = p.Resources.MetalCap
 			g.engine.Resources.Get(resource.Energy).Current = p.Resources.Energy
@@ -1017,14 +1154,14 @@ func (g *Game) getFactionFromSlot(slot int) entity.Faction {
 
 func (g *Game) positionCameraOnPlayerBase() {
 	// Find a player-owned building (preferably Command Nexus) to center camera on
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if b.Faction == entity.FactionPlayer {
 			g.engine.Camera.MoveTo(b.Center())
 			return
 		}
 	}
 	// Fallback to first player unit
-	for _, u := range g.units {
+	for _, u := range g.world.Units {
 		if u.Faction == entity.FactionPlayer {
 			g.engine.Camera.MoveTo(u.Center())
 			return
@@ -1066,8 +1203,12 @@ func (g *Game) initMultiplayerTerrain() {
 		}
 	}
 
+	// The server owns the simulation; the local world only mirrors its state
+	g.newWorld()
+
 	// Reset terrain cache so it gets rebuilt with new terrain
 	g.terrainCache = nil
+	g.minimap.InvalidateTerrain()
 
 	// Reset fog of war for new terrain
 	g.fogOfWar = fog.New(g.terrainMap.PixelWidth, g.terrainMap.PixelHeight, terrain.TileSize)
@@ -1094,24 +1235,6 @@ func (g *Game) handleMultiplayerSelection(inputState input.State) {
 	}
 }
 
-func (g *Game) handleMultiplayerCommand(worldPos emath.Vec2) {
-	// Send move command to server for selected units
-	if g.networkClient == nil || !g.networkClient.IsConnected() {
-		return
-	}
-
-	var selectedIDs []uint64
-	for _, u := range g.units {
-		if u.Selected && u.Faction == entity.FactionPlayer {
-			selectedIDs = append(selectedIDs, u.ID)
-		}
-	}
-
-	if len(selectedIDs) > 0 {
-		g.networkClient.SendMoveCommand(selectedIDs, worldPos.X, worldPos.Y)
-	}
-}
-
 func (g *Game) updatePaused(inputState input.State) error {
 	g.pauseMenu.UpdateSize(float64(g.screenWidth), float64(g.screenHeight))
 	g.pauseMenu.UpdateHover(inputState.MousePos)
@@ -1157,6 +1280,8 @@ func (g *Game) updatePlaying(inputState input.State) error {
 		if g.placementMode {
 			g.placementMode = false
 			g.placementDef = nil
+		} else if g.pendingAbility != nil {
+			g.pendingAbility = nil
 		} else {
 			g.state = StatePaused
 			return nil
@@ -1164,7 +1289,12 @@ func (g *Game) updatePlaying(inputState input.State) error {
 	}
 	if inputState.BuildTankPressed {
 		if factory := g.getSelectedFactory(); factory != nil {
-			factory.QueueProduction(entity.UnitDefs[entity.UnitTypeTank])
+			g.world.Submit(sim.Command{
+				Type:       sim.CmdProduceUnit,
+				Faction:    entity.FactionPlayer,
+				BuildingID: factory.ID,
+				UnitType:   entity.UnitTypeTank,
+			})
 		}
 	}
 	g.elapsedTime += tickRate
@@ -1173,7 +1303,6 @@ func (g *Game) updatePlaying(inputState input.State) error {
 	g.commandPanel.UpdateHeight(float64(g.screenHeight))
 	minimapY := float64(g.screenHeight) - minimapHeight - minimapMargin
 	g.minimap.SetPosition(minimapMargin, minimapY)
-	g.engine.Resources.Update(tickRate)
 	cam := g.engine.Camera
 	topOffset := g.resourceBar.Height()
 	leftOffset := 0.0
@@ -1192,6 +1321,15 @@ func (g *Game) updatePlaying(inputState input.State) error {
 			cam.ZoomOut(inputState.MousePos)
 		}
 	}
+	if g.resourceBar.UpdatePower(inputState.MousePos, inputState.LeftJustPressed) || inputState.PowerPressed {
+		g.cyclePowerPriority()
+	}
+	if inputState.RecyclePressed {
+		g.recycleSelected()
+	}
+	if inputState.AbilitySlot > 0 {
+		g.useAbilitySlot(inputState.AbilitySlot - 1)
+	}
 	if g.minimap.Contains(inputState.MousePos) {
 		if inputState.LeftJustPressed || inputState.LeftPressed {
 			worldPos := g.minimap.ScreenToWorld(inputState.MousePos)
@@ -1205,7 +1343,13 @@ func (g *Game) updatePlaying(inputState input.State) error {
 			g.placementDef = nil
 		}
 		if inputState.LeftJustPressed && g.placementValid {
-			g.placeBuilding(worldPos, g.placementDef, inputState.ShiftHeld)
+			g.world.Submit(sim.Command{
+				Type:         sim.CmdPlaceBuilding,
+				Faction:      entity.FactionPlayer,
+				TargetX:      worldPos.X,
+				TargetY:      worldPos.Y,
+				BuildingType: g.placementDef.Type,
+			})
 			// Keep placement mode active if shift is held for queue building
 			if !inputState.ShiftHeld {
 				g.placementMode = false
@@ -1214,20 +1358,48 @@ func (g *Game) updatePlaying(inputState input.State) error {
 		}
 	} else {
 		factory := g.getSelectedFactory()
+		lab := g.getSelectedLab()
 		buildingWithStructures := g.getSelectedBuildingWithStructures()
-		if factory != nil {
-			g.commandPanel.SetFactoryOptions(factory)
-			g.commandPanel.UpdateQueueCounts()
-		} else if buildingWithStructures != nil {
-			g.commandPanel.SetBuildingBuildOptions(buildingWithStructures)
-		} else {
-			g.commandPanel.SetVisible(false)
-		}
+		g.updateCommandPanelOptions(factory, lab, buildingWithStructures)
 		if g.commandPanel.Contains(inputState.MousePos) {
 			if inputState.LeftJustPressed {
-				if clickedUnit := g.commandPanel.UpdateUnit(inputState.MousePos, true); clickedUnit != nil {
+				if ability := g.commandPanel.UpdateAbility(inputState.MousePos, true); ability != nil {
+					g.selectAbility(ability)
+				} else if g.commandPanel.UpdateRecycle(inputState.MousePos, true) {
+					g.recycleSelected()
+				} else if priority, ok := g.commandPanel.UpdatePriority(inputState.MousePos, true); ok {
+					if building := g.getSelectedBuilding(); building != nil {
+						g.world.Submit(sim.Command{
+							Type:       sim.CmdSetPriority,
+							Faction:    entity.FactionPlayer,
+							BuildingID: building.ID,
+							Priority:   priority,
+						})
+					}
+				} else if stance, ok := g.commandPanel.UpdateStance(inputState.MousePos, true); ok {
+					g.world.Submit(sim.Command{
+						Type:    sim.CmdSetStance,
+						Faction: entity.FactionPlayer,
+						UnitIDs: g.selectedUnitIDs(),
+						Stance:  stance,
+					})
+				} else if clickedUnit := g.commandPanel.UpdateUnit(inputState.MousePos, true); clickedUnit != nil {
 					if factory != nil {
-						factory.QueueProduction(clickedUnit)
+						g.world.Submit(sim.Command{
+							Type:       sim.CmdProduceUnit,
+							Faction:    entity.FactionPlayer,
+							BuildingID: factory.ID,
+							UnitType:   clickedUnit.Type,
+						})
+					}
+				} else if research := g.commandPanel.UpdateResearch(inputState.MousePos, true); research != nil {
+					if lab != nil {
+						g.world.Submit(sim.Command{
+							Type:       sim.CmdResearch,
+							Faction:    entity.FactionPlayer,
+							BuildingID: lab.ID,
+							Research:   research.Type,
+						})
 					}
 				} else if clickedDef := g.commandPanel.Update(inputState.MousePos, true); clickedDef != nil {
 					g.placementMode = true
@@ -1236,7 +1408,21 @@ func (g *Game) updatePlaying(inputState input.State) error {
 			} else if inputState.RightJustPressed {
 				if clickedUnit := g.commandPanel.UpdateUnitRightClick(inputState.MousePos, true); clickedUnit != nil {
 					if factory != nil {
-						factory.RemoveFromQueue(clickedUnit.Type, g.engine.Resources)
+						g.world.Submit(sim.Command{
+							Type:       sim.CmdCancelProduction,
+							Faction:    entity.FactionPlayer,
+							BuildingID: factory.ID,
+							UnitType:   clickedUnit.Type,
+						})
+					}
+				} else if research := g.commandPanel.UpdateResearchRightClick(inputState.MousePos, true); research != nil {
+					if lab != nil {
+						g.world.Submit(sim.Command{
+							Type:       sim.CmdCancelResearch,
+							Faction:    entity.FactionPlayer,
+							BuildingID: lab.ID,
+							Research:   research.Type,
+						})
 					}
 				}
 			} else {
@@ -1252,21 +1438,31 @@ func (g *Game) updatePlaying(inputState input.State) error {
 				if bounds != nil {
 					g.tooltip.ShowUnit(hoveredUnit, bounds.Pos.X+bounds.Size.X+5, bounds.Pos.Y)
 				}
+			} else if hoveredResearch := g.commandPanel.GetHoveredResearch(inputState.MousePos); hoveredResearch != nil {
+				bounds := g.commandPanel.GetHoveredButtonBounds(inputState.MousePos)
+				if bounds != nil {
+					g.tooltip.ShowResearch(hoveredResearch, bounds.Pos.X+bounds.Size.X+5, bounds.Pos.Y)
+				}
 			} else {
 				g.tooltip.Hide()
 			}
+		} else if g.pendingAbility != nil {
+			g.tooltip.Hide()
+			g.updateAbilityTargeting(inputState)
 		} else {
 			g.tooltip.Hide()
 			g.handleSelection(inputState)
-			if inputState.RightJustPressed {
-				worldPos := cam.ScreenToWorld(inputState.MousePos)
-				g.handleRightClickCommand(worldPos)
-			}
+			g.updateRightOrder(inputState, func(start, end emath.Vec2, dragged bool) {
+				if cmd, ok := g.rightClickOrder(inputState, start, end, dragged); ok {
+					g.world.Submit(cmd)
+				}
+			})
 		}
 	}
-	g.engine.Resources.ResetDrains()
-	g.updateUnits()
-	g.updateBuildings()
+	g.world.Update(tickRate)
+	if g.playerNexus != nil && !g.playerNexus.Active {
+		g.state = StateDefeat
+	}
 	g.updateAI()
 	g.updateFogOfWar()
 	g.updateInfoPanel()
@@ -1291,7 +1487,7 @@ func (g *Game) checkVictoryConditions() {
 	playerUnits := 0
 	playerBuildings := 0
 
-	for _, u := range g.units {
+	for _, u := range g.world.Units {
 		if !u.Active {
 			continue
 		}
@@ -1302,7 +1498,7 @@ func (g *Game) checkVictoryConditions() {
 		}
 	}
 
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if b.Faction == entity.FactionEnemy {
 			enemyBuildings++
 		} else if b.Faction == entity.FactionPlayer {
@@ -1319,11 +1515,11 @@ func (g *Game) checkVictoryConditions() {
 
 // GameStateReader interface implementation for victory/defeat condition checks
 func (g *Game) GetUnits() []*entity.Unit {
-	return g.units
+	return g.world.Units
 }
 
 func (g *Game) GetBuildings() []*entity.Building {
-	return g.buildings
+	return g.world.Buildings
 }
 
 func (g *Game) GetElapsedTime() float64 {
@@ -1351,482 +1547,87 @@ func (g *Game) handleSelection(inputState input.State) {
 }
 func (g *Game) selectUnitAt(worldPos emath.Vec2, additive bool) {
 	if !additive {
-		for _, u := range g.units {
+		for _, u := range g.world.Units {
 			u.Selected = false
 		}
-		for _, b := range g.buildings {
+		for _, b := range g.world.Buildings {
 			b.Selected = false
 		}
 	}
-	for i := len(g.units) - 1; i >= 0; i-- {
-		if g.units[i].Contains(worldPos) && g.units[i].Faction == entity.FactionPlayer {
-			g.units[i].Selected = true
+	for i := len(g.world.Units) - 1; i >= 0; i-- {
+		if g.world.Units[i].Contains(worldPos) && g.world.Units[i].Faction == entity.FactionPlayer {
+			g.world.Units[i].Selected = true
 			return
 		}
 	}
-	for i := len(g.buildings) - 1; i >= 0; i-- {
-		if g.buildings[i].Contains(worldPos) && g.buildings[i].Faction == entity.FactionPlayer {
-			g.buildings[i].Selected = true
+	for i := len(g.world.Buildings) - 1; i >= 0; i-- {
+		if g.world.Buildings[i].Contains(worldPos) && g.world.Buildings[i].Faction == entity.FactionPlayer {
+			g.world.Buildings[i].Selected = true
 			return
 		}
 	}
 }
 func (g *Game) selectUnitsInBox(worldBox emath.Rect, additive bool) {
 	if !additive {
-		for _, u := range g.units {
+		for _, u := range g.world.Units {
 			u.Selected = false
 		}
-		for _, b := range g.buildings {
+		for _, b := range g.world.Buildings {
 			b.Selected = false
 		}
 	}
-	for _, u := range g.units {
+	for _, u := range g.world.Units {
 		if worldBox.Contains(u.Center()) && u.Faction == entity.FactionPlayer {
 			u.Selected = true
 		}
 	}
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if worldBox.Contains(b.Center()) && b.Faction == entity.FactionPlayer {
 			b.Selected = true
 		}
 	}
 }
-func (g *Game) commandMoveSelected(worldTarget emath.Vec2) {
-	selectedCount := 0
-	for _, u := range g.units {
-		if u.Selected {
-			selectedCount++
-		}
-	}
-	if selectedCount == 1 {
-		for _, u := range g.units {
-			if u.Selected {
-				u.SetTarget(worldTarget)
-				return
-			}
-		}
-	}
-	idx := 0
-	for _, u := range g.units {
-		if u.Selected {
-			row := idx / 3
-			col := idx % 3
-			offsetX := float64(col-1) * (unitSize + 5)
-			offsetY := float64(row) * (unitSize + 5)
-			formationTarget := emath.Vec2{
-				X: worldTarget.X + offsetX,
-				Y: worldTarget.Y + offsetY,
-			}
-			u.SetTarget(formationTarget)
-			idx++
-		}
-	}
-}
-func (g *Game) handleRightClickCommand(worldPos emath.Vec2) {
-	// Check if clicking on a friendly damaged unit for repair
-	var targetUnit *entity.Unit
-	for _, u := range g.units {
-		if u.Active && u.Faction == entity.FactionPlayer && u.Contains(worldPos) {
-			if u.Health < u.MaxHealth {
-				targetUnit = u
-				break
-			}
-		}
-	}
-
-	// If we clicked on a damaged friendly unit, check if any selected unit can repair
-	if targetUnit != nil {
-		repairAssigned := false
-		for _, u := range g.units {
-			if u.Selected && u.CanRepair() && u != targetUnit {
-				u.SetRepairTarget(targetUnit)
-				u.SetTarget(targetUnit.Center())
-				u.ClearBuildTask()
-				repairAssigned = true
-			}
-		}
-		if repairAssigned {
-			return
-		}
-	}
-
-	// Clear repair targets for selected units and move them
-	for _, u := range g.units {
-		if u.Selected {
-			u.ClearRepairTarget()
-		}
-	}
-	g.commandMoveSelected(worldPos)
-}
-func (g *Game) updateUnits() {
-	for _, u := range g.units {
-		if !u.Active {
-			continue
-		}
-		if u.HasBuildTask {
-			g.updateConstructorBuildTask(u)
-		}
-		if u.RepairTarget != nil {
-			g.updateRepairTask(u)
-		}
-		if !u.HasTarget {
-			continue
-		}
-		desiredPos := u.Update()
-		obstacles := make([]emath.Rect, 0, len(g.units)+len(g.buildings)-1)
-		for _, other := range g.units {
-			if other.ID != u.ID && other.Active {
-				obstacles = append(obstacles, other.Bounds())
-			}
-		}
-		for _, b := range g.buildings {
-			obstacles = append(obstacles, b.Bounds())
-		}
-		resolvedPos := g.engine.Collision.ResolveMovement(u.Bounds(), desiredPos, obstacles)
-
-		// If stuck (resolved position is same as current), try avoidance steering
-		if resolvedPos.DistanceSquared(u.Position) < 0.1 && u.HasTarget {
-			resolvedPos = g.engine.Collision.CalculateAvoidanceDirection(u.Bounds(), u.Target, u.Speed, obstacles)
-		}
-
-		u.ApplyPosition(resolvedPos)
-	}
-	g.updateCombat()
-	g.cleanupDeadUnits()
-	g.cleanupDeadBuildings()
-}
-func (g *Game) updateCombat() {
-	// Unit combat
-	for _, u := range g.units {
-		if !u.Active || !u.CanAttack() {
-			continue
-		}
-		// Check if current targets are still valid (clear if dead or out of pursuit range)
-		if u.AttackTarget != nil {
-			if !u.AttackTarget.Active || !u.IsInPursuitRange(u.AttackTarget) {
-				u.AttackTarget = nil
-			}
-		}
-		if u.BuildingAttackTarget != nil {
-			if !u.BuildingAttackTarget.Active || !u.IsBuildingInPursuitRange(u.BuildingAttackTarget) {
-				u.BuildingAttackTarget = nil
-			}
-		}
-
-		// Find new target if needed (only look within fire range for new targets)
-		if !u.HasAnyAttackTarget() {
-			var nearestEnemy *entity.Unit
-			var nearestBuilding *entity.Building
-			nearestUnitDist := u.Range + 1
-			nearestBuildingDist := u.Range + 1
-
-			// Find nearest enemy unit
-			for _, other := range g.units {
-				if other.Active && other.Faction != u.Faction {
-					dist := u.Center().Distance(other.Center())
-					if dist <= u.Range && dist < nearestUnitDist {
-						nearestUnitDist = dist
-						nearestEnemy = other
-					}
-				}
-			}
-
-			// Find nearest enemy building
-			for _, b := range g.buildings {
-				if b.Active && b.Faction != u.Faction {
-					dist := u.Center().Distance(b.Center())
-					if dist <= u.Range && dist < nearestBuildingDist {
-						nearestBuildingDist = dist
-						nearestBuilding = b
-					}
-				}
-			}
-
-			// Prioritize units over buildings
-			if nearestEnemy != nil {
-				u.SetAttackTarget(nearestEnemy)
-			} else if nearestBuilding != nil {
-				u.SetBuildingAttackTarget(nearestBuilding)
-			}
-		}
-
-		// Pursue enemy if they're out of fire range but in pursuit range
-		if u.AttackTarget != nil && u.AttackTarget.Active && !u.IsInRange(u.AttackTarget) {
-			// Only pursue if unit doesn't have another movement target
-			if !u.HasTarget {
-				u.SetTarget(u.AttackTarget.Center())
-			}
-		} else if u.BuildingAttackTarget != nil && u.BuildingAttackTarget.Active && !u.IsBuildingInRange(u.BuildingAttackTarget) {
-			if !u.HasTarget {
-				u.SetTarget(u.BuildingAttackTarget.Center())
-			}
-		}
-
-		if u.UpdateCombat(tickRate) {
-			if u.AttackTarget != nil && u.AttackTarget.Active {
-				projectile := entity.NewProjectile(g.nextProjectileID, u, u.AttackTarget)
-				g.projectiles = append(g.projectiles, projectile)
-				g.nextProjectileID++
-			} else if u.BuildingAttackTarget != nil && u.BuildingAttackTarget.Active {
-				projectile := entity.NewProjectileAtBuilding(g.nextProjectileID, u, u.BuildingAttackTarget)
-				g.projectiles = append(g.projectiles, projectile)
-				g.nextProjectileID++
-			}
-		}
-	}
-
-	// Building combat (laser towers, etc.)
-	g.updateBuildingCombat()
-
-	g.updateProjectiles()
-}
-
-func (g *Game) updateBuildingCombat() {
-	for _, b := range g.buildings {
-		if !b.Active || !b.CanAttack() {
-			continue
-		}
-
-		// Update cooldown
-		if b.FireCooldown > 0 {
-			b.FireCooldown -= tickRate
-		}
-
-		// Check if current target is still valid
-		if b.AttackTarget != nil && (!b.AttackTarget.Active || !b.IsInAttackRange(b.AttackTarget)) {
-			b.AttackTarget = nil
-		}
-
-		// Find new target if needed
-		if b.AttackTarget == nil {
-			var nearestEnemy *entity.Unit
-			nearestDist := b.Def.AttackRange + 1
-
-			for _, u := range g.units {
-				if u.Active && u.Faction != b.Faction {
-					dist := b.Center().Distance(u.Center())
-					if dist <= b.Def.AttackRange && dist < nearestDist {
-						nearestDist = dist
-						nearestEnemy = u
-					}
-				}
-			}
-
-			if nearestEnemy != nil {
-				b.SetAttackTarget(nearestEnemy)
-			}
-		}
-
-		// Fire if ready and have target
-		if b.FireCooldown <= 0 && b.AttackTarget != nil && b.AttackTarget.Active {
-			// Check if we have enough energy
-			energyRes := g.engine.Resources.Get(resource.Energy)
-			if energyRes.Current >= b.Def.EnergyPerShot {
-				energyRes.Spend(b.Def.EnergyPerShot)
-				projectile := entity.NewProjectileFromBuilding(g.nextProjectileID, b, b.AttackTarget)
-				g.projectiles = append(g.projectiles, projectile)
-				g.nextProjectileID++
-				b.FireCooldown = 1.0 / b.Def.FireRate
-			}
-		}
-	}
-}
-func (g *Game) updateProjectiles() {
-	aliveProjectiles := make([]*entity.Projectile, 0, len(g.projectiles))
-	for _, p := range g.projectiles {
-		if !p.Update(tickRate) {
-			aliveProjectiles = append(aliveProjectiles, p)
-		}
-	}
-	g.projectiles = aliveProjectiles
-}
-func (g *Game) cleanupDeadUnits() {
-	aliveUnits := make([]*entity.Unit, 0, len(g.units))
-	for _, u := range g.units {
-		if u.Active {
-			aliveUnits = append(aliveUnits, u)
-		} else {
-			wreckage := entity.NewWreckageFromUnit(g.nextWreckageID, u)
-			g.wreckages = append(g.wreckages, wreckage)
-			g.nextWreckageID++
-		}
-	}
-	g.units = aliveUnits
-}
-
-func (g *Game) cleanupDeadBuildings() {
-	aliveBuildings := make([]*entity.Building, 0, len(g.buildings))
-	for _, b := range g.buildings {
-		if b.Active {
-			aliveBuildings = append(aliveBuildings, b)
-		} else {
-			// Check if player's Command Nexus was destroyed
-			if b == g.playerNexus {
-				g.state = StateDefeat
-			}
-
-			wreckage := entity.NewWreckageFromBuilding(g.nextWreckageID, b)
-			g.wreckages = append(g.wreckages, wreckage)
-			g.nextWreckageID++
-		}
-	}
-	g.buildings = aliveBuildings
-}
-func (g *Game) updateConstructorBuildTask(u *entity.Unit) {
-	if u.HasTarget {
-		return
-	}
-	if !u.IsNearBuildSite() {
-		// Target a position just below (south of) the building site, so constructor doesn't end up inside
-		buildSiteTarget := emath.Vec2{
-			X: u.BuildPos.X + u.BuildDef.Size/2,
-			Y: u.BuildPos.Y + u.BuildDef.Size + u.Size.Y/2 + 5, // Below the building
-		}
-		u.SetTarget(buildSiteTarget)
-		return
-	}
-	if !u.IsBuilding {
-		building := entity.NewBuildingUnderConstruction(
-			g.nextBuildingID,
-			u.BuildPos.X,
-			u.BuildPos.Y,
-			u.BuildDef,
-		)
-		g.buildings = append(g.buildings, building)
-		g.nextBuildingID++
-		u.BuildTarget = building
-		u.IsBuilding = true
-	}
-	if u.BuildTarget != nil {
-		if u.BuildTarget.UpdateConstruction(tickRate, g.engine.Resources) {
-			g.applyBuildingEffects(u.BuildTarget.Def)
-			u.ClearBuildTask()
-		}
-	}
-}
-
-// Repair cost per health point
-const repairMetalCostPerHP = 0.5
-const repairEnergyCostPerHP = 0.25
-
-func (g *Game) updateRepairTask(u *entity.Unit) {
-	target := u.RepairTarget
-
-	// Check if target is still valid
-	if target == nil || !target.Active || target.Health >= target.MaxHealth {
-		u.ClearRepairTarget()
-		return
-	}
-
-	// Move towards target if not in range
-	if !u.IsInRepairRange(target) {
-		if !u.HasTarget {
-			u.SetTarget(target.Center())
-		}
-		return
-	}
-
-	// In range - perform repair
-	u.ClearTarget() // Stop moving
-
-	// Calculate repair amount for this tick
-	repairAmount := u.RepairRate * tickRate
-
-	// Don't over-repair
-	healthNeeded := target.MaxHealth - target.Health
-	if repairAmount > healthNeeded {
-		repairAmount = healthNeeded
-	}
-
-	// Calculate resource cost
-	metalCost := repairAmount * repairMetalCostPerHP
-	energyCost := repairAmount * repairEnergyCostPerHP
-
-	// Check if we have enough resources
-	resources := g.engine.Resources
-	metalRes := resources.Get(resource.Metal)
-	energyRes := resources.Get(resource.Energy)
-	if metalRes.Current < metalCost || energyRes.Current < energyCost {
-		return // Not enough resources - wait
-	}
-
-	// Consume resources and repair
-	metalRes.Spend(metalCost)
-	energyRes.Spend(energyCost)
-	target.Health += repairAmount
-
-	// Check if fully repaired
-	if target.Health >= target.MaxHealth {
-		target.Health = target.MaxHealth
-		u.ClearRepairTarget()
-	}
-}
-func (g *Game) applyBuildingEffects(def *entity.BuildingDef) {
-	resources := g.engine.Resources
-	if def.MetalProduction > 0 {
-		resources.AddProduction(resource.Metal, def.MetalProduction)
-	}
-	if def.EnergyProduction > 0 {
-		resources.AddProduction(resource.Energy, def.EnergyProduction)
-	}
-	if def.MetalConsumption > 0 {
-		resources.AddConsumption(resource.Metal, def.MetalConsumption)
-	}
-	if def.EnergyConsumption > 0 {
-		resources.AddConsumption(resource.Energy, def.EnergyConsumption)
-	}
-	if def.MetalStorage > 0 {
-		resources.AddCapacity(resource.Metal, def.MetalStorage)
-	}
-	if def.EnergyStorage > 0 {
-		resources.AddCapacity(resource.Energy, def.EnergyStorage)
-	}
-}
-func (g *Game) updateBuildings() {
-	for _, b := range g.buildings {
-		b.UpdateAnimation(tickRate)
-
-		// Auto-construction for buildings under construction
-		if !b.Completed {
-			if b.UpdateConstruction(tickRate, g.engine.Resources) {
-				g.applyBuildingEffects(b.Def)
-			}
-		}
-
-		if completedUnit := b.UpdateProduction(tickRate, g.engine.Resources); completedUnit != nil {
-			spawnPos := b.GetSpawnPoint()
-			unit := entity.NewUnitFromDef(g.nextUnitID, spawnPos.X, spawnPos.Y, completedUnit, b.Faction)
-			g.units = append(g.units, unit)
-			g.nextUnitID++
-			if b.HasRallyPoint {
-				unit.SetTarget(b.RallyPoint)
-			}
+func (g *Game) selectedUnitIDs() []uint64 {
+	var ids []uint64
+	for _, u := range g.world.Units {
+		if u.Selected && u.Faction == entity.FactionPlayer {
+			ids = append(ids, u.ID)
 		}
 	}
+	return ids
 }
 func (g *Game) updateAI() {
 	if g.enemyAI == nil {
 		return
 	}
-	g.enemyAI.Update(tickRate, g.units, g.buildings)
+	g.enemyAI.Update(tickRate, g.world.VisibleUnits(g.enemyAI.Faction), g.world.Buildings)
 }
 func (g *Game) updateFogOfWar() {
 	g.fogOfWar.ClearVisibility()
-	for _, u := range g.units {
-		if u.Active && u.Faction == entity.FactionPlayer {
-			center := u.Center()
-			g.fogOfWar.RevealCircle(center.X, center.Y, u.VisionRange)
-		}
+	for _, u := range g.world.Lookouts(entity.FactionPlayer, g.fogOfWar.TileSize) {
+		center := u.Center()
+		g.fogOfWar.RevealCircle(center.X, center.Y, u.VisionRange)
 	}
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if b.Completed && b.Faction == entity.FactionPlayer {
 			center := b.Center()
 			g.fogOfWar.RevealCircle(center.X, center.Y, b.Def.VisionRange)
 		}
 	}
+	// Smoke blocks the view, but the player's own sweeps see through it
+	for _, e := range g.world.Effects {
+		if e.Def.Conceals {
+			g.fogOfWar.Obscure(e.Center.X, e.Center.Y, e.Def.Radius)
+		}
+	}
+	for _, e := range g.world.Effects {
+		if e.Def.Reveals && e.Faction == entity.FactionPlayer {
+			g.fogOfWar.RevealCircle(e.Center.X, e.Center.Y, e.Def.Radius)
+		}
+	}
 }
 func (g *Game) getSelectedFactory() *entity.Building {
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if b.Selected && b.CanProduce() {
 			return b
 		}
@@ -1834,8 +1635,40 @@ func (g *Game) getSelectedFactory() *entity.Building {
 	return nil
 }
 
+// getSelectedLab returns the selected player building that runs research
+func (g *Game) getSelectedLab() *entity.Building {
+	for _, b := range g.world.Buildings {
+		if b.Selected && b.Faction == entity.FactionPlayer && b.CanResearch() {
+			return b
+		}
+	}
+	return nil
+}
+
+// updateCommandPanelOptions fills the command panel for the selection:
+// production, research, structures to build or unit stances
+func (g *Game) updateCommandPanelOptions(factory, lab, buildingWithStructures *entity.Building) {
+	tech := g.world.Tech(entity.FactionPlayer)
+	switch {
+	case factory != nil:
+		g.commandPanel.SetFactoryOptions(factory, tech)
+		g.commandPanel.UpdateQueueCounts()
+	case lab != nil:
+		g.commandPanel.SetResearchOptions(lab, tech, func(rt entity.ResearchType) bool {
+			return g.world.Researching(entity.FactionPlayer, rt)
+		})
+	case buildingWithStructures != nil:
+		g.commandPanel.SetBuildingBuildOptions(buildingWithStructures, tech)
+	default:
+		g.commandPanel.SetStanceOptions(g.world.Units)
+	}
+	g.commandPanel.AddPriorityOptions(g.getSelectedBuilding())
+	g.commandPanel.AddRecycleOption(g.getSelectedBuilding())
+	g.commandPanel.AddAbilityOptions(g.world.Units, g.pendingAbility)
+}
+
 func (g *Game) getSelectedBuilding() *entity.Building {
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if b.Selected && b.Faction == entity.FactionPlayer {
 			return b
 		}
@@ -1844,62 +1677,15 @@ func (g *Game) getSelectedBuilding() *entity.Building {
 }
 
 func (g *Game) getSelectedBuildingWithStructures() *entity.Building {
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if b.Selected && b.Faction == entity.FactionPlayer && b.Def != nil && len(b.Def.BuildableStructures) > 0 {
 			return b
 		}
 	}
 	return nil
 }
-func snapToGrid(pos emath.Vec2) emath.Vec2 {
-	return emath.Vec2{
-		X: math.Floor(pos.X/buildingGridSize) * buildingGridSize,
-		Y: math.Floor(pos.Y/buildingGridSize) * buildingGridSize,
-	}
-}
 func (g *Game) canPlaceBuilding(worldPos emath.Vec2, def *entity.BuildingDef) bool {
-	buildingPos := snapToGrid(worldPos)
-	bounds := emath.NewRect(buildingPos.X, buildingPos.Y, def.Size, def.Size)
-	if !g.terrainMap.IsBuildable(bounds) {
-		return false
-	}
-	if def.Type == entity.BuildingMetalExtractor {
-		if !g.hasMetal(bounds) {
-			return false
-		}
-	}
-	for _, u := range g.units {
-		if bounds.Intersects(u.Bounds()) {
-			return false
-		}
-	}
-	for _, b := range g.buildings {
-		if bounds.Intersects(b.Bounds()) {
-			return false
-		}
-	}
-	return true
-}
-func (g *Game) hasMetal(bounds emath.Rect) bool {
-	startX, startY := g.terrainMap.GetTileCoords(bounds.Pos.X, bounds.Pos.Y)
-	endX, endY := g.terrainMap.GetTileCoords(bounds.Pos.X+bounds.Size.X, bounds.Pos.Y+bounds.Size.Y)
-	for y := startY; y <= endY; y++ {
-		for x := startX; x <= endX; x++ {
-			if x >= 0 && x < g.terrainMap.Width && y >= 0 && y < g.terrainMap.Height {
-				if g.terrainMap.Tiles[y][x].HasMetal {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-func (g *Game) placeBuilding(worldPos emath.Vec2, def *entity.BuildingDef, queue bool) {
-	buildingPos := snapToGrid(worldPos)
-	building := entity.NewBuildingUnderConstruction(g.nextBuildingID, buildingPos.X, buildingPos.Y, def)
-	building.Faction = entity.FactionPlayer
-	g.buildings = append(g.buildings, building)
-	g.nextBuildingID++
+	return g.world.CanPlaceBuilding(worldPos, def)
 }
 func (g *Game) Draw(screen *ebiten.Image) {
 	switch g.state {
@@ -1964,26 +1750,22 @@ func (g *Game) drawPlaying(screen *ebiten.Image) {
 	terrainStart := time.Now()
 	g.drawTerrain(screen)
 	g.debugTerrainTime = time.Since(terrainStart)
-	for _, w := range g.wreckages {
+	for _, w := range g.world.Wreckages {
 		if cam.IsVisible(w.Bounds()) {
 			g.drawWreckage(screen, w)
 		}
 	}
-	for _, b := range g.buildings {
+	for _, b := range g.world.Buildings {
 		if cam.IsVisible(b.Bounds()) {
 			if b.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(b.Bounds()) {
 				g.drawBuilding(screen, b)
 			}
 		}
 	}
-	for _, u := range g.units {
-		if cam.IsVisible(u.Bounds()) {
-			if u.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(u.Bounds()) {
-				g.drawUnit(screen, u)
-			}
-		}
-	}
-	for _, p := range g.projectiles {
+	g.drawUnits(screen, false)
+	g.drawUnits(screen, true)
+	g.drawEffects(screen)
+	for _, p := range g.world.Projectiles {
 		if cam.IsVisible(p.Bounds()) {
 			if p.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(p.Bounds()) {
 				g.drawProjectile(screen, p)
@@ -1993,38 +1775,24 @@ func (g *Game) drawPlaying(screen *ebiten.Image) {
 	if g.placementMode && g.placementDef != nil {
 		g.drawPlacementPreview(screen)
 	}
+	g.drawOrderPreview(screen)
+	g.drawAbilityAim(screen)
 	if g.engine.Input.State().IsDragging {
 		box := g.engine.Input.GetSelectionBox()
 		r.DrawRectOutline(screen, box, 1, color.RGBA{0, 255, 0, 255})
 	}
-	g.resourceBar.Draw(screen, g.engine.Resources)
+	g.resourceBar.Draw(screen, g.engine.Resources, g.world.PowerGrid(entity.FactionPlayer))
 	g.commandPanel.Draw(screen, g.engine.Resources)
-	minimapEntities := make([]ui.MinimapEntity, 0, len(g.units)+len(g.buildings))
-	for _, u := range g.units {
-		if u.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(u.Bounds()) {
-			minimapEntities = append(minimapEntities, ui.MinimapEntity{
-				Position: u.Position,
-				Size:     u.Size,
-				Color:    u.Color,
-			})
-		}
-	}
-	for _, b := range g.buildings {
-		if b.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(b.Bounds()) {
-			minimapEntities = append(minimapEntities, ui.MinimapEntity{
-				Position: b.Position,
-				Size:     b.Size,
-				Color:    b.Color,
-			})
-		}
-	}
+	minimapEntities := g.minimapEntities()
 	minimapStart := time.Now()
 	g.minimap.Draw(screen, cam, g.terrainMap, g.fogOfWar, minimapEntities)
 	g.debugMinimapTime = time.Since(minimapStart)
 	instructionX := int(g.commandPanel.Width()) + 10
-	instructions := "WASD/Arrows: Scroll | Left Click: Select | Right Click: Move | ESC: Menu"
+	instructions := fmt.Sprintf("WASD/Arrows: Scroll | Left Click: Select | Right Click: Move/Attack/Guard/Reclaim/Load | Ctrl: Attack-Move | Alt: Patrol | U: Unload | Shift: Queue | Right Drag: Face/Area Reclaim | F: Formation (%s) | ESC: Menu", g.formationName())
 	if g.placementMode {
 		instructions = "Left Click: Place | Shift+Click: Queue Multiple | Right Click/ESC: Cancel"
+	} else if g.pendingAbility != nil {
+		instructions = abilityInstructions(g.pendingAbility)
 	} else if factory := g.getSelectedFactory(); factory != nil {
 		factoryName := factory.Def.Name
 		if factory.Producing {
@@ -2035,7 +1803,7 @@ func (g *Game) drawPlaying(screen *ebiten.Image) {
 	}
 	r.DrawTextAt(screen, instructions, instructionX, int(g.resourceBar.Height())+5)
 	fpsText := fmt.Sprintf("FPS: %.1f  TPS: %.1f  Units: %d  Buildings: %d  Projectiles: %d",
-		ebiten.ActualFPS(), ebiten.ActualTPS(), len(g.units), len(g.buildings), len(g.projectiles))
+		ebiten.ActualFPS(), ebiten.ActualTPS(), len(g.world.Units), len(g.world.Buildings), len(g.world.Projectiles))
 	ebitenutil.DebugPrintAt(screen, fpsText, 10, int(baseHeight)-20)
 	viewportBounds := cam.GetViewportBounds()
 	debugText := fmt.Sprintf("Cam: pos=(%.0f,%.0f) viewport=(%.0f,%.0f) zoom=%.2f visible=(%.0f,%.0f)",
@@ -2073,21 +1841,22 @@ func (g *Game) drawMultiplayerPlaying(screen *ebiten.Image) {
 
 	g.drawTerrain(screen)
 
-	for _, b := range g.buildings {
+	for _, w := range g.world.Wreckages {
+		if cam.IsVisible(w.Bounds()) && g.fogOfWar.IsExplored(w.Bounds()) {
+			g.drawWreckage(screen, w)
+		}
+	}
+	for _, b := range g.world.Buildings {
 		if cam.IsVisible(b.Bounds()) {
 			if b.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(b.Bounds()) {
 				g.drawBuilding(screen, b)
 			}
 		}
 	}
-	for _, u := range g.units {
-		if cam.IsVisible(u.Bounds()) {
-			if u.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(u.Bounds()) {
-				g.drawUnit(screen, u)
-			}
-		}
-	}
-	for _, p := range g.projectiles {
+	g.drawUnits(screen, false)
+	g.drawUnits(screen, true)
+	g.drawEffects(screen)
+	for _, p := range g.world.Projectiles {
 		if cam.IsVisible(p.Bounds()) && g.fogOfWar.IsVisible(p.Bounds()) {
 			g.drawProjectile(screen, p)
 		}
@@ -2098,50 +1867,97 @@ func (g *Game) drawMultiplayerPlaying(screen *ebiten.Image) {
 		g.drawPlacementPreview(screen)
 	}
 
+	g.drawOrderPreview(screen)
+	g.drawAbilityAim(screen)
+
 	if g.engine.Input.State().IsDragging {
 		box := g.engine.Input.GetSelectionBox()
 		r.DrawRectOutline(screen, box, 1, color.RGBA{0, 255, 0, 255})
 	}
 
-	g.resourceBar.Draw(screen, g.engine.Resources)
+	g.resourceBar.Draw(screen, g.engine.Resources, g.world.PowerGrid(entity.FactionPlayer))
 	g.commandPanel.Draw(screen, g.engine.Resources)
 
-	minimapEntities := make([]ui.MinimapEntity, 0, len(g.units)+len(g.buildings))
-	for _, u := range g.units {
-		if u.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(u.Bounds()) {
-			minimapEntities = append(minimapEntities, ui.MinimapEntity{
-				Position: u.Position,
-				Size:     u.Size,
-				Color:    u.Color,
-			})
-		}
-	}
-	for _, b := range g.buildings {
-		if b.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(b.Bounds()) {
-			minimapEntities = append(minimapEntities, ui.MinimapEntity{
-				Position: b.Position,
-				Size:     b.Size,
-				Color:    b.Color,
-			})
-		}
-	}
+	minimapEntities := g.minimapEntities()
 	g.minimap.Draw(screen, cam, g.terrainMap, g.fogOfWar, minimapEntities)
 
 	instructionX := int(g.commandPanel.Width()) + 10
 	if !g.commandPanel.IsVisible() {
 		instructionX = 10
 	}
-	instructions := "MULTIPLAYER | WASD/Arrows: Scroll | Left Click: Select | Right Click: Move | ESC: Leave"
+	instructions := fmt.Sprintf("MULTIPLAYER | WASD/Arrows: Scroll | Left Click: Select | Right Click: Move/Attack/Guard/Reclaim/Load | Ctrl: Attack-Move | Alt: Patrol | U: Unload | Shift: Queue | Right Drag: Face/Area Reclaim | F: Formation (%s) | P: Pause | ESC: Leave", g.formationName())
+	if g.pendingAbility != nil {
+		instructions = abilityInstructions(g.pendingAbility)
+	}
 	r.DrawTextAt(screen, instructions, instructionX, int(g.resourceBar.Height())+5)
 
+	if g.networkClient != nil {
+		if state := g.networkClient.GetGameState(); state != nil {
+			g.drawMultiplayerPause(screen, state)
+		}
+	}
+
 	fpsText := fmt.Sprintf("FPS: %.1f  Units: %d  Buildings: %d  Slot: %d",
-		ebiten.ActualFPS(), len(g.units), len(g.buildings), g.mpPlayerSlot)
+		ebiten.ActualFPS(), len(g.world.Units), len(g.world.Buildings), g.mpPlayerSlot)
 	ebitenutil.DebugPrintAt(screen, fpsText, 10, int(baseHeight)-20)
 
 	g.infoPanel.Draw(screen)
 	g.tooltip.Draw(screen)
 }
 
+// drawMultiplayerPause shows who paused the game, for how long, and the
+// resume vote or countdown
+func (g *Game) drawMultiplayerPause(screen *ebiten.Image, state *protocol.GameStatePayload) {
+	pausesLeft := 0
+	for _, p := range state.Players {
+		if p.Slot == g.mpPlayerSlot {
+			pausesLeft = p.PausesLeft
+			break
+		}
+	}
+
+	pause := state.Pause
+	if pause == nil {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Pauses left: %d", pausesLeft), g.screenWidth-110, int(g.resourceBar.Height())+5)
+		return
+	}
+
+	screenW := screen.Bounds().Dx()
+	boxWidth := 360.0
+	boxHeight := 80.0
+	boxX := (float64(screenW) - boxWidth) / 2
+	boxY := g.resourceBar.Height() + 40
+
+	vector.FillRect(screen, float32(boxX), float32(boxY), float32(boxWidth), float32(boxHeight), color.RGBA{30, 30, 40, 230}, false)
+	vector.StrokeRect(screen, float32(boxX), float32(boxY), float32(boxWidth), float32(boxHeight), 2, color.RGBA{80, 80, 100, 255}, false)
+
+	elapsed := int(pause.Elapsed)
+	title := fmt.Sprintf("PAUSED by %s (%d:%02d)", pause.ByName, elapsed/60, elapsed%60)
+	ebitenutil.DebugPrintAt(screen, title, int(boxX)+int(boxWidth)/2-len(title)*3, int(boxY)+12)
+
+	var status string
+	if pause.ResumeIn > 0 {
+		status = fmt.Sprintf("Resuming in %d...", int(math.Ceil(pause.ResumeIn)))
+	} else {
+		voted := false
+		for _, slot := range pause.ResumeVotes {
+			if slot == g.mpPlayerSlot {
+				voted = true
+				break
+			}
+		}
+		if voted {
+			status = fmt.Sprintf("Waiting for others to resume (%d voted)", len(pause.ResumeVotes))
+		} else {
+			status = fmt.Sprintf("Press P to vote resume (%d voted)", len(pause.ResumeVotes))
+		}
+	}
+	ebitenutil.DebugPrintAt(screen, status, int(boxX)+int(boxWidth)/2-len(status)*3, int(boxY)+36)
+
+	left := fmt.Sprintf("Your pauses left: %d", pausesLeft)
+	ebitenutil.DebugPrintAt(screen, left, int(boxX)+int(boxWidth)/2-len(left)*3, int(boxY)+56)
+}
+
 func (g *Game) getTileImage(c color.RGBA) *ebiten.Image {
 	if g.tileImages == nil {
 		g.tileImages = make(map[color.RGBA]*ebiten.Image)
@@ -2183,22 +1999,20 @@ func (g *Game) drawTerrain(screen *ebiten.Image) {
 				op := &ebiten.DrawImageOptions{}
 				op.GeoM.Translate(screenX, screenY)
 
-				// Use grass sprite for grass tiles, fallback to color for others
-				if tile.Type == terrain.TileGrass && g.grassTileScaled != nil {
+				// Use grass sprite for grass tiles, fallback to color for others.
+				// Deposits are mined out over time, so they are drawn on top
+				// every frame and left as grass here.
+				tileType := tile.Type
+				if tile.HasMetal {
+					tileType = terrain.TileGrass
+				}
+				if tileType == terrain.TileGrass && g.grassTileScaled != nil {
 					g.terrainCache.DrawImage(g.grassTileScaled, op)
 				} else {
-					tileColor := terrain.TileColorVariation(tile.Type, x, y).(color.RGBA)
+					tileColor := terrain.TileColorVariation(tileType, x, y).(color.RGBA)
 					tileImg := g.getTileImage(tileColor)
 					g.terrainCache.DrawImage(tileImg, op)
 				}
-
-				// Draw metal deposits on base terrain
-				if tile.HasMetal {
-					centerX := screenX + terrain.TileSize/2
-					centerY := screenY + terrain.TileSize/2
-					vector.DrawFilledCircle(g.terrainCache, float32(centerX), float32(centerY), 8, color.RGBA{180, 180, 200, 255}, false)
-					vector.DrawFilledCircle(g.terrainCache, float32(centerX), float32(centerY), 5, color.RGBA{120, 120, 140, 255}, false)
-				}
 			}
 		}
 	}
@@ -2239,12 +2053,13 @@ func (g *Game) drawTerrain(screen *ebiten.Image) {
 	op.GeoM.Scale(zoom, zoom)
 	screen.DrawImage(g.terrainCache.SubImage(srcRect).(*ebiten.Image), op)
 
-	// Draw fog overlay on top (only visible tiles)
 	startX, startY, endX, endY := g.terrainMap.GetVisibleTiles(
 		cam.Position.X, cam.Position.Y,
 		visibleWorldW, visibleWorldH,
 	)
+	g.drawDeposits(screen, startX, startY, endX, endY)
 
+	// Draw fog overlay on top (only visible tiles)
 	blackImg := g.getTileImage(color.RGBA{0, 0, 0, 255})
 	darkImg := g.getTileImage(color.RGBA{0, 0, 0, 153}) // 60% darkness for explored
 
@@ -2274,6 +2089,20 @@ func (g *Game) drawTerrain(screen *ebiten.Image) {
 	}
 	g.debugFogTiles = fogTileCount
 }
+
+// drawUnits draws the visible ground units or, above them, the aircraft
+func (g *Game) drawUnits(screen *ebiten.Image, aircraft bool) {
+	cam := g.engine.Camera
+	for _, u := range g.world.Units {
+		if u.IsAircraft() != aircraft || !cam.IsVisible(u.Bounds()) {
+			continue
+		}
+		if g.unitVisible(u) {
+			g.drawUnit(screen, u)
+		}
+	}
+}
+
 func (g *Game) drawUnit(screen *ebiten.Image, u *entity.Unit) {
 	r := g.engine.Renderer
 	cam := g.engine.Camera
@@ -2287,10 +2116,22 @@ func (g *Game) drawUnit(screen *ebiten.Image, u *entity.Unit) {
 		r.DrawRotatedRect(screen, screenCenter, selectionWidth, selectionHeight, u.Angle, color.RGBA{0, 255, 0, 128})
 	}
 	g.entityRenderer.DrawUnit(screen, u, screenPos, screenCenter, zoom)
+	if u.IsCloaked() {
+		g.drawCloak(screen, u)
+	}
 	if u.HasTarget && u.Selected {
+		from := screenCenter
+		for _, wp := range u.Path {
+			to := cam.WorldToScreen(wp)
+			r.DrawLine(screen, from, to, 1, color.RGBA{0, 255, 0, 80})
+			from = to
+		}
 		screenTarget := cam.WorldToScreen(u.Target)
 		r.DrawCircle(screen, screenTarget, float32(4*zoom), color.RGBA{0, 255, 0, 200})
 	}
+	if u.Selected {
+		g.drawOrderQueue(screen, u)
+	}
 	if u.HasBuildTask && u.Selected {
 		buildCenter := emath.Vec2{
 			X: u.BuildPos.X + u.BuildDef.Size/2,
@@ -2336,6 +2177,29 @@ func (g *Game) drawUnit(screen *ebiten.Image, u *entity.Unit) {
 		targetCenter := cam.WorldToScreen(u.AttackTarget.Center())
 		r.DrawLine(screen, screenCenter, targetCenter, 1, color.RGBA{255, 0, 0, 150})
 	}
+	if u.CanTransport() && (u.Selected || len(u.Cargo) > 0) {
+		g.drawCargo(screen, u, screenPos, scaledSize, zoom)
+	}
+	if u.Ability.BoostLeft > 0 {
+		r.DrawLine(screen, screenCenter, screenCenter.Sub(emath.Vec2{X: math.Cos(u.Angle), Y: math.Sin(u.Angle)}.Mul(scaledSize.X*0.8)), 3*float32(zoom), color.RGBA{255, 150, 40, 180})
+	}
+}
+
+// drawCargo shows a transport's hold under it, one pip per unit of room
+func (g *Game) drawCargo(screen *ebiten.Image, u *entity.Unit, screenPos, scaledSize emath.Vec2, zoom float64) {
+	r := g.engine.Renderer
+	capacity := u.Def.GetCapacity()
+	used := u.CargoUsed()
+	pip := min(6*zoom, scaledSize.X/float64(capacity)-zoom)
+	y := screenPos.Y + scaledSize.Y + 2*zoom
+	for i := range capacity {
+		c := color.RGBA{40, 40, 40, 200}
+		if i < used {
+			c = color.RGBA{180, 120, 255, 255}
+		}
+		x := screenPos.X + float64(i)*(pip+zoom)
+		r.DrawRect(screen, emath.Rect{Pos: emath.Vec2{X: x, Y: y}, Size: emath.Vec2{X: pip, Y: pip}}, c)
+	}
 }
 
 func (g *Game) drawProjectile(screen *ebiten.Image, p *entity.Projectile) {
@@ -2344,12 +2208,49 @@ func (g *Game) drawProjectile(screen *ebiten.Image, p *entity.Projectile) {
 	zoom := cam.GetZoom()
 	screenPos := cam.WorldToScreen(p.Position)
 	scaledSize := p.Size.Mul(zoom)
+	screenCenter := cam.WorldToScreen(p.Center())
+	switch p.Weapon {
+	case entity.WeaponFlame, entity.WeaponBallistic:
+		r.DrawCircle(screen, screenCenter, float32(scaledSize.X/2), p.Color)
+		return
+	case entity.WeaponRocket:
+		smokeEnd := cam.WorldToScreen(p.Center().Sub(p.Direction.Mul(16)))
+		r.DrawLine(screen, screenCenter, smokeEnd, 3, color.RGBA{200, 200, 200, 120})
+	}
 	screenBounds := emath.Rect{Pos: screenPos, Size: scaledSize}
 	r.DrawRect(screen, screenBounds, p.Color)
 	trailEnd := p.Position.Sub(p.Direction.Mul(8))
 	screenTrailEnd := cam.WorldToScreen(trailEnd)
-	r.DrawLine(screen, cam.WorldToScreen(p.Center()), screenTrailEnd, 2, color.RGBA{255, 150, 0, 150})
+	r.DrawLine(screen, screenCenter, screenTrailEnd, 2, color.RGBA{255, 150, 0, 150})
+}
+
+// drawDeposits draws the metal deposits among the given tiles, shrinking
+// the ore marker as a deposit is mined out
+func (g *Game) drawDeposits(screen *ebiten.Image, startX, startY, endX, endY int) {
+	cam := g.engine.Camera
+	zoom := cam.GetZoom()
+	tileImg := g.getTileImage(terrain.TileColors(terrain.TileMetal).(color.RGBA))
+	for y := startY; y < endY; y++ {
+		for x := startX; x < endX; x++ {
+			tile := g.terrainMap.Tiles[y][x]
+			if !tile.HasMetal {
+				continue
+			}
+			worldX, worldY := g.terrainMap.GetPixelCoords(x, y)
+			screenPos := cam.WorldToScreen(emath.Vec2{X: worldX, Y: worldY})
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(zoom, zoom)
+			op.GeoM.Translate(screenPos.X, screenPos.Y)
+			screen.DrawImage(tileImg, op)
+
+			center := screenPos.Add(emath.Vec2{X: terrain.TileSize / 2, Y: terrain.TileSize / 2}.Mul(zoom))
+			fill := 0.4 + 0.6*tile.DepositFraction()
+			vector.DrawFilledCircle(screen, float32(center.X), float32(center.Y), float32(8*fill*zoom), color.RGBA{180, 180, 200, 255}, false)
+			vector.DrawFilledCircle(screen, float32(center.X), float32(center.Y), float32(5*fill*zoom), color.RGBA{120, 120, 140, 255}, false)
+		}
+	}
 }
+
 func (g *Game) drawWreckage(screen *ebiten.Image, w *entity.Wreckage) {
 	r := g.engine.Renderer
 	cam := g.engine.Camera
@@ -2407,7 +2308,26 @@ func (g *Game) drawBuilding(screen *ebiten.Image, b *entity.Building) {
 		}
 		r.DrawRectOutline(screen, screenBounds, 2, borderColor)
 	}
-	if !b.Completed {
+	// Our underpowered buildings are dimmed, with a warning light that
+	// turns red once they are too short of power to work
+	if b.Completed && b.Faction == entity.FactionPlayer && b.Underpowered() {
+		r.DrawRect(screen, screenBounds, color.RGBA{0, 0, 0, uint8(140 * (1 - b.Power))})
+		light := ui.LowPowerColor
+		if b.Power < entity.MinDefensePower {
+			light = ui.NoPowerColor
+		}
+		lightPos := emath.Vec2{X: screenPos.X + scaledSize.X - 7*zoom, Y: screenPos.Y + 7*zoom}
+		r.DrawCircle(screen, lightPos, float32(4*zoom), light)
+	}
+	if b.Recycling {
+		barWidth := scaledSize.X - 10*zoom
+		barHeight := 8.0 * zoom
+		barX := screenPos.X + 5*zoom
+		barY := screenPos.Y + scaledSize.Y/2 - barHeight/2
+		r.DrawRect(screen, emath.Rect{Pos: emath.Vec2{X: barX, Y: barY}, Size: emath.Vec2{X: barWidth, Y: barHeight}}, color.RGBA{40, 40, 40, 220})
+		left := emath.Rect{Pos: emath.Vec2{X: barX, Y: barY}, Size: emath.Vec2{X: barWidth * (1 - b.RecycleProgress), Y: barHeight}}
+		r.DrawRect(screen, left, color.RGBA{80, 200, 200, 255})
+	} else if !b.Completed {
 		barWidth := scaledSize.X - 10*zoom
 		barHeight := 8.0 * zoom
 		barX := screenPos.X + 5*zoom
@@ -2452,7 +2372,7 @@ func (g *Game) drawPlacementPreview(screen *ebiten.Image) {
 	zoom := cam.GetZoom()
 	mousePos := g.engine.Input.State().MousePos
 	worldPos := cam.ScreenToWorld(mousePos)
-	buildingPos := snapToGrid(worldPos)
+	buildingPos := sim.SnapToGrid(worldPos)
 	screenPos := cam.WorldToScreen(buildingPos)
 	scaledSize := g.placementDef.Size * zoom
 	screenBounds := emath.Rect{
@@ -2496,7 +2416,11 @@ func (g *Game) Cleanup() {
 	log.Println("Game cleanup complete")
 }
 
+var netJSON = flag.Bool("net-json", false, "Receive multiplayer game state as JSON instead of binary (for debugging)")
+
 func main() {
+	flag.Parse()
+
 	ebiten.SetWindowSize(1280, 720)
 	ebiten.SetWindowTitle("Tanks RTS")
 	ebiten.SetFullscreen(true)
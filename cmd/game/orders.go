@@ -0,0 +1,380 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	"github.com/bklimczak/tanks/engine/input"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/protocol"
+	"github.com/bklimczak/tanks/engine/sim"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// updateRightOrder turns right-button input into an order. The order is
+// given on release at the point where the button went down, together with
+// the point where it came up and whether the mouse was dragged in between.
+func (g *Game) updateRightOrder(inputState input.State, issue func(start, end emath.Vec2, dragged bool)) {
+	cam := g.engine.Camera
+	if inputState.FormationPressed {
+		g.cycleFormation()
+	}
+	if inputState.RightJustPressed {
+		g.orderStart = cam.ScreenToWorld(inputState.MousePos)
+		g.orderPending = true
+	}
+	if inputState.RightJustReleased && g.orderPending {
+		g.orderPending = false
+		issue(g.orderStart, cam.ScreenToWorld(inputState.MousePos), inputState.IsRightDragging)
+	}
+}
+
+// cycleFormation switches to the next formation for group moves
+func (g *Game) cycleFormation() {
+	for i, f := range sim.Formations {
+		if f == g.formation {
+			g.formation = sim.Formations[(i+1)%len(sim.Formations)]
+			return
+		}
+	}
+	g.formation = sim.DefaultFormation
+}
+
+// cyclePowerPriority switches the player's grid to power the next class
+// of consumers first
+func (g *Game) cyclePowerPriority() {
+	next := g.world.PowerGrid(entity.FactionPlayer).First.Next()
+	if g.state == StateMultiplayerPlaying {
+		if g.networkClient != nil {
+			g.networkClient.SendPowerPriorityCommand(int(next))
+		}
+		return
+	}
+	g.world.Submit(sim.Command{
+		Type:    sim.CmdSetPowerPriority,
+		Faction: entity.FactionPlayer,
+		Power:   next,
+	})
+}
+
+// recycleSelected starts taking the player's selected buildings apart
+func (g *Game) recycleSelected() {
+	for _, b := range g.world.Buildings {
+		if !b.Selected || b.Faction != entity.FactionPlayer || !b.CanRecycle() {
+			continue
+		}
+		if g.state == StateMultiplayerPlaying {
+			if g.networkClient != nil {
+				g.networkClient.SendRecycleCommand(b.ID)
+			}
+			continue
+		}
+		g.world.Submit(sim.Command{
+			Type:       sim.CmdRecycle,
+			Faction:    entity.FactionPlayer,
+			BuildingID: b.ID,
+		})
+	}
+}
+
+// formationName is the formation as shown in the instructions bar
+func (g *Game) formationName() string {
+	switch g.formation {
+	case sim.FormationLine:
+		return "Line"
+	case sim.FormationWedge:
+		return "Wedge"
+	default:
+		return "Box"
+	}
+}
+
+// rightClickOrder works out what a right click at pos tells the selected
+// units to do: attack a visible enemy under the cursor, load, repair or
+// guard a friend, reclaim a wreck, or otherwise move there. Ctrl turns the
+// move into an attack-move, Alt into a patrol, U into an unload, and Shift
+// queues the order. A drag faces a group move towards end, or reclaims
+// every wreck in the dragged area when it starts on a wreck.
+func (g *Game) rightClickOrder(inputState input.State, pos, end emath.Vec2, dragged bool) (sim.Command, bool) {
+	unitIDs := g.selectedUnitIDs()
+	if len(unitIDs) == 0 {
+		return sim.Command{}, false
+	}
+	if dragged && g.reclaimDrag() {
+		return sim.Command{
+			Type:    sim.CmdReclaimArea,
+			Faction: entity.FactionPlayer,
+			UnitIDs: unitIDs,
+			TargetX: pos.X,
+			TargetY: pos.Y,
+			EndX:    end.X,
+			EndY:    end.Y,
+			Queue:   inputState.ShiftHeld,
+		}, true
+	}
+	if inputState.UnloadHeld && g.selectionCanTransport() {
+		cmd := sim.Command{
+			Type:    sim.CmdUnload,
+			Faction: entity.FactionPlayer,
+			UnitIDs: unitIDs,
+			TargetX: pos.X,
+			TargetY: pos.Y,
+			Queue:   inputState.ShiftHeld,
+		}
+		// Clicking a selected transport sets its cargo down where it is
+		if u := g.unitAt(pos); u != nil && u.Selected && u.CanTransport() && !inputState.ShiftHeld {
+			cmd.Type = sim.CmdUnloadAll
+		}
+		return cmd, true
+	}
+	var facing float64
+	hasFacing := dragged
+	if dragged {
+		dir := end.Sub(pos)
+		facing = math.Atan2(dir.Y, dir.X)
+	}
+	cmd := sim.Command{
+		Type:      sim.CmdMove,
+		Faction:   entity.FactionPlayer,
+		UnitIDs:   unitIDs,
+		TargetX:   pos.X,
+		TargetY:   pos.Y,
+		Formation: g.formation,
+		Facing:    facing,
+		HasFacing: hasFacing,
+		Queue:     inputState.ShiftHeld,
+	}
+	switch {
+	case inputState.CtrlHeld:
+		cmd.Type = sim.CmdAttackMove
+	case inputState.AltHeld:
+		cmd.Type = sim.CmdPatrol
+	}
+	if hasFacing || cmd.Type != sim.CmdMove {
+		return cmd, true
+	}
+
+	for _, u := range g.world.Units {
+		if !u.Active || !u.Contains(pos) {
+			continue
+		}
+		if u.Faction != entity.FactionPlayer {
+			if g.unitVisible(u) {
+				cmd.Type, cmd.TargetID = sim.CmdAttack, u.ID
+				return cmd, true
+			}
+			continue
+		}
+		if g.selectionCanLoad(u) {
+			cmd.Type, cmd.TargetID = sim.CmdLoad, u.ID
+		} else if u.Health < u.MaxHealth && g.selectionCanRepair(u) {
+			cmd.Type, cmd.TargetID = sim.CmdRepair, u.ID
+		} else if !u.Selected {
+			cmd.Type, cmd.TargetID = sim.CmdGuard, u.ID
+		}
+		return cmd, true
+	}
+	for _, b := range g.world.Buildings {
+		if !b.Active || !b.Contains(pos) {
+			continue
+		}
+		if b.Faction != entity.FactionPlayer {
+			if g.fogOfWar.IsVisible(b.Bounds()) {
+				cmd.Type, cmd.TargetID = sim.CmdAttack, b.ID
+				return cmd, true
+			}
+			continue
+		}
+		cmd.Type, cmd.TargetID = sim.CmdGuard, b.ID
+		return cmd, true
+	}
+	if wreck := g.wreckAt(pos); wreck != nil && g.selectionCanReclaim() {
+		cmd.Type, cmd.TargetID = sim.CmdReclaim, wreck.ID
+	}
+	return cmd, true
+}
+
+// wreckAt returns the wreck under pos, or nil
+func (g *Game) wreckAt(pos emath.Vec2) *entity.Wreckage {
+	for _, w := range g.world.Wreckages {
+		if w.Active && w.Contains(pos) {
+			return w
+		}
+	}
+	return nil
+}
+
+// selectionCanReclaim reports whether any selected unit can reclaim wrecks
+func (g *Game) selectionCanReclaim() bool {
+	for _, u := range g.world.Units {
+		if u.Selected && u.Faction == entity.FactionPlayer && u.CanReclaim() {
+			return true
+		}
+	}
+	return false
+}
+
+// reclaimDrag reports whether the right drag in progress is an area
+// reclaim, which starts on a wreck with reclaimers selected
+func (g *Game) reclaimDrag() bool {
+	return g.wreckAt(g.orderStart) != nil && g.selectionCanReclaim()
+}
+
+// unitAt returns the active unit under pos, or nil
+func (g *Game) unitAt(pos emath.Vec2) *entity.Unit {
+	for _, u := range g.world.Units {
+		if u.Active && u.Contains(pos) {
+			return u
+		}
+	}
+	return nil
+}
+
+// selectionCanTransport reports whether any selected unit is a transport
+func (g *Game) selectionCanTransport() bool {
+	for _, u := range g.world.Units {
+		if u.Selected && u.Faction == entity.FactionPlayer && u.CanTransport() {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionCanLoad reports whether a selected transport can pick target
+// up, or a selected unit can board it
+func (g *Game) selectionCanLoad(target *entity.Unit) bool {
+	for _, u := range g.world.Units {
+		if u.Selected && u.Faction == entity.FactionPlayer && (u.CanLoad(target) || target.CanLoad(u)) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionCanRepair reports whether any selected unit can repair target
+func (g *Game) selectionCanRepair(target *entity.Unit) bool {
+	for _, u := range g.world.Units {
+		if u.Selected && u.Faction == entity.FactionPlayer && u.CanRepair() && u != target {
+			return true
+		}
+	}
+	return false
+}
+
+// gameCommand converts an order for sending to the multiplayer server
+func gameCommand(cmd sim.Command) protocol.GameCommand {
+	return protocol.GameCommand{
+		Type:      protocol.CommandType(cmd.Type),
+		UnitIDs:   cmd.UnitIDs,
+		TargetX:   cmd.TargetX,
+		TargetY:   cmd.TargetY,
+		EndX:      cmd.EndX,
+		EndY:      cmd.EndY,
+		TargetID:  cmd.TargetID,
+		Formation: string(cmd.Formation),
+		Facing:    cmd.Facing,
+		HasFacing: cmd.HasFacing,
+		Ability:   int(cmd.Ability),
+		Queue:     cmd.Queue,
+	}
+}
+
+// orderColors tells queued orders apart on the map
+var orderColors = map[entity.OrderType]color.RGBA{
+	entity.OrderMove:       {0, 255, 0, 200},
+	entity.OrderAttackMove: {255, 160, 0, 200},
+	entity.OrderAttack:     {255, 40, 40, 200},
+	entity.OrderPatrol:     {80, 160, 255, 200},
+	entity.OrderGuard:      {0, 220, 220, 200},
+	entity.OrderReclaim:    {210, 170, 60, 200},
+	entity.OrderLoad:       {180, 120, 255, 200},
+	entity.OrderUnload:     {180, 120, 255, 200},
+}
+
+// drawOrderQueue draws the legs of a selected unit's order queue after
+// its current movement
+func (g *Game) drawOrderQueue(screen *ebiten.Image, u *entity.Unit) {
+	if len(u.Orders) == 0 {
+		return
+	}
+	cam := g.engine.Camera
+	r := g.engine.Renderer
+	zoom := cam.GetZoom()
+	from := u.Center()
+	if u.HasTarget {
+		from = u.Target
+	}
+	for i := range u.Orders {
+		o := &u.Orders[i]
+		c := orderColors[o.Type]
+		to := o.Point()
+		if i > 0 || !u.HasTarget {
+			faded := c
+			faded.A = 90
+			r.DrawLine(screen, cam.WorldToScreen(from), cam.WorldToScreen(to), 1, faded)
+		}
+		r.DrawCircle(screen, cam.WorldToScreen(to), float32(4*zoom), c)
+		from = to
+	}
+}
+
+// drawOrderPreview shows the facing arrow and the slots of the selected
+// units, or the area to reclaim, while a right drag is in progress
+func (g *Game) drawOrderPreview(screen *ebiten.Image) {
+	if !g.orderPending || !g.engine.Input.State().IsRightDragging {
+		return
+	}
+	if g.reclaimDrag() {
+		g.drawReclaimPreview(screen)
+		return
+	}
+	var units []*entity.Unit
+	for _, u := range g.world.Units {
+		if u.Selected && u.Active && u.Faction == entity.FactionPlayer {
+			units = append(units, u)
+		}
+	}
+	if len(units) == 0 {
+		return
+	}
+
+	cam := g.engine.Camera
+	r := g.engine.Renderer
+	zoom := cam.GetZoom()
+	end := cam.ScreenToWorld(g.engine.Input.State().MousePos)
+	dir := end.Sub(g.orderStart)
+	facing := math.Atan2(dir.Y, dir.X)
+
+	arrowColor := color.RGBA{0, 255, 0, 200}
+	from := cam.WorldToScreen(g.orderStart)
+	to := cam.WorldToScreen(end)
+	r.DrawLine(screen, from, to, 2, arrowColor)
+	for _, side := range []float64{-1, 1} {
+		head := end.Sub(emath.Vec2{X: math.Cos(facing + side*0.5), Y: math.Sin(facing + side*0.5)}.Mul(12))
+		r.DrawLine(screen, to, cam.WorldToScreen(head), 2, arrowColor)
+	}
+
+	for _, slot := range g.world.PreviewFormation(units, g.formation, g.orderStart, facing) {
+		r.DrawCircle(screen, cam.WorldToScreen(slot), float32(4*zoom), color.RGBA{0, 255, 0, 120})
+	}
+}
+
+// drawReclaimPreview outlines the area of a reclaim drag and the wrecks in it
+func (g *Game) drawReclaimPreview(screen *ebiten.Image) {
+	cam := g.engine.Camera
+	r := g.engine.Renderer
+	c := orderColors[entity.OrderReclaim]
+	end := cam.ScreenToWorld(g.engine.Input.State().MousePos)
+	area := emath.Rect{
+		Pos:  emath.Vec2{X: min(g.orderStart.X, end.X), Y: min(g.orderStart.Y, end.Y)},
+		Size: emath.Vec2{X: math.Abs(end.X - g.orderStart.X), Y: math.Abs(end.Y - g.orderStart.Y)},
+	}
+	r.DrawRectOutline(screen, emath.Rect{Pos: cam.WorldToScreen(area.Pos), Size: area.Size.Mul(cam.GetZoom())}, 1, c)
+	for _, w := range g.world.Wreckages {
+		if w.Active && w.Bounds().Intersects(area) {
+			b := w.Bounds()
+			r.DrawRectOutline(screen, emath.Rect{Pos: cam.WorldToScreen(b.Pos), Size: b.Size.Mul(cam.GetZoom())}, 2, c)
+		}
+	}
+}
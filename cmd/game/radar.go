@@ -0,0 +1,68 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	"github.com/bklimczak/tanks/engine/ui"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// radarBlipColor is the minimap mark of an enemy only radar can see
+var radarBlipColor = color.RGBA{255, 90, 90, 255}
+
+// unitVisible reports whether the player can see u: their own units always,
+// enemies when in sight and neither cloaked from every detector nor in smoke
+func (g *Game) unitVisible(u *entity.Unit) bool {
+	if u.Faction == entity.FactionPlayer {
+		return true
+	}
+	return g.fogOfWar.IsVisible(u.Bounds()) && !g.world.Concealed(u, entity.FactionPlayer)
+}
+
+// minimapEntities lists what the minimap shows: the units and buildings the
+// player can see, and a blip for every other enemy unit the player's radar
+// covers. Cloaked units need a detector to show up even on radar.
+func (g *Game) minimapEntities() []ui.MinimapEntity {
+	entities := make([]ui.MinimapEntity, 0, len(g.world.Units)+len(g.world.Buildings))
+	for _, u := range g.world.Units {
+		if !u.Active {
+			continue
+		}
+		switch {
+		case g.unitVisible(u):
+			entities = append(entities, ui.MinimapEntity{
+				Position: u.Position,
+				Size:     u.Size,
+				Color:    u.Color,
+			})
+		case g.world.OnRadar(u.Center(), entity.FactionPlayer) && (!u.IsCloaked() || g.world.Detected(u.Center(), entity.FactionPlayer)):
+			entities = append(entities, ui.MinimapEntity{
+				Position: u.Position,
+				Size:     u.Size,
+				Color:    radarBlipColor,
+				Blip:     true,
+			})
+		}
+	}
+	for _, b := range g.world.Buildings {
+		if b.Faction == entity.FactionPlayer || g.fogOfWar.IsVisible(b.Bounds()) {
+			entities = append(entities, ui.MinimapEntity{
+				Position: b.Position,
+				Size:     b.Size,
+				Color:    b.Color,
+			})
+		}
+	}
+	return entities
+}
+
+// drawCloak rings a cloaked unit so the player can tell it apart: their
+// own are hidden from enemies, others are ones a detector has found
+func (g *Game) drawCloak(screen *ebiten.Image, u *entity.Unit) {
+	cam := g.engine.Camera
+	center := cam.WorldToScreen(u.Center())
+	radius := float32(max(u.Size.X, u.Size.Y)/2*cam.GetZoom()) + 2
+	vector.StrokeCircle(screen, float32(center.X), float32(center.Y), radius, 1, color.NRGBA{150, 200, 255, 140}, true)
+}
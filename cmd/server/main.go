@@ -14,6 +14,8 @@ import (
 
 func main() {
 	addr := flag.String("addr", ":8080", "Server address")
+	dataDir := flag.String("data", "matches", "Directory for running match snapshots (empty disables persistence)")
+	snapshotInterval := flag.Duration("snapshot-interval", 30*time.Second, "How often running matches are snapshotted")
 	flag.Parse()
 
 	log.Println("=================================")
@@ -22,6 +24,12 @@ func main() {
 
 	srv := server.New()
 
+	if *dataDir != "" {
+		if err := srv.EnablePersistence(*dataDir, *snapshotInterval); err != nil {
+			log.Fatalf("Match persistence: %v", err)
+		}
+	}
+
 	// Channel to listen for OS signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -60,7 +60,6 @@ func NewEnemyAI(baseX, baseY float64) *EnemyAI {
 
 func (ai *EnemyAI) Update(dt float64, allUnits []*entity.Unit, allBuildings []*entity.Building) {
 	ai.updateUnitLists(allUnits, allBuildings)
-	ai.Resources.Update(dt)
 	ai.decisionTimer += dt
 	ai.attackTimer += dt
 	ai.produceTimer += dt
@@ -81,7 +80,7 @@ func (ai *EnemyAI) Update(dt float64, allUnits []*entity.Unit, allBuildings []*e
 		ai.doProduce()
 	}
 
-	ai.updateProduction(dt)
+	ai.updateProduction()
 }
 
 func (ai *EnemyAI) updateUnitLists(allUnits []*entity.Unit, allBuildings []*entity.Building) {
@@ -225,7 +224,8 @@ func (ai *EnemyAI) doProduce() {
 	// Production is handled in updateProduction
 }
 
-func (ai *EnemyAI) updateProduction(dt float64) {
+// updateProduction queues new units; the simulation builds them using ai.Resources
+func (ai *EnemyAI) updateProduction() {
 	for _, b := range ai.Buildings {
 		if b.Type != entity.BuildingTankFactory || !b.Completed {
 			continue
@@ -241,10 +241,6 @@ func (ai *EnemyAI) updateProduction(dt float64) {
 				ai.produceTimer = 0
 			}
 		}
-
-		if completedUnit := b.UpdateProduction(dt, ai.Resources); completedUnit != nil {
-			// Unit will be spawned by the game's building update system
-		}
 	}
 }
 
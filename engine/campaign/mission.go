@@ -185,6 +185,8 @@ func (m *Mission) GetBuildingType(typeName string) entity.BuildingType {
 		return entity.BuildingSolarPanel
 	case "LaserTower":
 		return entity.BuildingLaserTower
+	case "Airfield":
+		return entity.BuildingAirfield
 	default:
 		return entity.BuildingCommandNexus
 	}
@@ -4,10 +4,12 @@ import (
 	"math"
 
 	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/movement"
 )
 
+// TerrainChecker reports whether a unit of a movement class fits on the map
 type TerrainChecker interface {
-	IsPassable(bounds emath.Rect) bool
+	IsPassable(bounds emath.Rect, class movement.Class) bool
 }
 type Collidable interface {
 	Bounds() emath.Rect
@@ -32,20 +34,23 @@ func (s *System) CheckCollision(a, b emath.Rect) bool {
 	return a.Intersects(b)
 }
 
-func (s *System) ResolveMovement(mover emath.Rect, desiredPos emath.Vec2, obstacles []emath.Rect) emath.Vec2 {
+// ResolveMovement moves mover towards desiredPos without entering terrain its
+// movement class cannot cross or overlapping any obstacle, sliding along
+// whichever axis is still free
+func (s *System) ResolveMovement(mover emath.Rect, desiredPos emath.Vec2, obstacles []emath.Rect, class movement.Class) emath.Vec2 {
 	newBounds := emath.Rect{Pos: desiredPos, Size: mover.Size}
 	desiredPos = s.clampToWorld(desiredPos, mover.Size)
 	newBounds.Pos = desiredPos
-	if s.terrain != nil && !s.terrain.IsPassable(newBounds) {
+	if s.terrain != nil && !s.terrain.IsPassable(newBounds, class) {
 		xOnlyPos := emath.Vec2{X: desiredPos.X, Y: mover.Pos.Y}
 		xOnlyBounds := emath.Rect{Pos: xOnlyPos, Size: mover.Size}
-		if s.terrain.IsPassable(xOnlyBounds) {
+		if s.terrain.IsPassable(xOnlyBounds, class) {
 			desiredPos = xOnlyPos
 			newBounds.Pos = desiredPos
 		} else {
 			yOnlyPos := emath.Vec2{X: mover.Pos.X, Y: desiredPos.Y}
 			yOnlyBounds := emath.Rect{Pos: yOnlyPos, Size: mover.Size}
-			if s.terrain.IsPassable(yOnlyBounds) {
+			if s.terrain.IsPassable(yOnlyBounds, class) {
 				desiredPos = yOnlyPos
 				newBounds.Pos = desiredPos
 			} else {
@@ -65,7 +70,7 @@ func (s *System) ResolveMovement(mover emath.Rect, desiredPos emath.Vec2, obstac
 			}
 			xOnlyPos := emath.Vec2{X: desiredPos.X, Y: mover.Pos.Y}
 			xOnlyBounds := emath.Rect{Pos: xOnlyPos, Size: mover.Size}
-			if !xOnlyBounds.Intersects(obs) && (s.terrain == nil || s.terrain.IsPassable(xOnlyBounds)) {
+			if !xOnlyBounds.Intersects(obs) && (s.terrain == nil || s.terrain.IsPassable(xOnlyBounds, class)) {
 				desiredPos = xOnlyPos
 				newBounds.Pos = desiredPos
 				continue
@@ -73,7 +78,7 @@ func (s *System) ResolveMovement(mover emath.Rect, desiredPos emath.Vec2, obstac
 			if currentlyOverlapping && xOnlyBounds.Intersects(obs) {
 				currentOverlap := s.overlapAmount(mover, obs)
 				xOverlap := s.overlapAmount(xOnlyBounds, obs)
-				if xOverlap < currentOverlap && (s.terrain == nil || s.terrain.IsPassable(xOnlyBounds)) {
+				if xOverlap < currentOverlap && (s.terrain == nil || s.terrain.IsPassable(xOnlyBounds, class)) {
 					desiredPos = xOnlyPos
 					newBounds.Pos = desiredPos
 					continue
@@ -81,7 +86,7 @@ func (s *System) ResolveMovement(mover emath.Rect, desiredPos emath.Vec2, obstac
 			}
 			yOnlyPos := emath.Vec2{X: mover.Pos.X, Y: desiredPos.Y}
 			yOnlyBounds := emath.Rect{Pos: yOnlyPos, Size: mover.Size}
-			if !yOnlyBounds.Intersects(obs) && (s.terrain == nil || s.terrain.IsPassable(yOnlyBounds)) {
+			if !yOnlyBounds.Intersects(obs) && (s.terrain == nil || s.terrain.IsPassable(yOnlyBounds, class)) {
 				desiredPos = yOnlyPos
 				newBounds.Pos = desiredPos
 				continue
@@ -89,7 +94,7 @@ func (s *System) ResolveMovement(mover emath.Rect, desiredPos emath.Vec2, obstac
 			if currentlyOverlapping && yOnlyBounds.Intersects(obs) {
 				currentOverlap := s.overlapAmount(mover, obs)
 				yOverlap := s.overlapAmount(yOnlyBounds, obs)
-				if yOverlap < currentOverlap && (s.terrain == nil || s.terrain.IsPassable(yOnlyBounds)) {
+				if yOverlap < currentOverlap && (s.terrain == nil || s.terrain.IsPassable(yOnlyBounds, class)) {
 					desiredPos = yOnlyPos
 					newBounds.Pos = desiredPos
 					continue
@@ -100,6 +105,11 @@ func (s *System) ResolveMovement(mover emath.Rect, desiredPos emath.Vec2, obstac
 	}
 	return desiredPos
 }
+
+// ResolveFlight moves an aircraft, which nothing but the world edge blocks
+func (s *System) ResolveFlight(mover emath.Rect, desiredPos emath.Vec2) emath.Vec2 {
+	return s.clampToWorld(desiredPos, mover.Size)
+}
 func (s *System) overlapAmount(a, b emath.Rect) float64 {
 	overlapX := min(a.Pos.X+a.Size.X, b.Pos.X+b.Size.X) - max(a.Pos.X, b.Pos.X)
 	overlapY := min(a.Pos.Y+a.Size.Y, b.Pos.Y+b.Size.Y) - max(a.Pos.Y, b.Pos.Y)
@@ -126,7 +136,7 @@ func (s *System) clampToWorld(pos emath.Vec2, size emath.Vec2) emath.Vec2 {
 
 // CalculateAvoidanceDirection finds an alternative movement direction when blocked.
 // It tries multiple angles offset from the desired direction and returns the best passable one.
-func (s *System) CalculateAvoidanceDirection(mover emath.Rect, target emath.Vec2, speed float64, obstacles []emath.Rect) emath.Vec2 {
+func (s *System) CalculateAvoidanceDirection(mover emath.Rect, target emath.Vec2, speed float64, obstacles []emath.Rect, class movement.Class) emath.Vec2 {
 	moverCenter := emath.Vec2{X: mover.Pos.X + mover.Size.X/2, Y: mover.Pos.Y + mover.Size.Y/2}
 	toTarget := target.Sub(moverCenter)
 	distToTarget := toTarget.Length()
@@ -153,7 +163,7 @@ func (s *System) CalculateAvoidanceDirection(mover emath.Rect, target emath.Vec2
 		testBounds := emath.Rect{Pos: testPos, Size: mover.Size}
 
 		// Check terrain
-		if s.terrain != nil && !s.terrain.IsPassable(testBounds) {
+		if s.terrain != nil && !s.terrain.IsPassable(testBounds, class) {
 			continue
 		}
 
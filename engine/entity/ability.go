@@ -0,0 +1,193 @@
+package entity
+
+import emath "github.com/bklimczak/tanks/engine/math"
+
+// AbilityType identifies an activated unit ability
+type AbilityType int
+
+const (
+	AbilitySensorSweep AbilityType = iota
+	AbilitySmokeScreen
+	AbilitySpeedBoost
+	AbilityFieldRepair
+	NumAbilityTypes
+)
+
+func (t AbilityType) String() string {
+	if def := AbilityDefs[t]; def != nil {
+		return def.Name
+	}
+	return "Ability"
+}
+
+// AbilityTarget is what an ability is aimed at when it is used
+type AbilityTarget int
+
+const (
+	AbilityTargetSelf  AbilityTarget = iota // Fires at once on the units that use it
+	AbilityTargetPoint                      // Aimed at a point on the map
+	AbilityTargetUnit                       // Aimed at another unit
+)
+
+// AbilityDef describes an ability: what it costs, how it is aimed and
+// what it does
+type AbilityDef struct {
+	Type        AbilityType
+	Name        string
+	Description string
+	Target      AbilityTarget
+	EnergyCost  float64
+	Cooldown    float64 // Seconds before the unit can use it again
+	Range       float64 // Point and unit abilities: reach from the unit's center
+
+	// Effect; the zero value of each field does nothing
+	Radius     float64 // Area abilities: radius of the area
+	Duration   float64 // Seconds an area or a boost lasts
+	Reveals    bool    // Area: shows its faction everything inside, smoke included
+	Conceals   bool    // Area: hides units inside from enemy vision and fire
+	SpeedBonus float64 // Boost: extra speed, as a fraction of the base speed
+	Heal       float64 // Health restored to the target unit
+}
+
+// IsArea reports whether the ability leaves a lasting area on the map
+func (d *AbilityDef) IsArea() bool {
+	return d.Radius > 0 && d.Duration > 0
+}
+
+// AbilityDefs lists every ability; unit definitions pick theirs from it
+var AbilityDefs = map[AbilityType]*AbilityDef{
+	AbilitySensorSweep: {
+		Type:        AbilitySensorSweep,
+		Name:        "Sensor Sweep",
+		Description: "Reveals a distant area for a few seconds",
+		Target:      AbilityTargetPoint,
+		EnergyCost:  40,
+		Cooldown:    25,
+		Range:       700,
+		Radius:      220,
+		Duration:    6,
+		Reveals:     true,
+	},
+	AbilitySmokeScreen: {
+		Type:        AbilitySmokeScreen,
+		Name:        "Smoke Screen",
+		Description: "Smoke that hides the units inside it",
+		Target:      AbilityTargetPoint,
+		EnergyCost:  30,
+		Cooldown:    30,
+		Range:       200,
+		Radius:      90,
+		Duration:    10,
+		Conceals:    true,
+	},
+	AbilitySpeedBoost: {
+		Type:        AbilitySpeedBoost,
+		Name:        "Overdrive",
+		Description: "Drives much faster for a short time",
+		Target:      AbilityTargetSelf,
+		EnergyCost:  20,
+		Cooldown:    20,
+		Duration:    5,
+		SpeedBonus:  0.6,
+	},
+	AbilityFieldRepair: {
+		Type:        AbilityFieldRepair,
+		Name:        "Field Repair",
+		Description: "Patches up a damaged friendly unit",
+		Target:      AbilityTargetUnit,
+		EnergyCost:  50,
+		Cooldown:    20,
+		Range:       150,
+		Heal:        60,
+	},
+}
+
+// AbilityState is a unit's ability cooldowns and the boost it is under
+type AbilityState struct {
+	Cooldowns [NumAbilityTypes]float64 // Seconds until each ability is ready again
+	Boost     float64                  // Extra speed, as a fraction of the base speed
+	BoostLeft float64                  // Seconds the boost lasts
+}
+
+// Abilities returns the abilities of the unit's type
+func (u *Unit) Abilities() []AbilityType {
+	if u.Def == nil {
+		return nil
+	}
+	return u.Def.Abilities
+}
+
+// HasAbility reports whether the unit's type has the ability
+func (u *Unit) HasAbility(t AbilityType) bool {
+	for _, a := range u.Abilities() {
+		if a == t {
+			return true
+		}
+	}
+	return false
+}
+
+// AbilityReady reports whether the unit can use the ability now
+func (u *Unit) AbilityReady(t AbilityType) bool {
+	return u.Active && u.HasAbility(t) && u.Ability.Cooldowns[t] <= 0
+}
+
+// CanAbilityTarget reports whether a unit ability used by u may be aimed
+// at target. Healing goes to damaged friendly units other than u.
+func (u *Unit) CanAbilityTarget(def *AbilityDef, target *Unit) bool {
+	if target == nil || target == u || !target.Active || target.Carrier != nil {
+		return false
+	}
+	if def.Heal > 0 {
+		return target.Faction == u.Faction && target.Health < target.MaxHealth
+	}
+	return target.Faction != u.Faction
+}
+
+// UseAbility starts the ability's cooldown and applies what it does to the
+// unit itself; area and target effects are left to the caller
+func (u *Unit) UseAbility(def *AbilityDef) {
+	u.Ability.Cooldowns[def.Type] = def.Cooldown
+	if def.SpeedBonus > 0 {
+		u.Ability.Boost = def.SpeedBonus
+		u.Ability.BoostLeft = def.Duration
+	}
+}
+
+// UpdateAbilities counts down cooldowns and the running boost
+func (u *Unit) UpdateAbilities(dt float64) {
+	for i := range u.Ability.Cooldowns {
+		u.Ability.Cooldowns[i] = max(0, u.Ability.Cooldowns[i]-dt)
+	}
+	if u.Ability.BoostLeft > 0 {
+		u.Ability.BoostLeft -= dt
+		if u.Ability.BoostLeft <= 0 {
+			u.Ability.Boost, u.Ability.BoostLeft = 0, 0
+		}
+	}
+}
+
+// AreaEffect is an ability's lasting effect on an area of the map, such
+// as a smoke cloud or a sensor sweep
+type AreaEffect struct {
+	Def       *AbilityDef
+	Faction   Faction // Faction of the unit that made it
+	Center    emath.Vec2
+	Remaining float64 // Seconds left
+}
+
+// NewAreaEffect creates the area an ability leaves at center
+func NewAreaEffect(def *AbilityDef, faction Faction, center emath.Vec2) *AreaEffect {
+	return &AreaEffect{Def: def, Faction: faction, Center: center, Remaining: def.Duration}
+}
+
+// Covers reports whether p lies inside the area
+func (e *AreaEffect) Covers(p emath.Vec2) bool {
+	return e.Center.DistanceSquared(p) <= e.Def.Radius*e.Def.Radius
+}
+
+// Update counts the effect down and reports whether it is still there
+func (e *AreaEffect) Update(dt float64) bool {
+	e.Remaining -= dt
+	return e.Remaining > 0
+}
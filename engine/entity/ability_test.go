@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+func TestSpeedBoostWearsOff(t *testing.T) {
+	light := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeLightTank], FactionPlayer)
+	def := AbilityDefs[AbilitySpeedBoost]
+	if !light.AbilityReady(AbilitySpeedBoost) || light.AbilityReady(AbilitySmokeScreen) {
+		t.Fatal("a light tank should have the boost ready and no smoke")
+	}
+
+	light.UseAbility(def)
+	if got, want := light.MoveSpeed(), light.Speed*(1+def.SpeedBonus); got != want {
+		t.Errorf("boosted speed = %v, want %v", got, want)
+	}
+	if light.AbilityReady(AbilitySpeedBoost) {
+		t.Error("a used ability should be cooling down")
+	}
+
+	light.UpdateAbilities(def.Duration + 0.1)
+	if light.MoveSpeed() != light.Speed || light.AbilityReady(AbilitySpeedBoost) {
+		t.Errorf("after the boost: speed %v, ready %v", light.MoveSpeed(), light.AbilityReady(AbilitySpeedBoost))
+	}
+	light.UpdateAbilities(def.Cooldown)
+	if !light.AbilityReady(AbilitySpeedBoost) {
+		t.Error("the boost should be ready again once the cooldown runs out")
+	}
+}
+
+func TestFieldRepairTargetsDamagedFriends(t *testing.T) {
+	constructor := NewConstructor(1, 0, 0, FactionPlayer)
+	tank := NewTank(2, 50, 0, FactionPlayer)
+	enemy := NewTank(3, 50, 0, FactionEnemy)
+	def := AbilityDefs[AbilityFieldRepair]
+
+	if constructor.CanAbilityTarget(def, tank) {
+		t.Error("a unit at full health needs no repair")
+	}
+	tank.Health = 20
+	enemy.Health = 20
+	if !constructor.CanAbilityTarget(def, tank) || constructor.CanAbilityTarget(def, enemy) {
+		t.Error("field repair should take damaged friends only")
+	}
+}
+
+func TestAreaEffectCoversRadiusUntilItRunsOut(t *testing.T) {
+	def := AbilityDefs[AbilitySmokeScreen]
+	smoke := NewAreaEffect(def, FactionPlayer, emath.Vec2{X: 100, Y: 100})
+	if !smoke.Covers(emath.Vec2{X: 100 + def.Radius - 1, Y: 100}) || smoke.Covers(emath.Vec2{X: 100 + def.Radius + 1, Y: 100}) {
+		t.Error("smoke should cover its radius and nothing beyond")
+	}
+	if !smoke.Update(def.Duration-1) || smoke.Update(2) {
+		t.Error("smoke should last for its duration")
+	}
+}
@@ -0,0 +1,121 @@
+package entity
+
+// ArmorClass is what a unit or building is protected by
+type ArmorClass int
+
+const (
+	ArmorLight     ArmorClass = iota // Scouts, light vehicles, support units
+	ArmorHeavy                       // Main battle and heavy tanks
+	ArmorStructure                   // Buildings
+	ArmorAir                         // Aircraft
+	NumArmorClasses
+)
+
+func (a ArmorClass) String() string {
+	switch a {
+	case ArmorLight:
+		return "Light"
+	case ArmorHeavy:
+		return "Heavy"
+	case ArmorStructure:
+		return "Structure"
+	case ArmorAir:
+		return "Air"
+	default:
+		return "Armor"
+	}
+}
+
+// DamageType is the kind of damage a weapon deals
+type DamageType int
+
+const (
+	DamageKinetic   DamageType = iota // Guns and light cannons
+	DamagePiercing                    // Armor-piercing cannons and rockets
+	DamageExplosive                   // Shells and bombs
+	DamageFire                        // Flames and burning
+	DamageFlak                        // Anti-air fire
+	DamageEnergy                      // Lasers
+	NumDamageTypes
+)
+
+func (d DamageType) String() string {
+	switch d {
+	case DamageKinetic:
+		return "Kinetic"
+	case DamagePiercing:
+		return "Piercing"
+	case DamageExplosive:
+		return "Explosive"
+	case DamageFire:
+		return "Fire"
+	case DamageFlak:
+		return "Flak"
+	case DamageEnergy:
+		return "Energy"
+	default:
+		return "Damage"
+	}
+}
+
+// EffectivenessMatrix scales damage by the damage type dealing it (row)
+// and the armor class receiving it (column)
+type EffectivenessMatrix [NumDamageTypes][NumArmorClasses]float64
+
+// DefaultEffectiveness is the stock rock-paper-scissors between weapons
+// and armor
+var DefaultEffectiveness = EffectivenessMatrix{
+	//                Light Heavy Structure Air
+	DamageKinetic:   {1.25, 0.6, 0.5, 1.0},
+	DamagePiercing:  {0.75, 1.5, 1.0, 0.75},
+	DamageExplosive: {1.25, 0.75, 1.5, 0.25},
+	DamageFire:      {1.5, 0.5, 0.75, 0.5},
+	DamageFlak:      {0.5, 0.25, 0.25, 2.0},
+	DamageEnergy:    {1.0, 1.25, 0.5, 1.0},
+}
+
+// Effectiveness is the matrix applied to all damage. Replace it or change
+// single entries with Set to rebalance the game.
+var Effectiveness = DefaultEffectiveness
+
+// Multiplier returns how much of a hit of the given type gets through the
+// given armor
+func (m *EffectivenessMatrix) Multiplier(damage DamageType, armor ArmorClass) float64 {
+	if damage < 0 || damage >= NumDamageTypes || armor < 0 || armor >= NumArmorClasses {
+		return 1
+	}
+	return m[damage][armor]
+}
+
+// Set changes the multiplier of one damage type against one armor class
+func (m *EffectivenessMatrix) Set(damage DamageType, armor ArmorClass, multiplier float64) {
+	m[damage][armor] = multiplier
+}
+
+// Against splits the armor classes into those a damage type is strong
+// and weak against, leaving out the ones it deals normal damage to
+func (m *EffectivenessMatrix) Against(damage DamageType) (strong, weak []ArmorClass) {
+	for a := ArmorClass(0); a < NumArmorClasses; a++ {
+		switch mult := m.Multiplier(damage, a); {
+		case mult > 1:
+			strong = append(strong, a)
+		case mult < 1:
+			weak = append(weak, a)
+		}
+	}
+	return strong, weak
+}
+
+// Threats splits the damage types into those an armor class is vulnerable
+// to and those it resists
+func (m *EffectivenessMatrix) Threats(armor ArmorClass) (vulnerable, resists []DamageType) {
+	for d := DamageType(0); d < NumDamageTypes; d++ {
+		switch mult := m.Multiplier(d, armor); {
+		case mult > 1:
+			vulnerable = append(vulnerable, d)
+		case mult < 1:
+			resists = append(resists, d)
+		}
+	}
+	return vulnerable, resists
+}
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTakeDamageScalesByArmor(t *testing.T) {
+	heavy := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeHeavyTank], FactionPlayer)
+	light := NewUnitFromDef(2, 0, 0, UnitDefs[UnitTypeScout], FactionPlayer)
+
+	heavy.TakeDamage(10, DamagePiercing)
+	light.TakeDamage(10, DamagePiercing)
+	heavyLoss := heavy.MaxHealth - heavy.Health
+	lightLoss := light.MaxHealth - light.Health
+	if heavyLoss <= lightLoss {
+		t.Errorf("piercing should hurt heavy armor more: heavy lost %v, light lost %v", heavyLoss, lightLoss)
+	}
+	if want := 10 * Effectiveness.Multiplier(DamagePiercing, ArmorHeavy); heavyLoss != want {
+		t.Errorf("heavy lost %v, want %v", heavyLoss, want)
+	}
+}
+
+func TestEffectivenessIsConfigurable(t *testing.T) {
+	saved := Effectiveness
+	defer func() { Effectiveness = saved }()
+
+	Effectiveness.Set(DamageKinetic, ArmorStructure, 3)
+	b := NewBuilding(1, 0, 0, BuildingDefs[BuildingSolarPanel])
+	b.TakeDamage(10, DamageKinetic)
+	if got := b.MaxHealth - b.Health; got != 30 {
+		t.Errorf("building lost %v, want 30", got)
+	}
+	if DefaultEffectiveness[DamageKinetic][ArmorStructure] == 3 {
+		t.Error("changing Effectiveness should leave the defaults alone")
+	}
+}
+
+func TestAgainstSplitsStrongAndWeak(t *testing.T) {
+	strong, weak := DefaultEffectiveness.Against(DamageFlak)
+	if !slices.Equal(strong, []ArmorClass{ArmorAir}) {
+		t.Errorf("flak strong vs %v, want [Air]", strong)
+	}
+	if slices.Contains(weak, ArmorAir) || len(weak) != 3 {
+		t.Errorf("flak weak vs %v, want every ground class", weak)
+	}
+	if got := DefaultEffectiveness.Multiplier(NumDamageTypes, ArmorLight); got != 1 {
+		t.Errorf("unknown damage type multiplier = %v, want 1", got)
+	}
+}
@@ -20,11 +20,25 @@ type Building struct {
 	ProductionQueue       []*UnitDef
 	ProductionMetalSpent  float64
 	ProductionEnergySpent float64
+	Researching           bool
+	ResearchProgress      float64
+	CurrentResearch       *ResearchDef
+	ResearchQueue         []*ResearchDef
+	ResearchMetalSpent    float64
+	ResearchEnergySpent   float64
+	Recycling             bool    // Being taken apart by its owner
+	RecycleProgress       float64 // How far recycling has got, from 0 to 1
 	RallyPoint            emath.Vec2
 	HasRallyPoint         bool
 	Selected              bool
 	Health                float64
 	MaxHealth             float64
+	Burn                  Burn     // Fire damage still to be taken
+	Yield                 float64  // Extractors: metal per second drawn from the deposit last tick
+	DepositLeft           float64  // Extractors: metal left in the deposit tiles underneath
+	Power                 float64  // Share of its energy needs the grid covers, from 0 to 1
+	Priority              Priority // Whether the economy pays for its work first, last or not at all
+	Funding               float64  // Share of its work's cost the economy pays this tick, from 0 to 1
 
 	// Combat state for defensive buildings
 	AttackTarget *Unit
@@ -51,6 +65,8 @@ func NewBuilding(id uint64, x, y float64, def *BuildingDef) *Building {
 		BuildProgress: 1.0,
 		Health:        def.Health,
 		MaxHealth:     def.Health,
+		Power:         1,
+		Funding:       1,
 	}
 	b.RallyPoint = emath.Vec2{X: x + w + 20, Y: y + h/2}
 	b.HasRallyPoint = true
@@ -72,6 +88,8 @@ func NewBuildingUnderConstruction(id uint64, x, y float64, def *BuildingDef) *Bu
 		BuildProgress: 0.0,
 		Health:        def.Health * 0.1,
 		MaxHealth:     def.Health,
+		Power:         1,
+		Funding:       1,
 	}
 	b.RallyPoint = emath.Vec2{X: x + w + 20, Y: y + h/2}
 	b.HasRallyPoint = true
@@ -142,7 +160,7 @@ func (b *Building) UpdateConstruction(dt float64, resources *resource.Manager) b
 	return false
 }
 func (b *Building) CanProduce() bool {
-	return b.Def != nil && b.Def.IsFactory && b.Completed
+	return b.Def != nil && b.Def.IsFactory && b.Completed && !b.Recycling
 }
 func (b *Building) QueueProduction(unitDef *UnitDef) {
 	if !b.CanProduce() || unitDef == nil {
@@ -214,15 +232,35 @@ func (b *Building) UpdateProduction(dt float64, resources *resource.Manager) *Un
 	if !b.Producing {
 		return nil
 	}
-	progressDelta := dt / b.ProductionTime
-	targetProgress := b.ProductionProgress + progressDelta
+	b.ProductionProgress = payForProgress(dt, b.ProductionTime, b.CurrentProduction.Cost, b.ProductionProgress,
+		&b.ProductionMetalSpent, &b.ProductionEnergySpent, resources)
+	if b.ProductionProgress >= 1.0 {
+		completedUnit := b.CurrentProduction
+		b.Producing = false
+		b.ProductionProgress = 0
+		b.CurrentProduction = nil
+		b.ProductionMetalSpent = 0
+		b.ProductionEnergySpent = 0
+		b.startNextProduction()
+		return completedUnit
+	}
+	return nil
+}
+
+// payForProgress advances work lasting duration seconds by dt, as far as
+// the resources can pay for it. The work's metal and energy cost is spent
+// in step with its progress; metalSpent and energySpent hold what it has
+// taken so far. It returns the new progress.
+func payForProgress(dt, duration float64, cost map[resource.Type]float64, progress float64, metalSpent, energySpent *float64, resources *resource.Manager) float64 {
+	progressDelta := dt / duration
+	targetProgress := progress + progressDelta
 	if targetProgress > 1.0 {
 		targetProgress = 1.0
 	}
-	metalCost := b.CurrentProduction.Cost[resource.Metal]
-	energyCost := b.CurrentProduction.Cost[resource.Energy]
-	metalNeeded := targetProgress*metalCost - b.ProductionMetalSpent
-	energyNeeded := targetProgress*energyCost - b.ProductionEnergySpent
+	metalCost := cost[resource.Metal]
+	energyCost := cost[resource.Energy]
+	metalNeeded := targetProgress*metalCost - *metalSpent
+	energyNeeded := targetProgress*energyCost - *energySpent
 	metalAvailable := resources.Get(resource.Metal).Current
 	energyAvailable := resources.Get(resource.Energy).Current
 	metalToSpend := metalNeeded
@@ -235,17 +273,17 @@ func (b *Building) UpdateProduction(dt float64, resources *resource.Manager) *Un
 	}
 	var actualProgress float64
 	if metalCost > 0 && energyCost > 0 {
-		metalProgress := (b.ProductionMetalSpent + metalToSpend) / metalCost
-		energyProgress := (b.ProductionEnergySpent + energyToSpend) / energyCost
+		metalProgress := (*metalSpent + metalToSpend) / metalCost
+		energyProgress := (*energySpent + energyToSpend) / energyCost
 		if metalProgress < energyProgress {
 			actualProgress = metalProgress
 		} else {
 			actualProgress = energyProgress
 		}
 	} else if metalCost > 0 {
-		actualProgress = (b.ProductionMetalSpent + metalToSpend) / metalCost
+		actualProgress = (*metalSpent + metalToSpend) / metalCost
 	} else if energyCost > 0 {
-		actualProgress = (b.ProductionEnergySpent + energyToSpend) / energyCost
+		actualProgress = (*energySpent + energyToSpend) / energyCost
 	} else {
 		actualProgress = targetProgress
 	}
@@ -253,32 +291,102 @@ func (b *Building) UpdateProduction(dt float64, resources *resource.Manager) *Un
 		actualProgress = targetProgress
 	}
 	if metalCost > 0 {
-		metalToSpend = actualProgress*metalCost - b.ProductionMetalSpent
+		metalToSpend = actualProgress*metalCost - *metalSpent
 		if metalToSpend > 0 {
 			resources.Get(resource.Metal).SpendWithTracking(metalToSpend)
-			b.ProductionMetalSpent += metalToSpend
+			*metalSpent += metalToSpend
 		}
 	}
 	if energyCost > 0 {
-		energyToSpend = actualProgress*energyCost - b.ProductionEnergySpent
+		energyToSpend = actualProgress*energyCost - *energySpent
 		if energyToSpend > 0 {
 			resources.Get(resource.Energy).SpendWithTracking(energyToSpend)
-			b.ProductionEnergySpent += energyToSpend
+			*energySpent += energyToSpend
 		}
 	}
-	b.ProductionProgress = actualProgress
-	if b.ProductionProgress >= 1.0 {
-		completedUnit := b.CurrentProduction
-		b.Producing = false
-		b.ProductionProgress = 0
-		b.CurrentProduction = nil
-		b.ProductionMetalSpent = 0
-		b.ProductionEnergySpent = 0
-		b.startNextProduction()
-		return completedUnit
+	return actualProgress
+}
+
+// CanResearch reports whether the building runs research projects
+func (b *Building) CanResearch() bool {
+	return b.Def != nil && b.Def.IsLab && b.Completed && !b.Recycling
+}
+
+// QueueResearch adds a project to the research queue
+func (b *Building) QueueResearch(def *ResearchDef) {
+	if !b.CanResearch() || def == nil {
+		return
+	}
+	b.ResearchQueue = append(b.ResearchQueue, def)
+	if !b.Researching {
+		b.startNextResearch()
 	}
-	return nil
 }
+
+func (b *Building) startNextResearch() {
+	if len(b.ResearchQueue) == 0 {
+		return
+	}
+	b.CurrentResearch = b.ResearchQueue[0]
+	b.ResearchQueue = b.ResearchQueue[1:]
+	b.Researching = true
+	b.ResearchProgress = 0
+}
+
+// HasResearch reports whether a project is running or queued here
+func (b *Building) HasResearch(rt ResearchType) bool {
+	if b.Researching && b.CurrentResearch != nil && b.CurrentResearch.Type == rt {
+		return true
+	}
+	for _, def := range b.ResearchQueue {
+		if def.Type == rt {
+			return true
+		}
+	}
+	return false
+}
+
+// CancelResearch drops a project from the queue, refunding what a running
+// project has spent
+func (b *Building) CancelResearch(rt ResearchType, resources *resource.Manager) {
+	for i, def := range b.ResearchQueue {
+		if def.Type == rt {
+			b.ResearchQueue = append(b.ResearchQueue[:i], b.ResearchQueue[i+1:]...)
+			return
+		}
+	}
+	if !b.Researching || b.CurrentResearch == nil || b.CurrentResearch.Type != rt {
+		return
+	}
+	refund(resources, b.ResearchMetalSpent, b.ResearchEnergySpent)
+	b.stopResearch()
+	b.startNextResearch()
+}
+
+// UpdateResearch advances the running project and returns it once finished
+func (b *Building) UpdateResearch(dt float64, resources *resource.Manager) *ResearchDef {
+	if !b.Researching {
+		return nil
+	}
+	b.ResearchProgress = payForProgress(dt, b.CurrentResearch.ResearchTime, b.CurrentResearch.Cost, b.ResearchProgress,
+		&b.ResearchMetalSpent, &b.ResearchEnergySpent, resources)
+	if b.ResearchProgress < 1.0 {
+		return nil
+	}
+	finished := b.CurrentResearch
+	b.stopResearch()
+	b.startNextResearch()
+	return finished
+}
+
+func (b *Building) stopResearch() {
+	b.Researching = false
+	b.ResearchProgress = 0
+	b.CurrentResearch = nil
+	b.ResearchMetalSpent = 0
+	b.ResearchEnergySpent = 0
+}
+
 func (b *Building) GetSpawnPoint() emath.Vec2 {
 	return emath.Vec2{
 		X: b.Position.X + b.Size.X/2 - 11,
@@ -286,8 +394,18 @@ func (b *Building) GetSpawnPoint() emath.Vec2 {
 	}
 }
 
-func (b *Building) TakeDamage(damage float64) bool {
-	b.Health -= damage
+// Armor returns the building's armor class
+func (b *Building) Armor() ArmorClass {
+	if b.Def == nil {
+		return ArmorStructure
+	}
+	return b.Def.Armor
+}
+
+// TakeDamage applies a hit, scaled by how effective its damage type is
+// against the building's armor, and reports whether it destroyed the building
+func (b *Building) TakeDamage(damage float64, kind DamageType) bool {
+	b.Health -= damage * Effectiveness.Multiplier(kind, b.Armor())
 	if b.Health <= 0 {
 		b.Health = 0
 		b.Active = false
@@ -305,7 +423,15 @@ func (b *Building) HealthRatio() float64 {
 
 // CanAttack returns true if this building can attack enemies
 func (b *Building) CanAttack() bool {
-	return b.Def != nil && b.Def.CanAttack && b.Completed
+	return b.Def != nil && b.Def.CanAttack && b.Completed && !b.Recycling
+}
+
+// CanTarget reports whether the building's weapon can hit target
+func (b *Building) CanTarget(target *Unit) bool {
+	if target.IsAircraft() {
+		return b.Def.AntiAir
+	}
+	return b.Def.AntiGround
 }
 
 // IsInAttackRange checks if a target unit is within attack range
@@ -0,0 +1,125 @@
+package entity
+
+import emath "github.com/bklimczak/tanks/engine/math"
+
+// OrderType identifies what a queued unit order does
+type OrderType int
+
+const (
+	OrderMove       OrderType = iota // Drive to a point
+	OrderAttackMove                  // Drive to a point, stopping to fight enemies met on the way
+	OrderAttack                      // Destroy one unit or building
+	OrderPatrol                      // Attack-move to a point, then go back to the end of the queue
+	OrderGuard                       // Stay near a friendly unit or building and fight what comes close
+	OrderReclaim                     // Pull the metal out of a wreck until it is cleared
+	OrderLoad                        // Pick up a unit, or board a transport
+	OrderUnload                      // Drive to a point and set all cargo down there
+)
+
+// Order is one entry of a unit's order queue
+type Order struct {
+	Type     OrderType
+	Pos      emath.Vec2 // Destination of move, attack-move and patrol orders
+	Unit     *Unit      // Unit to attack, guard, pick up or board
+	Building *Building  // Building to attack or guard
+	Wreck    *Wreckage  // Wreck to reclaim
+	Engaged  bool       // The unit stopped to fight on its way
+}
+
+// Point returns where the order leads, following a moving target
+func (o *Order) Point() emath.Vec2 {
+	if o.Unit != nil {
+		return o.Unit.Center()
+	}
+	if o.Building != nil {
+		return o.Building.Center()
+	}
+	if o.Wreck != nil {
+		return o.Wreck.Center()
+	}
+	return o.Pos
+}
+
+// TargetActive reports whether the unit, building or wreck an attack,
+// guard, reclaim or load order refers to still exists
+func (o *Order) TargetActive() bool {
+	if o.Unit != nil {
+		return o.Unit.Active
+	}
+	if o.Wreck != nil {
+		return o.Wreck.Active
+	}
+	return o.Building != nil && o.Building.Active
+}
+
+// CurrentOrder returns the order being carried out, or nil when idle
+func (u *Unit) CurrentOrder() *Order {
+	if len(u.Orders) == 0 {
+		return nil
+	}
+	return &u.Orders[0]
+}
+
+// GiveOrder replaces all orders with o and starts it
+func (u *Unit) GiveOrder(o Order) {
+	u.Orders = append(u.Orders[:0], o)
+	u.startOrder()
+}
+
+// QueueOrder adds o to the end of the queue, starting it at once when the
+// unit has nothing else to do
+func (u *Unit) QueueOrder(o Order) {
+	u.Orders = append(u.Orders, o)
+	if len(u.Orders) == 1 {
+		u.startOrder()
+	}
+}
+
+// NextOrder finishes the current order and starts the one after it.
+// Patrol legs go back to the end of the queue so patrols loop.
+func (u *Unit) NextOrder() {
+	if len(u.Orders) == 0 {
+		return
+	}
+	done := u.Orders[0]
+	u.Orders = append(u.Orders[:0], u.Orders[1:]...)
+	if done.Type == OrderPatrol {
+		done.Engaged = false
+		u.Orders = append(u.Orders, done)
+	}
+	if len(u.Orders) > 0 {
+		u.startOrder()
+	}
+}
+
+// ClearOrders drops the order queue without touching current movement
+func (u *Unit) ClearOrders() {
+	u.Orders = nil
+}
+
+func (u *Unit) startOrder() {
+	o := &u.Orders[0]
+	u.ClearRepairTarget()
+	switch o.Type {
+	case OrderAttack:
+		u.ClearTarget()
+		if o.Unit != nil {
+			u.SetAttackTarget(o.Unit)
+		} else {
+			u.SetBuildingAttackTarget(o.Building)
+		}
+		u.AttackOrdered = true
+	case OrderGuard:
+		u.ClearTarget()
+		u.ClearAttackTarget()
+	case OrderReclaim:
+		u.ClearAttackTarget()
+		u.SetTarget(o.Wreck.Center())
+	case OrderLoad:
+		u.ClearAttackTarget()
+		u.SetTarget(o.Unit.Center())
+	default:
+		u.ClearAttackTarget()
+		u.SetTarget(o.Pos)
+	}
+}
@@ -0,0 +1,116 @@
+package entity
+
+// PowerClass groups energy consumers for power priorities
+type PowerClass int
+
+const (
+	PowerBalanced   PowerClass = iota // No priority, every consumer gets the same share
+	PowerDefense                      // Turrets and other armed buildings
+	PowerProduction                   // Factories, labs and the Nexus
+	PowerEconomy                      // Extractors, foundries and everything else
+	NumPowerClasses
+)
+
+func (c PowerClass) String() string {
+	switch c {
+	case PowerBalanced:
+		return "Balanced"
+	case PowerDefense:
+		return "Defense"
+	case PowerProduction:
+		return "Production"
+	case PowerEconomy:
+		return "Economy"
+	default:
+		return "Power"
+	}
+}
+
+// Next returns the priority after c, wrapping around
+func (c PowerClass) Next() PowerClass {
+	return (c + 1) % NumPowerClasses
+}
+
+// MinDefensePower is the power level below which armed buildings go
+// offline; above it they reload in proportion to their power
+const MinDefensePower = 0.5
+
+// PowerClass returns the priority group the building's consumption falls in
+func (d *BuildingDef) PowerClass() PowerClass {
+	switch {
+	case d.CanAttack:
+		return PowerDefense
+	case d.IsFactory || d.IsLab || len(d.BuildableStructures) > 0:
+		return PowerProduction
+	default:
+		return PowerEconomy
+	}
+}
+
+// PowerGrid shares a faction's energy among its consumers. When demand
+// outruns supply the First class is served before the rest, which share
+// what is left evenly.
+type PowerGrid struct {
+	First  PowerClass
+	Supply float64 // Energy per second available to consumers
+	Demand float64 // Energy per second the consumers need
+}
+
+// Level returns the share of demand the grid covers, from 0 to 1
+func (g *PowerGrid) Level() float64 {
+	if g.Demand <= 0 || g.Supply >= g.Demand {
+		return 1
+	}
+	return max(0, g.Supply/g.Demand)
+}
+
+// Allocate works out Demand from the completed consumers among buildings,
+// all of which belong to the grid's faction, and sets each building's
+// Power. Buildings that need no energy always run at full power.
+func (g *PowerGrid) Allocate(buildings []*Building) {
+	var demand [NumPowerClasses]float64
+	for _, b := range buildings {
+		if consumes(b) {
+			demand[b.Def.PowerClass()] += b.Def.EnergyConsumption
+		}
+	}
+	g.Demand = 0
+	for _, d := range demand {
+		g.Demand += d
+	}
+
+	var level [NumPowerClasses]float64
+	rest := g.Level()
+	if g.First != PowerBalanced && demand[g.First] > 0 {
+		level[g.First] = min(1, max(0, g.Supply/demand[g.First]))
+		if others := g.Demand - demand[g.First]; others > 0 {
+			rest = min(1, max(0, (g.Supply-demand[g.First])/others))
+		}
+	}
+	for c := range level {
+		if PowerClass(c) != g.First || g.First == PowerBalanced || demand[c] == 0 {
+			level[c] = rest
+		}
+	}
+
+	for _, b := range buildings {
+		b.Power = 1
+		if consumes(b) {
+			b.Power = level[b.Def.PowerClass()]
+		}
+	}
+}
+
+func consumes(b *Building) bool {
+	return b.Active && b.Completed && !b.Recycling && b.Def.EnergyConsumption > 0
+}
+
+// Underpowered reports whether the building runs below full power
+func (b *Building) Underpowered() bool {
+	return b.Power < 1
+}
+
+// Offline reports whether the building has too little power to work at all
+func (b *Building) Offline() bool {
+	return b.Def != nil && b.Def.CanAttack && b.Power < MinDefensePower
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"math"
+	"testing"
+)
+
+func powerTestBuildings() (turret, factory, extractor, solar *Building) {
+	turret = NewBuilding(1, 0, 0, BuildingDefs[BuildingAutocannonTurret])
+	factory = NewBuilding(2, 0, 0, BuildingDefs[BuildingTanksFactory])
+	extractor = NewBuilding(3, 0, 0, BuildingDefs[BuildingOreExtractor])
+	solar = NewBuilding(4, 0, 0, BuildingDefs[BuildingSolarArray])
+	return
+}
+
+func TestBalancedGridSharesShortfall(t *testing.T) {
+	turret, factory, extractor, solar := powerTestBuildings()
+	demand := turret.Def.EnergyConsumption + factory.Def.EnergyConsumption + extractor.Def.EnergyConsumption
+	grid := &PowerGrid{Supply: demand / 2}
+	grid.Allocate([]*Building{turret, factory, extractor, solar})
+
+	if grid.Demand != demand {
+		t.Errorf("demand = %v, want %v", grid.Demand, demand)
+	}
+	for _, b := range []*Building{turret, factory, extractor} {
+		if math.Abs(b.Power-0.5) > 1e-9 {
+			t.Errorf("%s power = %v, want 0.5", b.Def.Name, b.Power)
+		}
+	}
+	if solar.Power != 1 {
+		t.Errorf("non-consumer power = %v, want 1", solar.Power)
+	}
+}
+
+func TestPriorityClassIsPoweredFirst(t *testing.T) {
+	turret, factory, extractor, _ := powerTestBuildings()
+	rest := factory.Def.EnergyConsumption + extractor.Def.EnergyConsumption
+	grid := &PowerGrid{First: PowerDefense, Supply: turret.Def.EnergyConsumption + rest/4}
+	grid.Allocate([]*Building{turret, factory, extractor})
+
+	if turret.Power != 1 || turret.Offline() {
+		t.Errorf("turret power = %v, want 1", turret.Power)
+	}
+	if math.Abs(factory.Power-0.25) > 1e-9 || math.Abs(extractor.Power-0.25) > 1e-9 {
+		t.Errorf("others = %v, %v, want 0.25", factory.Power, extractor.Power)
+	}
+
+	grid.First = PowerProduction
+	grid.Supply = factory.Def.EnergyConsumption + 1
+	grid.Allocate([]*Building{turret, factory, extractor})
+	if factory.Power != 1 {
+		t.Errorf("factory power = %v, want 1", factory.Power)
+	}
+	if !turret.Offline() {
+		t.Errorf("turret at %v power should be offline", turret.Power)
+	}
+}
+
+func TestIncompleteBuildingsDrawNoPower(t *testing.T) {
+	turret := NewBuildingUnderConstruction(1, 0, 0, BuildingDefs[BuildingAutocannonTurret])
+	grid := &PowerGrid{}
+	grid.Allocate([]*Building{turret})
+	if grid.Demand != 0 || grid.Level() != 1 || turret.Power != 1 {
+		t.Errorf("demand %v, level %v, power %v; want 0, 1, 1", grid.Demand, grid.Level(), turret.Power)
+	}
+}
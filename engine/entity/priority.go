@@ -0,0 +1,77 @@
+package entity
+
+import "github.com/bklimczak/tanks/engine/resource"
+
+// Priority decides which buildings are paid first when a faction cannot
+// afford everything it is building, producing and researching
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+	PriorityPaused // Spends nothing and keeps its progress
+	NumPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "Normal"
+	case PriorityHigh:
+		return "High"
+	case PriorityPaused:
+		return "Paused"
+	default:
+		return "Priority"
+	}
+}
+
+// Priorities lists the levels in the order they are shown
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityPaused}
+
+// fundingOrder is the order levels are paid in; paused buildings are not
+var fundingOrder = []Priority{PriorityHigh, PriorityNormal}
+
+// ShareOut splits available among claims by priority. A level is paid in
+// full before the next one gets anything; a level that cannot be paid in
+// full shares what is left in proportion to its claims. It returns the
+// fraction of its claims each level is paid.
+func ShareOut(available float64, claims [NumPriorities]float64) [NumPriorities]float64 {
+	var paid [NumPriorities]float64
+	for _, p := range fundingOrder {
+		if claims[p] <= 0 {
+			paid[p] = 1
+			continue
+		}
+		paid[p] = min(1, max(0, available/claims[p]))
+		available -= paid[p] * claims[p]
+	}
+	return paid
+}
+
+// Upkeep returns the metal and energy per second the building's current
+// construction, production and research cost at full speed
+func (b *Building) Upkeep() (metal, energy float64) {
+	add := func(cost map[resource.Type]float64, duration, speed float64) {
+		if duration > 0 {
+			metal += cost[resource.Metal] / duration * speed
+			energy += cost[resource.Energy] / duration * speed
+		}
+	}
+	if !b.Completed && !b.Recycling {
+		add(b.Def.Cost, b.Def.BuildTime, 1)
+	}
+	if b.Producing && b.CurrentProduction != nil {
+		add(b.CurrentProduction.Cost, b.ProductionTime, b.Power)
+	}
+	if b.Researching && b.CurrentResearch != nil {
+		add(b.CurrentResearch.Cost, b.CurrentResearch.ResearchTime, b.Power)
+	}
+	return metal, energy
+}
+
+// UsesResources reports whether the building can spend metal and energy
+// on work, and so whether its priority matters
+func (b *Building) UsesResources() bool {
+	return (!b.Completed && !b.Recycling) || b.CanProduce() || b.CanResearch()
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/resource"
+)
+
+func TestShareOutPaysHighPriorityFirst(t *testing.T) {
+	var claims [NumPriorities]float64
+	claims[PriorityHigh] = 30
+	claims[PriorityNormal] = 40
+	claims[PriorityPaused] = 100
+
+	paid := ShareOut(50, claims)
+	if paid[PriorityHigh] != 1 {
+		t.Errorf("high paid %v, want 1", paid[PriorityHigh])
+	}
+	if math.Abs(paid[PriorityNormal]-0.5) > 1e-9 {
+		t.Errorf("normal paid %v, want 0.5 of its claims", paid[PriorityNormal])
+	}
+	if paid[PriorityPaused] != 0 {
+		t.Errorf("paused paid %v, want 0", paid[PriorityPaused])
+	}
+
+	paid = ShareOut(15, claims)
+	if math.Abs(paid[PriorityHigh]-0.5) > 1e-9 || paid[PriorityNormal] != 0 {
+		t.Errorf("short of the high claims: paid %v", paid)
+	}
+}
+
+func TestUpkeepFollowsWork(t *testing.T) {
+	def := BuildingDefs[BuildingTanksFactory]
+	site := NewBuildingUnderConstruction(1, 0, 0, def)
+	metal, energy := site.Upkeep()
+	if want := def.Cost[resource.Metal] / def.BuildTime; math.Abs(metal-want) > 1e-9 {
+		t.Errorf("construction metal upkeep = %v, want %v", metal, want)
+	}
+	if want := def.Cost[resource.Energy] / def.BuildTime; math.Abs(energy-want) > 1e-9 {
+		t.Errorf("construction energy upkeep = %v, want %v", energy, want)
+	}
+
+	factory := NewBuilding(2, 0, 0, def)
+	if metal, energy := factory.Upkeep(); metal != 0 || energy != 0 {
+		t.Errorf("idle factory upkeep = %v, %v, want 0", metal, energy)
+	}
+	tank := UnitDefs[UnitTypeTank]
+	factory.QueueProduction(tank)
+	factory.Power = 0.5
+	metal, _ = factory.Upkeep()
+	if want := tank.Cost[resource.Metal] / tank.BuildTime * 0.5; math.Abs(metal-want) > 1e-9 {
+		t.Errorf("half-powered production upkeep = %v, want %v", metal, want)
+	}
+}
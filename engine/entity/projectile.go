@@ -1,60 +1,96 @@
 package entity
 
 import (
-	emath "github.com/bklimczak/tanks/engine/math"
 	"image/color"
+	"math"
+
+	emath "github.com/bklimczak/tanks/engine/math"
 )
 
 type Projectile struct {
 	Entity
+	Shooter        *Unit // Unit credited with the damage, nil for buildings
 	Damage         float64
 	Speed          float64
 	Target         *Unit     // Target unit (for homing) or nil for straight shots
 	BuildingTarget *Building // Target building
 	Direction      emath.Vec2
+	Airborne       bool // Aimed at an aircraft, so it passes over ground targets
+
+	Weapon     WeaponType
+	DamageType DamageType
+	Aim        emath.Vec2 // Ballistic and flame: point it was fired at
+	Splash     float64    // Ballistic: blast radius
+	TurnRate   float64    // Rocket: radians per second it can turn
+	Fuel       float64    // Rocket and flame: distance left before it burns out
+	Landed     bool       // Ballistic: reached its aim point and should explode
 }
 
 const ProjectileSpeed = 400.0
 const ProjectileSize = 4.0
 
 func NewProjectile(id uint64, shooter *Unit, target *Unit) *Projectile {
-	startPos := shooter.Center()
-	targetPos := target.Center()
-	dir := targetPos.Sub(startPos).Normalize()
-	return &Projectile{
-		Entity: Entity{
-			ID:       id,
-			Position: emath.Vec2{X: startPos.X - ProjectileSize/2, Y: startPos.Y - ProjectileSize/2},
-			Size:     emath.Vec2{X: ProjectileSize, Y: ProjectileSize},
-			Color:    color.RGBA{255, 200, 50, 255}, // Yellow/orange
-			Active:   true,
-			Faction:  shooter.Faction,
-		},
-		Damage:    shooter.Damage,
-		Speed:     ProjectileSpeed,
-		Target:    target,
-		Direction: dir,
-	}
+	p := newShot(id, shooter, target.Center())
+	p.Target = target
+	p.Airborne = target.IsAircraft()
+	return p
 }
 
 func NewProjectileAtBuilding(id uint64, shooter *Unit, target *Building) *Projectile {
+	p := newShot(id, shooter, target.Center())
+	p.BuildingTarget = target
+	return p
+}
+
+// NewShell creates a ballistic shell that lands on aim and explodes there,
+// whatever is standing on it by then
+func NewShell(id uint64, shooter *Unit, aim emath.Vec2) *Projectile {
+	p := newShot(id, shooter, aim)
+	p.Aim = aim
+	if shooter.Def != nil && shooter.Def.Combat != nil {
+		p.Splash = shooter.Def.Combat.SplashRadius
+	}
+	return p
+}
+
+// NewFlame creates a burst of flame towards aim. Flames only show where a
+// flame weapon fired; its damage is dealt to the whole cone when it fires.
+func NewFlame(id uint64, shooter *Unit, aim emath.Vec2) *Projectile {
+	p := newShot(id, shooter, aim)
+	p.Aim = aim
+	p.Damage = 0
+	p.Speed = FlameSpeed
+	p.Fuel = shooter.Range
+	return p
+}
+
+// newShot creates a projectile of the shooter's weapon flying from the
+// shooter towards pos
+func newShot(id uint64, shooter *Unit, pos emath.Vec2) *Projectile {
 	startPos := shooter.Center()
-	targetPos := target.Center()
-	dir := targetPos.Sub(startPos).Normalize()
-	return &Projectile{
+	weapon := shooter.Weapon()
+	style := weapon.Style()
+	p := &Projectile{
 		Entity: Entity{
 			ID:       id,
-			Position: emath.Vec2{X: startPos.X - ProjectileSize/2, Y: startPos.Y - ProjectileSize/2},
-			Size:     emath.Vec2{X: ProjectileSize, Y: ProjectileSize},
-			Color:    color.RGBA{255, 200, 50, 255}, // Yellow/orange
+			Position: emath.Vec2{X: startPos.X - style.Size/2, Y: startPos.Y - style.Size/2},
+			Size:     emath.Vec2{X: style.Size, Y: style.Size},
+			Color:    style.Color,
 			Active:   true,
 			Faction:  shooter.Faction,
 		},
-		Damage:         shooter.Damage,
-		Speed:          ProjectileSpeed,
-		BuildingTarget: target,
-		Direction:      dir,
+		Damage:     shooter.Damage,
+		Speed:      shooter.ShotSpeed(),
+		Direction:  pos.Sub(startPos).Normalize(),
+		Weapon:     weapon,
+		DamageType: shooter.DamageType(),
+		Shooter:    shooter,
+	}
+	if weapon == WeaponRocket {
+		p.TurnRate = shooter.Def.Combat.TurnRate
+		p.Fuel = shooter.Range * RocketFuel
 	}
+	return p
 }
 
 // NewProjectileFromBuilding creates a projectile fired from a building (like a laser tower)
@@ -71,29 +107,109 @@ func NewProjectileFromBuilding(id uint64, shooter *Building, target *Unit) *Proj
 			Active:   true,
 			Faction:  shooter.Faction,
 		},
-		Damage:    shooter.Def.Damage,
-		Speed:     ProjectileSpeed * 1.5, // Laser is faster
-		Target:    target,
-		Direction: dir,
+		Damage:     shooter.Def.Damage,
+		Speed:      ProjectileSpeed * 1.5, // Laser is faster
+		Target:     target,
+		Direction:  dir,
+		Airborne:   target.IsAircraft(),
+		DamageType: shooter.Def.DamageType,
+	}
+}
+
+// Hits reports whether the projectile is inside bounds, with a small margin
+func (p *Projectile) Hits(bounds emath.Rect) bool {
+	hitBounds := emath.Rect{
+		Pos:  emath.Vec2{X: bounds.Pos.X - 2, Y: bounds.Pos.Y - 2},
+		Size: emath.Vec2{X: bounds.Size.X + 4, Y: bounds.Size.Y + 4},
 	}
+	return hitBounds.Contains(p.Center())
 }
+
+// Update moves the projectile and damages its target on contact. It
+// returns true once the projectile is used up.
 func (p *Projectile) Update(dt float64) bool {
 	if !p.Active {
 		return true
 	}
+	switch p.Weapon {
+	case WeaponBallistic:
+		return p.updateShell(dt)
+	case WeaponFlame:
+		p.Position = p.Position.Add(p.Direction.Mul(p.Speed * dt))
+		return p.burnFuel(p.Speed * dt)
+	case WeaponRocket:
+		p.steer(dt)
+		p.Position = p.Position.Add(p.Direction.Mul(p.Speed * dt))
+		if p.hitTarget() {
+			return true
+		}
+		return p.burnFuel(p.Speed * dt)
+	}
+
 	movement := p.Direction.Mul(p.Speed * dt)
 	p.Position = p.Position.Add(movement)
+	if p.hitTarget() {
+		return true
+	}
+
+	// Deactivate if no valid target
+	if (p.Target == nil || !p.Target.Active) && (p.BuildingTarget == nil || !p.BuildingTarget.Active) {
+		p.Active = false
+		return true
+	}
+	return false
+}
+
+// updateShell flies a shell to its aim point and marks it landed there
+func (p *Projectile) updateShell(dt float64) bool {
+	toAim := p.Aim.Sub(p.Center())
+	step := p.Speed * dt
+	if toAim.Length() <= step {
+		p.Position = p.Aim.Sub(p.Size.Mul(0.5))
+		p.Landed = true
+		p.Active = false
+		return true
+	}
+	p.Position = p.Position.Add(p.Direction.Mul(step))
+	return false
+}
 
+// steer turns a rocket towards its target by at most its turn rate
+func (p *Projectile) steer(dt float64) {
+	var targetPos emath.Vec2
+	switch {
+	case p.Target != nil && p.Target.Active:
+		targetPos = p.Target.Center()
+	case p.BuildingTarget != nil && p.BuildingTarget.Active:
+		targetPos = p.BuildingTarget.Center()
+	default:
+		return // Target gone, fly on straight
+	}
+	current := math.Atan2(p.Direction.Y, p.Direction.X)
+	wanted := targetPos.Sub(p.Center())
+	turn := math.Remainder(math.Atan2(wanted.Y, wanted.X)-current, 2*math.Pi)
+	maxTurn := p.TurnRate * dt
+	turn = max(-maxTurn, min(maxTurn, turn))
+	p.Direction = emath.Vec2{X: math.Cos(current + turn), Y: math.Sin(current + turn)}
+}
+
+// burnFuel uses up distance travelled and deactivates the projectile once
+// it runs out
+func (p *Projectile) burnFuel(dist float64) bool {
+	p.Fuel -= dist
+	if p.Fuel <= 0 {
+		p.Active = false
+		return true
+	}
+	return false
+}
+
+// hitTarget damages the projectile's target if it has reached it
+func (p *Projectile) hitTarget() bool {
 	// Check hit on unit target
 	if p.Target != nil && p.Target.Active {
-		projectileCenter := p.Center()
-		targetBounds := p.Target.Bounds()
-		hitBounds := emath.Rect{
-			Pos:  emath.Vec2{X: targetBounds.Pos.X - 2, Y: targetBounds.Pos.Y - 2},
-			Size: emath.Vec2{X: targetBounds.Size.X + 4, Y: targetBounds.Size.Y + 4},
-		}
-		if hitBounds.Contains(projectileCenter) {
-			p.Target.TakeDamage(p.Damage)
+		if p.Hits(p.Target.Bounds()) {
+			DamageUnit(p.Shooter, p.Target, p.Damage, p.DamageType)
 			p.Active = false
 			return true
 		}
@@ -101,23 +217,11 @@ func (p *Projectile) Update(dt float64) bool {
 
 	// Check hit on building target
 	if p.BuildingTarget != nil && p.BuildingTarget.Active {
-		projectileCenter := p.Center()
-		targetBounds := p.BuildingTarget.Bounds()
-		hitBounds := emath.Rect{
-			Pos:  emath.Vec2{X: targetBounds.Pos.X - 2, Y: targetBounds.Pos.Y - 2},
-			Size: emath.Vec2{X: targetBounds.Size.X + 4, Y: targetBounds.Size.Y + 4},
-		}
-		if hitBounds.Contains(projectileCenter) {
-			p.BuildingTarget.TakeDamage(p.Damage)
+		if p.Hits(p.BuildingTarget.Bounds()) {
+			DamageBuilding(p.Shooter, p.BuildingTarget, p.Damage, p.DamageType)
 			p.Active = false
 			return true
 		}
 	}
-
-	// Deactivate if no valid target
-	if (p.Target == nil || !p.Target.Active) && (p.BuildingTarget == nil || !p.BuildingTarget.Active) {
-		p.Active = false
-		return true
-	}
 	return false
 }
@@ -0,0 +1,94 @@
+package entity
+
+import "github.com/bklimczak/tanks/engine/resource"
+
+const (
+	DefaultRecycleRefund = 0.5 // Share of a building's cost returned when it is recycled
+	RecycleTimeScale     = 0.5 // Taking a building apart takes this share of its build time
+	MinRecycleTime       = 2.0 // Seconds even the cheapest building takes to recycle
+)
+
+// RecycleRefundShare returns the share of its cost a building of this type
+// returns when recycled
+func (d *BuildingDef) RecycleRefundShare() float64 {
+	if d.RecycleRefund > 0 {
+		return d.RecycleRefund
+	}
+	return DefaultRecycleRefund
+}
+
+// CanRecycle reports whether the building can start being taken apart
+func (b *Building) CanRecycle() bool {
+	return b.Active && !b.Recycling
+}
+
+// StartRecycle begins taking the building apart. Its production and
+// research stop, with what the running unit and project have spent
+// refunded. It reports whether recycling started.
+func (b *Building) StartRecycle(resources *resource.Manager) bool {
+	if !b.CanRecycle() {
+		return false
+	}
+	refund(resources, b.ProductionMetalSpent, b.ProductionEnergySpent)
+	b.ProductionQueue = nil
+	b.Producing = false
+	b.ProductionProgress = 0
+	b.CurrentProduction = nil
+	b.ProductionMetalSpent = 0
+	b.ProductionEnergySpent = 0
+
+	refund(resources, b.ResearchMetalSpent, b.ResearchEnergySpent)
+	b.ResearchQueue = nil
+	b.stopResearch()
+
+	b.AttackTarget = nil
+	b.Recycling = true
+	b.RecycleProgress = 0
+	return true
+}
+
+// RecycleTime returns how long taking the building apart takes; sites
+// still under construction come down faster
+func (b *Building) RecycleTime() float64 {
+	return max(MinRecycleTime, b.Def.BuildTime*RecycleTimeScale*b.BuildProgress)
+}
+
+// RecycleValue returns the metal and energy recycling the building returns
+func (b *Building) RecycleValue() (metal, energy float64) {
+	share := b.Def.RecycleRefundShare()
+	return b.MetalSpent * share, b.EnergySpent * share
+}
+
+// UpdateRecycle advances the deconstruction. Once it is done the refund is
+// paid, the building is removed and true is returned.
+func (b *Building) UpdateRecycle(dt float64, resources *resource.Manager) bool {
+	if !b.Recycling || b.Recycled() {
+		return false
+	}
+	b.RecycleProgress = min(1, b.RecycleProgress+dt/b.RecycleTime())
+	if b.RecycleProgress < 1 {
+		return false
+	}
+	metal, energy := b.RecycleValue()
+	refund(resources, metal, energy)
+	b.Active = false
+	return true
+}
+
+// Recycled reports whether the building was taken apart by its owner, as
+// opposed to destroyed, and so leaves no wreck
+func (b *Building) Recycled() bool {
+	return b.Recycling && b.RecycleProgress >= 1
+}
+
+func refund(resources *resource.Manager, metal, energy float64) {
+	if resources == nil {
+		return
+	}
+	if metal > 0 {
+		resources.Get(resource.Metal).Add(metal)
+	}
+	if energy > 0 {
+		resources.Get(resource.Energy).Add(energy)
+	}
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/resource"
+)
+
+func TestRecycleRefundsShareOfCost(t *testing.T) {
+	def := BuildingDefs[BuildingTanksFactory]
+	factory := NewBuilding(1, 0, 0, def)
+	factory.MetalSpent = def.Cost[resource.Metal]
+	factory.EnergySpent = def.Cost[resource.Energy]
+	res := resource.NewManager()
+	res.Get(resource.Metal).Current = 0
+
+	if !factory.StartRecycle(res) || factory.CanProduce() {
+		t.Fatal("recycling factory should start and stop producing")
+	}
+	if factory.StartRecycle(res) {
+		t.Error("a building already recycling should not start again")
+	}
+	for i := 0; i < 1000 && !factory.UpdateRecycle(0.5, res); i++ {
+	}
+	if !factory.Recycled() || factory.Active {
+		t.Fatalf("recycled %v active %v; want a removed building", factory.Recycled(), factory.Active)
+	}
+	want := def.Cost[resource.Metal] * DefaultRecycleRefund
+	if got := res.Get(resource.Metal).Current; math.Abs(got-want) > 1e-6 {
+		t.Errorf("metal after recycling = %v, want %v", got, want)
+	}
+}
+
+func TestRecycleRefundsProductionQueue(t *testing.T) {
+	factory := NewBuilding(1, 0, 0, BuildingDefs[BuildingTanksFactory])
+	res := resource.NewManager()
+	res.Get(resource.Metal).Current = 0
+	factory.QueueProduction(UnitDefs[UnitTypeTank])
+	factory.QueueProduction(UnitDefs[UnitTypeTank])
+	factory.ProductionMetalSpent = 40
+	factory.ProductionEnergySpent = 10
+
+	factory.StartRecycle(res)
+	if len(factory.ProductionQueue) != 0 || factory.Producing || factory.CurrentProduction != nil {
+		t.Error("recycling should clear the production queue")
+	}
+	if got := res.Get(resource.Metal).Current; got != 40 {
+		t.Errorf("metal after cancelling production = %v, want 40", got)
+	}
+}
@@ -0,0 +1,251 @@
+package entity
+
+import (
+	"slices"
+
+	"github.com/bklimczak/tanks/engine/resource"
+)
+
+// ResearchType identifies a research project
+type ResearchType int
+
+const (
+	ResearchHeavyChassis ResearchType = iota
+	ResearchRocketry
+	ResearchHoverDrives
+	ResearchFlight
+	ResearchCompositeArmor
+	ResearchReactiveArmor
+	ResearchTargeting
+	NumResearchTypes
+)
+
+func (t ResearchType) String() string {
+	if def := ResearchDefs[t]; def != nil {
+		return def.Name
+	}
+	return "Research"
+}
+
+// Upgrade is a global bonus research gives every unit of a faction. The
+// zero value changes nothing; bonuses of several projects add up.
+type Upgrade struct {
+	Plating float64 // Fraction of incoming damage absorbed
+	Range   float64 // Extra weapon range, as a fraction of the base range
+}
+
+// Add returns the combined bonus of two upgrades
+func (u Upgrade) Add(o Upgrade) Upgrade {
+	return Upgrade{Plating: u.Plating + o.Plating, Range: u.Range + o.Range}
+}
+
+// ResearchDef describes a research project: what it costs, what it needs
+// first and what finishing it unlocks
+type ResearchDef struct {
+	Type             ResearchType
+	Name             string
+	Description      string
+	Cost             map[resource.Type]float64
+	ResearchTime     float64
+	Requires         []ResearchType // Projects that must be finished first
+	UnlocksUnits     []UnitType
+	UnlocksBuildings []BuildingType
+	Upgrade          Upgrade
+}
+
+// ResearchDefs is the tech tree. Units and buildings unlocked by a project
+// cannot be produced or placed until it is finished.
+var ResearchDefs = map[ResearchType]*ResearchDef{
+	ResearchHeavyChassis: {
+		Type:        ResearchHeavyChassis,
+		Name:        "Heavy Chassis",
+		Description: "Reinforced hulls for heavy tanks and artillery",
+		Cost: map[resource.Type]float64{
+			resource.Metal:  300,
+			resource.Energy: 150,
+		},
+		ResearchTime: 30,
+		UnlocksUnits: []UnitType{UnitTypeHeavyTank, UnitTypeArtillery},
+	},
+	ResearchRocketry: {
+		Type:        ResearchRocketry,
+		Name:        "Rocketry",
+		Description: "Guided rockets for the rocket tank",
+		Cost: map[resource.Type]float64{
+			resource.Metal:  350,
+			resource.Energy: 200,
+		},
+		ResearchTime: 35,
+		Requires:     []ResearchType{ResearchHeavyChassis},
+		UnlocksUnits: []UnitType{UnitTypeRocketTank},
+	},
+	ResearchHoverDrives: {
+		Type:        ResearchHoverDrives,
+		Name:        "Hover Drives",
+		Description: "Lift fans that carry vehicles over water",
+		Cost: map[resource.Type]float64{
+			resource.Metal:  400,
+			resource.Energy: 200,
+		},
+		ResearchTime:     40,
+		UnlocksBuildings: []BuildingType{BuildingHoverBay},
+	},
+	ResearchFlight: {
+		Type:        ResearchFlight,
+		Name:        "Flight",
+		Description: "Airframes for gunships and bombers",
+		Cost: map[resource.Type]float64{
+			resource.Metal:  600,
+			resource.Energy: 300,
+		},
+		ResearchTime:     50,
+		Requires:         []ResearchType{ResearchHoverDrives},
+		UnlocksBuildings: []BuildingType{BuildingAirfield},
+	},
+	ResearchCompositeArmor: {
+		Type:        ResearchCompositeArmor,
+		Name:        "Composite Armor",
+		Description: "All units take 15% less damage",
+		Cost: map[resource.Type]float64{
+			resource.Metal:  400,
+			resource.Energy: 200,
+		},
+		ResearchTime: 40,
+		Upgrade:      Upgrade{Plating: 0.15},
+	},
+	ResearchReactiveArmor: {
+		Type:        ResearchReactiveArmor,
+		Name:        "Reactive Armor",
+		Description: "All units take a further 10% less damage",
+		Cost: map[resource.Type]float64{
+			resource.Metal:  600,
+			resource.Energy: 400,
+		},
+		ResearchTime: 60,
+		Requires:     []ResearchType{ResearchCompositeArmor},
+		Upgrade:      Upgrade{Plating: 0.1},
+	},
+	ResearchTargeting: {
+		Type:        ResearchTargeting,
+		Name:        "Advanced Targeting",
+		Description: "All weapons reach 10% further",
+		Cost: map[resource.Type]float64{
+			resource.Metal:  350,
+			resource.Energy: 250,
+		},
+		ResearchTime: 35,
+		Upgrade:      Upgrade{Range: 0.1},
+	},
+}
+
+// AllResearchTypes lists the tech tree in the order it is shown
+var AllResearchTypes = []ResearchType{
+	ResearchHeavyChassis,
+	ResearchRocketry,
+	ResearchHoverDrives,
+	ResearchFlight,
+	ResearchCompositeArmor,
+	ResearchReactiveArmor,
+	ResearchTargeting,
+}
+
+// UnitLock returns the project that unlocks a unit type, if any
+func UnitLock(unitType UnitType) (ResearchType, bool) {
+	for _, rt := range AllResearchTypes {
+		if slices.Contains(ResearchDefs[rt].UnlocksUnits, unitType) {
+			return rt, true
+		}
+	}
+	return 0, false
+}
+
+// BuildingLock returns the project that unlocks a building type, if any
+func BuildingLock(buildingType BuildingType) (ResearchType, bool) {
+	for _, rt := range AllResearchTypes {
+		if slices.Contains(ResearchDefs[rt].UnlocksBuildings, buildingType) {
+			return rt, true
+		}
+	}
+	return 0, false
+}
+
+// Tech is the research a faction has finished. The zero value has none.
+type Tech struct {
+	done [NumResearchTypes]bool
+}
+
+// Has reports whether a project is finished
+func (t *Tech) Has(rt ResearchType) bool {
+	return rt >= 0 && rt < NumResearchTypes && t.done[rt]
+}
+
+// Complete marks a project as finished
+func (t *Tech) Complete(rt ResearchType) {
+	if rt >= 0 && rt < NumResearchTypes {
+		t.done[rt] = true
+	}
+}
+
+// Completed lists the finished projects in tech tree order
+func (t *Tech) Completed() []ResearchType {
+	var done []ResearchType
+	for _, rt := range AllResearchTypes {
+		if t.done[rt] {
+			done = append(done, rt)
+		}
+	}
+	return done
+}
+
+// SetCompleted replaces the finished projects
+func (t *Tech) SetCompleted(done []ResearchType) {
+	t.done = [NumResearchTypes]bool{}
+	for _, rt := range done {
+		t.Complete(rt)
+	}
+}
+
+// Available reports whether a project is unfinished and everything it
+// requires is finished
+func (t *Tech) Available(rt ResearchType) bool {
+	def := ResearchDefs[rt]
+	if def == nil || t.Has(rt) {
+		return false
+	}
+	for _, req := range def.Requires {
+		if !t.Has(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// UnitUnlocked reports whether a unit type may be produced
+func (t *Tech) UnitUnlocked(unitType UnitType) bool {
+	rt, locked := UnitLock(unitType)
+	return !locked || t.Has(rt)
+}
+
+// BuildingUnlocked reports whether a building type may be placed
+func (t *Tech) BuildingUnlocked(buildingType BuildingType) bool {
+	rt, locked := BuildingLock(buildingType)
+	return !locked || t.Has(rt)
+}
+
+// Upgrade returns the combined bonus of every finished project
+func (t *Tech) Upgrade() Upgrade {
+	var up Upgrade
+	for _, rt := range t.Completed() {
+		up = up.Add(ResearchDefs[rt].Upgrade)
+	}
+	return up
+}
+
+// SetUpgrade moves the unit from its current research bonus to up,
+// rescaling its weapon reach from the old bonus to the new one
+func (u *Unit) SetUpgrade(up Upgrade) {
+	scale := (1 + up.Range) / (1 + u.Upgrade.Range)
+	u.Range *= scale
+	u.PursuitRange *= scale
+	u.Upgrade = up
+}
@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/resource"
+)
+
+func TestTechUnlocksFollowTheTree(t *testing.T) {
+	var tech Tech
+	if tech.UnitUnlocked(UnitTypeHeavyTank) || tech.BuildingUnlocked(BuildingAirfield) {
+		t.Error("heavy tank and airfield should start locked")
+	}
+	if !tech.UnitUnlocked(UnitTypeTank) || !tech.BuildingUnlocked(BuildingTanksFactory) {
+		t.Error("tank and tanks factory should never be locked")
+	}
+	if tech.Available(ResearchFlight) {
+		t.Error("flight should need hover drives first")
+	}
+
+	tech.Complete(ResearchHoverDrives)
+	if !tech.Available(ResearchFlight) || tech.Available(ResearchHoverDrives) {
+		t.Error("finishing hover drives should open flight and close itself")
+	}
+	tech.Complete(ResearchFlight)
+	if !tech.BuildingUnlocked(BuildingAirfield) {
+		t.Error("flight should unlock the airfield")
+	}
+}
+
+func TestUpgradeScalesRangeAndDamageTaken(t *testing.T) {
+	var tech Tech
+	tech.SetCompleted([]ResearchType{ResearchTargeting, ResearchCompositeArmor})
+	tank := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeTank], FactionPlayer)
+	base := tank.Range
+
+	tank.SetUpgrade(tech.Upgrade())
+	if want := base * 1.1; math.Abs(tank.Range-want) > 1e-9 {
+		t.Errorf("range = %v, want %v", tank.Range, want)
+	}
+	tank.TakeDamage(100, DamageKinetic)
+	want := 100 * Effectiveness.Multiplier(DamageKinetic, tank.Armor()) * 0.85
+	if got := tank.MaxHealth - tank.Health; math.Abs(got-want) > 1e-9 {
+		t.Errorf("took %v damage, want %v", got, want)
+	}
+
+	tank.SetUpgrade(Upgrade{})
+	if math.Abs(tank.Range-base) > 1e-9 {
+		t.Errorf("range after removing the upgrade = %v, want %v", tank.Range, base)
+	}
+}
+
+func TestResearchQueueSpendsAndRefunds(t *testing.T) {
+	lab := NewBuilding(1, 0, 0, BuildingDefs[BuildingDataUplink])
+	res := resource.NewManager()
+	res.Get(resource.Metal).Current = 1000
+	res.Get(resource.Energy).Current = 1000
+
+	def := ResearchDefs[ResearchTargeting]
+	lab.QueueResearch(def)
+	lab.QueueResearch(ResearchDefs[ResearchHeavyChassis])
+	lab.UpdateResearch(def.ResearchTime/2, res)
+	if math.Abs(lab.ResearchMetalSpent-def.Cost[resource.Metal]/2) > 1e-6 {
+		t.Errorf("spent %v metal halfway, want %v", lab.ResearchMetalSpent, def.Cost[resource.Metal]/2)
+	}
+
+	lab.CancelResearch(ResearchTargeting, res)
+	if got := res.Get(resource.Metal).Current; math.Abs(got-1000) > 1e-6 {
+		t.Errorf("metal after cancel = %v, want a full refund to 1000", got)
+	}
+	if lab.CurrentResearch != ResearchDefs[ResearchHeavyChassis] {
+		t.Error("cancelling should start the next queued project")
+	}
+
+	var finished *ResearchDef
+	for i := 0; finished == nil && i < 100; i++ {
+		finished = lab.UpdateResearch(1, res)
+	}
+	if finished != ResearchDefs[ResearchHeavyChassis] || lab.Researching {
+		t.Errorf("finished %v, still researching %v", finished, lab.Researching)
+	}
+}
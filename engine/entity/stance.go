@@ -0,0 +1,35 @@
+package entity
+
+// Stance controls how a combat unit engages enemies it was not ordered to attack
+type Stance int
+
+const (
+	StanceAggressive   Stance = iota // Engage anything in range and chase it
+	StanceDefensive                  // Engage in range, chase only a short way from its post
+	StanceHoldPosition               // Engage in range without moving
+	StanceHoldFire                   // Only fire when ordered to attack
+)
+
+// DefensiveLeash is how far a defensive unit follows an enemy from its post
+const DefensiveLeash = 150.0
+
+// Stances lists every stance in the order the command panel shows them
+var Stances = []Stance{StanceAggressive, StanceDefensive, StanceHoldPosition, StanceHoldFire}
+
+func (s Stance) String() string {
+	switch s {
+	case StanceDefensive:
+		return "Defensive"
+	case StanceHoldPosition:
+		return "Hold Position"
+	case StanceHoldFire:
+		return "Hold Fire"
+	default:
+		return "Aggressive"
+	}
+}
+
+// AutoAcquires reports whether units in this stance pick their own targets
+func (s Stance) AutoAcquires() bool {
+	return s != StanceHoldFire
+}
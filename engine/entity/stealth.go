@@ -0,0 +1,59 @@
+package entity
+
+import emath "github.com/bklimczak/tanks/engine/math"
+
+// IsCloaked reports whether enemies need a detector to see the unit
+func (u *Unit) IsCloaked() bool {
+	return u.Def != nil && u.Def.Cloaked
+}
+
+// DetectionRange returns how close cloaked enemies must be for the unit to
+// reveal them, 0 if it is no detector
+func (u *Unit) DetectionRange() float64 {
+	if u.Def == nil || !u.Active {
+		return 0
+	}
+	return u.Def.DetectionRange
+}
+
+// Detects reports whether the unit reveals cloaked enemies at p
+func (u *Unit) Detects(p emath.Vec2) bool {
+	r := u.DetectionRange()
+	return r > 0 && u.Center().DistanceSquared(p) <= r*r
+}
+
+// sensing reports whether the building's sensors work: it must be
+// finished and have enough power
+func (b *Building) sensing() bool {
+	return b.Def != nil && b.Active && b.Completed && !b.Recycling && b.Power >= MinDefensePower
+}
+
+// DetectionRange returns how close cloaked enemies must be for the
+// building to reveal them, 0 if it is no detector or its sensors are down
+func (b *Building) DetectionRange() float64 {
+	if !b.sensing() {
+		return 0
+	}
+	return b.Def.DetectionRange
+}
+
+// RadarRange returns the radius in which the building shows enemy units
+// out of sight on the minimap, 0 if it has no radar or its sensors are down
+func (b *Building) RadarRange() float64 {
+	if !b.sensing() {
+		return 0
+	}
+	return b.Def.RadarRange
+}
+
+// Detects reports whether the building reveals cloaked enemies at p
+func (b *Building) Detects(p emath.Vec2) bool {
+	r := b.DetectionRange()
+	return r > 0 && b.Center().DistanceSquared(p) <= r*r
+}
+
+// OnRadar reports whether p lies inside the building's radar coverage
+func (b *Building) OnRadar(p emath.Vec2) bool {
+	r := b.RadarRange()
+	return r > 0 && b.Center().DistanceSquared(p) <= r*r
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+func TestScoutDetectsWithinRange(t *testing.T) {
+	phantom := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypePhantomTank], FactionEnemy)
+	scout := NewScout(2, 0, 0, FactionPlayer)
+	if !phantom.IsCloaked() || scout.IsCloaked() {
+		t.Fatal("only the phantom tank should be cloaked")
+	}
+
+	r := scout.DetectionRange()
+	near := scout.Center().Add(emath.Vec2{X: r - 1})
+	far := scout.Center().Add(emath.Vec2{X: r + 1})
+	if !scout.Detects(near) || scout.Detects(far) {
+		t.Error("a scout should detect within its range and nothing beyond")
+	}
+	if phantom.Detects(near) {
+		t.Error("units without a detection range should detect nothing")
+	}
+}
+
+func TestUplinkSensorsNeedPower(t *testing.T) {
+	uplink := NewBuildingUnderConstruction(1, 0, 0, BuildingDefs[BuildingDataUplink])
+	p := uplink.Center().Add(emath.Vec2{X: 100})
+	if uplink.Detects(p) || uplink.OnRadar(p) {
+		t.Error("an unfinished uplink should not sense anything")
+	}
+
+	uplink.Completed = true
+	if !uplink.Detects(p) || !uplink.OnRadar(p) {
+		t.Error("a finished uplink should detect and show radar nearby")
+	}
+	if uplink.RadarRange() <= uplink.DetectionRange() {
+		t.Error("radar should reach further than detection")
+	}
+
+	uplink.Power = MinDefensePower / 2
+	if uplink.Detects(p) || uplink.OnRadar(p) {
+		t.Error("an uplink short of power should lose its sensors")
+	}
+}
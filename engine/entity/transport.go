@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"slices"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// CanTransport reports whether the unit has a cargo hold
+func (u *Unit) CanTransport() bool {
+	return u.Def != nil && u.Def.CanTransport()
+}
+
+// CargoSize returns the room the unit takes in a transport, 0 if it cannot
+// be carried
+func (u *Unit) CargoSize() int {
+	if u.Def == nil {
+		return 0
+	}
+	return u.Def.GetCargoSize()
+}
+
+// CargoUsed returns the room taken by the units aboard
+func (u *Unit) CargoUsed() int {
+	used := 0
+	for _, c := range u.Cargo {
+		used += c.CargoSize()
+	}
+	return used
+}
+
+// CargoRoom returns the room left in the hold
+func (u *Unit) CargoRoom() int {
+	if u.Def == nil {
+		return 0
+	}
+	return u.Def.GetCapacity() - u.CargoUsed()
+}
+
+// CanLoad reports whether other may board the transport u now
+func (u *Unit) CanLoad(other *Unit) bool {
+	if !u.CanTransport() || other == nil || other == u || !u.Active || !other.Active {
+		return false
+	}
+	if other.Faction != u.Faction || other.Carrier != nil {
+		return false
+	}
+	size := other.CargoSize()
+	return size > 0 && size <= u.CargoRoom()
+}
+
+// IsInLoadRange reports whether other is close enough to board u
+func (u *Unit) IsInLoadRange(other *Unit) bool {
+	if other == nil || u.Def == nil {
+		return false
+	}
+	return u.Bounds().DistanceTo(other.Center()) <= u.Def.GetLoadRange()+max(other.Size.X, other.Size.Y)/2
+}
+
+// Load takes other aboard. It drops whatever it was doing and leaves the
+// world until it is unloaded.
+func (u *Unit) Load(other *Unit) {
+	other.ClearOrders()
+	other.ClearTarget()
+	other.ClearAttackTarget()
+	other.ClearRepairTarget()
+	other.BuildQueue = nil
+	other.ClearBuildTask()
+	other.Formation = FormationSlot{}
+	other.Selected = false
+	other.Active = false
+	other.Carrier = u
+	other.Position = u.Center().Sub(other.Size.Mul(0.5))
+	u.Cargo = append(u.Cargo, other)
+}
+
+// Unload sets other down at pos, the top-left corner of its bounds, and
+// returns it to the world
+func (u *Unit) Unload(other *Unit, pos emath.Vec2) {
+	i := slices.Index(u.Cargo, other)
+	if i < 0 {
+		return
+	}
+	u.Cargo = slices.Delete(u.Cargo, i, i+1)
+	other.Carrier = nil
+	other.Active = true
+	other.Position = pos
+	other.LastPosition = pos
+	other.Post = other.Center()
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+func TestCargoSizeFollowsFootprint(t *testing.T) {
+	if got := UnitDefs[UnitTypeScout].GetCargoSize(); got != 1 {
+		t.Errorf("scout cargo size = %d, want 1", got)
+	}
+	if got := UnitDefs[UnitTypeTank].GetCargoSize(); got != 2 {
+		t.Errorf("tank cargo size = %d, want 2", got)
+	}
+	for _, ut := range []UnitType{UnitTypeGunship, UnitTypeHoverTransport, UnitTypeAirTransport} {
+		if got := UnitDefs[ut].GetCargoSize(); got != 0 {
+			t.Errorf("%s cargo size = %d, want 0", UnitDefs[ut].Name, got)
+		}
+	}
+}
+
+func TestTransportLoadsUntilFull(t *testing.T) {
+	lifter := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeAirTransport], FactionPlayer)
+	tank := NewTank(2, 0, 0, FactionPlayer)
+	scout := NewScout(3, 0, 0, FactionPlayer)
+	enemy := NewScout(4, 0, 0, FactionEnemy)
+
+	if lifter.CanLoad(enemy) {
+		t.Error("transports should not load enemy units")
+	}
+	if !lifter.CanLoad(tank) {
+		t.Fatal("an empty skylifter should take a tank")
+	}
+	tank.SetTarget(emath.Vec2{X: 500, Y: 500})
+	tank.Selected = true
+	lifter.Load(tank)
+	if tank.Active || tank.Carrier != lifter || tank.HasTarget || tank.Selected {
+		t.Errorf("a loaded unit should leave the world and drop its move")
+	}
+	if lifter.CargoRoom() != 0 || lifter.CanLoad(scout) {
+		t.Errorf("room left %d; a full hold should take nothing more", lifter.CargoRoom())
+	}
+
+	lifter.Unload(tank, emath.Vec2{X: 40, Y: 60})
+	if !tank.Active || tank.Carrier != nil || tank.Position != (emath.Vec2{X: 40, Y: 60}) || len(lifter.Cargo) != 0 {
+		t.Errorf("unloaded tank active %v at %v, cargo %d", tank.Active, tank.Position, len(lifter.Cargo))
+	}
+}
@@ -2,9 +2,17 @@ package entity
 
 import (
 	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/pathfinding"
 	"math"
 )
 
+// FormationSlot is a unit's place in a group formation
+type FormationSlot struct {
+	GroupID uint64     // Shared by the units of one formation, 0 when not in formation
+	Kind    string     // Layout the slot belongs to
+	Offset  emath.Vec2 // Position relative to the group center, +X along the group facing
+}
+
 // BuildTask represents a queued building construction task
 type BuildTask struct {
 	Def *BuildingDef
@@ -17,9 +25,15 @@ type Unit struct {
 	Type                 UnitType
 	Target               emath.Vec2
 	HasTarget            bool
+	Path                 []emath.Vec2           // Waypoints to Target, the last one is Target itself
+	NeedsPath            bool                   // Set when the path to Target must be (re)planned
+	Flow                 *pathfinding.FlowField // Shared field of a group move, followed until close to Target
 	Selected             bool
 	Speed                float64
+	GroupSpeed           float64       // Speed cap of the unit's group move, 0 when moving alone
+	Formation            FormationSlot // Place in the formation of the last group move
 	StuckCounter         int
+	StuckRepaths         int // Paths replanned since the unit last made progress
 	LastPosition         emath.Vec2
 	Angle                float64       // Body angle (direction unit is facing)
 	TurretAngle          float64       // Turret angle (direction turret is aiming)
@@ -29,10 +43,18 @@ type Unit struct {
 	MaxHealth            float64
 	Damage               float64
 	Range                float64
+	MinRange             float64 // Targets closer than this cannot be hit
 	FireRate             float64
 	FireCooldown         float64
 	AttackTarget         *Unit
 	BuildingAttackTarget *Building
+	AttackOrdered        bool       // The attack target was given by an order rather than picked automatically
+	Stance               Stance     // How the unit engages enemies on its own
+	Post                 emath.Vec2 // Where the unit last stood idle; defensive units return here
+	Burn                 Burn       // Fire damage still to be taken
+	Experience           float64    // Damage dealt plus kill bonuses
+	Rank                 Rank       // Veterancy rank earned from Experience
+	Upgrade              Upgrade    // Research bonus the unit's stats include
 	VisionRange          float64
 	PursuitRange         float64   // Range to keep chasing an enemy (usually > fire range)
 	BuildTarget          *Building
@@ -41,13 +63,24 @@ type Unit struct {
 	HasBuildTask         bool
 	IsBuilding           bool
 	BuildQueue           []BuildTask // Queue of pending build tasks
+	Orders               []Order     // Order queue; the first entry is being carried out
 	RepairRate           float64     // Health per second when repairing
 	RepairRange          float64     // Range to repair units
 	RepairTarget         *Unit       // Unit being repaired
+	ReclaimRate          float64     // Metal per second when reclaiming wreckage
+	ReclaimRange         float64     // Range to reclaim wreckage
+	Cargo                []*Unit     // Units carried, out of the world while aboard
+	Carrier              *Unit       // Transport the unit rides in, nil when in the world
+	Ability              AbilityState // Ability cooldowns and running boost
 }
 
 const (
 	RotationSpeedBasic = 0.08 // Default fallback rotation speed
+
+	stuckRepathTicks = 15 // Ticks without progress before the path is replanned
+	maxStuckRepaths  = 3  // Replans tried before a stuck unit gives up
+	waypointRadius   = 12 // Distance at which a waypoint counts as reached
+	flowArriveRadius = 75 // Distance to Target at which a unit leaves its flow field
 )
 
 // NewConstructor creates a constructor unit (convenience function)
@@ -93,11 +126,14 @@ func NewUnitFromDef(id uint64, x, y float64, def *UnitDef, faction Faction) *Uni
 		MaxHealth:           def.Health,
 		Damage:              def.GetDamage(),
 		Range:               def.GetRange(),
+		MinRange:            def.GetMinRange(),
 		FireRate:            def.GetFireRate(),
 		VisionRange:         def.VisionRange,
 		PursuitRange:        def.GetRange() * 1.5, // Chase enemies 1.5x further than fire range
 		RepairRate:          def.GetRepairRate(),
 		RepairRange:         def.GetRepairRange(),
+		ReclaimRate:         def.GetReclaimRate(),
+		ReclaimRange:        def.GetReclaimRange(),
 	}
 }
 func (u *Unit) CanBuild() bool {
@@ -128,46 +164,127 @@ func (u *Unit) SetRepairTarget(target *Unit) {
 func (u *Unit) ClearRepairTarget() {
 	u.RepairTarget = nil
 }
+func (u *Unit) CanReclaim() bool {
+	return u.Def != nil && u.Def.CanReclaimWreckage() && u.ReclaimRate > 0 && u.ReclaimRange > 0
+}
+func (u *Unit) IsInReclaimRange(wreck *Wreckage) bool {
+	if wreck == nil {
+		return false
+	}
+	return wreck.Bounds().DistanceTo(u.Center()) <= u.ReclaimRange
+}
 func (u *Unit) GetBuildOptions() []*BuildingDef {
 	return GetBuildableDefs(u.Type)
 }
 func (u *Unit) SetTarget(target emath.Vec2) {
 	u.Target = target
 	u.HasTarget = true
+	u.Path = nil
+	u.Flow = nil
+	u.NeedsPath = !u.IsAircraft() // Aircraft fly straight
+	u.GroupSpeed = 0
 	u.StuckCounter = 0
+	u.StuckRepaths = 0
+}
+
+// SetFlowTarget moves the unit to target by following a flow field shared
+// with the rest of its group
+func (u *Unit) SetFlowTarget(target emath.Vec2, flow *pathfinding.FlowField) {
+	u.SetTarget(target)
+	if u.IsAircraft() {
+		return
+	}
+	u.Flow = flow
+	u.NeedsPath = false
 }
 func (u *Unit) ClearTarget() {
 	u.HasTarget = false
+	u.Path = nil
+	u.Flow = nil
+	u.NeedsPath = false
+	u.GroupSpeed = 0
 	u.Velocity = emath.Vec2{}
 	u.StuckCounter = 0
+	u.StuckRepaths = 0
+}
+
+// SetPath gives the unit planned waypoints to follow. When the planner
+// could only get close, the end of the path becomes the new target.
+func (u *Unit) SetPath(path []emath.Vec2) {
+	u.Path = path
+	u.Flow = nil
+	u.NeedsPath = false
+	if len(path) > 0 {
+		u.Target = path[len(path)-1]
+	}
+}
+
+// MoveSpeed is the speed the unit currently drives at, held back to the
+// pace of its group during a formation move and pushed by a speed boost
+func (u *Unit) MoveSpeed() float64 {
+	speed := u.Speed * (1 + u.Ability.Boost)
+	if u.GroupSpeed > 0 && u.GroupSpeed < speed {
+		return u.GroupSpeed
+	}
+	return speed
+}
+
+// Waypoint returns the point the unit is currently steering towards
+func (u *Unit) Waypoint() emath.Vec2 {
+	if u.Flow != nil {
+		if wp, ok := u.Flow.Waypoint(u.Center()); ok {
+			return wp
+		}
+	}
+	if len(u.Path) > 0 {
+		return u.Path[0]
+	}
+	return u.Target
 }
 func (u *Unit) Update() emath.Vec2 {
 	if !u.HasTarget {
 		return u.Position
 	}
 	currentCenter := u.Center()
-	direction := u.Target.Sub(currentCenter)
-	distSquared := direction.LengthSquared()
-	if distSquared < u.Speed*u.Speed {
+	speed := u.MoveSpeed()
+	distSquared := u.Target.DistanceSquared(currentCenter)
+	if distSquared < speed*speed {
 		u.Position = u.Target.Sub(u.Size.Mul(0.5))
 		u.ClearTarget()
 		return u.Position
 	}
+	if u.Flow != nil && !u.NeedsPath {
+		// Near the end the group splits up, so the last stretch to the
+		// unit's own spot is planned on its own
+		if _, ok := u.Flow.Waypoint(currentCenter); !ok || distSquared < flowArriveRadius*flowArriveRadius {
+			u.NeedsPath = true
+		}
+	}
+	for len(u.Path) > 1 && u.Path[0].DistanceSquared(currentCenter) < waypointRadius*waypointRadius {
+		u.Path = u.Path[1:]
+	}
 	if u.Position.DistanceSquared(u.LastPosition) < 0.1 {
 		u.StuckCounter++
-		if u.StuckCounter > 30 {
-			u.ClearTarget()
-			return u.Position
+		if u.StuckCounter > stuckRepathTicks {
+			if u.StuckRepaths >= maxStuckRepaths {
+				u.ClearTarget()
+				return u.Position
+			}
+			u.StuckRepaths++
+			u.StuckCounter = 0
+			u.NeedsPath = true
 		}
 	} else {
 		u.StuckCounter = 0
+		u.StuckRepaths = 0
 	}
 	u.LastPosition = u.Position
+	direction := u.Waypoint().Sub(currentCenter)
 	targetAngle := math.Atan2(direction.Y, direction.X)
 	u.rotateTowards(targetAngle)
 	u.Velocity = emath.Vec2{
-		X: math.Cos(u.Angle) * u.Speed,
-		Y: math.Sin(u.Angle) * u.Speed,
+		X: math.Cos(u.Angle) * speed,
+		Y: math.Sin(u.Angle) * speed,
 	}
 	return u.Position.Add(u.Velocity)
 }
@@ -268,12 +385,66 @@ func (u *Unit) IsNearBuildSite() bool {
 func (u *Unit) CanAttack() bool {
 	return u.Damage > 0 && u.Range > 0
 }
+
+// IsAircraft reports whether the unit flies over terrain and ground units
+func (u *Unit) IsAircraft() bool {
+	return u.Def != nil && u.Def.IsAircraft()
+}
+
+// Movement returns the unit's movement class; units without a def drive
+func (u *Unit) Movement() MovementClass {
+	if u.Def == nil {
+		return MovementGround
+	}
+	return u.Def.Movement
+}
+
+// CanTarget reports whether the unit's weapon can hit target: aircraft
+// need an anti-air weapon, ground units an anti-ground one
+func (u *Unit) CanTarget(target *Unit) bool {
+	if target.IsAircraft() {
+		return u.Def != nil && u.Def.Combat != nil && u.Def.Combat.AntiAir
+	}
+	return u.CanTargetGround()
+}
+
+// CanTargetGround reports whether the unit can shoot ground units and buildings
+func (u *Unit) CanTargetGround() bool {
+	return u.Def == nil || u.Def.Combat == nil || u.Def.Combat.AntiGround
+}
+
+// Weapon returns the type of the unit's weapon
+func (u *Unit) Weapon() WeaponType {
+	if u.Def == nil || u.Def.Combat == nil {
+		return WeaponCannon
+	}
+	return u.Def.Combat.Weapon
+}
+
+// ShotSpeed returns how fast the unit's projectiles fly, per second
+func (u *Unit) ShotSpeed() float64 {
+	if u.Def != nil && u.Def.Combat != nil && u.Def.Combat.ProjectileSpeed > 0 {
+		return u.Def.Combat.ProjectileSpeed
+	}
+	return ProjectileSpeed
+}
+
+// InFiringRange reports whether a target dist away is neither too far nor
+// inside the weapon's minimum range
+func (u *Unit) InFiringRange(dist float64) bool {
+	return dist <= u.Range && dist >= u.MinRange
+}
+
+// TooClose reports whether a point is inside the weapon's minimum range
+func (u *Unit) TooClose(pos emath.Vec2) bool {
+	return u.MinRange > 0 && u.Center().Distance(pos) < u.MinRange
+}
 func (u *Unit) IsInRange(target *Unit) bool {
 	if target == nil {
 		return false
 	}
 	dist := u.Center().Distance(target.Center())
-	return dist <= u.Range
+	return u.InFiringRange(dist)
 }
 
 func (u *Unit) IsBuildingInRange(target *Building) bool {
@@ -281,7 +452,7 @@ func (u *Unit) IsBuildingInRange(target *Building) bool {
 		return false
 	}
 	dist := u.Center().Distance(target.Center())
-	return dist <= u.Range
+	return u.InFiringRange(dist)
 }
 
 func (u *Unit) IsInPursuitRange(target *Unit) bool {
@@ -300,8 +471,27 @@ func (u *Unit) IsBuildingInPursuitRange(target *Building) bool {
 	return dist <= u.PursuitRange
 }
 
-func (u *Unit) TakeDamage(damage float64) bool {
-	u.Health -= damage
+// Armor returns the unit's armor class
+func (u *Unit) Armor() ArmorClass {
+	if u.Def == nil {
+		return ArmorLight
+	}
+	return u.Def.Armor
+}
+
+// DamageType returns the type of damage the unit's weapon deals
+func (u *Unit) DamageType() DamageType {
+	if u.Def == nil || u.Def.Combat == nil {
+		return DamageKinetic
+	}
+	return u.Def.Combat.DamageType
+}
+
+// TakeDamage applies a hit, scaled by how effective its damage type is
+// against the unit's armor and less any researched plating, and reports
+// whether it destroyed the unit
+func (u *Unit) TakeDamage(damage float64, kind DamageType) bool {
+	u.Health -= damage * Effectiveness.Multiplier(kind, u.Armor()) * (1 - u.Upgrade.Plating)
 	if u.Health <= 0 {
 		u.Health = 0
 		u.Active = false
@@ -333,16 +523,19 @@ func (u *Unit) UpdateCombat(dt float64) bool {
 func (u *Unit) SetAttackTarget(target *Unit) {
 	u.AttackTarget = target
 	u.BuildingAttackTarget = nil
+	u.AttackOrdered = false
 }
 
 func (u *Unit) SetBuildingAttackTarget(target *Building) {
 	u.BuildingAttackTarget = target
 	u.AttackTarget = nil
+	u.AttackOrdered = false
 }
 
 func (u *Unit) ClearAttackTarget() {
 	u.AttackTarget = nil
 	u.BuildingAttackTarget = nil
+	u.AttackOrdered = false
 }
 
 func (u *Unit) HasAnyAttackTarget() bool {
@@ -3,7 +3,9 @@ package entity
 import (
 	"fmt"
 	"image/color"
+	"math"
 
+	"github.com/bklimczak/tanks/engine/movement"
 	"github.com/bklimczak/tanks/engine/resource"
 )
 
@@ -20,6 +22,13 @@ const (
 	UnitTypeRocketTank
 	UnitTypeFlameTank
 	UnitTypeAAVehicle
+	UnitTypeGunship
+	UnitTypeBomber
+	UnitTypeHoverScout
+	UnitTypeHoverTank
+	UnitTypeHoverTransport
+	UnitTypeAirTransport
+	UnitTypePhantomTank
 )
 
 func (t UnitType) String() string {
@@ -42,16 +51,57 @@ func (t UnitType) String() string {
 		return "Flame Tank"
 	case UnitTypeAAVehicle:
 		return "AA Vehicle"
+	case UnitTypeGunship:
+		return "Gunship"
+	case UnitTypeBomber:
+		return "Bomber"
+	case UnitTypeHoverScout:
+		return "Hover Scout"
+	case UnitTypeHoverTank:
+		return "Hover Tank"
+	case UnitTypeHoverTransport:
+		return "Hover Transport"
+	case UnitTypeAirTransport:
+		return "Skylifter"
+	case UnitTypePhantomTank:
+		return "Phantom Tank"
 	default:
 		return "Unit"
 	}
 }
 
+// cargoSizeStep is the footprint, in pixels along the longer side, that
+// takes one unit of room in a transport
+const cargoSizeStep = 30.0
+
+// MovementClass decides where a unit can move and what blocks it
+type MovementClass = movement.Class
+
+const (
+	MovementGround = movement.Ground // Drives on land, blocked by water, buildings and other ground units
+	MovementAir    = movement.Air    // Flies over everything and only meets other aircraft
+	MovementHover  = movement.Hover  // Glides over land and water alike
+	MovementNaval  = movement.Naval  // Sails on water and cannot come ashore
+)
+
 // CombatDef contains combat-related stats for units that can attack
 type CombatDef struct {
-	Damage   float64
-	Range    float64
-	FireRate float64
+	Damage     float64
+	Range      float64
+	FireRate   float64
+	AntiAir    bool // Can shoot at aircraft
+	AntiGround bool // Can shoot at ground units and buildings
+
+	// Weapon behaviour; the zero value is a kinetic cannon
+	Weapon          WeaponType
+	DamageType      DamageType
+	MinRange        float64 // Targets closer than this cannot be hit
+	ProjectileSpeed float64 // 0 uses ProjectileSpeed
+	SplashRadius    float64 // Ballistic: blast radius around the landing point
+	TurnRate        float64 // Rocket: how fast it turns towards its target, in radians per second
+	ConeAngle       float64 // Flame: full width of the cone, in radians
+	BurnDamage      float64 // Flame: damage per second while a target burns
+	BurnDuration    float64 // Flame: how long a target keeps burning, in seconds
 }
 
 // ConstructionDef contains construction/repair capabilities
@@ -60,6 +110,14 @@ type ConstructionDef struct {
 	RepairRate     float64
 	RepairRange    float64
 	CanRepairUnits bool
+	ReclaimRate    float64 // Metal per second pulled out of wreckage
+	ReclaimRange   float64 // Reach from the unit's center to the edge of a wreck
+}
+
+// TransportDef contains the cargo hold of units that carry others
+type TransportDef struct {
+	Capacity  int     // Room in the hold, in cargo size
+	LoadRange float64 // Reach from the transport's edge to a unit it loads
 }
 
 // TankRenderDef contains tank-specific rendering with hull and turret
@@ -84,17 +142,26 @@ type UnitDef struct {
 	BuildTime   float64
 	Health      float64
 	VisionRange float64
+	Armor       ArmorClass
 
 	// Movement
 	RotationSpeed float64
-	IsHoverUnit   bool
+	Movement      MovementClass
 	IsInfantry    bool
 
 	// Optional capabilities - nil means unit doesn't have this capability
 	Combat       *CombatDef       // nil for non-combat units
 	Construction *ConstructionDef // nil for non-constructor units
+	Transport    *TransportDef    // nil for units that carry nothing
 	TankRender   *TankRenderDef   // nil for non-tank units
 
+	CargoSize int           // Room taken in a transport, 0 derives it from the footprint
+	Abilities []AbilityType // Activated abilities, shown as command panel buttons
+
+	// Stealth
+	Cloaked        bool    // Hidden from enemies that have no detector in range
+	DetectionRange float64 // Reveals cloaked enemies this close, 0 for none
+
 	// Simple sprite (used when TankRender is nil)
 	SpritePath  string
 	SpriteScale float64
@@ -105,6 +172,16 @@ func (d *UnitDef) CanAttack() bool {
 	return d.Combat != nil && d.Combat.Damage > 0
 }
 
+// IsAircraft returns true if the unit flies
+func (d *UnitDef) IsAircraft() bool {
+	return d.Movement == MovementAir
+}
+
+// IsHoverUnit returns true if the unit can cross both land and water
+func (d *UnitDef) IsHoverUnit() bool {
+	return d.Movement == MovementHover
+}
+
 // CanConstruct returns true if unit can build structures
 func (d *UnitDef) CanConstruct() bool {
 	return d.Construction != nil && len(d.Construction.BuildableTypes) > 0
@@ -115,6 +192,44 @@ func (d *UnitDef) CanRepairUnits() bool {
 	return d.Construction != nil && d.Construction.CanRepairUnits
 }
 
+// CanReclaimWreckage returns true if unit can pull metal out of wreckage
+func (d *UnitDef) CanReclaimWreckage() bool {
+	return d.Construction != nil && d.Construction.ReclaimRate > 0
+}
+
+// CanTransport returns true if unit can carry other units
+func (d *UnitDef) CanTransport() bool {
+	return d.Transport != nil && d.Transport.Capacity > 0
+}
+
+// GetCapacity returns the room in the cargo hold or 0 if unit carries nothing
+func (d *UnitDef) GetCapacity() int {
+	if d.Transport == nil {
+		return 0
+	}
+	return d.Transport.Capacity
+}
+
+// GetLoadRange returns the loading reach or 0 if unit carries nothing
+func (d *UnitDef) GetLoadRange() float64 {
+	if d.Transport == nil {
+		return 0
+	}
+	return d.Transport.LoadRange
+}
+
+// GetCargoSize returns the room the unit takes in a transport, or 0 if it
+// cannot be carried. Aircraft, ships and transports ride in nothing.
+func (d *UnitDef) GetCargoSize() int {
+	if d.IsAircraft() || d.Movement == MovementNaval || d.CanTransport() {
+		return 0
+	}
+	if d.CargoSize > 0 {
+		return d.CargoSize
+	}
+	return max(1, int(math.Ceil(max(d.GetWidth(), d.GetHeight())/cargoSizeStep)))
+}
+
 // HasTurret returns true if unit has a rotating turret
 func (d *UnitDef) HasTurret() bool {
 	return d.TankRender != nil
@@ -136,6 +251,14 @@ func (d *UnitDef) GetRange() float64 {
 	return d.Combat.Range
 }
 
+// GetMinRange returns the minimum attack range or 0 if there is none
+func (d *UnitDef) GetMinRange() float64 {
+	if d.Combat == nil {
+		return 0
+	}
+	return d.Combat.MinRange
+}
+
 // GetFireRate returns fire rate or 0 if non-combat unit
 func (d *UnitDef) GetFireRate() float64 {
 	if d.Combat == nil {
@@ -168,6 +291,22 @@ func (d *UnitDef) GetRepairRange() float64 {
 	return d.Construction.RepairRange
 }
 
+// GetReclaimRate returns reclaim rate or 0 if unit can't reclaim
+func (d *UnitDef) GetReclaimRate() float64 {
+	if d.Construction == nil {
+		return 0
+	}
+	return d.Construction.ReclaimRate
+}
+
+// GetReclaimRange returns reclaim range or 0 if unit can't reclaim
+func (d *UnitDef) GetReclaimRange() float64 {
+	if d.Construction == nil {
+		return 0
+	}
+	return d.Construction.ReclaimRange
+}
+
 // GetTurretRotationSpeed returns turret speed or 0 if no turret
 func (d *UnitDef) GetTurretRotationSpeed() float64 {
 	if d.TankRender == nil {
@@ -217,6 +356,8 @@ const (
 	BuildingEnergyStorageLarge
 	BuildingMechFactory
 	BuildingLaserTower
+	// Air
+	BuildingAirfield
 	NumBuildingTypes
 )
 
@@ -262,6 +403,8 @@ func (t BuildingType) String() string {
 		return "Mech Factory"
 	case BuildingLaserTower:
 		return "Laser Tower"
+	case BuildingAirfield:
+		return "Airfield"
 	default:
 		return "Unknown"
 	}
@@ -276,7 +419,7 @@ type BuildingDef struct {
 	Height            float64 // Explicit height (takes precedence over Size)
 	Color             color.Color
 	Cost              map[resource.Type]float64
-	MetalProduction   float64
+	MetalProduction   float64 // Per second; extractors draw this much from each deposit tile they cover
 	EnergyProduction  float64
 	MetalConsumption  float64
 	EnergyConsumption float64
@@ -285,8 +428,13 @@ type BuildingDef struct {
 	BuildTime         float64
 	VisionRange       float64
 	Health            float64
+	Armor             ArmorClass
+	RecycleRefund     float64 // Share of its cost returned when recycled, DefaultRecycleRefund when 0
+	RadarRange        float64 // Shows enemy units out of sight as minimap blips, 0 for none
+	DetectionRange    float64 // Reveals cloaked enemies this close, 0 for none
 
 	IsFactory           bool
+	IsLab               bool // Runs research projects
 	ProducesUnits       []UnitType
 	BuildableStructures []BuildingType
 	RequiresDeposit     bool
@@ -296,6 +444,7 @@ type BuildingDef struct {
 	AttackRange   float64
 	FireRate      float64
 	EnergyPerShot float64
+	DamageType    DamageType
 
 	AntiAir    bool
 	AntiGround bool
@@ -334,6 +483,7 @@ var AllBuildableTypes = []BuildingType{
 	BuildingWall,
 	BuildingAutocannonTurret,
 	BuildingMissileBattery,
+	BuildingAirfield,
 }
 
 var UnitDefs = map[UnitType]*UnitDef{
@@ -352,18 +502,22 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     5.0,
 		Health:        100,
 		VisionRange:   250,
+		Armor:         ArmorHeavy,
 		RotationSpeed: 0.03,
 		SpriteScale:   0.25,
 		Combat: &CombatDef{
-			Damage:   15,
-			Range:    150,
-			FireRate: 1.0,
+			Damage:     15,
+			Range:      150,
+			FireRate:   1.0,
+			AntiGround: true,
+			DamageType: DamagePiercing,
 		},
 		TankRender: &TankRenderDef{
 			HullSpritePath:      "units/color_a/Hull_01.png",
 			GunSpritePath:       "units/color_a/Gun_01.png",
 			TurretRotationSpeed: 0.08,
 		},
+		Abilities: []AbilityType{AbilitySmokeScreen},
 	},
 	UnitTypeScout: {
 		Type:        UnitTypeScout,
@@ -379,13 +533,17 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     2.0,
 		Health:        40,
 		VisionRange:   400,
+		Armor:         ArmorLight,
 		RotationSpeed: 0.2,
 		SpritePath:    "scout.png",
 		Combat: &CombatDef{
-			Damage:   5,
-			Range:    100,
-			FireRate: 2.0,
+			Damage:     5,
+			Range:      100,
+			FireRate:   2.0,
+			AntiGround: true,
 		},
+		Abilities:      []AbilityType{AbilitySensorSweep},
+		DetectionRange: 300,
 	},
 	UnitTypeConstructor: {
 		Type:        UnitTypeConstructor,
@@ -401,10 +559,14 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     8.0,
 		Health:        60,
 		VisionRange:   200,
+		Armor:         ArmorLight,
 		RotationSpeed: 0.1,
 		Construction: &ConstructionDef{
 			BuildableTypes: AllBuildableTypes,
+			ReclaimRate:    10,
+			ReclaimRange:   40,
 		},
+		Abilities: []AbilityType{AbilityFieldRepair},
 	},
 	UnitTypeLightTank: {
 		Type:        UnitTypeLightTank,
@@ -421,18 +583,21 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     3.0,
 		Health:        60,
 		VisionRange:   300,
+		Armor:         ArmorLight,
 		RotationSpeed: 0.05,
 		SpriteScale:   0.22,
 		Combat: &CombatDef{
-			Damage:   10,
-			Range:    120,
-			FireRate: 1.5,
+			Damage:     10,
+			Range:      120,
+			FireRate:   1.5,
+			AntiGround: true,
 		},
 		TankRender: &TankRenderDef{
 			HullSpritePath:      "units/color_a/Hull_02.png",
 			GunSpritePath:       "units/color_a/Gun_02.png",
 			TurretRotationSpeed: 0.10,
 		},
+		Abilities: []AbilityType{AbilitySpeedBoost},
 	},
 	UnitTypeHeavyTank: {
 		Type:        UnitTypeHeavyTank,
@@ -449,12 +614,15 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     8.0,
 		Health:        200,
 		VisionRange:   200,
+		Armor:         ArmorHeavy,
 		RotationSpeed: 0.02,
 		SpriteScale:   0.28,
 		Combat: &CombatDef{
-			Damage:   25,
-			Range:    180,
-			FireRate: 0.6,
+			Damage:     25,
+			Range:      180,
+			FireRate:   0.6,
+			AntiGround: true,
+			DamageType: DamagePiercing,
 		},
 		TankRender: &TankRenderDef{
 			HullSpritePath:      "units/color_a/Hull_05.png",
@@ -477,12 +645,20 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     10.0,
 		Health:        50,
 		VisionRange:   400,
+		Armor:         ArmorLight,
 		RotationSpeed: 0.02,
 		SpriteScale:   0.25,
 		Combat: &CombatDef{
-			Damage:   40,
-			Range:    350,
-			FireRate: 0.3,
+			Damage:     40,
+			Range:      350,
+			FireRate:   0.3,
+			AntiGround: true,
+
+			Weapon:          WeaponBallistic,
+			DamageType:      DamageExplosive,
+			MinRange:        120,
+			ProjectileSpeed: 250,
+			SplashRadius:    50,
 		},
 		TankRender: &TankRenderDef{
 			HullSpritePath:      "units/color_a/Hull_03.png",
@@ -505,12 +681,19 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     7.0,
 		Health:        80,
 		VisionRange:   250,
+		Armor:         ArmorLight,
 		RotationSpeed: 0.03,
 		SpriteScale:   0.25,
 		Combat: &CombatDef{
-			Damage:   30,
-			Range:    200,
-			FireRate: 0.5,
+			Damage:     30,
+			Range:      200,
+			FireRate:   0.5,
+			AntiGround: true,
+
+			Weapon:          WeaponRocket,
+			DamageType:      DamagePiercing,
+			ProjectileSpeed: 550,
+			TurnRate:        2.5,
 		},
 		TankRender: &TankRenderDef{
 			HullSpritePath:      "units/color_a/Hull_04.png",
@@ -533,12 +716,20 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     6.0,
 		Health:        90,
 		VisionRange:   200,
+		Armor:         ArmorHeavy,
 		RotationSpeed: 0.04,
 		SpriteScale:   0.25,
 		Combat: &CombatDef{
-			Damage:   8,
-			Range:    80,
-			FireRate: 4.0,
+			Damage:     8,
+			Range:      80,
+			FireRate:   4.0,
+			AntiGround: true,
+
+			Weapon:       WeaponFlame,
+			DamageType:   DamageFire,
+			ConeAngle:    0.6,
+			BurnDamage:   6,
+			BurnDuration: 3,
 		},
 		TankRender: &TankRenderDef{
 			HullSpritePath:      "units/color_a/Hull_06.png",
@@ -561,12 +752,16 @@ var UnitDefs = map[UnitType]*UnitDef{
 		BuildTime:     5.0,
 		Health:        50,
 		VisionRange:   350,
+		Armor:         ArmorLight,
 		RotationSpeed: 0.05,
 		SpriteScale:   0.22,
 		Combat: &CombatDef{
-			Damage:   12,
-			Range:    250,
-			FireRate: 2.0,
+			Damage:     12,
+			Range:      250,
+			FireRate:   2.0,
+			AntiAir:    true,
+			AntiGround: true,
+			DamageType: DamageFlak,
 		},
 		TankRender: &TankRenderDef{
 			HullSpritePath:      "units/color_a/Hull_07.png",
@@ -574,6 +769,194 @@ var UnitDefs = map[UnitType]*UnitDef{
 			TurretRotationSpeed: 0.12,
 		},
 	},
+	UnitTypeGunship: {
+		Type:        UnitTypeGunship,
+		Name:        "Gunship",
+		Description: "Fast attack aircraft, strafes ground targets",
+		Size:        36,
+		Speed:       4.0,
+		Color:       color.RGBA{90, 110, 130, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal:  220,
+			resource.Energy: 120,
+		},
+		BuildTime:     9.0,
+		Health:        110,
+		VisionRange:   350,
+		Armor:         ArmorAir,
+		RotationSpeed: 0.06,
+		Movement:      MovementAir,
+		Combat: &CombatDef{
+			Damage:     9,
+			Range:      160,
+			FireRate:   2.5,
+			AntiGround: true,
+		},
+	},
+	UnitTypeBomber: {
+		Type:        UnitTypeBomber,
+		Name:        "Bomber",
+		Description: "Flies over targets and drops heavy bombs",
+		Width:       50,
+		Height:      40,
+		Speed:       3.0,
+		Color:       color.RGBA{70, 80, 100, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal:  300,
+			resource.Energy: 150,
+		},
+		BuildTime:     12.0,
+		Health:        160,
+		VisionRange:   250,
+		Armor:         ArmorAir,
+		RotationSpeed: 0.04,
+		Movement:      MovementAir,
+		Combat: &CombatDef{
+			Damage:     60,
+			Range:      40,
+			FireRate:   0.4,
+			AntiGround: true,
+
+			Weapon:          WeaponBallistic,
+			DamageType:      DamageExplosive,
+			ProjectileSpeed: 150,
+			SplashRadius:    45,
+		},
+	},
+	UnitTypeHoverScout: {
+		Type:        UnitTypeHoverScout,
+		Name:        "Hover Scout",
+		Description: "Fast recon hovercraft, crosses water",
+		Size:        18,
+		Speed:       5.5,
+		Color:       color.RGBA{120, 170, 200, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal:  50,
+			resource.Energy: 30,
+		},
+		BuildTime:     3.0,
+		Health:        45,
+		VisionRange:   380,
+		Armor:         ArmorLight,
+		RotationSpeed: 0.18,
+		Movement:      MovementHover,
+		SpritePath:    "scout.png",
+		Combat: &CombatDef{
+			Damage:     5,
+			Range:      110,
+			FireRate:   2.0,
+			AntiGround: true,
+		},
+		DetectionRange: 300,
+	},
+	UnitTypeHoverTank: {
+		Type:        UnitTypeHoverTank,
+		Name:        "Hover Tank",
+		Description: "Light hover tank, crosses land and water",
+		Width:       50,
+		Height:      38,
+		Speed:       3.2,
+		Color:       color.RGBA{90, 140, 180, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal:  160,
+			resource.Energy: 80,
+		},
+		BuildTime:     6.0,
+		Health:        100,
+		VisionRange:   250,
+		Armor:         ArmorLight,
+		RotationSpeed: 0.06,
+		SpriteScale:   0.25,
+		Movement:      MovementHover,
+		Combat: &CombatDef{
+			Damage:     12,
+			Range:      160,
+			FireRate:   1.2,
+			AntiGround: true,
+		},
+		TankRender: &TankRenderDef{
+			HullSpritePath:      "units/color_a/Hull_08.png",
+			GunSpritePath:       "units/color_a/Gun_04.png",
+			TurretRotationSpeed: 0.10,
+		},
+	},
+	UnitTypeHoverTransport: {
+		Type:        UnitTypeHoverTransport,
+		Name:        "Hover Transport",
+		Description: "Unarmed hovercraft, ferries ground units across water",
+		Width:       64,
+		Height:      44,
+		Speed:       2.8,
+		Color:       color.RGBA{110, 150, 170, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal:  180,
+			resource.Energy: 60,
+		},
+		BuildTime:     7.0,
+		Health:        160,
+		VisionRange:   220,
+		Armor:         ArmorLight,
+		RotationSpeed: 0.05,
+		Movement:      MovementHover,
+		Transport: &TransportDef{
+			Capacity:  8,
+			LoadRange: 30,
+		},
+	},
+	UnitTypeAirTransport: {
+		Type:        UnitTypeAirTransport,
+		Name:        "Skylifter",
+		Description: "Fast unarmed aircraft, lifts a tank or two light units",
+		Width:       48,
+		Height:      40,
+		Speed:       4.5,
+		Color:       color.RGBA{120, 125, 140, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal:  200,
+			resource.Energy: 100,
+		},
+		BuildTime:     8.0,
+		Health:        90,
+		VisionRange:   250,
+		Armor:         ArmorAir,
+		RotationSpeed: 0.07,
+		Movement:      MovementAir,
+		Transport: &TransportDef{
+			Capacity:  2,
+			LoadRange: 20,
+		},
+	},
+	UnitTypePhantomTank: {
+		Type:        UnitTypePhantomTank,
+		Name:        "Phantom Tank",
+		Description: "Cloaked raider, seen only by detectors",
+		Width:       50,
+		Height:      35,
+		Speed:       3.0,
+		Color:       color.RGBA{70, 80, 110, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal:  180,
+			resource.Energy: 150,
+		},
+		BuildTime:     9.0,
+		Health:        70,
+		VisionRange:   250,
+		Armor:         ArmorLight,
+		RotationSpeed: 0.05,
+		SpriteScale:   0.22,
+		Combat: &CombatDef{
+			Damage:     14,
+			Range:      130,
+			FireRate:   1.0,
+			AntiGround: true,
+		},
+		TankRender: &TankRenderDef{
+			HullSpritePath:      "units/color_d/Hull_02.png",
+			GunSpritePath:       "units/color_d/Gun_02.png",
+			TurretRotationSpeed: 0.10,
+		},
+		Cloaked: true,
+	},
 }
 
 // CreateTankDef creates a custom tank definition with specified hull, gun, and color
@@ -615,12 +998,16 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         0,
 		VisionRange:       350,
 		Health:            1000,
+		Armor:             ArmorStructure,
 		IsFactory:         false,
 		ProducesUnits:     []UnitType{},
 		BuildableStructures: []BuildingType{
 			BuildingTanksFactory,
 			BuildingMetalExtractor,
 			BuildingSolarPanel,
+			BuildingDataUplink,
+			BuildingHoverBay,
+			BuildingAirfield,
 		},
 		MetalStorage:  500,
 		EnergyStorage: 100,
@@ -638,6 +1025,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:        8,
 		VisionRange:      100,
 		Health:           150,
+		Armor:            ArmorStructure,
 		SpritePath:       "buildings/solar_array.png",
 	},
 	BuildingFusionReactor: {
@@ -653,6 +1041,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:        15,
 		VisionRange:      120,
 		Health:           300,
+		Armor:            ArmorStructure,
 	},
 	BuildingOreExtractor: {
 		Type:        BuildingOreExtractor,
@@ -668,6 +1057,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         12,
 		VisionRange:       100,
 		Health:            250,
+		Armor:             ArmorStructure,
 		RequiresDeposit:   true,
 	},
 	BuildingAlloyFoundry: {
@@ -684,6 +1074,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         18,
 		VisionRange:       100,
 		Health:            400,
+		Armor:             ArmorStructure,
 		MetalStorage:      500,
 	},
 	BuildingTanksFactory: {
@@ -700,6 +1091,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         15,
 		VisionRange:       150,
 		Health:            500,
+		Armor:             ArmorStructure,
 		IsFactory:         true,
 		ProducesUnits: []UnitType{
 			UnitTypeLightTank,
@@ -709,6 +1101,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 			UnitTypeRocketTank,
 			UnitTypeFlameTank,
 			UnitTypeAAVehicle,
+			UnitTypePhantomTank,
 			UnitTypeScout,
 		},
 		SpritePath:     "buildings/factory.png",
@@ -719,7 +1112,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 	BuildingHoverBay: {
 		Type:        BuildingHoverBay,
 		Name:        "Hover Bay",
-		Description: "Produces scout vehicles",
+		Description: "Produces hover vehicles that cross water",
 		Size:        60,
 		Color:       color.RGBA{80, 100, 140, 255},
 		Cost: map[resource.Type]float64{
@@ -729,13 +1122,14 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         18,
 		VisionRange:       150,
 		Health:            500,
+		Armor:             ArmorStructure,
 		IsFactory:         true,
-		ProducesUnits:     []UnitType{UnitTypeScout},
+		ProducesUnits:     []UnitType{UnitTypeHoverScout, UnitTypeHoverTank, UnitTypeHoverTransport},
 	},
 	BuildingDataUplink: {
 		Type:        BuildingDataUplink,
 		Name:        "Data Uplink",
-		Description: "Extends radar range significantly",
+		Description: "Radar station that detects cloaked units and runs research",
 		Size:        30,
 		Color:       color.RGBA{100, 200, 200, 255},
 		Cost: map[resource.Type]float64{
@@ -745,6 +1139,10 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         10,
 		VisionRange:       500,
 		Health:            150,
+		Armor:             ArmorStructure,
+		IsLab:             true,
+		RadarRange:        900,
+		DetectionRange:    500,
 	},
 	BuildingWall: {
 		Type:        BuildingWall,
@@ -758,6 +1156,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:   2,
 		VisionRange: 50,
 		Health:      500,
+		Armor:       ArmorStructure,
 	},
 	BuildingAutocannonTurret: {
 		Type:        BuildingAutocannonTurret,
@@ -772,6 +1171,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         8,
 		VisionRange:       250,
 		Health:            300,
+		Armor:             ArmorStructure,
 		CanAttack:         true,
 		Damage:            12,
 		AttackRange:       200,
@@ -792,6 +1192,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         10,
 		VisionRange:       300,
 		Health:            250,
+		Armor:             ArmorStructure,
 		CanAttack:         true,
 		Damage:            25,
 		AttackRange:       280,
@@ -800,6 +1201,28 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		AntiAir:           true,
 		AntiGround:        true,
 	},
+	BuildingAirfield: {
+		Type:        BuildingAirfield,
+		Name:        "Airfield",
+		Description: "Produces aircraft",
+		Width:       128,
+		Height:      80,
+		Color:       color.RGBA{110, 115, 125, 255},
+		Cost: map[resource.Type]float64{
+			resource.Metal: 1000,
+		},
+		EnergyConsumption: 20,
+		BuildTime:         20,
+		VisionRange:       200,
+		Health:            600,
+		Armor:             ArmorStructure,
+		IsFactory:         true,
+		ProducesUnits: []UnitType{
+			UnitTypeGunship,
+			UnitTypeBomber,
+			UnitTypeAirTransport,
+		},
+	},
 
 	// === LEGACY BUILDINGS ===
 	BuildingTankFactory: {
@@ -816,6 +1239,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         10,
 		VisionRange:       150,
 		Health:            500,
+		Armor:             ArmorStructure,
 		IsFactory:         true,
 		ProducesUnits: []UnitType{
 			UnitTypeLightTank,
@@ -825,6 +1249,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 			UnitTypeRocketTank,
 			UnitTypeFlameTank,
 			UnitTypeAAVehicle,
+			UnitTypePhantomTank,
 			UnitTypeScout,
 		},
 	},
@@ -841,6 +1266,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:        5,
 		VisionRange:      100,
 		Health:           150,
+		Armor:            ArmorStructure,
 	},
 	BuildingMetalExtractor: {
 		Type:        BuildingMetalExtractor,
@@ -857,6 +1283,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         5,
 		VisionRange:       100,
 		Health:            200,
+		Armor:             ArmorStructure,
 		RequiresDeposit:   true,
 	},
 	BuildingMetalStorage: {
@@ -872,6 +1299,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:    4,
 		VisionRange:  80,
 		Health:       300,
+		Armor:        ArmorStructure,
 	},
 	BuildingEnergyStorage: {
 		Type:        BuildingEnergyStorage,
@@ -886,6 +1314,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:     4,
 		VisionRange:   80,
 		Health:        300,
+		Armor:         ArmorStructure,
 	},
 	BuildingMetalStorageLarge: {
 		Type:        BuildingMetalStorageLarge,
@@ -901,6 +1330,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:    8,
 		VisionRange:  100,
 		Health:       600,
+		Armor:        ArmorStructure,
 	},
 	BuildingEnergyStorageLarge: {
 		Type:        BuildingEnergyStorageLarge,
@@ -916,6 +1346,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:     8,
 		VisionRange:   100,
 		Health:        600,
+		Armor:         ArmorStructure,
 	},
 	BuildingMechFactory: {
 		Type:        BuildingMechFactory,
@@ -931,6 +1362,7 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:         15,
 		VisionRange:       150,
 		Health:            600,
+		Armor:             ArmorStructure,
 		IsFactory:         true,
 		ProducesUnits:     []UnitType{UnitTypeConstructor},
 	},
@@ -947,11 +1379,13 @@ var BuildingDefs = map[BuildingType]*BuildingDef{
 		BuildTime:     6,
 		VisionRange:   300,
 		Health:        250,
+		Armor:         ArmorStructure,
 		CanAttack:     true,
 		Damage:        25,
 		AttackRange:   250,
 		FireRate:      2.0,
 		EnergyPerShot: 5,
+		DamageType:    DamageEnergy,
 		AntiGround:    true,
 		AntiAir:       true,
 	},
@@ -0,0 +1,129 @@
+package entity
+
+// Rank is how experienced a unit is
+type Rank int
+
+const (
+	RankRookie Rank = iota
+	RankVeteran
+	RankElite
+	RankHeroic
+	NumRanks
+)
+
+func (r Rank) String() string {
+	switch r {
+	case RankRookie:
+		return "Rookie"
+	case RankVeteran:
+		return "Veteran"
+	case RankElite:
+		return "Elite"
+	case RankHeroic:
+		return "Heroic"
+	default:
+		return "Rank"
+	}
+}
+
+// KillExperience is the share of a victim's max health awarded as bonus
+// experience for destroying it, on top of the damage dealt
+const KillExperience = 0.5
+
+// RankExperience is the experience needed for each rank, as a multiple of
+// the unit's base health, so sturdier units need more fighting to rank up
+var RankExperience = [NumRanks]float64{
+	RankRookie:  0,
+	RankVeteran: 1,
+	RankElite:   3,
+	RankHeroic:  6,
+}
+
+// RankBonus scales a unit's stats; 1 leaves a stat unchanged
+type RankBonus struct {
+	Damage   float64
+	Health   float64
+	FireRate float64
+	Vision   float64
+}
+
+// RankBonuses are the stat multipliers of each rank
+var RankBonuses = [NumRanks]RankBonus{
+	RankRookie:  {Damage: 1, Health: 1, FireRate: 1, Vision: 1},
+	RankVeteran: {Damage: 1.1, Health: 1.1, FireRate: 1.1, Vision: 1.1},
+	RankElite:   {Damage: 1.25, Health: 1.2, FireRate: 1.2, Vision: 1.2},
+	RankHeroic:  {Damage: 1.5, Health: 1.35, FireRate: 1.3, Vision: 1.3},
+}
+
+// GainExperience credits the unit for damage it dealt and, if the hit was
+// a kill, the victim's max health. It reports whether the unit ranked up.
+func (u *Unit) GainExperience(dealt float64, killed bool, victimMaxHealth float64) bool {
+	u.Experience += dealt
+	if killed {
+		u.Experience += victimMaxHealth * KillExperience
+	}
+	rank := u.Rank
+	for rank+1 < NumRanks && u.Experience >= u.rankThreshold(rank+1) {
+		rank++
+	}
+	if rank == u.Rank {
+		return false
+	}
+	u.SetRank(rank)
+	return true
+}
+
+// SetRank moves the unit to rank, rescaling its stats from the old rank's
+// bonuses to the new one's. Current health grows or shrinks with max health.
+func (u *Unit) SetRank(rank Rank) {
+	rank = max(RankRookie, min(rank, NumRanks-1))
+	from, to := RankBonuses[u.Rank], RankBonuses[rank]
+	u.Damage *= to.Damage / from.Damage
+	u.FireRate *= to.FireRate / from.FireRate
+	u.VisionRange *= to.Vision / from.Vision
+	healthScale := to.Health / from.Health
+	u.MaxHealth *= healthScale
+	u.Health *= healthScale
+	u.Rank = rank
+}
+
+// NextRankExperience returns the experience needed for the next rank, or
+// 0 at the top rank
+func (u *Unit) NextRankExperience() float64 {
+	if u.Rank+1 >= NumRanks {
+		return 0
+	}
+	return u.rankThreshold(u.Rank + 1)
+}
+
+func (u *Unit) rankThreshold(rank Rank) float64 {
+	base := u.MaxHealth / RankBonuses[u.Rank].Health
+	if u.Def != nil {
+		base = u.Def.Health
+	}
+	return RankExperience[rank] * base
+}
+
+// DamageUnit applies a hit to target and credits attacker, which may be
+// nil for damage from buildings, with the damage dealt. It reports
+// whether the hit destroyed the target.
+func DamageUnit(attacker, target *Unit, damage float64, kind DamageType) bool {
+	before := target.Health
+	killed := target.TakeDamage(damage, kind)
+	if attacker != nil && attacker.Faction != target.Faction {
+		attacker.GainExperience(before-target.Health, killed, target.MaxHealth)
+	}
+	return killed
+}
+
+// DamageBuilding applies a hit to target and credits attacker, which may
+// be nil, with the damage dealt. It reports whether the hit destroyed the
+// target.
+func DamageBuilding(attacker *Unit, target *Building, damage float64, kind DamageType) bool {
+	before := target.Health
+	killed := target.TakeDamage(damage, kind)
+	if attacker != nil && attacker.Faction != target.Faction {
+		attacker.GainExperience(before-target.Health, killed, target.MaxHealth)
+	}
+	return killed
+}
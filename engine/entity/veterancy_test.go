@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKillsRankUpAndRaiseStats(t *testing.T) {
+	tank := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeTank], FactionPlayer)
+	damage, fireRate, vision := tank.Damage, tank.FireRate, tank.VisionRange
+
+	for i := uint64(0); tank.Rank < RankVeteran; i++ {
+		if i > 20 {
+			t.Fatal("tank never ranked up")
+		}
+		victim := NewUnitFromDef(10+i, 0, 0, UnitDefs[UnitTypeScout], FactionEnemy)
+		for victim.Active {
+			DamageUnit(tank, victim, 50, DamageKinetic)
+		}
+	}
+
+	bonus := RankBonuses[tank.Rank]
+	if math.Abs(tank.Damage-damage*bonus.Damage) > 1e-9 ||
+		math.Abs(tank.FireRate-fireRate*bonus.FireRate) > 1e-9 ||
+		math.Abs(tank.VisionRange-vision*bonus.Vision) > 1e-9 {
+		t.Errorf("rank %v stats not raised: damage %v, fire rate %v, vision %v", tank.Rank, tank.Damage, tank.FireRate, tank.VisionRange)
+	}
+	if want := UnitDefs[UnitTypeTank].Health * bonus.Health; math.Abs(tank.MaxHealth-want) > 1e-9 {
+		t.Errorf("max health = %v, want %v", tank.MaxHealth, want)
+	}
+}
+
+func TestExperienceCountsOnlyDamageDealt(t *testing.T) {
+	tank := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeTank], FactionPlayer)
+	scout := NewUnitFromDef(2, 0, 0, UnitDefs[UnitTypeScout], FactionEnemy)
+	scout.Health = 10
+
+	DamageUnit(tank, scout, 1000, DamageKinetic)
+	want := 10 + scout.MaxHealth*KillExperience
+	if tank.Experience != want {
+		t.Errorf("experience = %v, want %v (overkill should not count)", tank.Experience, want)
+	}
+
+	ally := NewUnitFromDef(3, 0, 0, UnitDefs[UnitTypeScout], FactionPlayer)
+	DamageUnit(tank, ally, 5, DamageKinetic)
+	if tank.Experience != want {
+		t.Error("hurting an ally should not give experience")
+	}
+}
+
+func TestSetRankRoundTrips(t *testing.T) {
+	u := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeHeavyTank], FactionPlayer)
+	base := *u
+	u.SetRank(RankHeroic)
+	u.SetRank(RankRookie)
+	if math.Abs(u.Damage-base.Damage) > 1e-9 || math.Abs(u.MaxHealth-base.MaxHealth) > 1e-9 {
+		t.Errorf("stats after heroic and back: damage %v, max health %v; want %v, %v", u.Damage, u.MaxHealth, base.Damage, base.MaxHealth)
+	}
+}
@@ -0,0 +1,119 @@
+package entity
+
+import (
+	"image/color"
+	"math"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// WeaponType decides how a unit's shots travel and deal damage
+type WeaponType int
+
+const (
+	WeaponCannon    WeaponType = iota // Shell flying straight at its target, hits the first enemy in the way
+	WeaponBallistic                   // Shell lobbed at a predicted ground point, splash damage where it lands
+	WeaponRocket                      // Fast rocket that turns towards its target at a limited rate
+	WeaponFlame                       // Short-range cone that damages and ignites everything in it
+)
+
+func (t WeaponType) String() string {
+	switch t {
+	case WeaponCannon:
+		return "Cannon"
+	case WeaponBallistic:
+		return "Ballistic"
+	case WeaponRocket:
+		return "Rocket"
+	case WeaponFlame:
+		return "Flame"
+	default:
+		return "Weapon"
+	}
+}
+
+const (
+	SplashEdgeDamage = 0.25 // Fraction of a blast's damage dealt at the edge of its radius
+	RocketFuel       = 1.5  // Rockets fly this many times the launcher's range before burning out
+	FlameSpeed       = 250.0
+)
+
+// ProjectileStyle is how a weapon's shots are drawn
+type ProjectileStyle struct {
+	Size  float64
+	Color color.Color
+}
+
+var projectileStyles = map[WeaponType]ProjectileStyle{
+	WeaponCannon:    {Size: ProjectileSize, Color: color.RGBA{255, 200, 50, 255}},
+	WeaponBallistic: {Size: 7, Color: color.RGBA{60, 60, 60, 255}},
+	WeaponRocket:    {Size: 5, Color: color.RGBA{230, 230, 230, 255}},
+	WeaponFlame:     {Size: 8, Color: color.RGBA{255, 120, 20, 200}},
+}
+
+// Style returns how the weapon's shots are drawn
+func (t WeaponType) Style() ProjectileStyle {
+	if s, ok := projectileStyles[t]; ok {
+		return s
+	}
+	return projectileStyles[WeaponCannon]
+}
+
+// SplashDamage returns the damage a blast deals at dist from its center:
+// full damage at the center, falling off linearly to SplashEdgeDamage at
+// the edge and nothing beyond it
+func SplashDamage(damage, dist, radius float64) float64 {
+	if radius <= 0 || dist > radius {
+		return 0
+	}
+	return damage * (1 - (1-SplashEdgeDamage)*dist/radius)
+}
+
+// InCone reports whether point lies within a cone of the given full
+// angle and length opening from origin along dir (a unit vector)
+func InCone(origin, dir, point emath.Vec2, angle, length float64) bool {
+	to := point.Sub(origin)
+	dist := to.Length()
+	if dist > length {
+		return false
+	}
+	if dist < 1 {
+		return true
+	}
+	return to.Dot(dir) >= dist*math.Cos(angle/2)
+}
+
+// Burn is fire damage a unit or building keeps taking after being hit by
+// a flame weapon
+type Burn struct {
+	DPS       float64 // Damage per second
+	Remaining float64 // Seconds left
+	Source    *Unit   // Unit that lit the fire, credited with its damage
+}
+
+// Ignite sets the target burning, keeping the stronger and longer of the
+// current and new burn
+func (b *Burn) Ignite(dps, duration float64, source *Unit) {
+	b.Source = source
+	b.DPS = max(b.DPS, dps)
+	b.Remaining = max(b.Remaining, duration)
+}
+
+// Tick advances the burn by dt and returns the damage dealt meanwhile
+func (b *Burn) Tick(dt float64) float64 {
+	if b.Remaining <= 0 {
+		return 0
+	}
+	step := min(dt, b.Remaining)
+	damage := b.DPS * step
+	b.Remaining -= step
+	if b.Remaining <= 0 {
+		*b = Burn{}
+	}
+	return damage
+}
+
+// Burning reports whether there is burn damage left to deal
+func (b *Burn) Burning() bool {
+	return b.Remaining > 0
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"math"
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+func TestSplashDamageFallsOff(t *testing.T) {
+	if got := SplashDamage(100, 0, 50); got != 100 {
+		t.Errorf("center: got %v, want 100", got)
+	}
+	if got := SplashDamage(100, 50, 50); math.Abs(got-100*SplashEdgeDamage) > 1e-9 {
+		t.Errorf("edge: got %v, want %v", got, 100*SplashEdgeDamage)
+	}
+	if got := SplashDamage(100, 51, 50); got != 0 {
+		t.Errorf("outside: got %v, want 0", got)
+	}
+	if SplashDamage(100, 20, 50) <= SplashDamage(100, 30, 50) {
+		t.Error("damage should fall off with distance")
+	}
+}
+
+func TestInCone(t *testing.T) {
+	origin := emath.Vec2{}
+	dir := emath.Vec2{X: 1}
+	tests := []struct {
+		point emath.Vec2
+		want  bool
+	}{
+		{emath.Vec2{X: 50}, true},
+		{emath.Vec2{X: 50, Y: 10}, true},
+		{emath.Vec2{X: 50, Y: 40}, false},
+		{emath.Vec2{X: -50}, false},
+		{emath.Vec2{X: 90}, false},
+	}
+	for _, tt := range tests {
+		if got := InCone(origin, dir, tt.point, 0.6, 80); got != tt.want {
+			t.Errorf("InCone(%v) = %v, want %v", tt.point, got, tt.want)
+		}
+	}
+}
+
+func TestBurnRunsOut(t *testing.T) {
+	var b Burn
+	b.Ignite(6, 3, nil)
+	b.Ignite(4, 1, nil) // A weaker, shorter burn does not cut the first short
+	total := 0.0
+	for b.Burning() {
+		total += b.Tick(0.5)
+	}
+	if math.Abs(total-18) > 1e-9 {
+		t.Errorf("burn dealt %v, want 18", total)
+	}
+}
+
+func TestRocketTurnsAtLimitedRate(t *testing.T) {
+	def := *UnitDefs[UnitTypeRocketTank]
+	shooter := NewUnitFromDef(1, 0, 0, &def, FactionPlayer)
+	target := NewUnitFromDef(2, 0, 150, UnitDefs[UnitTypeTank], FactionEnemy)
+	p := NewProjectile(3, shooter, target)
+	p.Direction = emath.Vec2{X: 1} // Launched sideways, away from the target
+
+	p.steer(0.1)
+	turned := math.Atan2(p.Direction.Y, p.Direction.X)
+	if want := def.Combat.TurnRate * 0.1; math.Abs(turned-want) > 1e-9 {
+		t.Errorf("turned %v rad in 0.1s, want %v", turned, want)
+	}
+}
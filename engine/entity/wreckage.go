@@ -51,3 +51,15 @@ func NewWreckageFromBuilding(id uint64, building *Building) *Wreckage {
 		MetalValue: metalValue,
 	}
 }
+
+// Reclaim takes up to amount metal out of the wreck and returns how much
+// was taken. A wreck with no metal left is cleared away.
+func (w *Wreckage) Reclaim(amount float64) float64 {
+	taken := min(amount, w.MetalValue)
+	w.MetalValue -= taken
+	if w.MetalValue <= 0 {
+		w.MetalValue = 0
+		w.Active = false
+	}
+	return taken
+}
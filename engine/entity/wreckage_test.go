@@ -0,0 +1,33 @@
+package entity
+
+import "testing"
+
+func TestReclaimClearsEmptyWreck(t *testing.T) {
+	tank := NewUnitFromDef(1, 0, 0, UnitDefs[UnitTypeTank], FactionPlayer)
+	wreck := NewWreckageFromUnit(1, tank)
+	metal := wreck.MetalValue
+
+	if got := wreck.Reclaim(metal / 2); got != metal/2 || !wreck.Active {
+		t.Fatalf("first half: took %v, active %v; want %v, true", got, wreck.Active, metal/2)
+	}
+	if got := wreck.Reclaim(metal); got != metal/2 {
+		t.Errorf("second pull took %v, want the %v left", got, metal/2)
+	}
+	if wreck.Active || wreck.MetalValue != 0 {
+		t.Errorf("emptied wreck still active %v with %v metal", wreck.Active, wreck.MetalValue)
+	}
+}
+
+func TestConstructorReclaimRange(t *testing.T) {
+	c := NewConstructor(1, 0, 0, FactionPlayer)
+	if !c.CanReclaim() {
+		t.Fatal("constructor cannot reclaim")
+	}
+	wreck := NewWreckageFromUnit(1, NewTank(2, c.Center().X+c.ReclaimRange-1, 0, FactionEnemy))
+	if !c.IsInReclaimRange(wreck) {
+		t.Error("wreck just inside reclaim range counted as out of range")
+	}
+	if NewTank(3, 0, 0, FactionPlayer).CanReclaim() {
+		t.Error("tank should not reclaim")
+	}
+}
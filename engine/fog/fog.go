@@ -78,6 +78,33 @@ func (f *FogOfWar) RevealCircle(worldX, worldY, radius float64) {
 	}
 }
 
+// Obscure hides the visible tiles in a circle again, as smoke does
+func (f *FogOfWar) Obscure(worldX, worldY, radius float64) {
+	tileX := int(worldX / f.TileSize)
+	tileY := int(worldY / f.TileSize)
+	tileRadius := int(radius/f.TileSize) + 1
+
+	for dy := -tileRadius; dy <= tileRadius; dy++ {
+		for dx := -tileRadius; dx <= tileRadius; dx++ {
+			checkX := tileX + dx
+			checkY := tileY + dy
+
+			if checkX < 0 || checkX >= f.Width || checkY < 0 || checkY >= f.Height {
+				continue
+			}
+
+			tileCenterX := (float64(checkX) + 0.5) * f.TileSize
+			tileCenterY := (float64(checkY) + 0.5) * f.TileSize
+			distSq := (tileCenterX-worldX)*(tileCenterX-worldX) + (tileCenterY-worldY)*(tileCenterY-worldY)
+
+			if distSq <= radius*radius && f.Tiles[checkY][checkX] == Visible {
+				f.Tiles[checkY][checkX] = Explored
+				f.Version++
+			}
+		}
+	}
+}
+
 func (f *FogOfWar) GetTileState(worldX, worldY float64) TileState {
 	tileX := int(worldX / f.TileSize)
 	tileY := int(worldY / f.TileSize)
@@ -0,0 +1,43 @@
+package fog
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestObscureHidesUntilRevealedAgain(t *testing.T) {
+	f := New(1000, 1000, 25)
+	f.RevealCircle(500, 500, 200)
+	f.Obscure(600, 500, 50)
+	if f.GetTileState(600, 500) != Explored {
+		t.Error("an obscured tile should drop back to explored")
+	}
+	if f.GetTileState(400, 500) != Visible {
+		t.Error("tiles outside the obscured circle should stay visible")
+	}
+
+	f.RevealCircle(510, 510, 150)
+	if f.GetTileState(600, 500) != Visible {
+		t.Error("a reveal after Obscure should show the tile again")
+	}
+}
+
+// BenchmarkRevealArmy reveals 500 units gathered in a few blobs, as in a
+// late game army
+func BenchmarkRevealArmy(b *testing.B) {
+	f := New(6000, 3375, 25)
+	r := rand.New(rand.NewSource(1))
+	type pos struct{ x, y float64 }
+	units := make([]pos, 500)
+	for i := range units {
+		blob := float64(i % 5)
+		units[i] = pos{x: 1000 + blob*800 + r.Float64()*200, y: 1500 + r.Float64()*200}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.ClearVisibility()
+		for _, u := range units {
+			f.RevealCircle(u.x, u.y, 200)
+		}
+	}
+}
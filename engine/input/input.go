@@ -7,32 +7,48 @@ import (
 )
 
 type State struct {
-	MousePos         emath.Vec2
-	LeftPressed      bool
-	LeftJustPressed  bool
-	LeftJustReleased bool
-	RightPressed     bool
-	RightJustPressed bool
-	ShiftHeld        bool
-	EscapePressed    bool
-	ScrollUp         bool
-	ScrollDown       bool
-	ScrollLeft       bool
-	ScrollRight      bool
-	BuildTankPressed bool // T key to build tank
-	MenuUp           bool // Up arrow only (not W, for menu)
-	MenuDown         bool // Down arrow only (not S, for menu)
-	EnterPressed     bool // Enter/Return key
-	BackspacePressed bool // Backspace key for text input
-	IsDragging       bool
-	DragStart        emath.Vec2
-	DragEnd          emath.Vec2
-	MouseWheelY      float64 // Mouse wheel vertical scroll (positive = up/zoom in)
+	MousePos          emath.Vec2
+	LeftPressed       bool
+	LeftJustPressed   bool
+	LeftJustReleased  bool
+	RightPressed      bool
+	RightJustPressed  bool
+	RightJustReleased bool
+	ShiftHeld         bool
+	CtrlHeld          bool // Turns a right-click move into an attack-move
+	AltHeld           bool // Turns a right-click move into a patrol
+	UnloadHeld        bool // U key; turns a right-click into an unload there
+	EscapePressed     bool
+	ScrollUp          bool
+	ScrollDown        bool
+	ScrollLeft        bool
+	ScrollRight       bool
+	BuildTankPressed  bool // T key to build tank
+	PausePressed      bool // P key to pause/resume multiplayer
+	FormationPressed  bool // F key to cycle the group move formation
+	PowerPressed      bool // O key to cycle the power priority
+	RecyclePressed    bool // Delete key to recycle the selected buildings
+	AbilitySlot       int  // 1-based ability button whose hotkey was pressed, 0 for none
+	MenuUp            bool // Up arrow only (not W, for menu)
+	MenuDown          bool // Down arrow only (not S, for menu)
+	EnterPressed      bool // Enter/Return key
+	BackspacePressed  bool // Backspace key for text input
+	IsDragging        bool
+	DragStart         emath.Vec2
+	DragEnd           emath.Vec2
+	IsRightDragging   bool       // Right button held and moved past the drag threshold
+	RightDragStart    emath.Vec2 // Screen position the right button went down at
+	MouseWheelY       float64    // Mouse wheel vertical scroll (positive = up/zoom in)
 }
+
+// abilityKeys are the hotkeys of the command panel's ability buttons
+var abilityKeys = []ebiten.Key{ebiten.KeyQ, ebiten.KeyE, ebiten.KeyR, ebiten.KeyG}
+
 type Manager struct {
-	state         State
-	dragStarted   bool
-	dragThreshold float64
+	state            State
+	dragStarted      bool
+	rightDragStarted bool
+	dragThreshold    float64
 }
 
 func NewManager() *Manager {
@@ -48,13 +64,27 @@ func (m *Manager) Update() {
 	m.state.LeftJustReleased = inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft)
 	m.state.RightPressed = ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
 	m.state.RightJustPressed = inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight)
+	m.state.RightJustReleased = inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonRight)
 	m.state.ShiftHeld = ebiten.IsKeyPressed(ebiten.KeyShift)
+	m.state.CtrlHeld = ebiten.IsKeyPressed(ebiten.KeyControl)
+	m.state.AltHeld = ebiten.IsKeyPressed(ebiten.KeyAlt)
+	m.state.UnloadHeld = ebiten.IsKeyPressed(ebiten.KeyU)
 	m.state.EscapePressed = inpututil.IsKeyJustPressed(ebiten.KeyEscape)
 	m.state.ScrollUp = ebiten.IsKeyPressed(ebiten.KeyUp) || ebiten.IsKeyPressed(ebiten.KeyW)
 	m.state.ScrollDown = ebiten.IsKeyPressed(ebiten.KeyDown) || ebiten.IsKeyPressed(ebiten.KeyS)
 	m.state.ScrollLeft = ebiten.IsKeyPressed(ebiten.KeyLeft) || ebiten.IsKeyPressed(ebiten.KeyA)
 	m.state.ScrollRight = ebiten.IsKeyPressed(ebiten.KeyRight) || ebiten.IsKeyPressed(ebiten.KeyD)
 	m.state.BuildTankPressed = inpututil.IsKeyJustPressed(ebiten.KeyT)
+	m.state.PausePressed = inpututil.IsKeyJustPressed(ebiten.KeyP)
+	m.state.FormationPressed = inpututil.IsKeyJustPressed(ebiten.KeyF)
+	m.state.PowerPressed = inpututil.IsKeyJustPressed(ebiten.KeyO)
+	m.state.RecyclePressed = inpututil.IsKeyJustPressed(ebiten.KeyDelete)
+	m.state.AbilitySlot = 0
+	for i, key := range abilityKeys {
+		if inpututil.IsKeyJustPressed(key) {
+			m.state.AbilitySlot = i + 1
+		}
+	}
 	m.state.MenuUp = inpututil.IsKeyJustPressed(ebiten.KeyUp)
 	m.state.MenuDown = inpututil.IsKeyJustPressed(ebiten.KeyDown)
 	m.state.EnterPressed = inpututil.IsKeyJustPressed(ebiten.KeyEnter)
@@ -78,6 +108,21 @@ func (m *Manager) Update() {
 		m.state.DragEnd = m.state.MousePos
 		m.dragStarted = false
 	}
+	// Right drag sets the facing of a move order. IsRightDragging stays set
+	// on the release frame so the order can tell a drag from a click.
+	if m.state.RightJustPressed {
+		m.state.RightDragStart = m.state.MousePos
+		m.rightDragStarted = true
+		m.state.IsRightDragging = false
+	}
+	if m.rightDragStarted && m.state.MousePos.Distance(m.state.RightDragStart) > m.dragThreshold {
+		m.state.IsRightDragging = true
+	}
+	if m.state.RightJustReleased {
+		m.rightDragStarted = false
+	} else if !m.rightDragStarted {
+		m.state.IsRightDragging = false
+	}
 }
 func (m *Manager) State() State {
 	return m.state
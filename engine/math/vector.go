@@ -71,3 +71,11 @@ func (r Rect) Intersects(other Rect) bool {
 		r.Pos.Y < other.Pos.Y+other.Size.Y &&
 		r.Pos.Y+r.Size.Y > other.Pos.Y
 }
+
+// DistanceTo returns how far p is from the nearest point of the rect, or 0
+// if p is inside it
+func (r Rect) DistanceTo(p Vec2) float64 {
+	dx := max(r.Pos.X-p.X, 0, p.X-(r.Pos.X+r.Size.X))
+	dy := max(r.Pos.Y-p.Y, 0, p.Y-(r.Pos.Y+r.Size.Y))
+	return math.Hypot(dx, dy)
+}
@@ -0,0 +1,47 @@
+// Package movement defines how units get around the map. It sits below the
+// terrain, collision, pathfinding and entity packages so they can all agree
+// on which surfaces a unit may cross.
+package movement
+
+// Class decides where a unit can move and what blocks it
+type Class int
+
+const (
+	Ground Class = iota // Drives on land, blocked by water, buildings and other ground units
+	Air                 // Flies over everything and only meets other aircraft
+	Hover               // Glides over land and water alike
+	Naval               // Sails on water and cannot come ashore
+)
+
+// Classes lists every movement class, in order
+var Classes = []Class{Ground, Air, Hover, Naval}
+
+func (c Class) String() string {
+	switch c {
+	case Ground:
+		return "Ground"
+	case Air:
+		return "Air"
+	case Hover:
+		return "Hover"
+	case Naval:
+		return "Naval"
+	default:
+		return "Unknown"
+	}
+}
+
+// Crosses reports whether the class can move over a surface. Land is any
+// tile ground units can drive on; water is open water.
+func (c Class) Crosses(land, water bool) bool {
+	switch c {
+	case Air:
+		return true
+	case Hover:
+		return land || water
+	case Naval:
+		return water
+	default:
+		return land
+	}
+}
@@ -0,0 +1,28 @@
+package movement
+
+import "testing"
+
+func TestCrosses(t *testing.T) {
+	tests := []struct {
+		class       Class
+		land, water bool
+	}{
+		{Ground, true, false},
+		{Air, true, true},
+		{Hover, true, true},
+		{Naval, false, true},
+	}
+	for _, tt := range tests {
+		if got := tt.class.Crosses(true, false); got != tt.land {
+			t.Errorf("%v on land: got %v, want %v", tt.class, got, tt.land)
+		}
+		if got := tt.class.Crosses(false, true); got != tt.water {
+			t.Errorf("%v on water: got %v, want %v", tt.class, got, tt.water)
+		}
+	}
+	for _, class := range Classes {
+		if class != Air && class.Crosses(false, false) {
+			t.Errorf("%v should not cross impassable ground", class)
+		}
+	}
+}
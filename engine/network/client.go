@@ -2,163 +2,33 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
+	"github.com/bklimczak/tanks/engine/protocol"
 	"github.com/gorilla/websocket"
 )
 
-type MessageType string
-
-const (
-	// Client -> Server
-	MsgSetName     MessageType = "set_name"
-	MsgCreateLobby MessageType = "create_lobby"
-	MsgJoinLobby   MessageType = "join_lobby"
-	MsgLeaveLobby  MessageType = "leave_lobby"
-	MsgListLobbies MessageType = "list_lobbies"
-	MsgSetReady    MessageType = "set_ready"
-	MsgStartGame   MessageType = "start_game"
-	MsgGameCommand MessageType = "game_command"
-
-	// Server -> Client
-	MsgWelcome      MessageType = "welcome"
-	MsgLobbyList    MessageType = "lobby_list"
-	MsgLobbyCreated MessageType = "lobby_created"
-	MsgLobbyJoined  MessageType = "lobby_joined"
-	MsgLobbyLeft    MessageType = "lobby_left"
-	MsgLobbyUpdate  MessageType = "lobby_update"
-	MsgGameStarting MessageType = "game_starting"
-	MsgGameState    MessageType = "game_state"
-	MsgGameEnd      MessageType = "game_end"
-	MsgError        MessageType = "error"
-)
-
-type Message struct {
-	Type    MessageType     `json:"type"`
-	Payload json.RawMessage `json:"payload,omitempty"`
-}
-
-type PlayerInfo struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Ready   bool   `json:"ready"`
-	Faction int    `json:"faction"`
-	Alive   bool   `json:"alive"`
-}
-
-type LobbyInfo struct {
-	ID         string       `json:"id"`
-	Name       string       `json:"name"`
-	HostID     string       `json:"hostId"`
-	HostName   string       `json:"hostName"`
-	Players    []PlayerInfo `json:"players"`
-	MaxPlayers int          `json:"maxPlayers"`
-	State      string       `json:"state"`
-}
-
-type LobbyListPayload struct {
-	Lobbies []LobbyInfo `json:"lobbies"`
-}
-
-type LobbyCreatedPayload struct {
-	Lobby LobbyInfo `json:"lobby"`
-}
-
-type LobbyJoinedPayload struct {
-	Lobby LobbyInfo `json:"lobby"`
-}
-
-type LobbyUpdatePayload struct {
-	Lobby LobbyInfo `json:"lobby"`
-}
-
-type GameStartingPayload struct {
-	Lobby    LobbyInfo `json:"lobby"`
-	YourSlot int       `json:"yourSlot"`
-}
-
-type WelcomePayload struct {
-	PlayerID string `json:"playerId"`
-}
-
-type ErrorPayload struct {
-	Message string `json:"message"`
-}
-
-type UnitState struct {
-	ID        uint64  `json:"id"`
-	Type      int     `json:"type"`
-	OwnerSlot int     `json:"owner"`
-	X         float64 `json:"x"`
-	Y         float64 `json:"y"`
-	Health    float64 `json:"hp"`
-	MaxHealth float64 `json:"maxHp"`
-	Angle     float64 `json:"angle"`
-}
-
-type BuildingState struct {
-	ID            uint64  `json:"id"`
-	Type          int     `json:"type"`
-	OwnerSlot     int     `json:"owner"`
-	X             float64 `json:"x"`
-	Y             float64 `json:"y"`
-	Health        float64 `json:"hp"`
-	MaxHealth     float64 `json:"maxHp"`
-	Completed     bool    `json:"done"`
-	BuildProgress float64 `json:"progress"`
-}
-
-type ProjectileState struct {
-	ID        uint64  `json:"id"`
-	OwnerSlot int     `json:"owner"`
-	X         float64 `json:"x"`
-	Y         float64 `json:"y"`
-}
-
-type ResourceStateNet struct {
-	Metal     float64 `json:"metal"`
-	MetalCap  float64 `json:"metalCap"`
-	Energy    float64 `json:"energy"`
-	EnergyCap float64 `json:"energyCap"`
-}
-
-type PlayerGameState struct {
-	Slot      int              `json:"slot"`
-	Name      string           `json:"name"`
-	Alive     bool             `json:"alive"`
-	Resources ResourceStateNet `json:"resources"`
-}
-
-type GameStatePayload struct {
-	Tick        uint64            `json:"tick"`
-	Players     []PlayerGameState `json:"players"`
-	Units       []UnitState       `json:"units"`
-	Buildings   []BuildingState   `json:"buildings"`
-	Projectiles []ProjectileState `json:"projectiles"`
-}
-
-type GameEndPayload struct {
-	WinnerSlot int    `json:"winnerSlot"`
-	WinnerName string `json:"winnerName"`
-}
-
 type Client struct {
 	conn         *websocket.Conn
 	serverAddr   string
 	connected    bool
 	playerName   string
 	playerID     string
-	currentLobby *LobbyInfo
+	sessionToken string
+	encodings    []string
+	encoding     string
+	currentLobby *protocol.LobbyInfo
 
 	mu          sync.RWMutex
-	lobbies     []LobbyInfo
-	gameState   *GameStatePayload
+	lobbies     []protocol.LobbyInfo
+	gameState   *protocol.GameStatePayload
 	gameStarted bool
 	gameEnded   bool
-	gameEndInfo *GameEndPayload
+	gameEndInfo *protocol.GameEndPayload
 	lastError   string
 	yourSlot    int
 	isHost      bool
@@ -167,6 +37,7 @@ type Client struct {
 func NewClient(playerName string) *Client {
 	return &Client{
 		playerName: playerName,
+		encodings:  []string{protocol.EncodingBinary, protocol.EncodingJSON},
 		yourSlot:   -1,
 	}
 }
@@ -178,21 +49,96 @@ func (c *Client) Connect(serverAddr string) error {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 
+	c.mu.RLock()
+	token := c.sessionToken
+	c.mu.RUnlock()
+
+	welcome, err := handshake(conn, protocol.HelloPayload{
+		Version:      protocol.Version,
+		Name:         c.playerName,
+		Encodings:    c.encodings,
+		SessionToken: token,
+	})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.playerID = welcome.PlayerID
+	c.sessionToken = welcome.SessionToken
+	c.encoding = welcome.Encoding
+	c.mu.Unlock()
+	log.Printf("Connected as player: %s (game state encoding: %s)", welcome.PlayerID, welcome.Encoding)
+
 	c.conn = conn
 	c.serverAddr = serverAddr
 	c.connected = true
 
 	go c.readLoop()
 
-	// Send player name
-	c.send(Message{Type: MsgSetName, Payload: mustMarshal(map[string]string{"name": c.playerName})})
-
 	return nil
 }
 
-func mustMarshal(v interface{}) json.RawMessage {
-	data, _ := json.Marshal(v)
-	return data
+// handshake announces our protocol version and waits for the server's Welcome
+func handshake(conn *websocket.Conn, helloPayload protocol.HelloPayload) (protocol.WelcomePayload, error) {
+	var welcome protocol.WelcomePayload
+
+	hello, err := protocol.NewMessage(protocol.MsgHello, helloPayload)
+	if err != nil {
+		return welcome, err
+	}
+	data, err := json.Marshal(hello)
+	if err != nil {
+		return welcome, err
+	}
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return welcome, fmt.Errorf("failed to send hello: %w", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	_, data, err = conn.ReadMessage()
+	if err != nil {
+		return welcome, fmt.Errorf("no welcome from server: %w", err)
+	}
+
+	var msg protocol.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return welcome, err
+	}
+	if msg.Type != protocol.MsgWelcome {
+		return welcome, fmt.Errorf("unexpected %s message during handshake", msg.Type)
+	}
+	if err := msg.Decode(&welcome); err != nil {
+		return welcome, err
+	}
+	if !welcome.Accepted() {
+		return welcome, errors.New(welcome.Reason)
+	}
+	return welcome, nil
+}
+
+// Reconnect connects to the last server again. The session token from the
+// previous connection puts us back into a match in progress.
+func (c *Client) Reconnect() error {
+	if c.serverAddr == "" {
+		return errors.New("never connected")
+	}
+	return c.Connect(c.serverAddr)
+}
+
+// SetEncoding restricts the game state encoding offered on the next Connect,
+// e.g. to force readable JSON while debugging
+func (c *Client) SetEncoding(encoding string) {
+	c.encodings = []string{encoding}
+}
+
+func (c *Client) GetEncoding() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.encoding
 }
 
 func (c *Client) Disconnect() {
@@ -217,7 +163,7 @@ func (c *Client) readLoop() {
 	}()
 
 	for {
-		_, data, err := c.conn.ReadMessage()
+		frameType, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
@@ -225,7 +171,12 @@ func (c *Client) readLoop() {
 			return
 		}
 
-		var msg Message
+		if frameType == websocket.BinaryMessage {
+			c.handleBinary(data)
+			continue
+		}
+
+		var msg protocol.Message
 		if err := json.Unmarshal(data, &msg); err != nil {
 			log.Printf("Failed to parse message: %v", err)
 			continue
@@ -235,27 +186,18 @@ func (c *Client) readLoop() {
 	}
 }
 
-func (c *Client) handleMessage(msg Message) {
+func (c *Client) handleMessage(msg protocol.Message) {
 	switch msg.Type {
-	case MsgWelcome:
-		var payload WelcomePayload
-		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
-			c.mu.Lock()
-			c.playerID = payload.PlayerID
-			c.mu.Unlock()
-			log.Printf("Connected as player: %s", payload.PlayerID)
-		}
-
-	case MsgLobbyList:
-		var payload LobbyListPayload
+	case protocol.MsgLobbyList:
+		var payload protocol.LobbyListPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.lobbies = payload.Lobbies
 			c.mu.Unlock()
 		}
 
-	case MsgLobbyCreated:
-		var payload LobbyCreatedPayload
+	case protocol.MsgLobbyCreated:
+		var payload protocol.LobbyCreatedPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.currentLobby = &payload.Lobby
@@ -265,8 +207,8 @@ func (c *Client) handleMessage(msg Message) {
 			log.Printf("Created lobby: %s", payload.Lobby.Name)
 		}
 
-	case MsgLobbyJoined:
-		var payload LobbyJoinedPayload
+	case protocol.MsgLobbyJoined:
+		var payload protocol.LobbyJoinedPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.currentLobby = &payload.Lobby
@@ -282,8 +224,8 @@ func (c *Client) handleMessage(msg Message) {
 			log.Printf("Joined lobby: %s", payload.Lobby.Name)
 		}
 
-	case MsgLobbyUpdate:
-		var payload LobbyUpdatePayload
+	case protocol.MsgLobbyUpdate:
+		var payload protocol.LobbyUpdatePayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.currentLobby = &payload.Lobby
@@ -297,15 +239,15 @@ func (c *Client) handleMessage(msg Message) {
 			c.mu.Unlock()
 		}
 
-	case MsgLobbyLeft:
+	case protocol.MsgLobbyLeft:
 		c.mu.Lock()
 		c.currentLobby = nil
 		c.isHost = false
 		c.yourSlot = -1
 		c.mu.Unlock()
 
-	case MsgGameStarting:
-		var payload GameStartingPayload
+	case protocol.MsgGameStarting:
+		var payload protocol.GameStartingPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.gameStarted = true
@@ -314,16 +256,16 @@ func (c *Client) handleMessage(msg Message) {
 			log.Printf("Game starting! Your slot: %d", payload.YourSlot)
 		}
 
-	case MsgGameState:
-		var payload GameStatePayload
+	case protocol.MsgGameState:
+		var payload protocol.GameStatePayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.gameState = &payload
 			c.mu.Unlock()
 		}
 
-	case MsgGameEnd:
-		var payload GameEndPayload
+	case protocol.MsgGameEnd:
+		var payload protocol.GameEndPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.gameEnded = true
@@ -331,8 +273,8 @@ func (c *Client) handleMessage(msg Message) {
 			c.mu.Unlock()
 		}
 
-	case MsgError:
-		var payload ErrorPayload
+	case protocol.MsgError:
+		var payload protocol.ErrorPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err == nil {
 			c.mu.Lock()
 			c.lastError = payload.Message
@@ -342,7 +284,26 @@ func (c *Client) handleMessage(msg Message) {
 	}
 }
 
-func (c *Client) send(msg Message) error {
+func (c *Client) handleBinary(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	switch data[0] {
+	case protocol.FrameGameState:
+		payload, err := protocol.DecodeGameState(data)
+		if err != nil {
+			log.Printf("Failed to decode game state: %v", err)
+			return
+		}
+		c.mu.Lock()
+		c.gameState = &payload
+		c.mu.Unlock()
+	default:
+		log.Printf("Unknown binary frame tag: %d", data[0])
+	}
+}
+
+func (c *Client) send(msg protocol.Message) error {
 	if !c.connected || c.conn == nil {
 		return fmt.Errorf("not connected")
 	}
@@ -357,108 +318,159 @@ func (c *Client) send(msg Message) error {
 }
 
 func (c *Client) RequestLobbyList() error {
-	return c.send(Message{Type: MsgListLobbies})
+	return c.send(protocol.Message{Type: protocol.MsgListLobbies})
+}
+
+func (c *Client) sendPayload(msgType protocol.MessageType, payload interface{}) error {
+	msg, err := protocol.NewMessage(msgType, payload)
+	if err != nil {
+		return err
+	}
+	return c.send(msg)
 }
 
 func (c *Client) CreateLobby(name string, maxPlayers int) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"name":       name,
-		"maxPlayers": maxPlayers,
+	return c.sendPayload(protocol.MsgCreateLobby, protocol.CreateLobbyPayload{
+		Name:       name,
+		MaxPlayers: maxPlayers,
 	})
-	return c.send(Message{Type: MsgCreateLobby, Payload: payload})
 }
 
 func (c *Client) JoinLobby(lobbyID string) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"lobbyId": lobbyID,
-	})
-	return c.send(Message{Type: MsgJoinLobby, Payload: payload})
+	return c.sendPayload(protocol.MsgJoinLobby, protocol.JoinLobbyPayload{LobbyID: lobbyID})
 }
 
 func (c *Client) LeaveLobby() error {
-	err := c.send(Message{Type: MsgLeaveLobby})
+	err := c.send(protocol.Message{Type: protocol.MsgLeaveLobby})
 	return err
 }
 
 func (c *Client) SetReady(ready bool) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"ready": ready,
-	})
-	return c.send(Message{Type: MsgSetReady, Payload: payload})
+	return c.sendPayload(protocol.MsgSetReady, protocol.SetReadyPayload{Ready: ready})
 }
 
 func (c *Client) StartGame() error {
-	return c.send(Message{Type: MsgStartGame})
+	return c.send(protocol.Message{Type: protocol.MsgStartGame})
 }
 
-func (c *Client) SendCommand(command string, data interface{}) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"command": map[string]interface{}{
-			"type": command,
-		},
+// RequestPause asks the server to pause the running game
+func (c *Client) RequestPause() error {
+	return c.send(protocol.Message{Type: protocol.MsgPauseGame})
+}
+
+// RequestResume votes to resume a paused game
+func (c *Client) RequestResume() error {
+	return c.send(protocol.Message{Type: protocol.MsgResumeGame})
+}
+
+func (c *Client) SendCommand(cmd protocol.GameCommand) error {
+	return c.sendPayload(protocol.MsgGameCommand, protocol.GameCommandPayload{Command: cmd})
+}
+
+// SendMoveCommand orders units to a point. Groups take up the named
+// formation, facing the given direction when hasFacing is set.
+func (c *Client) SendMoveCommand(unitIDs []uint64, x, y float64, formation string, facing float64, hasFacing bool) error {
+	return c.SendCommand(protocol.GameCommand{
+		Type:      protocol.CmdMove,
+		UnitIDs:   unitIDs,
+		TargetX:   x,
+		TargetY:   y,
+		Formation: formation,
+		Facing:    facing,
+		HasFacing: hasFacing,
 	})
-	return c.send(Message{Type: MsgGameCommand, Payload: payload})
 }
 
-func (c *Client) SendMoveCommand(unitIDs []uint64, x, y float64) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"command": map[string]interface{}{
-			"type":    "move",
-			"unitIds": unitIDs,
-			"x":       x,
-			"y":       y,
-		},
+// SendSetStanceCommand changes how the given units engage enemies
+func (c *Client) SendSetStanceCommand(unitIDs []uint64, stance int) error {
+	return c.SendCommand(protocol.GameCommand{
+		Type:    protocol.CmdSetStance,
+		UnitIDs: unitIDs,
+		Stance:  stance,
 	})
-	return c.send(Message{Type: MsgGameCommand, Payload: payload})
 }
 
 func (c *Client) SendProduceUnitCommand(buildingID uint64, unitType int) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"command": map[string]interface{}{
-			"type":       "produce_unit",
-			"buildingId": buildingID,
-			"unitType":   unitType,
-		},
+	return c.SendCommand(protocol.GameCommand{
+		Type:       protocol.CmdProduceUnit,
+		BuildingID: buildingID,
+		UnitType:   unitType,
 	})
-	return c.send(Message{Type: MsgGameCommand, Payload: payload})
 }
 
 func (c *Client) SendCancelProductionCommand(buildingID uint64, unitType int) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"command": map[string]interface{}{
-			"type":       "cancel_production",
-			"buildingId": buildingID,
-			"unitType":   unitType,
-		},
+	return c.SendCommand(protocol.GameCommand{
+		Type:       protocol.CmdCancelProduction,
+		BuildingID: buildingID,
+		UnitType:   unitType,
+	})
+}
+
+// SendResearchCommand queues a research project at a lab
+func (c *Client) SendResearchCommand(buildingID uint64, research int) error {
+	return c.SendCommand(protocol.GameCommand{
+		Type:       protocol.CmdResearch,
+		BuildingID: buildingID,
+		Research:   research,
+	})
+}
+
+// SendCancelResearchCommand drops a research project from a lab's queue
+func (c *Client) SendCancelResearchCommand(buildingID uint64, research int) error {
+	return c.SendCommand(protocol.GameCommand{
+		Type:       protocol.CmdCancelResearch,
+		BuildingID: buildingID,
+		Research:   research,
+	})
+}
+
+// SendPowerPriorityCommand sets which consumers the player's grid powers first
+func (c *Client) SendPowerPriorityCommand(power int) error {
+	return c.SendCommand(protocol.GameCommand{
+		Type:  protocol.CmdSetPowerPriority,
+		Power: power,
+	})
+}
+
+// SendSetPriorityCommand sets how a building's work is paid for
+func (c *Client) SendSetPriorityCommand(buildingID uint64, priority int) error {
+	return c.SendCommand(protocol.GameCommand{
+		Type:       protocol.CmdSetPriority,
+		BuildingID: buildingID,
+		Priority:   priority,
+	})
+}
+
+// SendRecycleCommand starts taking one of the player's buildings apart
+func (c *Client) SendRecycleCommand(buildingID uint64) error {
+	return c.SendCommand(protocol.GameCommand{
+		Type:       protocol.CmdRecycle,
+		BuildingID: buildingID,
 	})
-	return c.send(Message{Type: MsgGameCommand, Payload: payload})
 }
 
 func (c *Client) SendPlaceBuildingCommand(buildingType int, x, y float64) error {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"command": map[string]interface{}{
-			"type":         "place_building",
-			"buildingType": buildingType,
-			"x":            x,
-			"y":            y,
-		},
+	return c.SendCommand(protocol.GameCommand{
+		Type:         protocol.CmdPlaceBuilding,
+		BuildingType: buildingType,
+		TargetX:      x,
+		TargetY:      y,
 	})
-	return c.send(Message{Type: MsgGameCommand, Payload: payload})
 }
 
-func (c *Client) GetLobbies() []LobbyInfo {
+func (c *Client) GetLobbies() []protocol.LobbyInfo {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.lobbies
 }
 
-func (c *Client) GetCurrentLobby() *LobbyInfo {
+func (c *Client) GetCurrentLobby() *protocol.LobbyInfo {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.currentLobby
 }
 
-func (c *Client) GetGameState() *GameStatePayload {
+func (c *Client) GetGameState() *protocol.GameStatePayload {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.gameState
@@ -476,7 +488,7 @@ func (c *Client) IsGameEnded() bool {
 	return c.gameEnded
 }
 
-func (c *Client) GetGameEndInfo() *GameEndPayload {
+func (c *Client) GetGameEndInfo() *protocol.GameEndPayload {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.gameEndInfo
@@ -525,5 +537,6 @@ func (c *Client) ResetGameState() {
 	c.gameState = nil
 	c.gameEndInfo = nil
 	c.currentLobby = nil
+	c.sessionToken = "" // Leaving gives up the seat; the next connection starts fresh
 	c.mu.Unlock()
 }
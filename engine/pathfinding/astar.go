@@ -0,0 +1,245 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"math"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// goalSearchRadius is how far, in cells, a blocked goal is moved to the
+// nearest walkable cell
+const goalSearchRadius = 12
+
+// maxSmoothCells caps the length of a smoothed segment, in cells. Longer
+// sight checks cost more than the extra waypoints they save.
+const maxSmoothCells = 16
+
+type openNode struct {
+	index int32
+	f     float64
+}
+
+type openList []openNode
+
+func (o openList) Len() int            { return len(o) }
+func (o openList) Less(i, j int) bool  { return o[i].f < o[j].f }
+func (o openList) Swap(i, j int)       { o[i], o[j] = o[j], o[i] }
+func (o *openList) Push(x interface{}) { *o = append(*o, x.(openNode)) }
+func (o *openList) Pop() interface{} {
+	old := *o
+	n := old[len(old)-1]
+	*o = old[:len(old)-1]
+	return n
+}
+
+// FindPath returns waypoints leading a unit of the given size from start
+// to goal, not including start. A goal on a blocked cell is moved to the
+// nearest walkable cell, and an unreachable goal is replaced by the
+// closest reachable point, so the last waypoint may differ from goal.
+// Returns nil when no route exists at all.
+func (g *Grid) FindPath(start, goal, size emath.Vec2) []emath.Vec2 {
+	sx, sy := g.clampCell(g.CellAt(start))
+	gx, gy := g.clampCell(g.CellAt(goal))
+	if !g.Walkable(gx, gy) {
+		var ok bool
+		gx, gy, ok = g.nearestWalkable(gx, gy)
+		if !ok {
+			return nil
+		}
+		goal = g.CellCenter(gx, gy)
+	}
+	if sx == gx && sy == gy {
+		return []emath.Vec2{goal}
+	}
+
+	cells, reached := g.search(sx, sy, gx, gy)
+	if cells == nil {
+		return nil
+	}
+
+	path := make([]emath.Vec2, len(cells))
+	for i, c := range cells {
+		path[i] = g.CellCenter(int(c)%g.Width, int(c)/g.Width)
+	}
+	if reached {
+		path[len(path)-1] = goal
+	}
+	return g.smooth(start, path, size)
+}
+
+func (g *Grid) clampCell(x, y int) (int, int) {
+	return max(0, min(x, g.Width-1)), max(0, min(y, g.Height-1))
+}
+
+// nearestWalkable searches rings of growing radius around a cell
+func (g *Grid) nearestWalkable(cx, cy int) (int, int, bool) {
+	for r := 1; r <= goalSearchRadius; r++ {
+		bestDist := math.MaxInt
+		bx, by := 0, 0
+		for y := cy - r; y <= cy+r; y++ {
+			for x := cx - r; x <= cx+r; x++ {
+				if max(abs(x-cx), abs(y-cy)) != r || !g.Walkable(x, y) {
+					continue
+				}
+				if d := (x-cx)*(x-cx) + (y-cy)*(y-cy); d < bestDist {
+					bestDist, bx, by = d, x, y
+				}
+			}
+		}
+		if bestDist != math.MaxInt {
+			return bx, by, true
+		}
+	}
+	return 0, 0, false
+}
+
+// search runs A* with 8-way movement. Diagonal steps may not cut the
+// corner of a blocked cell. The start cell is always usable so a unit
+// brushing against a building can still leave. When the goal cannot be
+// reached the route to the explored cell closest to it is returned with
+// reached false.
+func (g *Grid) search(sx, sy, gx, gy int) (cells []int32, reached bool) {
+	g.searchID++
+	if g.searchID == 0 {
+		clear(g.visit)
+		g.searchID = 1
+	}
+	g.open = g.open[:0]
+
+	startIdx := int32(sy*g.Width + sx)
+	goalIdx := int32(gy*g.Width + gx)
+	g.touch(startIdx)
+	g.gScore[startIdx] = 0
+	heap.Push(&g.open, openNode{index: startIdx, f: octile(sx, sy, gx, gy)})
+
+	best := startIdx
+	bestH := octile(sx, sy, gx, gy)
+
+	for g.open.Len() > 0 {
+		cur := heap.Pop(&g.open).(openNode)
+		if g.closed[cur.index] {
+			continue
+		}
+		g.closed[cur.index] = true
+
+		if cur.index == goalIdx {
+			return g.reconstruct(goalIdx), true
+		}
+
+		cx, cy := int(cur.index)%g.Width, int(cur.index)/g.Width
+		if h := octile(cx, cy, gx, gy); h < bestH {
+			best, bestH = cur.index, h
+		}
+
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+				nx, ny := cx+dx, cy+dy
+				if !g.Walkable(nx, ny) {
+					continue
+				}
+				cost := 1.0
+				if dx != 0 && dy != 0 {
+					if !g.Walkable(cx+dx, cy) || !g.Walkable(cx, cy+dy) {
+						continue
+					}
+					cost = math.Sqrt2
+				}
+				ni := int32(ny*g.Width + nx)
+				g.touch(ni)
+				if g.closed[ni] {
+					continue
+				}
+				tentative := g.gScore[cur.index] + cost
+				if tentative < g.gScore[ni] {
+					g.gScore[ni] = tentative
+					g.parent[ni] = cur.index
+					heap.Push(&g.open, openNode{index: ni, f: tentative + octile(nx, ny, gx, gy)})
+				}
+			}
+		}
+	}
+
+	if best == startIdx {
+		return nil, false
+	}
+	return g.reconstruct(best), false
+}
+
+// touch resets a cell's search entries the first time a search sees it
+func (g *Grid) touch(i int32) {
+	if g.visit[i] == g.searchID {
+		return
+	}
+	g.visit[i] = g.searchID
+	g.gScore[i] = math.Inf(1)
+	g.parent[i] = -1
+	g.closed[i] = false
+}
+
+// reconstruct walks parents back from a cell, excluding the start cell
+func (g *Grid) reconstruct(end int32) []int32 {
+	var cells []int32
+	for i := end; g.parent[i] != -1; i = g.parent[i] {
+		cells = append(cells, i)
+	}
+	for l, r := 0, len(cells)-1; l < r; l, r = l+1, r-1 {
+		cells[l], cells[r] = cells[r], cells[l]
+	}
+	return cells
+}
+
+// smooth drops waypoints that can be skipped by driving straight to a
+// later one without the unit's footprint touching a blocked cell. Each
+// waypoint is kept only when the one after it is not in sight of the
+// last kept point or is too far away.
+func (g *Grid) smooth(start emath.Vec2, path []emath.Vec2, size emath.Vec2) []emath.Vec2 {
+	if len(path) < 2 {
+		return path
+	}
+	smoothed := make([]emath.Vec2, 0, len(path))
+	anchor := start
+	maxDist := maxSmoothCells * g.CellSize
+	for i := 0; i < len(path)-1; i++ {
+		if anchor.DistanceSquared(path[i+1]) <= maxDist*maxDist && g.clearLine(anchor, path[i+1], size) {
+			continue
+		}
+		smoothed = append(smoothed, path[i])
+		anchor = path[i]
+	}
+	return append(smoothed, path[len(path)-1])
+}
+
+// clearLine sweeps a footprint of the given size along a segment
+func (g *Grid) clearLine(from, to, size emath.Vec2) bool {
+	step := g.CellSize / 2
+	steps := int(math.Ceil(from.Distance(to) / step))
+	half := size.Mul(0.5)
+	for s := 0; s <= steps; s++ {
+		t := 1.0
+		if steps > 0 {
+			t = float64(s) / float64(steps)
+		}
+		p := from.Add(to.Sub(from).Mul(t))
+		if !g.areaWalkable(emath.Rect{Pos: p.Sub(half), Size: size}) {
+			return false
+		}
+	}
+	return true
+}
+
+// octile is the 8-way distance between two cells
+func octile(x1, y1, x2, y2 int) float64 {
+	dx, dy := abs(x1-x2), abs(y1-y2)
+	return float64(max(dx, dy)) + (math.Sqrt2-1)*float64(min(dx, dy))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,155 @@
+package pathfinding
+
+import (
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+const testCell = 25
+
+// gridFromRows builds a grid from rows of '.' (open) and '#' (water)
+func gridFromRows(rows ...string) *Grid {
+	return NewGrid(len(rows[0]), len(rows), testCell, func(x, y int) bool {
+		return rows[y][x] != '#'
+	})
+}
+
+// walkPath checks every step of a path keeps a point-sized unit off blocked cells
+func walkPath(t *testing.T, g *Grid, start emath.Vec2, path []emath.Vec2) {
+	t.Helper()
+	from := start
+	for _, to := range path {
+		if !g.clearLine(from, to, emath.Vec2{}) {
+			t.Fatalf("segment %v -> %v crosses a blocked cell", from, to)
+		}
+		from = to
+	}
+}
+
+func TestFindPathAroundWall(t *testing.T) {
+	g := gridFromRows(
+		"..........",
+		"..........",
+		"#######...",
+		"..........",
+		"..........",
+	)
+	start := g.CellCenter(0, 0)
+	goal := g.CellCenter(0, 4)
+
+	path := g.FindPath(start, goal, emath.Vec2{})
+	if len(path) == 0 {
+		t.Fatal("expected a path around the wall")
+	}
+	if path[len(path)-1] != goal {
+		t.Errorf("path ends at %v, want %v", path[len(path)-1], goal)
+	}
+	walkPath(t, g, start, path)
+
+	// Smoothing should leave only the turns around the end of the wall
+	if len(path) > 4 {
+		t.Errorf("path has %d waypoints, expected smoothing to cut it to at most 4: %v", len(path), path)
+	}
+}
+
+func TestFindPathStraightLine(t *testing.T) {
+	g := gridFromRows(
+		"..........",
+		"..........",
+		"..........",
+	)
+	goal := g.CellCenter(9, 2)
+	path := g.FindPath(g.CellCenter(0, 0), goal, emath.Vec2{X: 20, Y: 20})
+	if len(path) != 1 || path[0] != goal {
+		t.Errorf("open ground should give a single waypoint at the goal, got %v", path)
+	}
+}
+
+func TestFindPathBuildingObstacle(t *testing.T) {
+	g := gridFromRows(
+		".....",
+		".....",
+		".....",
+	)
+	// A building covering the middle column except the bottom row
+	g.SetObstacles([]emath.Rect{emath.NewRect(2*testCell, 0, testCell, 2*testCell)})
+
+	start := g.CellCenter(0, 0)
+	path := g.FindPath(start, g.CellCenter(4, 0), emath.Vec2{})
+	if len(path) == 0 {
+		t.Fatal("expected a path under the building")
+	}
+	walkPath(t, g, start, path)
+
+	g.SetObstacles(nil)
+	if path := g.FindPath(start, g.CellCenter(4, 0), emath.Vec2{}); len(path) != 1 {
+		t.Errorf("clearing obstacles should open the direct route, got %v", path)
+	}
+}
+
+func TestFindPathNoCornerCutting(t *testing.T) {
+	g := gridFromRows(
+		".#",
+		"#.",
+	)
+	if path := g.FindPath(g.CellCenter(0, 0), g.CellCenter(1, 1), emath.Vec2{}); path != nil {
+		t.Errorf("diagonal squeeze between two blocked cells should be refused, got %v", path)
+	}
+}
+
+func TestFindPathBlockedGoal(t *testing.T) {
+	g := gridFromRows(
+		"......",
+		"....##",
+		"....##",
+	)
+	path := g.FindPath(g.CellCenter(0, 0), g.CellCenter(5, 2), emath.Vec2{})
+	if len(path) == 0 {
+		t.Fatal("expected a path to the nearest open cell")
+	}
+	end := path[len(path)-1]
+	if x, y := g.CellAt(end); !g.Walkable(x, y) {
+		t.Errorf("path ends on blocked cell (%d, %d)", x, y)
+	}
+}
+
+func TestFindPathUnreachableGoal(t *testing.T) {
+	g := gridFromRows(
+		"..#...",
+		"..#.#.",
+		"..#...",
+	)
+	// The goal sits inside a walled-off pocket, so the path should get as
+	// close as it can from the left side
+	path := g.FindPath(g.CellCenter(0, 1), g.CellCenter(3, 1), emath.Vec2{})
+	if len(path) == 0 {
+		t.Fatal("expected a partial path")
+	}
+	if x, _ := g.CellAt(path[len(path)-1]); x != 1 {
+		t.Errorf("partial path should stop next to the wall, ends at %v", path[len(path)-1])
+	}
+}
+
+func BenchmarkFindPath(b *testing.B) {
+	// A large map with long walls forcing a winding route
+	const w, h = 240, 135
+	g := NewGrid(w, h, testCell, func(x, y int) bool {
+		if x%40 == 20 {
+			if (x/40)%2 == 0 {
+				return y > h-5
+			}
+			return y < 5
+		}
+		return true
+	})
+	start := g.CellCenter(1, 1)
+	goal := g.CellCenter(w-2, h-2)
+	size := emath.Vec2{X: 20, Y: 20}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if g.FindPath(start, goal, size) == nil {
+			b.Fatal("no path")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"math"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+const (
+	// maxCachedFlowFields is how many flow fields a grid keeps for reuse
+	maxCachedFlowFields = 8
+
+	// flowReuseCells is how close, in cells, a new destination must be to
+	// a cached field's goal for the field to be reused
+	flowReuseCells = 2
+
+	// flowLookahead is how many cells ahead along the field a waypoint may
+	// be taken, which evens out the 45 degree steps of the grid
+	flowLookahead = 3
+)
+
+// FlowField stores, for every cell, the next cell on a shortest route to
+// one goal. It is built once and shared by any number of units.
+type FlowField struct {
+	grid    *Grid
+	goalX   int
+	goalY   int
+	version uint64
+	cost    []float64 // Cell index -> distance to goal in cells, +Inf if unreachable
+	next    []int32   // Cell index -> next cell towards goal, -1 at the goal or if unreachable
+}
+
+// FlowTo returns a flow field leading to goal, reusing a cached field for
+// a nearby goal when the obstacles have not changed since it was built.
+// Returns nil when no walkable cell is near goal.
+func (g *Grid) FlowTo(goal emath.Vec2) *FlowField {
+	gx, gy := g.clampCell(g.CellAt(goal))
+	if !g.Walkable(gx, gy) {
+		var ok bool
+		gx, gy, ok = g.nearestWalkable(gx, gy)
+		if !ok {
+			return nil
+		}
+	}
+
+	for i, f := range g.flowCache {
+		if f.version == g.obstacleVersion && max(abs(f.goalX-gx), abs(f.goalY-gy)) <= flowReuseCells {
+			// Move to front so the least recently used field is evicted first
+			copy(g.flowCache[1:i+1], g.flowCache[:i])
+			g.flowCache[0] = f
+			return f
+		}
+	}
+
+	f := g.buildFlowField(gx, gy)
+	if len(g.flowCache) < maxCachedFlowFields {
+		g.flowCache = append(g.flowCache, nil)
+	}
+	copy(g.flowCache[1:], g.flowCache)
+	g.flowCache[0] = f
+	return f
+}
+
+// buildFlowField runs Dijkstra outward from the goal cell
+func (g *Grid) buildFlowField(gx, gy int) *FlowField {
+	n := g.Width * g.Height
+	f := &FlowField{
+		grid:    g,
+		goalX:   gx,
+		goalY:   gy,
+		version: g.obstacleVersion,
+		cost:    make([]float64, n),
+		next:    make([]int32, n),
+	}
+	for i := range f.cost {
+		f.cost[i] = math.Inf(1)
+		f.next[i] = -1
+	}
+
+	goalIdx := int32(gy*g.Width + gx)
+	f.cost[goalIdx] = 0
+	open := openList{{index: goalIdx}}
+	for open.Len() > 0 {
+		cur := heap.Pop(&open).(openNode)
+		if cur.f > f.cost[cur.index] {
+			continue
+		}
+		cx, cy := int(cur.index)%g.Width, int(cur.index)/g.Width
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+				step, ok := g.step(cx, cy, dx, dy)
+				if !ok {
+					continue
+				}
+				ni := int32((cy+dy)*g.Width + cx + dx)
+				if c := cur.f + step; c < f.cost[ni] {
+					f.cost[ni] = c
+					f.next[ni] = cur.index
+					heap.Push(&open, openNode{index: ni, f: c})
+				}
+			}
+		}
+	}
+	return f
+}
+
+// step returns the cost of moving from a cell to its neighbor, refusing
+// blocked cells and diagonals that cut a blocked corner
+func (g *Grid) step(x, y, dx, dy int) (float64, bool) {
+	if !g.Walkable(x+dx, y+dy) {
+		return 0, false
+	}
+	if dx != 0 && dy != 0 {
+		if !g.Walkable(x+dx, y) || !g.Walkable(x, y+dy) {
+			return 0, false
+		}
+		return math.Sqrt2, true
+	}
+	return 1, true
+}
+
+// Goal returns the center of the goal cell
+func (f *FlowField) Goal() emath.Vec2 {
+	return f.grid.CellCenter(f.goalX, f.goalY)
+}
+
+// Waypoint returns the point a unit at pos should steer towards. It is
+// false once the unit is on the goal cell or cannot reach the goal from
+// where it stands.
+func (f *FlowField) Waypoint(pos emath.Vec2) (emath.Vec2, bool) {
+	g := f.grid
+	x, y := g.CellAt(pos)
+	if x < 0 || y < 0 || x >= g.Width || y >= g.Height {
+		return emath.Vec2{}, false
+	}
+	cell := int32(y*g.Width + x)
+
+	if math.IsInf(f.cost[cell], 1) {
+		// Units overlapping a building footprint step to the best
+		// neighbouring cell first
+		cell = -1
+		best := math.Inf(1)
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || ny < 0 || nx >= g.Width || ny >= g.Height {
+					continue
+				}
+				if c := f.cost[ny*g.Width+nx]; c < best {
+					best, cell = c, int32(ny*g.Width+nx)
+				}
+			}
+		}
+		if cell == -1 {
+			return emath.Vec2{}, false
+		}
+	} else if f.next[cell] == -1 {
+		return emath.Vec2{}, false
+	}
+
+	// The first step is always safe; further steps only while the
+	// straight line to them stays clear
+	for i := 0; i < flowLookahead && f.next[cell] != -1; i++ {
+		next := f.next[cell]
+		if i > 0 && !g.clearLine(pos, f.center(next), emath.Vec2{}) {
+			break
+		}
+		cell = next
+	}
+	return f.center(cell), true
+}
+
+func (f *FlowField) center(cell int32) emath.Vec2 {
+	return f.grid.CellCenter(int(cell)%f.grid.Width, int(cell)/f.grid.Width)
+}
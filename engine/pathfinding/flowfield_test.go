@@ -0,0 +1,130 @@
+package pathfinding
+
+import (
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// followField steps a point-sized unit along a field until it stops
+func followField(t *testing.T, f *FlowField, pos emath.Vec2) emath.Vec2 {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		wp, ok := f.Waypoint(pos)
+		if !ok {
+			return pos
+		}
+		if !f.grid.clearLine(pos, wp, emath.Vec2{}) {
+			t.Fatalf("waypoint %v from %v crosses a blocked cell", wp, pos)
+		}
+		pos = wp
+	}
+	t.Fatal("flow field did not converge")
+	return pos
+}
+
+func TestFlowFieldReachesGoal(t *testing.T) {
+	g := gridFromRows(
+		"..........",
+		"..........",
+		"#######...",
+		"..........",
+		"..........",
+	)
+	f := g.FlowTo(g.CellCenter(0, 4))
+	if f == nil {
+		t.Fatal("expected a flow field")
+	}
+	for _, start := range []emath.Vec2{g.CellCenter(0, 0), g.CellCenter(5, 1), g.CellCenter(9, 4)} {
+		if end := followField(t, f, start); end != f.Goal() {
+			t.Errorf("from %v the field ends at %v, want %v", start, end, f.Goal())
+		}
+	}
+}
+
+func TestFlowFieldUnreachable(t *testing.T) {
+	g := gridFromRows(
+		"..#...",
+		"..#...",
+		"..#...",
+	)
+	f := g.FlowTo(g.CellCenter(4, 1))
+	if _, ok := f.Waypoint(g.CellCenter(0, 1)); ok {
+		t.Error("cells cut off from the goal should have no waypoint")
+	}
+}
+
+func TestFlowFieldCache(t *testing.T) {
+	g := gridFromRows(
+		"..........",
+		"..........",
+		"..........",
+	)
+	f := g.FlowTo(g.CellCenter(5, 1))
+	if g.FlowTo(g.CellCenter(6, 2)) != f {
+		t.Error("a nearby destination should reuse the cached field")
+	}
+	if g.FlowTo(g.CellCenter(0, 0)) == f {
+		t.Error("a distant destination should build a new field")
+	}
+
+	// Setting the same obstacles again keeps the cache
+	g.SetObstacles(nil)
+	if g.FlowTo(g.CellCenter(5, 1)) != f {
+		t.Error("unchanged obstacles should keep the cached field")
+	}
+
+	g.SetObstacles([]emath.Rect{emath.NewRect(3*testCell, 0, testCell, testCell)})
+	if g.FlowTo(g.CellCenter(5, 1)) == f {
+		t.Error("changed obstacles should invalidate the cached field")
+	}
+}
+
+// benchGroup returns a map with scattered lakes and a group of unit
+// positions on the far side from the destination
+func benchGroup() (*Grid, []emath.Vec2, emath.Vec2) {
+	const w, h = 240, 135
+	g := NewGrid(w, h, testCell, func(x, y int) bool {
+		return !((x/15)%3 == 1 && (y/15)%3 == 1)
+	})
+	var starts []emath.Vec2
+	for i := 0; i < 40; i++ {
+		starts = append(starts, g.CellCenter(2+i%8, 2+i/8))
+	}
+	return g, starts, g.CellCenter(w-5, h-5)
+}
+
+func BenchmarkGroupMovePerUnitAStar(b *testing.B) {
+	g, starts, goal := benchGroup()
+	size := emath.Vec2{X: 20, Y: 20}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range starts {
+			g.FindPath(s, goal, size)
+		}
+	}
+}
+
+func BenchmarkGroupMoveFlowField(b *testing.B) {
+	g, starts, goal := benchGroup()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.flowCache = g.flowCache[:0]
+		f := g.FlowTo(goal)
+		for _, s := range starts {
+			f.Waypoint(s)
+		}
+	}
+}
+
+func BenchmarkGroupMoveFlowFieldCached(b *testing.B) {
+	g, starts, goal := benchGroup()
+	g.FlowTo(goal)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f := g.FlowTo(goal)
+		for _, s := range starts {
+			f.Waypoint(s)
+		}
+	}
+}
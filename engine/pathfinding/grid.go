@@ -0,0 +1,122 @@
+// Package pathfinding finds routes for ground units across the tile grid.
+// Terrain passability is fixed when the grid is created; buildings are
+// dynamic obstacles refreshed by the owner of the grid as they come and go.
+package pathfinding
+
+import (
+	"math"
+	"slices"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// Grid is a walkability grid of square cells
+type Grid struct {
+	Width, Height int
+	CellSize      float64
+
+	terrain  []bool // Cell index -> blocked by terrain
+	occupied []bool // Cell index -> blocked by a building
+	scratch  []bool // Next obstacle layer while it is compared with occupied
+
+	obstacleVersion uint64 // Bumped whenever the obstacles change
+	flowCache       []*FlowField
+
+	// Search buffers, reused between searches. A cell's entries are only
+	// valid when its visit stamp equals the current search.
+	visit    []uint32
+	searchID uint32
+	gScore   []float64
+	parent   []int32
+	closed   []bool
+	open     openList
+}
+
+// NewGrid creates a width x height grid. passable reports whether the
+// terrain of a cell can be driven over.
+func NewGrid(width, height int, cellSize float64, passable func(x, y int) bool) *Grid {
+	n := width * height
+	g := &Grid{
+		Width:    width,
+		Height:   height,
+		CellSize: cellSize,
+		terrain:  make([]bool, n),
+		occupied: make([]bool, n),
+		scratch:  make([]bool, n),
+		visit:    make([]uint32, n),
+		gScore:   make([]float64, n),
+		parent:   make([]int32, n),
+		closed:   make([]bool, n),
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			g.terrain[y*width+x] = !passable(x, y)
+		}
+	}
+	return g
+}
+
+// SetObstacles replaces the dynamic obstacles with the given rectangles.
+// Every cell a rectangle overlaps is blocked. Cached flow fields are
+// dropped only if the blocked cells actually changed.
+func (g *Grid) SetObstacles(rects []emath.Rect) {
+	clear(g.scratch)
+	for _, r := range rects {
+		minX, minY, maxX, maxY := g.cellSpan(r)
+		for y := max(minY, 0); y <= min(maxY, g.Height-1); y++ {
+			for x := max(minX, 0); x <= min(maxX, g.Width-1); x++ {
+				g.scratch[y*g.Width+x] = true
+			}
+		}
+	}
+	if slices.Equal(g.scratch, g.occupied) {
+		return
+	}
+	g.occupied, g.scratch = g.scratch, g.occupied
+	g.obstacleVersion++
+	g.flowCache = g.flowCache[:0]
+}
+
+// Walkable reports whether a cell is inside the grid and not blocked
+func (g *Grid) Walkable(x, y int) bool {
+	if x < 0 || y < 0 || x >= g.Width || y >= g.Height {
+		return false
+	}
+	i := y*g.Width + x
+	return !g.terrain[i] && !g.occupied[i]
+}
+
+// CellAt returns the cell containing a world position
+func (g *Grid) CellAt(pos emath.Vec2) (int, int) {
+	return int(math.Floor(pos.X / g.CellSize)), int(math.Floor(pos.Y / g.CellSize))
+}
+
+// CellCenter returns the world position of the center of a cell
+func (g *Grid) CellCenter(x, y int) emath.Vec2 {
+	return emath.Vec2{
+		X: (float64(x) + 0.5) * g.CellSize,
+		Y: (float64(y) + 0.5) * g.CellSize,
+	}
+}
+
+// areaWalkable reports whether every cell overlapped by a rect is walkable
+func (g *Grid) areaWalkable(r emath.Rect) bool {
+	minX, minY, maxX, maxY := g.cellSpan(r)
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			if !g.Walkable(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// cellSpan returns the range of cells a rect overlaps. The far edge is
+// pulled in slightly so a rect ending on a cell border does not claim the
+// next cell.
+func (g *Grid) cellSpan(r emath.Rect) (minX, minY, maxX, maxY int) {
+	minX, minY = g.CellAt(r.Pos)
+	maxX, maxY = g.CellAt(r.Pos.Add(r.Size).Sub(emath.Vec2{X: 0.01, Y: 0.01}))
+	return minX, minY, maxX, maxY
+}
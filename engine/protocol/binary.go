@@ -0,0 +1,643 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Encodings a client may offer in Hello, in order of preference
+const (
+	EncodingJSON   = "json"
+	EncodingBinary = "binary"
+)
+
+// Binary frame tags. Every binary WebSocket frame starts with one of these.
+const (
+	FrameGameState byte = 1
+)
+
+// Quantization steps used by the binary encoding
+const (
+	positionScale = 4.0 // 0.25 px
+	healthScale   = 8.0 // 0.125 hp
+	timeScale     = 10  // 0.1 s
+	angleSteps    = 1 << 16
+	progressSteps = 1<<16 - 1
+)
+
+// Per-entity flag bits
+const (
+	statePaused byte = 1 << iota
+)
+
+const (
+	playerAlive byte = 1 << iota
+)
+
+const (
+	unitHasTarget byte = 1 << iota
+	_
+	_
+	unitHasOrders
+	_
+	_
+	unitHasCargo
+	unitHasAbilities
+)
+
+// Unit stances take the two flag bits between unitHasTarget and
+// unitHasOrders, and veterancy ranks the two bits above unitHasOrders
+const (
+	unitStanceShift = 1
+	unitStanceMask  = 0x3
+	unitRankShift   = 4
+	unitRankMask    = 0x3
+)
+
+const (
+	buildingCompleted byte = 1 << iota
+	buildingProducing
+	buildingMining
+	buildingResearching
+	buildingUnderpowered
+)
+
+// Building priorities take the two flag bits above buildingUnderpowered,
+// and buildingRecycling the top bit
+const (
+	buildingPriorityShift      = 5
+	buildingPriorityMask       = 0x3
+	buildingRecycling     byte = 1 << 7
+)
+
+var errShortFrame = errors.New("binary frame truncated")
+
+// NegotiateEncoding picks the first encoding offered by the client that the
+// server supports, falling back to JSON
+func NegotiateEncoding(offered []string) string {
+	for _, enc := range offered {
+		if enc == EncodingJSON || enc == EncodingBinary {
+			return enc
+		}
+	}
+	return EncodingJSON
+}
+
+// EncodeGameState serializes a game state into a compact binary frame
+func EncodeGameState(p *GameStatePayload) []byte {
+	w := &binaryWriter{buf: make([]byte, 0, 64+len(p.Units)*24+len(p.Buildings)*24+len(p.Projectiles)*12)}
+	w.byte(FrameGameState)
+	w.uvarint(p.Tick)
+
+	var stateFlags byte
+	if p.Pause != nil {
+		stateFlags |= statePaused
+	}
+	w.byte(stateFlags)
+	if p.Pause != nil {
+		w.varint(int64(p.Pause.BySlot)) // -1 when paused by the server
+		w.string(p.Pause.ByName)
+		w.seconds(p.Pause.Elapsed)
+		w.seconds(p.Pause.ResumeIn)
+		w.uvarint(uint64(len(p.Pause.ResumeVotes)))
+		for _, slot := range p.Pause.ResumeVotes {
+			w.uvarint(uint64(slot))
+		}
+	}
+
+	w.uvarint(uint64(len(p.Players)))
+	for _, pl := range p.Players {
+		var flags byte
+		if pl.Alive {
+			flags |= playerAlive
+		}
+		w.uvarint(uint64(pl.Slot))
+		w.byte(flags)
+		w.uvarint(uint64(pl.PausesLeft))
+		w.string(pl.Name)
+		w.float32(pl.Resources.Metal)
+		w.float32(pl.Resources.MetalCap)
+		w.float32(pl.Resources.MetalProd)
+		w.float32(pl.Resources.Energy)
+		w.float32(pl.Resources.EnergyCap)
+		w.float32(pl.Resources.EnergyProd)
+		w.uvarint(uint64(len(pl.Research)))
+		for _, rt := range pl.Research {
+			w.uvarint(uint64(rt))
+		}
+		w.uvarint(uint64(pl.PowerFirst))
+		w.progress(pl.PowerLevel)
+	}
+
+	w.uvarint(uint64(len(p.Units)))
+	for _, u := range p.Units {
+		var flags byte
+		if u.HasTarget {
+			flags |= unitHasTarget
+		}
+		flags |= byte(u.Stance&unitStanceMask) << unitStanceShift
+		flags |= byte(u.Rank&unitRankMask) << unitRankShift
+		if len(u.Orders) > 0 {
+			flags |= unitHasOrders
+		}
+		if len(u.Cargo) > 0 {
+			flags |= unitHasCargo
+		}
+		abilities := len(u.Cooldowns) > 0 || u.BoostLeft > 0
+		if abilities {
+			flags |= unitHasAbilities
+		}
+		w.uvarint(u.ID)
+		w.uvarint(uint64(u.Type))
+		w.uvarint(uint64(u.OwnerSlot))
+		w.byte(flags)
+		w.position(u.PosX)
+		w.position(u.PosY)
+		w.health(u.Health)
+		w.health(u.MaxHealth)
+		w.angle(u.Angle)
+		w.angle(u.TurretAngle)
+		if u.HasTarget {
+			w.position(u.TargetX)
+			w.position(u.TargetY)
+		}
+		if len(u.Orders) > 0 {
+			w.uvarint(uint64(len(u.Orders)))
+			for _, o := range u.Orders {
+				w.byte(byte(o.Type))
+				w.position(o.X)
+				w.position(o.Y)
+			}
+		}
+		if len(u.Cargo) > 0 {
+			w.uvarint(uint64(len(u.Cargo)))
+			for _, c := range u.Cargo {
+				w.uvarint(c.ID)
+				w.uvarint(uint64(c.Type))
+				w.health(c.Health)
+				w.health(c.MaxHealth)
+			}
+		}
+		if abilities {
+			w.uvarint(uint64(len(u.Cooldowns)))
+			for _, cd := range u.Cooldowns {
+				w.seconds(cd)
+			}
+			w.seconds(u.BoostLeft)
+		}
+	}
+
+	w.uvarint(uint64(len(p.Buildings)))
+	for _, b := range p.Buildings {
+		var flags byte
+		if b.Completed {
+			flags |= buildingCompleted
+		}
+		if b.Producing {
+			flags |= buildingProducing
+		}
+		mining := b.Yield > 0 || b.Deposit > 0
+		if mining {
+			flags |= buildingMining
+		}
+		if b.Researching {
+			flags |= buildingResearching
+		}
+		if b.PowerDeficit > 0 {
+			flags |= buildingUnderpowered
+		}
+		flags |= byte(b.Priority&buildingPriorityMask) << buildingPriorityShift
+		if b.Recycling {
+			flags |= buildingRecycling
+		}
+		w.uvarint(b.ID)
+		w.uvarint(uint64(b.Type))
+		w.uvarint(uint64(b.OwnerSlot))
+		w.byte(flags)
+		w.position(b.PosX)
+		w.position(b.PosY)
+		w.health(b.Health)
+		w.health(b.MaxHealth)
+		if !b.Completed {
+			w.progress(b.BuildProgress)
+		}
+		if b.Producing {
+			w.progress(b.ProdProgress)
+			w.uvarint(uint64(b.ProdType))
+		}
+		if mining {
+			w.float32(b.Yield)
+			w.health(b.Deposit)
+		}
+		if b.Researching {
+			w.progress(b.ResProgress)
+			w.uvarint(uint64(b.ResType))
+		}
+		if b.PowerDeficit > 0 {
+			w.progress(b.PowerDeficit)
+		}
+		if b.Recycling {
+			w.progress(b.RecycleProg)
+		}
+	}
+
+	w.uvarint(uint64(len(p.Projectiles)))
+	for _, pr := range p.Projectiles {
+		w.uvarint(pr.ID)
+		w.uvarint(uint64(pr.OwnerSlot))
+		w.position(pr.PosX)
+		w.position(pr.PosY)
+		w.position(pr.TargetX)
+		w.position(pr.TargetY)
+		w.uvarint(uint64(pr.Weapon))
+	}
+
+	w.uvarint(uint64(len(p.Wreckages)))
+	for _, wr := range p.Wreckages {
+		w.uvarint(wr.ID)
+		w.position(wr.PosX)
+		w.position(wr.PosY)
+		w.position(wr.Width)
+		w.position(wr.Height)
+		w.health(wr.Metal)
+	}
+
+	w.uvarint(uint64(len(p.Deposits)))
+	for _, d := range p.Deposits {
+		w.uvarint(uint64(d.X))
+		w.uvarint(uint64(d.Y))
+		w.health(d.Amount)
+	}
+
+	w.uvarint(uint64(len(p.Effects)))
+	for _, e := range p.Effects {
+		w.uvarint(uint64(e.Ability))
+		w.uvarint(uint64(e.OwnerSlot))
+		w.position(e.X)
+		w.position(e.Y)
+		w.seconds(e.Remaining)
+	}
+
+	return w.buf
+}
+
+// DecodeGameState parses a frame produced by EncodeGameState
+func DecodeGameState(data []byte) (GameStatePayload, error) {
+	var p GameStatePayload
+	r := &binaryReader{buf: data}
+
+	if tag := r.byte(); tag != FrameGameState {
+		if r.err != nil {
+			return p, r.err
+		}
+		return p, fmt.Errorf("unexpected binary frame tag %d", tag)
+	}
+	p.Tick = r.uvarint()
+
+	if r.byte()&statePaused != 0 {
+		pause := &PauseState{}
+		pause.BySlot = int(r.varint())
+		pause.ByName = r.string()
+		pause.Elapsed = r.seconds()
+		pause.ResumeIn = r.seconds()
+		if n := r.count(); n > 0 {
+			pause.ResumeVotes = make([]int, n)
+			for i := range pause.ResumeVotes {
+				pause.ResumeVotes[i] = int(r.uvarint())
+			}
+		}
+		p.Pause = pause
+	}
+
+	p.Players = make([]PlayerGameState, r.count())
+	for i := range p.Players {
+		pl := &p.Players[i]
+		pl.Slot = int(r.uvarint())
+		pl.Alive = r.byte()&playerAlive != 0
+		pl.PausesLeft = int(r.uvarint())
+		pl.Name = r.string()
+		pl.Resources.Metal = r.float32()
+		pl.Resources.MetalCap = r.float32()
+		pl.Resources.MetalProd = r.float32()
+		pl.Resources.Energy = r.float32()
+		pl.Resources.EnergyCap = r.float32()
+		pl.Resources.EnergyProd = r.float32()
+		if n := r.count(); n > 0 {
+			pl.Research = make([]int, n)
+			for j := range pl.Research {
+				pl.Research[j] = int(r.uvarint())
+			}
+		}
+		pl.PowerFirst = int(r.uvarint())
+		pl.PowerLevel = r.progress()
+	}
+
+	p.Units = make([]UnitState, r.count())
+	for i := range p.Units {
+		u := &p.Units[i]
+		u.ID = r.uvarint()
+		u.Type = int(r.uvarint())
+		u.OwnerSlot = int(r.uvarint())
+		flags := r.byte()
+		u.Stance = int(flags>>unitStanceShift) & unitStanceMask
+		u.Rank = int(flags>>unitRankShift) & unitRankMask
+		u.PosX = r.position()
+		u.PosY = r.position()
+		u.Health = r.health()
+		u.MaxHealth = r.health()
+		u.Angle = r.angle()
+		u.TurretAngle = r.angle()
+		if flags&unitHasTarget != 0 {
+			u.HasTarget = true
+			u.TargetX = r.position()
+			u.TargetY = r.position()
+		}
+		if flags&unitHasOrders != 0 {
+			u.Orders = make([]OrderState, r.count())
+			for j := range u.Orders {
+				o := &u.Orders[j]
+				o.Type = int(r.byte())
+				o.X = r.position()
+				o.Y = r.position()
+			}
+		}
+		if flags&unitHasCargo != 0 {
+			u.Cargo = make([]CargoState, r.count())
+			for j := range u.Cargo {
+				c := &u.Cargo[j]
+				c.ID = r.uvarint()
+				c.Type = int(r.uvarint())
+				c.Health = r.health()
+				c.MaxHealth = r.health()
+			}
+		}
+		if flags&unitHasAbilities != 0 {
+			if n := r.count(); n > 0 {
+				u.Cooldowns = make([]float64, n)
+				for j := range u.Cooldowns {
+					u.Cooldowns[j] = r.seconds()
+				}
+			}
+			u.BoostLeft = r.seconds()
+		}
+	}
+
+	p.Buildings = make([]BuildingState, r.count())
+	for i := range p.Buildings {
+		b := &p.Buildings[i]
+		b.ID = r.uvarint()
+		b.Type = int(r.uvarint())
+		b.OwnerSlot = int(r.uvarint())
+		flags := r.byte()
+		b.PosX = r.position()
+		b.PosY = r.position()
+		b.Health = r.health()
+		b.MaxHealth = r.health()
+		b.Completed = flags&buildingCompleted != 0
+		b.Priority = int(flags>>buildingPriorityShift) & buildingPriorityMask
+		if b.Completed {
+			b.BuildProgress = 1
+		} else {
+			b.BuildProgress = r.progress()
+		}
+		if flags&buildingProducing != 0 {
+			b.Producing = true
+			b.ProdProgress = r.progress()
+			b.ProdType = int(r.uvarint())
+		}
+		if flags&buildingMining != 0 {
+			b.Yield = r.float32()
+			b.Deposit = r.health()
+		}
+		if flags&buildingResearching != 0 {
+			b.Researching = true
+			b.ResProgress = r.progress()
+			b.ResType = int(r.uvarint())
+		}
+		if flags&buildingUnderpowered != 0 {
+			b.PowerDeficit = r.progress()
+		}
+		if flags&buildingRecycling != 0 {
+			b.Recycling = true
+			b.RecycleProg = r.progress()
+		}
+	}
+
+	p.Projectiles = make([]ProjectileState, r.count())
+	for i := range p.Projectiles {
+		pr := &p.Projectiles[i]
+		pr.ID = r.uvarint()
+		pr.OwnerSlot = int(r.uvarint())
+		pr.PosX = r.position()
+		pr.PosY = r.position()
+		pr.TargetX = r.position()
+		pr.TargetY = r.position()
+		pr.Weapon = int(r.uvarint())
+	}
+
+	if n := r.count(); n > 0 {
+		p.Wreckages = make([]WreckageState, n)
+		for i := range p.Wreckages {
+			wr := &p.Wreckages[i]
+			wr.ID = r.uvarint()
+			wr.PosX = r.position()
+			wr.PosY = r.position()
+			wr.Width = r.position()
+			wr.Height = r.position()
+			wr.Metal = r.health()
+		}
+	}
+
+	if n := r.count(); n > 0 {
+		p.Deposits = make([]DepositState, n)
+		for i := range p.Deposits {
+			d := &p.Deposits[i]
+			d.X = int(r.uvarint())
+			d.Y = int(r.uvarint())
+			d.Amount = r.health()
+		}
+	}
+
+	if n := r.count(); n > 0 {
+		p.Effects = make([]EffectState, n)
+		for i := range p.Effects {
+			e := &p.Effects[i]
+			e.Ability = int(r.uvarint())
+			e.OwnerSlot = int(r.uvarint())
+			e.X = r.position()
+			e.Y = r.position()
+			e.Remaining = r.seconds()
+		}
+	}
+
+	if r.err != nil {
+		return GameStatePayload{}, r.err
+	}
+	return p, nil
+}
+
+// binaryWriter appends quantized values to a byte slice
+type binaryWriter struct {
+	buf []byte
+}
+
+func (w *binaryWriter) byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *binaryWriter) uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *binaryWriter) varint(v int64) {
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *binaryWriter) float32(f float64) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(float32(f)))
+}
+
+func (w *binaryWriter) position(v float64) {
+	w.varint(int64(math.Round(v * positionScale)))
+}
+
+func (w *binaryWriter) health(v float64) {
+	if v < 0 {
+		v = 0
+	}
+	w.uvarint(uint64(math.Round(v * healthScale)))
+}
+
+func (w *binaryWriter) seconds(v float64) {
+	if v < 0 {
+		v = 0
+	}
+	w.uvarint(uint64(math.Round(v * timeScale)))
+}
+
+func (w *binaryWriter) angle(a float64) {
+	a = math.Mod(a, 2*math.Pi)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	step := uint32(math.Round(a/(2*math.Pi)*angleSteps)) % angleSteps
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(step))
+}
+
+func (w *binaryWriter) progress(v float64) {
+	v = math.Max(0, math.Min(1, v))
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(math.Round(v*progressSteps)))
+}
+
+// binaryReader consumes values written by binaryWriter. The first error
+// sticks and every later read returns a zero value.
+type binaryReader struct {
+	buf []byte
+	err error
+}
+
+func (r *binaryReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = errShortFrame
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *binaryReader) byte() byte {
+	b := r.take(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errShortFrame
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errShortFrame
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+// count reads a slice length and rejects lengths the frame cannot hold
+func (r *binaryReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		if r.err == nil {
+			r.err = errShortFrame
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) string() string {
+	return string(r.take(r.count()))
+}
+
+func (r *binaryReader) float32() float64 {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+}
+
+func (r *binaryReader) position() float64 {
+	return float64(r.varint()) / positionScale
+}
+
+func (r *binaryReader) health() float64 {
+	return float64(r.uvarint()) / healthScale
+}
+
+func (r *binaryReader) seconds() float64 {
+	return float64(r.uvarint()) / timeScale
+}
+
+func (r *binaryReader) angle() float64 {
+	b := r.take(2)
+	if b == nil {
+		return 0
+	}
+	return float64(binary.LittleEndian.Uint16(b)) / angleSteps * 2 * math.Pi
+}
+
+func (r *binaryReader) progress() float64 {
+	b := r.take(2)
+	if b == nil {
+		return 0
+	}
+	return float64(binary.LittleEndian.Uint16(b)) / progressSteps
+}
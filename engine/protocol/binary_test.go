@@ -0,0 +1,227 @@
+package protocol
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// largeGameState builds a mid-game sized state for size and speed comparisons
+func largeGameState(units, buildings, projectiles int) *GameStatePayload {
+	p := &GameStatePayload{Tick: 36000}
+	for slot := 0; slot < 4; slot++ {
+		p.Players = append(p.Players, PlayerGameState{
+			Slot: slot, Name: "player", Alive: true,
+			Resources: ResourceStateNet{Metal: 812.4, MetalCap: 2000, MetalProd: 3.2, Energy: 150.7, EnergyCap: 400, EnergyProd: 12.5},
+		})
+	}
+	for i := 0; i < units; i++ {
+		p.Units = append(p.Units, UnitState{
+			ID: uint64(i), Type: i % 9, OwnerSlot: i % 4,
+			PosX: 123.456 + float64(i*13%6000), PosY: 78.9 + float64(i*7%3400),
+			Health: 87.5, MaxHealth: 120, Angle: float64(i%628) / 100, TurretAngle: float64(i%314) / 100,
+			HasTarget: i%2 == 0, TargetX: 3000.25, TargetY: 1500.75, Stance: i % 4, Rank: i / 3 % 4,
+		})
+		if i%3 == 0 {
+			p.Units[i].Orders = []OrderState{{Type: 0, X: 100.5, Y: 200.25}, {Type: 3, X: 4000, Y: 80}}
+		}
+		if i%5 == 0 {
+			p.Units[i].Cargo = []CargoState{{ID: uint64(i + 1000), Type: 2, Health: 62.5, MaxHealth: 100}}
+		}
+	}
+	for i := 0; i < buildings; i++ {
+		p.Buildings = append(p.Buildings, BuildingState{
+			ID: uint64(i), Type: i % 12, OwnerSlot: i % 4,
+			PosX: float64(i * 50), PosY: float64(i * 25), Health: 900, MaxHealth: 1000,
+			Completed: i%5 != 0, BuildProgress: 0.5, Producing: i%3 == 0, ProdProgress: 0.3, ProdType: 2,
+		})
+	}
+	for i := 0; i < projectiles; i++ {
+		p.Projectiles = append(p.Projectiles, ProjectileState{
+			ID: uint64(i), OwnerSlot: i % 4, PosX: float64(i) * 3.3, PosY: float64(i) * 1.7, TargetX: 500, TargetY: 600, Weapon: i % 4,
+		})
+	}
+	return p
+}
+
+func TestBinaryGameStateRoundTrip(t *testing.T) {
+	want := sampleGameState()
+	want.Buildings[0].BuildProgress = 1 // completed buildings always decode as fully built
+
+	got, err := DecodeGameState(EncodeGameState(&want))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if got.Tick != want.Tick || len(got.Players) != len(want.Players) || len(got.Units) != len(want.Units) ||
+		len(got.Buildings) != len(want.Buildings) || len(got.Projectiles) != len(want.Projectiles) ||
+		len(got.Wreckages) != len(want.Wreckages) || len(got.Deposits) != len(want.Deposits) ||
+		len(got.Effects) != len(want.Effects) {
+		t.Fatalf("shape mismatch: got %+v", got)
+	}
+
+	near := func(name string, a, b, tol float64) {
+		t.Helper()
+		if math.Abs(a-b) > tol {
+			t.Errorf("%s = %v, want %v (±%v)", name, a, b, tol)
+		}
+	}
+	angleNear := func(name string, a, b float64) {
+		t.Helper()
+		d := math.Mod(a-b, 2*math.Pi)
+		if d > math.Pi {
+			d -= 2 * math.Pi
+		} else if d < -math.Pi {
+			d += 2 * math.Pi
+		}
+		near(name, d, 0, 2*math.Pi/angleSteps)
+	}
+
+	if got.Pause == nil {
+		t.Fatal("pause state lost")
+	}
+	if got.Pause.BySlot != want.Pause.BySlot || got.Pause.ByName != want.Pause.ByName ||
+		!reflect.DeepEqual(got.Pause.ResumeVotes, want.Pause.ResumeVotes) {
+		t.Errorf("pause = %+v, want %+v", *got.Pause, *want.Pause)
+	}
+	near("pause elapsed", got.Pause.Elapsed, want.Pause.Elapsed, 0.5/timeScale)
+	near("pause resumeIn", got.Pause.ResumeIn, want.Pause.ResumeIn, 0.5/timeScale)
+
+	for i, pl := range want.Players {
+		g := got.Players[i]
+		if g.Slot != pl.Slot || g.Name != pl.Name || g.Alive != pl.Alive || g.PausesLeft != pl.PausesLeft ||
+			!reflect.DeepEqual(g.Research, pl.Research) || g.PowerFirst != pl.PowerFirst {
+			t.Errorf("player %d = %+v, want %+v", i, g, pl)
+		}
+		near("metal", g.Resources.Metal, pl.Resources.Metal, 1e-3)
+		near("energyProd", g.Resources.EnergyProd, pl.Resources.EnergyProd, 1e-3)
+		near("power level", g.PowerLevel, pl.PowerLevel, 1.0/progressSteps)
+	}
+
+	for i, u := range want.Units {
+		g := got.Units[i]
+		if g.ID != u.ID || g.Type != u.Type || g.OwnerSlot != u.OwnerSlot || g.HasTarget != u.HasTarget ||
+			g.Stance != u.Stance || g.Rank != u.Rank {
+			t.Errorf("unit %d = %+v, want %+v", i, g, u)
+		}
+		near("unit x", g.PosX, u.PosX, 0.5/positionScale)
+		near("unit y", g.PosY, u.PosY, 0.5/positionScale)
+		near("unit hp", g.Health, u.Health, 0.5/healthScale)
+		near("unit tx", g.TargetX, u.TargetX, 0.5/positionScale)
+		angleNear("unit angle", g.Angle, u.Angle)
+		angleNear("turret angle", g.TurretAngle, u.TurretAngle)
+		if !reflect.DeepEqual(g.Cargo, u.Cargo) {
+			t.Errorf("unit %d cargo = %+v, want %+v", i, g.Cargo, u.Cargo)
+		}
+		if !reflect.DeepEqual(g.Cooldowns, u.Cooldowns) || g.BoostLeft != u.BoostLeft {
+			t.Errorf("unit %d cooldowns = %v boost %v, want %v boost %v", i, g.Cooldowns, g.BoostLeft, u.Cooldowns, u.BoostLeft)
+		}
+	}
+
+	for i, b := range want.Buildings {
+		g := got.Buildings[i]
+		if g.ID != b.ID || g.Type != b.Type || g.Completed != b.Completed || g.Producing != b.Producing || g.ProdType != b.ProdType ||
+			g.Researching != b.Researching || g.ResType != b.ResType || g.Priority != b.Priority ||
+			g.Recycling != b.Recycling {
+			t.Errorf("building %d = %+v, want %+v", i, g, b)
+		}
+		near("build progress", g.BuildProgress, b.BuildProgress, 1.0/progressSteps)
+		near("prod progress", g.ProdProgress, b.ProdProgress, 1.0/progressSteps)
+		near("yield", g.Yield, b.Yield, 1e-3)
+		near("deposit", g.Deposit, b.Deposit, 0.5/healthScale)
+		near("research progress", g.ResProgress, b.ResProgress, 1.0/progressSteps)
+		near("power deficit", g.PowerDeficit, b.PowerDeficit, 1.0/progressSteps)
+		near("recycle progress", g.RecycleProg, b.RecycleProg, 1.0/progressSteps)
+	}
+
+	for i, pr := range want.Projectiles {
+		g := got.Projectiles[i]
+		if g.ID != pr.ID || g.OwnerSlot != pr.OwnerSlot || g.Weapon != pr.Weapon {
+			t.Errorf("projectile %d = %+v, want %+v", i, g, pr)
+		}
+		near("projectile x", g.PosX, pr.PosX, 0.5/positionScale)
+	}
+
+	for i, wr := range want.Wreckages {
+		g := got.Wreckages[i]
+		if g.ID != wr.ID {
+			t.Errorf("wreck %d = %+v, want %+v", i, g, wr)
+		}
+		near("wreck y", g.PosY, wr.PosY, 0.5/positionScale)
+		near("wreck width", g.Width, wr.Width, 0.5/positionScale)
+		near("wreck metal", g.Metal, wr.Metal, 0.5/healthScale)
+	}
+
+	for i, d := range want.Deposits {
+		g := got.Deposits[i]
+		if g.X != d.X || g.Y != d.Y {
+			t.Errorf("deposit %d = %+v, want %+v", i, g, d)
+		}
+		near("deposit amount", g.Amount, d.Amount, 0.5/healthScale)
+	}
+
+	for i, e := range want.Effects {
+		g := got.Effects[i]
+		if g.Ability != e.Ability || g.OwnerSlot != e.OwnerSlot {
+			t.Errorf("effect %d = %+v, want %+v", i, g, e)
+		}
+		near("effect y", g.Y, e.Y, 0.5/positionScale)
+		near("effect remaining", g.Remaining, e.Remaining, 0.5/timeScale)
+	}
+}
+
+func TestDecodeGameStateTruncated(t *testing.T) {
+	state := sampleGameState()
+	frame := EncodeGameState(&state)
+	for n := 0; n < len(frame); n++ {
+		if _, err := DecodeGameState(frame[:n]); err == nil {
+			t.Fatalf("decoding %d of %d bytes succeeded", n, len(frame))
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		offered []string
+		want    string
+	}{
+		{nil, EncodingJSON},
+		{[]string{EncodingBinary, EncodingJSON}, EncodingBinary},
+		{[]string{EncodingJSON, EncodingBinary}, EncodingJSON},
+		{[]string{"msgpack", EncodingBinary}, EncodingBinary},
+	}
+	for _, tt := range tests {
+		if got := NegotiateEncoding(tt.offered); got != tt.want {
+			t.Errorf("NegotiateEncoding(%v) = %q, want %q", tt.offered, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkGameStateJSON(b *testing.B) {
+	state := largeGameState(300, 60, 80)
+	b.ReportAllocs()
+	var size int
+	for i := 0; i < b.N; i++ {
+		msg, err := NewMessage(MsgGameState, state)
+		if err != nil {
+			b.Fatal(err)
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func BenchmarkGameStateBinary(b *testing.B) {
+	state := largeGameState(300, 60, 80)
+	b.ReportAllocs()
+	var size int
+	for i := 0; i < b.N; i++ {
+		size = len(EncodeGameState(state))
+	}
+	b.ReportMetric(float64(size), "bytes/msg")
+}
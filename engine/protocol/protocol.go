@@ -0,0 +1,371 @@
+// Package protocol defines the WebSocket messages exchanged between the
+// multiplayer server and game clients. Both sides import it, so the wire
+// format is declared exactly once.
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the wire protocol version. Bump it whenever a message or
+// payload changes in a way older peers cannot read.
+const Version = 15
+
+// MessageType identifies the type of WebSocket message
+type MessageType string
+
+const (
+	// Client -> Server messages
+	MsgHello       MessageType = "hello"
+	MsgSetName     MessageType = "set_name"
+	MsgCreateLobby MessageType = "create_lobby"
+	MsgJoinLobby   MessageType = "join_lobby"
+	MsgLeaveLobby  MessageType = "leave_lobby"
+	MsgListLobbies MessageType = "list_lobbies"
+	MsgSetReady    MessageType = "set_ready"
+	MsgStartGame   MessageType = "start_game"
+	MsgGameCommand MessageType = "game_command" // Refused with an error while the game is paused
+	MsgPauseGame   MessageType = "pause_game"
+	MsgResumeGame  MessageType = "resume_game"
+
+	// Server -> Client messages
+	MsgWelcome      MessageType = "welcome"
+	MsgLobbyList    MessageType = "lobby_list"
+	MsgLobbyCreated MessageType = "lobby_created"
+	MsgLobbyJoined  MessageType = "lobby_joined"
+	MsgLobbyLeft    MessageType = "lobby_left"
+	MsgLobbyUpdate  MessageType = "lobby_update"
+	MsgGameStarting MessageType = "game_starting"
+	MsgGameState    MessageType = "game_state"
+	MsgGameEnd      MessageType = "game_end"
+	MsgError        MessageType = "error"
+)
+
+// Message is the envelope for all WebSocket communication
+type Message struct {
+	Type    MessageType     `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewMessage creates a message with the given type and payload
+func NewMessage(msgType MessageType, payload interface{}) (Message, error) {
+	var raw json.RawMessage
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return Message{}, err
+		}
+		raw = data
+	}
+	return Message{Type: msgType, Payload: raw}, nil
+}
+
+// Decode unmarshals the message payload into v
+func (m Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+// CheckVersion reports why a peer speaking the given protocol version
+// cannot talk to this build, or nil if it can
+func CheckVersion(version int) error {
+	switch {
+	case version == Version:
+		return nil
+	case version <= 0:
+		return fmt.Errorf("client did not announce a protocol version; server requires version %d, please update your game", Version)
+	case version < Version:
+		return fmt.Errorf("client protocol version %d is older than server version %d, please update your game", version, Version)
+	default:
+		return fmt.Errorf("client protocol version %d is newer than server version %d, the server needs to be updated", version, Version)
+	}
+}
+
+// CommandType identifies game commands
+type CommandType string
+
+const (
+	CmdMove             CommandType = "move"
+	CmdAttack           CommandType = "attack"
+	CmdAttackMove       CommandType = "attack_move"
+	CmdPatrol           CommandType = "patrol"
+	CmdGuard            CommandType = "guard"
+	CmdStop             CommandType = "stop"
+	CmdRepair           CommandType = "repair"
+	CmdReclaim          CommandType = "reclaim"
+	CmdReclaimArea      CommandType = "reclaim_area"
+	CmdPlaceBuilding    CommandType = "place_building"
+	CmdProduceUnit      CommandType = "produce_unit"
+	CmdCancelProduction CommandType = "cancel_production"
+	CmdResearch         CommandType = "research"
+	CmdCancelResearch   CommandType = "cancel_research"
+	CmdSetRallyPoint    CommandType = "set_rally"
+	CmdSetStance        CommandType = "set_stance"
+	CmdSetPowerPriority CommandType = "set_power_priority"
+	CmdSetPriority      CommandType = "set_priority"
+	CmdRecycle          CommandType = "recycle"
+	CmdLoad             CommandType = "load"
+	CmdUnload           CommandType = "unload"
+	CmdUnloadAll        CommandType = "unload_all"
+	CmdUseAbility       CommandType = "use_ability"
+)
+
+// GameCommand represents a player action in the game
+type GameCommand struct {
+	Type         CommandType `json:"type"`
+	UnitIDs      []uint64    `json:"unitIds,omitempty"`
+	BuildingID   uint64      `json:"buildingId,omitempty"`
+	TargetX      float64     `json:"x,omitempty"`
+	TargetY      float64     `json:"y,omitempty"`
+	EndX         float64     `json:"x2,omitempty"` // Far corner of an area command
+	EndY         float64     `json:"y2,omitempty"`
+	TargetID     uint64      `json:"targetId,omitempty"`
+	BuildingType int         `json:"buildingType,omitempty"`
+	UnitType     int         `json:"unitType,omitempty"`
+	Research     int         `json:"research,omitempty"`  // entity.ResearchType of a research command
+	Formation    string      `json:"formation,omitempty"` // "line", "box" or "wedge" for group moves
+	Facing       float64     `json:"facing,omitempty"`    // Facing of a group move in radians
+	HasFacing    bool        `json:"hasFacing,omitempty"`
+	Stance       int         `json:"stance,omitempty"`   // 0 aggressive, 1 defensive, 2 hold position, 3 hold fire
+	Power        int         `json:"power,omitempty"`    // entity.PowerClass to power first, 0 for balanced
+	Priority     int         `json:"priority,omitempty"` // entity.Priority of a building, 0 normal, 1 high, 2 paused
+	Ability      int         `json:"ability,omitempty"`  // entity.AbilityType of a use_ability command
+	Queue        bool        `json:"queue,omitempty"`    // Append to the units' order queues
+}
+
+// Client -> Server payloads
+
+// HelloPayload opens the handshake; the server answers with Welcome
+type HelloPayload struct {
+	Version      int      `json:"version"`
+	Name         string   `json:"name,omitempty"`
+	Encodings    []string `json:"encodings,omitempty"`    // Game state encodings, preferred first
+	SessionToken string   `json:"sessionToken,omitempty"` // From an earlier Welcome, to rejoin a match in progress
+}
+
+type SetNamePayload struct {
+	Name string `json:"name"`
+}
+
+type CreateLobbyPayload struct {
+	Name       string `json:"name"`
+	MaxPlayers int    `json:"maxPlayers"`
+}
+
+type JoinLobbyPayload struct {
+	LobbyID string `json:"lobbyId"`
+}
+
+type SetReadyPayload struct {
+	Ready bool `json:"ready"`
+}
+
+type GameCommandPayload struct {
+	Command GameCommand `json:"command"`
+}
+
+// Server -> Client payloads
+
+// WelcomePayload answers Hello. When the handshake is rejected PlayerID is
+// empty, Reason explains why and the server closes the connection.
+type WelcomePayload struct {
+	PlayerID     string `json:"playerId,omitempty"`
+	Version      int    `json:"version"`
+	Encoding     string `json:"encoding,omitempty"`     // Encoding used for game state messages
+	SessionToken string `json:"sessionToken,omitempty"` // Secret the client presents to rejoin after a disconnect
+	Reason       string `json:"reason,omitempty"`
+}
+
+// Accepted reports whether the server accepted the handshake
+func (w WelcomePayload) Accepted() bool {
+	return w.Reason == ""
+}
+
+type LobbyInfo struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	HostID     string       `json:"hostId"`
+	HostName   string       `json:"hostName"`
+	Players    []PlayerInfo `json:"players"`
+	MaxPlayers int          `json:"maxPlayers"`
+	State      string       `json:"state"` // "waiting", "playing", "finished"
+}
+
+type PlayerInfo struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Faction int    `json:"faction"`
+	Alive   bool   `json:"alive"`
+}
+
+type LobbyListPayload struct {
+	Lobbies []LobbyInfo `json:"lobbies"`
+}
+
+type LobbyCreatedPayload struct {
+	Lobby LobbyInfo `json:"lobby"`
+}
+
+type LobbyJoinedPayload struct {
+	Lobby LobbyInfo `json:"lobby"`
+}
+
+type LobbyUpdatePayload struct {
+	Lobby LobbyInfo `json:"lobby"`
+}
+
+type GameStartingPayload struct {
+	Lobby    LobbyInfo `json:"lobby"`
+	YourSlot int       `json:"yourSlot"` // 0-3, determines spawn position
+}
+
+type ErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// Game state payloads
+
+type UnitState struct {
+	ID          uint64       `json:"id"`
+	Type        int          `json:"type"`
+	OwnerSlot   int          `json:"owner"` // Player slot 0-3
+	PosX        float64      `json:"x"`
+	PosY        float64      `json:"y"`
+	Health      float64      `json:"hp"`
+	MaxHealth   float64      `json:"maxHp"`
+	Angle       float64      `json:"angle"`
+	TurretAngle float64      `json:"turretAngle"`
+	HasTarget   bool         `json:"hasTarget,omitempty"`
+	TargetX     float64      `json:"tx,omitempty"`
+	TargetY     float64      `json:"ty,omitempty"`
+	Stance      int          `json:"stance,omitempty"`
+	Rank        int          `json:"rank,omitempty"`      // Veterancy rank, 0 for rookies
+	Orders      []OrderState `json:"orders,omitempty"`    // Queued orders, drawn for selected units
+	Cargo       []CargoState `json:"cargo,omitempty"`     // Units aboard a transport
+	Cooldowns   []float64    `json:"cooldowns,omitempty"` // Seconds until each entity.AbilityType is ready
+	BoostLeft   float64      `json:"boost,omitempty"`     // Seconds left of a speed boost
+}
+
+// CargoState is a unit carried by a transport
+type CargoState struct {
+	ID        uint64  `json:"id"`
+	Type      int     `json:"type"`
+	Health    float64 `json:"hp"`
+	MaxHealth float64 `json:"maxHp"`
+}
+
+// OrderState is a queued unit order as far as clients need to draw it
+type OrderState struct {
+	Type int     `json:"type"` // 0 move, 1 attack-move, 2 attack, 3 patrol, 4 guard, 5 reclaim, 6 load, 7 unload
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+type BuildingState struct {
+	ID            uint64  `json:"id"`
+	Type          int     `json:"type"`
+	OwnerSlot     int     `json:"owner"` // Player slot 0-3
+	PosX          float64 `json:"x"`
+	PosY          float64 `json:"y"`
+	Health        float64 `json:"hp"`
+	MaxHealth     float64 `json:"maxHp"`
+	Completed     bool    `json:"done"`
+	BuildProgress float64 `json:"progress,omitempty"`
+	Producing     bool    `json:"producing,omitempty"`
+	ProdProgress  float64 `json:"prodProgress,omitempty"`
+	ProdType      int     `json:"prodType,omitempty"`
+	Yield         float64 `json:"yield,omitempty"`   // Extractors: metal per second drawn from the deposit
+	Deposit       float64 `json:"deposit,omitempty"` // Extractors: metal left in the deposit underneath
+	Researching   bool    `json:"researching,omitempty"`
+	ResProgress   float64 `json:"resProgress,omitempty"`
+	ResType       int     `json:"resType,omitempty"`
+	PowerDeficit  float64 `json:"powerDeficit,omitempty"` // Share of its energy needs the grid leaves unmet
+	Priority      int     `json:"priority,omitempty"`     // entity.Priority of its work
+	Recycling     bool    `json:"recycling,omitempty"`
+	RecycleProg   float64 `json:"recycleProgress,omitempty"`
+}
+
+type ProjectileState struct {
+	ID        uint64  `json:"id"`
+	OwnerSlot int     `json:"owner"`
+	PosX      float64 `json:"x"`
+	PosY      float64 `json:"y"`
+	TargetX   float64 `json:"tx"` // 0, 0 when homing in on a unit hidden from the receiver
+	TargetY   float64 `json:"ty"`
+	Weapon    int     `json:"weapon,omitempty"` // entity.WeaponType that fired it
+}
+
+// WreckageState is a wreck left by a destroyed unit or building
+type WreckageState struct {
+	ID     uint64  `json:"id"`
+	PosX   float64 `json:"x"`
+	PosY   float64 `json:"y"`
+	Width  float64 `json:"w"`
+	Height float64 `json:"h"`
+	Metal  float64 `json:"metal"` // Metal left to reclaim
+}
+
+// DepositState is the metal left in one deposit tile. Deposit tiles missing
+// from a game state are mined out.
+type DepositState struct {
+	X      int     `json:"x"`
+	Y      int     `json:"y"`
+	Amount float64 `json:"amount"`
+}
+
+// EffectState is an ability area on the map, such as smoke or a sweep
+type EffectState struct {
+	Ability   int     `json:"ability"` // entity.AbilityType that made it
+	OwnerSlot int     `json:"owner"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Remaining float64 `json:"remaining"` // Seconds left
+}
+
+type ResourceStateNet struct {
+	Metal      float64 `json:"metal"`
+	MetalCap   float64 `json:"metalCap"`
+	MetalProd  float64 `json:"metalProd"`
+	Energy     float64 `json:"energy"`
+	EnergyCap  float64 `json:"energyCap"`
+	EnergyProd float64 `json:"energyProd"`
+}
+
+type PlayerGameState struct {
+	Slot       int              `json:"slot"`
+	Name       string           `json:"name"`
+	Alive      bool             `json:"alive"`
+	PausesLeft int              `json:"pausesLeft"`
+	Resources  ResourceStateNet `json:"resources"`
+	Research   []int            `json:"research,omitempty"`   // Finished entity.ResearchType values
+	PowerFirst int              `json:"powerFirst,omitempty"` // entity.PowerClass the grid powers first
+	PowerLevel float64          `json:"powerLevel"`           // Share of energy demand the grid covers
+}
+
+// PauseState describes a running pause. Times are in seconds.
+type PauseState struct {
+	BySlot      int     `json:"bySlot"` // -1 when the server paused the game
+	ByName      string  `json:"byName"`
+	Elapsed     float64 `json:"elapsed"`
+	ResumeIn    float64 `json:"resumeIn,omitempty"` // Countdown to resume, 0 while waiting for votes
+	ResumeVotes []int   `json:"resumeVotes,omitempty"`
+}
+
+type GameStatePayload struct {
+	Tick        uint64            `json:"tick"`
+	Pause       *PauseState       `json:"pause,omitempty"` // Set while the game is paused
+	Players     []PlayerGameState `json:"players"`
+	Units       []UnitState       `json:"units"`
+	Buildings   []BuildingState   `json:"buildings"`
+	Projectiles []ProjectileState `json:"projectiles"`
+	Wreckages   []WreckageState   `json:"wreckages,omitempty"`
+	Deposits    []DepositState    `json:"deposits,omitempty"`
+	Effects     []EffectState     `json:"effects,omitempty"`
+}
+
+type GameEndPayload struct {
+	WinnerSlot int    `json:"winnerSlot"`
+	WinnerName string `json:"winnerName"`
+	Reason     string `json:"reason"` // "last_standing", "surrender"
+}
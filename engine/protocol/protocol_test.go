@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleGameState() GameStatePayload {
+	return GameStatePayload{
+		Tick:  1234,
+		Pause: &PauseState{BySlot: 1, ByName: "bob", Elapsed: 12.5, ResumeIn: 2, ResumeVotes: []int{0, 1}},
+		Players: []PlayerGameState{
+			{Slot: 0, Name: "alice", Alive: true, PausesLeft: 2, Resources: ResourceStateNet{Metal: 500, MetalCap: 2000, MetalProd: 2.5, Energy: 80, EnergyCap: 200, EnergyProd: -1}, Research: []int{0, 4}, PowerFirst: 1, PowerLevel: 0.75},
+			{Slot: 1, Name: "bob", Alive: false},
+		},
+		Units: []UnitState{
+			{ID: 7, Type: 2, OwnerSlot: 1, PosX: 410.5, PosY: 320.25, Health: 80, MaxHealth: 100, Angle: 1.5, TurretAngle: -0.5, HasTarget: true, TargetX: 900, TargetY: 100, Stance: 2, Rank: 1, Orders: []OrderState{{Type: 1, X: 50, Y: 60}}, Cooldowns: []float64{0, 12.5, 0, 0}, BoostLeft: 3.5},
+			{ID: 8, Type: 14, OwnerSlot: 1, PosX: 600, PosY: 320, Health: 160, MaxHealth: 160, Cargo: []CargoState{{ID: 9, Type: 2, Health: 75, MaxHealth: 100}, {ID: 10, Type: 1, Health: 80, MaxHealth: 80}}},
+		},
+		Buildings: []BuildingState{
+			{ID: 3, Type: 0, OwnerSlot: 0, PosX: 400, PosY: 300, Health: 1000, MaxHealth: 1000, Completed: true},
+			{ID: 4, Type: 5, OwnerSlot: 1, PosX: 800, PosY: 300, Health: 50, MaxHealth: 500, BuildProgress: 0.4, Producing: true, ProdProgress: 0.25, ProdType: 2, Priority: 2},
+			{ID: 5, Type: 9, OwnerSlot: 0, PosX: 200, PosY: 300, Health: 250, MaxHealth: 250, Completed: true, BuildProgress: 1, Yield: 15, Deposit: 1875.5, Recycling: true, RecycleProg: 0.3},
+			{ID: 6, Type: 7, OwnerSlot: 0, PosX: 100, PosY: 100, Health: 150, MaxHealth: 150, Completed: true, BuildProgress: 1, Researching: true, ResProgress: 0.6, ResType: 3, PowerDeficit: 0.25},
+		},
+		Projectiles: []ProjectileState{
+			{ID: 99, OwnerSlot: 0, PosX: 1, PosY: 2, TargetX: 3, TargetY: 4, Weapon: 2},
+		},
+		Wreckages: []WreckageState{
+			{ID: 0, PosX: 640, PosY: 480.5, Width: 40, Height: 30, Metal: 37.5},
+		},
+		Deposits: []DepositState{
+			{X: 8, Y: 12, Amount: 1875.5},
+		},
+		Effects: []EffectState{
+			{Ability: 1, OwnerSlot: 1, X: 700, Y: 410.5, Remaining: 7.5},
+		},
+	}
+}
+
+// roundTrip encodes payload the way one side sends it and decodes it the
+// way the other side reads it
+func roundTrip(t *testing.T, msgType MessageType, payload interface{}) interface{} {
+	t.Helper()
+
+	msg, err := NewMessage(msgType, payload)
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.Type != msgType {
+		t.Fatalf("type = %q, want %q", decoded.Type, msgType)
+	}
+
+	out := reflect.New(reflect.TypeOf(payload))
+	if err := decoded.Decode(out.Interface()); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return out.Elem().Interface()
+}
+
+func TestRoundTrip(t *testing.T) {
+	lobby := LobbyInfo{
+		ID:         "abc",
+		Name:       "test",
+		HostID:     "p1",
+		HostName:   "alice",
+		Players:    []PlayerInfo{{ID: "p1", Name: "alice", Ready: true, Faction: 0, Alive: true}},
+		MaxPlayers: 4,
+		State:      "waiting",
+	}
+
+	tests := []struct {
+		name    string
+		msgType MessageType
+		payload interface{}
+	}{
+		{"hello", MsgHello, HelloPayload{Version: Version, Name: "alice"}},
+		{"welcome", MsgWelcome, WelcomePayload{PlayerID: "p1", Version: Version, SessionToken: "secret"}},
+		{"rejoin hello", MsgHello, HelloPayload{Version: Version, Name: "alice", SessionToken: "secret"}},
+		{"welcome rejected", MsgWelcome, WelcomePayload{Version: Version, Reason: "too old"}},
+		{"set name", MsgSetName, SetNamePayload{Name: "alice"}},
+		{"create lobby", MsgCreateLobby, CreateLobbyPayload{Name: "test", MaxPlayers: 2}},
+		{"join lobby", MsgJoinLobby, JoinLobbyPayload{LobbyID: "abc"}},
+		{"set ready", MsgSetReady, SetReadyPayload{Ready: true}},
+		{"command", MsgGameCommand, GameCommandPayload{Command: GameCommand{
+			Type: CmdMove, UnitIDs: []uint64{1, 2, 3}, TargetX: 10, TargetY: 20,
+			Formation: "wedge", Facing: 1.5, HasFacing: true,
+		}}},
+		{"ability command", MsgGameCommand, GameCommandPayload{Command: GameCommand{
+			Type: CmdUseAbility, UnitIDs: []uint64{4}, TargetX: 300, TargetY: 200, Ability: 1,
+		}}},
+		{"lobby list", MsgLobbyList, LobbyListPayload{Lobbies: []LobbyInfo{lobby}}},
+		{"lobby created", MsgLobbyCreated, LobbyCreatedPayload{Lobby: lobby}},
+		{"game starting", MsgGameStarting, GameStartingPayload{Lobby: lobby, YourSlot: 1}},
+		{"game state", MsgGameState, sampleGameState()},
+		{"game end", MsgGameEnd, GameEndPayload{WinnerSlot: 1, WinnerName: "bob", Reason: "last_standing"}},
+		{"error", MsgError, ErrorPayload{Message: "nope"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundTrip(t, tt.msgType, tt.payload)
+			if !reflect.DeepEqual(got, tt.payload) {
+				t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", got, tt.payload)
+			}
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	if err := CheckVersion(Version); err != nil {
+		t.Fatalf("same version rejected: %v", err)
+	}
+
+	for _, v := range []int{0, Version - 1, Version + 1} {
+		err := CheckVersion(v)
+		if err == nil {
+			t.Errorf("version %d accepted, want rejection", v)
+			continue
+		}
+		if !strings.Contains(err.Error(), "version") {
+			t.Errorf("version %d: reason %q does not explain the mismatch", v, err)
+		}
+	}
+}
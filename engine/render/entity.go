@@ -33,22 +33,60 @@ func (er *EntityRenderer) DrawUnit(screen *ebiten.Image, u *entity.Unit, screenP
 		def = entity.UnitDefs[u.Type]
 	}
 
-	// Check if this unit has hull+gun sprites (tank-style rendering)
-	if def != nil && def.HasTurret() {
-		er.drawTank(screen, u, screenCenter, zoom)
-		return
+	if def != nil && def.IsAircraft() {
+		er.drawAircraftShadow(screen, screenPos, scaledSize, zoom)
 	}
 
-	// Check for single sprite
+	var sprite *ebiten.Image
 	if def != nil && def.SpritePath != "" {
-		sprite := er.assets.GetSprite(def.SpritePath)
-		if sprite != nil {
-			er.drawUnitSprite(screen, sprite, u, screenCenter, zoom)
-			return
-		}
+		sprite = er.assets.GetSprite(def.SpritePath)
+	}
+	switch {
+	case def != nil && def.HasTurret():
+		// Hull and gun sprites, tank-style rendering
+		er.drawTank(screen, u, screenCenter, zoom)
+	case sprite != nil:
+		er.drawUnitSprite(screen, sprite, u, screenCenter, zoom)
+	default:
+		er.drawUnitFallback(screen, u, screenPos, screenCenter, scaledSize, zoom)
 	}
 
-	er.drawUnitFallback(screen, u, screenPos, screenCenter, scaledSize, zoom)
+	er.drawRank(screen, u.Rank, screenPos, zoom)
+}
+
+// drawRank draws one chevron per veterancy rank stacked down the unit's
+// left side, clear of the health bar above it
+func (er *EntityRenderer) drawRank(screen *ebiten.Image, rank entity.Rank, screenPos emath.Vec2, zoom float64) {
+	if rank <= entity.RankRookie {
+		return
+	}
+	chevronColor := color.RGBA{255, 215, 60, 255}
+	if rank >= entity.RankHeroic {
+		chevronColor = color.RGBA{255, 140, 40, 255}
+	}
+	width := max(6*zoom, 4)
+	height := width / 2
+	spacing := height + max(2*zoom, 1.5)
+	stroke := float32(max(1.5*zoom, 1))
+	left := screenPos.X - width - 2*zoom
+	top := screenPos.Y + height
+	for i := 0; i < int(rank); i++ {
+		y := top + float64(i)*spacing
+		tip := emath.Vec2{X: left + width/2, Y: y - height}
+		er.renderer.DrawLine(screen, emath.Vec2{X: left, Y: y}, tip, stroke, chevronColor)
+		er.renderer.DrawLine(screen, tip, emath.Vec2{X: left + width, Y: y}, stroke, chevronColor)
+	}
+}
+
+// drawAircraftShadow draws a shadow on the ground below and behind an
+// aircraft so it reads as flying above the map
+func (er *EntityRenderer) drawAircraftShadow(screen *ebiten.Image, screenPos, scaledSize emath.Vec2, zoom float64) {
+	offset := 10 * zoom
+	shadow := emath.Rect{
+		Pos:  emath.Vec2{X: screenPos.X + offset, Y: screenPos.Y + offset},
+		Size: scaledSize.Mul(0.8),
+	}
+	er.renderer.DrawRect(screen, shadow, color.RGBA{0, 0, 0, 70})
 }
 
 func (er *EntityRenderer) drawUnitSprite(screen *ebiten.Image, sprite *ebiten.Image, u *entity.Unit, screenCenter emath.Vec2, zoom float64) {
@@ -36,10 +36,11 @@ type Resource struct {
 	Production        float64
 	Consumption       float64
 	ConstructionDrain float64
+	Extraction        float64 // Per second drawn from finite deposits, worked out again every tick
 }
 
 func (r *Resource) NetFlow() float64 {
-	return r.Production - r.Consumption
+	return r.Production + r.Extraction - r.Consumption
 }
 func (r *Resource) Update(delta float64) {
 	r.Current += r.NetFlow() * delta
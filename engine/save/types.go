@@ -31,6 +31,10 @@ type GameState struct {
 	Units        []UnitState     `yaml:"units"`
 	Buildings    []BuildingState `yaml:"buildings"`
 	Wreckages    []WreckageState `yaml:"wreckages"`
+	Deposits     []DepositState  `yaml:"deposits"`
+	Research     []ResearchState `yaml:"research,omitempty"`
+	Power        []PowerState    `yaml:"power,omitempty"`
+	Effects      []EffectState   `yaml:"effects,omitempty"`
 	FogOfWar     FogState        `yaml:"fog_of_war"`
 	EnemyAI      AIState         `yaml:"enemy_ai"`
 	MissionState MissionState    `yaml:"mission_state,omitempty"`
@@ -40,6 +44,46 @@ type GameState struct {
 	GameTime     float64         `yaml:"game_time"`
 }
 
+// WorldState is the serialized form of a simulation world: its entities,
+// ID counters and tick. Resources are stored by the caller, which knows
+// how factions map to players.
+type WorldState struct {
+	Tick             uint64 `yaml:"tick"`
+	NextUnitID       uint64 `yaml:"next_unit_id"`
+	NextBuildingID   uint64 `yaml:"next_building_id"`
+	NextWreckageID   uint64 `yaml:"next_wreckage_id"`
+	NextProjectileID uint64 `yaml:"next_projectile_id"`
+
+	Units     []UnitState     `yaml:"units"`
+	Buildings []BuildingState `yaml:"buildings"`
+	Wreckages []WreckageState `yaml:"wreckages"`
+	Deposits  []DepositState  `yaml:"deposits"` // Nil in saves made before deposits could run out
+	Research  []ResearchState `yaml:"research,omitempty"`
+	Power     []PowerState    `yaml:"power,omitempty"`
+	Effects   []EffectState   `yaml:"effects,omitempty"`
+}
+
+// ResearchState is the research one faction has finished
+type ResearchState struct {
+	Faction entity.Faction        `yaml:"faction"`
+	Done    []entity.ResearchType `yaml:"done"`
+}
+
+// PowerState is the power priority one faction has set
+type PowerState struct {
+	Faction entity.Faction    `yaml:"faction"`
+	First   entity.PowerClass `yaml:"first"`
+}
+
+// EffectState is an ability area still on the map
+type EffectState struct {
+	Ability   entity.AbilityType `yaml:"ability"`
+	Faction   entity.Faction     `yaml:"faction"`
+	X         float64            `yaml:"x"`
+	Y         float64            `yaml:"y"`
+	Remaining float64            `yaml:"remaining"`
+}
+
 type ResourcesState struct {
 	Metal  ResourceState `yaml:"metal"`
 	Energy ResourceState `yaml:"energy"`
@@ -69,6 +113,14 @@ type UnitState struct {
 
 	AttackTargetID         uint64 `yaml:"attack_target_id,omitempty"`
 	BuildingAttackTargetID uint64 `yaml:"building_attack_target_id,omitempty"`
+	AttackOrdered          bool   `yaml:"attack_ordered,omitempty"`
+
+	Stance entity.Stance `yaml:"stance,omitempty"`
+	PostX  float64       `yaml:"post_x,omitempty"`
+	PostY  float64       `yaml:"post_y,omitempty"`
+
+	Experience float64     `yaml:"experience,omitempty"`
+	Rank       entity.Rank `yaml:"rank,omitempty"`
 
 	HasBuildTask  bool                `yaml:"has_build_task,omitempty"`
 	BuildDefType  entity.BuildingType `yaml:"build_def_type,omitempty"`
@@ -78,9 +130,16 @@ type UnitState struct {
 	IsBuilding    bool                `yaml:"is_building,omitempty"`
 
 	BuildQueue []BuildTaskState `yaml:"build_queue,omitempty"`
+	Orders     []OrderState     `yaml:"orders,omitempty"`
 
 	RepairTargetID uint64  `yaml:"repair_target_id,omitempty"`
 	FireCooldown   float64 `yaml:"fire_cooldown,omitempty"`
+
+	Cooldowns []float64 `yaml:"cooldowns,omitempty"` // Seconds left per ability type
+	Boost     float64   `yaml:"boost,omitempty"`
+	BoostLeft float64   `yaml:"boost_left,omitempty"`
+
+	Cargo []UnitState `yaml:"cargo,omitempty"` // Units aboard a transport
 }
 
 type BuildTaskState struct {
@@ -89,6 +148,17 @@ type BuildTaskState struct {
 	PosY    float64             `yaml:"pos_y"`
 }
 
+// OrderState is one entry of a unit's order queue. Attack, guard, load and
+// reclaim orders name their target by ID.
+type OrderState struct {
+	Type             entity.OrderType `yaml:"type"`
+	PosX             float64          `yaml:"pos_x,omitempty"`
+	PosY             float64          `yaml:"pos_y,omitempty"`
+	TargetID         uint64           `yaml:"target_id,omitempty"`
+	TargetBuildingID uint64           `yaml:"target_building_id,omitempty"`
+	WreckID          uint64           `yaml:"wreck_id,omitempty"`
+}
+
 type BuildingState struct {
 	ID       uint64              `yaml:"id"`
 	Type     entity.BuildingType `yaml:"type"`
@@ -98,10 +168,11 @@ type BuildingState struct {
 	Health   float64             `yaml:"health"`
 	Selected bool                `yaml:"selected,omitempty"`
 
-	Completed     bool    `yaml:"completed"`
-	BuildProgress float64 `yaml:"build_progress"`
-	MetalSpent    float64 `yaml:"metal_spent,omitempty"`
-	EnergySpent   float64 `yaml:"energy_spent,omitempty"`
+	Completed     bool            `yaml:"completed"`
+	BuildProgress float64         `yaml:"build_progress"`
+	MetalSpent    float64         `yaml:"metal_spent,omitempty"`
+	EnergySpent   float64         `yaml:"energy_spent,omitempty"`
+	Priority      entity.Priority `yaml:"priority,omitempty"`
 
 	Producing             bool              `yaml:"producing,omitempty"`
 	ProductionProgress    float64           `yaml:"production_progress,omitempty"`
@@ -110,6 +181,16 @@ type BuildingState struct {
 	ProductionEnergySpent float64           `yaml:"production_energy_spent,omitempty"`
 	ProductionQueue       []entity.UnitType `yaml:"production_queue,omitempty"`
 
+	Researching         bool                  `yaml:"researching,omitempty"`
+	ResearchProgress    float64               `yaml:"research_progress,omitempty"`
+	CurrentResearchType entity.ResearchType   `yaml:"current_research_type,omitempty"`
+	ResearchMetalSpent  float64               `yaml:"research_metal_spent,omitempty"`
+	ResearchEnergySpent float64               `yaml:"research_energy_spent,omitempty"`
+	ResearchQueue       []entity.ResearchType `yaml:"research_queue,omitempty"`
+
+	Recycling       bool    `yaml:"recycling,omitempty"`
+	RecycleProgress float64 `yaml:"recycle_progress,omitempty"`
+
 	RallyPointX   float64 `yaml:"rally_point_x,omitempty"`
 	RallyPointY   float64 `yaml:"rally_point_y,omitempty"`
 	HasRallyPoint bool    `yaml:"has_rally_point,omitempty"`
@@ -127,6 +208,13 @@ type WreckageState struct {
 	MetalValue float64 `yaml:"metal_value"`
 }
 
+// DepositState is the metal left in one deposit tile
+type DepositState struct {
+	X      int     `yaml:"x"`
+	Y      int     `yaml:"y"`
+	Amount float64 `yaml:"amount"`
+}
+
 type FogState struct {
 	Width    int      `yaml:"width"`
 	Height   int      `yaml:"height"`
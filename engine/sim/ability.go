@@ -0,0 +1,80 @@
+package sim
+
+import (
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/resource"
+)
+
+// useAbility carries out an ability command. Abilities a unit uses on
+// itself fire for every commanded unit that has them ready; point and unit
+// abilities are used once, by the closest ready unit in range.
+func (w *World) useAbility(cmd Command, target emath.Vec2) {
+	def := entity.AbilityDefs[cmd.Ability]
+	if def == nil {
+		return
+	}
+	var targetUnit *entity.Unit
+	if def.Target == entity.AbilityTargetUnit {
+		if targetUnit = w.Unit(cmd.TargetID); targetUnit == nil {
+			return
+		}
+		target = targetUnit.Center()
+	}
+	energy := w.Resources(cmd.Faction).Get(resource.Energy)
+
+	var caster *entity.Unit
+	bestDist := 0.0
+	for _, u := range w.ownedUnits(cmd) {
+		if !u.AbilityReady(def.Type) {
+			continue
+		}
+		if def.Target == entity.AbilityTargetSelf {
+			if energy.Current >= def.EnergyCost {
+				w.cast(u, def, target, nil)
+			}
+			continue
+		}
+		if targetUnit != nil && !u.CanAbilityTarget(def, targetUnit) {
+			continue
+		}
+		if dist := u.Center().Distance(target); dist <= def.Range && (caster == nil || dist < bestDist) {
+			caster, bestDist = u, dist
+		}
+	}
+	if caster != nil && energy.Current >= def.EnergyCost {
+		w.cast(caster, def, target, targetUnit)
+	}
+}
+
+// cast pays for an ability used by u and applies it at pos, or to target
+// for unit abilities
+func (w *World) cast(u *entity.Unit, def *entity.AbilityDef, pos emath.Vec2, target *entity.Unit) {
+	w.Resources(u.Faction).Get(resource.Energy).Spend(def.EnergyCost)
+	u.UseAbility(def)
+	if def.IsArea() {
+		w.Effects = append(w.Effects, entity.NewAreaEffect(def, u.Faction, pos))
+	}
+	if target != nil && def.Heal > 0 {
+		target.Health = min(target.MaxHealth, target.Health+def.Heal)
+	}
+}
+
+// updateAbilities counts down unit cooldowns and boosts, and removes
+// areas that have run out
+func (w *World) updateAbilities(dt float64) {
+	for _, u := range w.Units {
+		u.UpdateAbilities(dt)
+		for _, c := range u.Cargo {
+			c.UpdateAbilities(dt)
+		}
+	}
+	alive := w.Effects[:0]
+	for _, e := range w.Effects {
+		if e.Update(dt) {
+			alive = append(alive, e)
+		}
+	}
+	clear(w.Effects[len(alive):])
+	w.Effects = alive
+}
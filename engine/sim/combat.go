@@ -0,0 +1,301 @@
+package sim
+
+import (
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/resource"
+)
+
+// updateCombat handles target acquisition, pursuit and firing
+func (w *World) updateCombat(dt float64) {
+	for _, u := range w.Units {
+		if !u.Active || !u.CanAttack() {
+			continue
+		}
+		// An idle unit guards the spot it stands on
+		if !u.HasTarget && !u.HasAnyAttackTarget() {
+			u.Post = u.Center()
+		}
+
+		// Check if current targets are still valid (clear if dead or out of pursuit range)
+		if u.AttackTarget != nil {
+			if !u.AttackTarget.Active || !u.IsInPursuitRange(u.AttackTarget) || w.Concealed(u.AttackTarget, u.Faction) {
+				u.AttackTarget = nil
+			}
+		}
+		if u.BuildingAttackTarget != nil {
+			if !u.BuildingAttackTarget.Active || !u.IsBuildingInPursuitRange(u.BuildingAttackTarget) {
+				u.BuildingAttackTarget = nil
+			}
+		}
+		if !u.HasAnyAttackTarget() {
+			u.AttackOrdered = false
+		} else if !u.AttackOrdered && (!stanceAllowsTarget(u) || u.TooClose(attackPoint(u))) {
+			u.ClearAttackTarget()
+			// Without an order of its own the unit was only chasing
+			if u.Stance == entity.StanceDefensive && u.CurrentOrder() == nil {
+				u.SetTarget(u.Post)
+			}
+		}
+
+		// Find new target if needed (only look within fire range for new targets)
+		if !u.HasAnyAttackTarget() && u.Stance.AutoAcquires() {
+			center := u.Center()
+			var nearestEnemy *entity.Unit
+			var nearestBuilding *entity.Building
+			nearestUnitDist := u.Range + 1
+			nearestBuildingDist := u.Range + 1
+
+			// Units may have moved up to maxUnitSpeed since the index was built
+			w.unitBuf = w.unitIndex.QueryRadius(center, u.Range+w.maxUnitSpeed, w.unitBuf[:0])
+			for _, other := range w.unitBuf {
+				if other.Active && other.Faction != u.Faction && u.CanTarget(other) && !w.Concealed(other, u.Faction) {
+					dist := center.Distance(other.Center())
+					if u.InFiringRange(dist) && dist < nearestUnitDist {
+						nearestUnitDist = dist
+						nearestEnemy = other
+					}
+				}
+			}
+
+			if u.CanTargetGround() {
+				w.buildingBuf = w.buildingIndex.QueryRadius(center, u.Range, w.buildingBuf[:0])
+			} else {
+				w.buildingBuf = w.buildingBuf[:0]
+			}
+			for _, b := range w.buildingBuf {
+				if b.Active && b.Faction != u.Faction {
+					dist := center.Distance(b.Center())
+					if u.InFiringRange(dist) && dist < nearestBuildingDist {
+						nearestBuildingDist = dist
+						nearestBuilding = b
+					}
+				}
+			}
+
+			// Prioritize units over buildings
+			if nearestEnemy != nil {
+				u.SetAttackTarget(nearestEnemy)
+			} else if nearestBuilding != nil {
+				u.SetBuildingAttackTarget(nearestBuilding)
+			}
+		}
+
+		// Pursue enemy if they're out of fire range but in pursuit range.
+		// Units holding position only move for targets they were ordered to
+		// attack, and nobody closes in on a target inside the minimum range.
+		canPursue := (u.Stance != entity.StanceHoldPosition || u.AttackOrdered) && !u.TooClose(attackPoint(u))
+		if canPursue && u.AttackTarget != nil && u.AttackTarget.Active && !u.IsInRange(u.AttackTarget) {
+			// Only pursue if unit doesn't have another movement target
+			if !u.HasTarget {
+				u.SetTarget(u.AttackTarget.Center())
+			}
+		} else if canPursue && u.BuildingAttackTarget != nil && u.BuildingAttackTarget.Active && !u.IsBuildingInRange(u.BuildingAttackTarget) {
+			if !u.HasTarget {
+				u.SetTarget(u.BuildingAttackTarget.Center())
+			}
+		}
+
+		if u.UpdateCombat(dt) {
+			w.fire(u)
+		}
+	}
+
+	w.updateBuildingCombat(dt)
+}
+
+// attackPoint returns the center of a unit's attack target, or the unit's
+// own center if it has none
+func attackPoint(u *entity.Unit) emath.Vec2 {
+	if u.AttackTarget != nil {
+		return u.AttackTarget.Center()
+	}
+	if u.BuildingAttackTarget != nil {
+		return u.BuildingAttackTarget.Center()
+	}
+	return u.Center()
+}
+
+// fire launches a unit's weapon at its attack target
+func (w *World) fire(u *entity.Unit) {
+	var p *entity.Projectile
+	switch {
+	case u.AttackTarget != nil && u.AttackTarget.Active:
+		switch u.Weapon() {
+		case entity.WeaponBallistic:
+			p = entity.NewShell(w.NextProjectileID, u, leadTarget(u, u.AttackTarget))
+		case entity.WeaponFlame:
+			p = w.fireFlame(u, u.AttackTarget.Center())
+		default:
+			p = entity.NewProjectile(w.NextProjectileID, u, u.AttackTarget)
+		}
+	case u.BuildingAttackTarget != nil && u.BuildingAttackTarget.Active:
+		switch u.Weapon() {
+		case entity.WeaponBallistic:
+			p = entity.NewShell(w.NextProjectileID, u, u.BuildingAttackTarget.Center())
+		case entity.WeaponFlame:
+			p = w.fireFlame(u, u.BuildingAttackTarget.Center())
+		default:
+			p = entity.NewProjectileAtBuilding(w.NextProjectileID, u, u.BuildingAttackTarget)
+		}
+	default:
+		return
+	}
+	w.Projectiles = append(w.Projectiles, p)
+	w.NextProjectileID++
+}
+
+// leadTarget predicts where a moving unit will be when a shot fired at it
+// now arrives, assuming it keeps its current velocity
+func leadTarget(u, target *entity.Unit) emath.Vec2 {
+	aim := target.Center()
+	if !target.HasTarget {
+		return aim
+	}
+	// The second pass corrects the flight time for the predicted point
+	for range 2 {
+		ticks := u.Center().Distance(aim) / u.ShotSpeed() / TickRate
+		aim = target.Center().Add(target.Velocity.Mul(ticks))
+	}
+	return aim
+}
+
+// fireFlame damages and ignites every enemy in the flame cone of a unit
+// aimed at aim, and returns the burst of flame to show for it
+func (w *World) fireFlame(u *entity.Unit, aim emath.Vec2) *entity.Projectile {
+	combat := u.Def.Combat
+	center := u.Center()
+	dir := aim.Sub(center).Normalize()
+
+	w.unitBuf = w.unitIndex.QueryRadius(center, u.Range+w.maxUnitSpeed, w.unitBuf[:0])
+	for _, other := range w.unitBuf {
+		if other.Active && other.Faction != u.Faction && u.CanTarget(other) &&
+			entity.InCone(center, dir, other.Center(), combat.ConeAngle, u.Range) {
+			entity.DamageUnit(u, other, u.Damage, u.DamageType())
+			other.Burn.Ignite(combat.BurnDamage, combat.BurnDuration, u)
+		}
+	}
+	if u.CanTargetGround() {
+		w.buildingBuf = w.buildingIndex.QueryRadius(center, u.Range, w.buildingBuf[:0])
+		for _, b := range w.buildingBuf {
+			if b.Active && b.Faction != u.Faction && entity.InCone(center, dir, b.Center(), combat.ConeAngle, u.Range) {
+				entity.DamageBuilding(u, b, u.Damage, u.DamageType())
+				b.Burn.Ignite(combat.BurnDamage, combat.BurnDuration, u)
+			}
+		}
+	}
+	return entity.NewFlame(w.NextProjectileID, u, aim)
+}
+
+// explode deals a landed shell's splash damage to the ground units and
+// buildings around where it landed
+func (w *World) explode(p *entity.Projectile) {
+	center := p.Center()
+	area := emath.Rect{
+		Pos:  center.Sub(emath.Vec2{X: p.Splash, Y: p.Splash}),
+		Size: emath.Vec2{X: 2 * p.Splash, Y: 2 * p.Splash},
+	}
+	w.unitBuf = w.unitIndex.Query(area, w.unitBuf[:0])
+	for _, u := range w.unitBuf {
+		if u.Active && u.Faction != p.Faction && !u.IsAircraft() {
+			if damage := entity.SplashDamage(p.Damage, u.Bounds().DistanceTo(center), p.Splash); damage > 0 {
+				entity.DamageUnit(p.Shooter, u, damage, p.DamageType)
+			}
+		}
+	}
+	w.buildingBuf = w.buildingIndex.Query(area, w.buildingBuf[:0])
+	for _, b := range w.buildingBuf {
+		if b.Active && b.Faction != p.Faction {
+			if damage := entity.SplashDamage(p.Damage, b.Bounds().DistanceTo(center), p.Splash); damage > 0 {
+				entity.DamageBuilding(p.Shooter, b, damage, p.DamageType)
+			}
+		}
+	}
+}
+
+// updateBurning deals damage to everything still on fire
+func (w *World) updateBurning(dt float64) {
+	for _, u := range w.Units {
+		if u.Active && u.Burn.Burning() {
+			entity.DamageUnit(u.Burn.Source, u, u.Burn.Tick(dt), entity.DamageFire)
+		}
+	}
+	for _, b := range w.Buildings {
+		if b.Active && b.Burn.Burning() {
+			entity.DamageBuilding(b.Burn.Source, b, b.Burn.Tick(dt), entity.DamageFire)
+		}
+	}
+}
+
+// stanceAllowsTarget reports whether a unit's stance lets it keep
+// engaging a target it picked by itself
+func stanceAllowsTarget(u *entity.Unit) bool {
+	switch u.Stance {
+	case entity.StanceHoldFire:
+		return false
+	case entity.StanceHoldPosition:
+		if u.AttackTarget != nil {
+			return u.IsInRange(u.AttackTarget)
+		}
+		return u.IsBuildingInRange(u.BuildingAttackTarget)
+	case entity.StanceDefensive:
+		// Chase no further than the leash from the post
+		return u.Post.Distance(attackPoint(u)) <= entity.DefensiveLeash+u.Range
+	default:
+		return true
+	}
+}
+
+// updateBuildingCombat lets defensive buildings fire, paid for with their faction's energy
+func (w *World) updateBuildingCombat(dt float64) {
+	for _, b := range w.Buildings {
+		if !b.Active || !b.CanAttack() {
+			continue
+		}
+
+		// Without enough power the turret goes offline; otherwise it
+		// reloads in proportion to its power
+		if b.Offline() {
+			b.AttackTarget = nil
+			continue
+		}
+		if b.FireCooldown > 0 {
+			b.FireCooldown -= dt * b.Power
+		}
+
+		if b.AttackTarget != nil && (!b.AttackTarget.Active || !b.IsInAttackRange(b.AttackTarget) || w.Concealed(b.AttackTarget, b.Faction)) {
+			b.AttackTarget = nil
+		}
+
+		if b.AttackTarget == nil {
+			var nearestEnemy *entity.Unit
+			nearestDist := b.Def.AttackRange + 1
+
+			center := b.Center()
+			w.unitBuf = w.unitIndex.QueryRadius(center, b.Def.AttackRange+w.maxUnitSpeed, w.unitBuf[:0])
+			for _, u := range w.unitBuf {
+				if u.Active && u.Faction != b.Faction && b.CanTarget(u) && !w.Concealed(u, b.Faction) {
+					dist := center.Distance(u.Center())
+					if dist <= b.Def.AttackRange && dist < nearestDist {
+						nearestDist = dist
+						nearestEnemy = u
+					}
+				}
+			}
+
+			if nearestEnemy != nil {
+				b.SetAttackTarget(nearestEnemy)
+			}
+		}
+
+		if b.FireCooldown <= 0 && b.AttackTarget != nil && b.AttackTarget.Active {
+			energyRes := w.Resources(b.Faction).Get(resource.Energy)
+			if energyRes.Current >= b.Def.EnergyPerShot {
+				energyRes.Spend(b.Def.EnergyPerShot)
+				w.Projectiles = append(w.Projectiles, entity.NewProjectileFromBuilding(w.NextProjectileID, b, b.AttackTarget))
+				w.NextProjectileID++
+				b.FireCooldown = 1.0 / b.Def.FireRate
+			}
+		}
+	}
+}
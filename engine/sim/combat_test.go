@@ -0,0 +1,82 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// TestStanceChase has an enemy back away, always just out of firing
+// range, from a unit that found it in range
+func TestStanceChase(t *testing.T) {
+	tests := []struct {
+		stance       entity.Stance
+		wantAcquired bool
+		wantChase    func(chase float64) bool // How far the unit may follow while engaged
+		wantTarget   bool                     // Still engaged at the end
+	}{
+		{entity.StanceAggressive, true, func(c float64) bool { return c > 2*entity.DefensiveLeash }, true},
+		{entity.StanceDefensive, true, func(c float64) bool { return c > 0 && c <= entity.DefensiveLeash }, false},
+		{entity.StanceHoldPosition, true, func(c float64) bool { return c == 0 }, false},
+		{entity.StanceHoldFire, false, func(c float64) bool { return c == 0 }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stance.String(), func(t *testing.T) {
+			w := newTestWorld()
+			u := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 300, 1000, entity.FactionPlayer)
+			w.Submit(Command{Type: CmdSetStance, Faction: entity.FactionPlayer, UnitIDs: []uint64{u.ID}, Stance: tt.stance})
+			start := u.Center()
+			enemy := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], start.X+u.Range-40, 1000, entity.FactionEnemy)
+			enemy.Stance = entity.StanceHoldFire
+			enemy.Health, enemy.MaxHealth = 1e9, 1e9
+
+			acquired := false
+			chase := 0.0
+			for tick := 0; tick < 200; tick++ {
+				w.Update(TickRate)
+				if u.AttackTarget == enemy {
+					acquired = true
+					chase = max(chase, u.Center().Distance(start))
+				}
+				if acquired {
+					enemy.Position.X = u.Center().X + u.Range + 20 - enemy.Size.X/2
+				}
+			}
+
+			if acquired != tt.wantAcquired {
+				t.Errorf("acquired the enemy = %v, want %v", acquired, tt.wantAcquired)
+			}
+			if !tt.wantChase(chase) {
+				t.Errorf("followed the enemy %v from its post", chase)
+			}
+			if got := u.AttackTarget == enemy; got != tt.wantTarget {
+				t.Errorf("still engaged = %v, want %v", got, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestDefensiveUnitReturnsToPost(t *testing.T) {
+	w := newTestWorld()
+	u := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 300, 1000, entity.FactionPlayer)
+	w.Submit(Command{Type: CmdSetStance, Faction: entity.FactionPlayer, UnitIDs: []uint64{u.ID}, Stance: entity.StanceDefensive})
+	post := u.Center()
+	enemy := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], post.X+u.Range-40, 1000, entity.FactionEnemy)
+	enemy.Stance = entity.StanceHoldFire
+	enemy.Health, enemy.MaxHealth = 1e9, 1e9
+
+	// Lure it off its post, then move the enemy out of reach
+	for tick := 0; tick < 120; tick++ {
+		w.Update(TickRate)
+		enemy.Position.X = u.Center().X + u.Range + 20 - enemy.Size.X/2
+	}
+	enemy.Position = emath.Vec2{X: 1800, Y: 1800}
+	for tick := 0; tick < 300; tick++ {
+		w.Update(TickRate)
+	}
+	if d := u.Center().Distance(post); d > 5 {
+		t.Errorf("unit stopped %v from its post, want it back", d)
+	}
+}
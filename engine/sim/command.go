@@ -0,0 +1,430 @@
+package sim
+
+import (
+	"math"
+	"slices"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/pathfinding"
+)
+
+// CommandType identifies what a command does. The values match the
+// multiplayer wire names so server commands convert directly.
+type CommandType string
+
+const (
+	CmdMove             CommandType = "move"
+	CmdAttackMove       CommandType = "attack_move"
+	CmdPatrol           CommandType = "patrol"
+	CmdGuard            CommandType = "guard"
+	CmdAttack           CommandType = "attack"
+	CmdStop             CommandType = "stop"
+	CmdRepair           CommandType = "repair"
+	CmdReclaim          CommandType = "reclaim"
+	CmdReclaimArea      CommandType = "reclaim_area"
+	CmdPlaceBuilding    CommandType = "place_building"
+	CmdProduceUnit      CommandType = "produce_unit"
+	CmdCancelProduction CommandType = "cancel_production"
+	CmdResearch         CommandType = "research"
+	CmdCancelResearch   CommandType = "cancel_research"
+	CmdSetRallyPoint    CommandType = "set_rally"
+	CmdSetStance        CommandType = "set_stance"
+	CmdSetPowerPriority CommandType = "set_power_priority"
+	CmdSetPriority      CommandType = "set_priority"
+	CmdRecycle          CommandType = "recycle"
+	CmdLoad             CommandType = "load"
+	CmdUnload           CommandType = "unload"
+	CmdUnloadAll        CommandType = "unload_all"
+	CmdUseAbility       CommandType = "use_ability"
+)
+
+// flowFieldMinGroup is the group size from which a move order shares one
+// flow field instead of planning a path per unit
+const flowFieldMinGroup = 8
+
+// Command is a player order issued on behalf of a faction
+type Command struct {
+	Type         CommandType
+	Faction      entity.Faction
+	UnitIDs      []uint64
+	TargetX      float64
+	TargetY      float64
+	EndX         float64 // Far corner of an area command; TargetX/Y is the near one
+	EndY         float64
+	TargetID     uint64
+	BuildingID   uint64
+	BuildingType entity.BuildingType
+	UnitType     entity.UnitType
+	Research     entity.ResearchType
+	Formation    Formation // Layout of a group move, DefaultFormation when empty
+	Facing       float64   // Direction a group move faces, in radians
+	HasFacing    bool      // Facing is set; otherwise the group faces its direction of travel
+	Stance       entity.Stance
+	Power        entity.PowerClass  // Consumers the faction's grid powers first
+	Priority     entity.Priority    // How a building's work is paid for
+	Ability      entity.AbilityType // Ability a use_ability command fires
+	Queue        bool               // Append to the units' order queues instead of replacing them
+}
+
+// Submit queues a command to run at the start of the next tick
+func (w *World) Submit(cmd Command) {
+	w.commands = append(w.commands, cmd)
+}
+
+// processCommands executes all queued commands in submission order
+func (w *World) processCommands() {
+	for _, cmd := range w.commands {
+		w.executeCommand(cmd)
+	}
+	w.commands = w.commands[:0]
+}
+
+// ownedUnits resolves command unit IDs to active units of the faction
+func (w *World) ownedUnits(cmd Command) []*entity.Unit {
+	units := make([]*entity.Unit, 0, len(cmd.UnitIDs))
+	for _, id := range cmd.UnitIDs {
+		if u := w.Unit(id); u != nil && u.Faction == cmd.Faction {
+			units = append(units, u)
+		}
+	}
+	return units
+}
+
+// executeCommand applies a single command to the world
+func (w *World) executeCommand(cmd Command) {
+	target := emath.Vec2{X: cmd.TargetX, Y: cmd.TargetY}
+
+	switch cmd.Type {
+	case CmdMove, CmdAttackMove, CmdPatrol:
+		w.moveUnits(cmd, target)
+
+	case CmdAttack:
+		targetUnit := w.Unit(cmd.TargetID)
+		targetBuilding := w.Building(cmd.TargetID)
+		var order entity.Order
+		if targetUnit != nil && targetUnit.Faction != cmd.Faction {
+			order = entity.Order{Type: entity.OrderAttack, Unit: targetUnit}
+		} else if targetBuilding != nil && targetBuilding.Faction != cmd.Faction {
+			order = entity.Order{Type: entity.OrderAttack, Building: targetBuilding}
+		} else {
+			return
+		}
+		for _, u := range w.ownedUnits(cmd) {
+			canHit := u.CanTargetGround()
+			if order.Unit != nil {
+				canHit = u.CanTarget(order.Unit)
+			}
+			if u.CanAttack() && canHit {
+				giveOrder(u, order, cmd.Queue)
+			}
+		}
+
+	case CmdGuard:
+		order := entity.Order{Type: entity.OrderGuard}
+		if targetUnit := w.Unit(cmd.TargetID); targetUnit != nil && targetUnit.Faction == cmd.Faction {
+			order.Unit = targetUnit
+		} else if targetBuilding := w.Building(cmd.TargetID); targetBuilding != nil && targetBuilding.Faction == cmd.Faction {
+			order.Building = targetBuilding
+		} else {
+			return
+		}
+		for _, u := range w.ownedUnits(cmd) {
+			if u != order.Unit {
+				giveOrder(u, order, cmd.Queue)
+			}
+		}
+
+	case CmdStop:
+		for _, u := range w.ownedUnits(cmd) {
+			u.ClearOrders()
+			u.ClearTarget()
+			u.ClearAttackTarget()
+			u.ClearRepairTarget()
+		}
+
+	case CmdRepair:
+		targetUnit := w.Unit(cmd.TargetID)
+		if targetUnit == nil || targetUnit.Faction != cmd.Faction {
+			return
+		}
+		for _, u := range w.ownedUnits(cmd) {
+			if u.CanRepair() && u != targetUnit {
+				u.ClearOrders()
+				u.SetRepairTarget(targetUnit)
+				u.SetTarget(targetUnit.Center())
+				u.ClearBuildTask()
+			}
+		}
+
+	case CmdReclaim:
+		wreck := w.Wreck(cmd.TargetID)
+		if wreck == nil {
+			return
+		}
+		for _, u := range w.ownedUnits(cmd) {
+			if u.CanReclaim() {
+				if !cmd.Queue {
+					u.ClearBuildTask()
+				}
+				giveOrder(u, entity.Order{Type: entity.OrderReclaim, Wreck: wreck}, cmd.Queue)
+			}
+		}
+
+	case CmdReclaimArea:
+		area := areaRect(target, emath.Vec2{X: cmd.EndX, Y: cmd.EndY})
+		wrecks := w.wrecksIn(area)
+		if len(wrecks) == 0 {
+			return
+		}
+		for _, u := range w.ownedUnits(cmd) {
+			if !u.CanReclaim() {
+				continue
+			}
+			if !cmd.Queue {
+				u.ClearBuildTask()
+			}
+			for i, wreck := range reclaimRoute(u.Center(), wrecks) {
+				giveOrder(u, entity.Order{Type: entity.OrderReclaim, Wreck: wreck}, cmd.Queue || i > 0)
+			}
+		}
+
+	case CmdLoad:
+		target := w.Unit(cmd.TargetID)
+		if target == nil || target.Faction != cmd.Faction {
+			return
+		}
+		// Transports pick the target up; other units board it
+		for _, u := range w.ownedUnits(cmd) {
+			if u.CanLoad(target) || target.CanLoad(u) {
+				giveOrder(u, entity.Order{Type: entity.OrderLoad, Unit: target}, cmd.Queue)
+			}
+		}
+
+	case CmdUnload:
+		for _, u := range w.ownedUnits(cmd) {
+			if u.CanTransport() {
+				giveOrder(u, entity.Order{Type: entity.OrderUnload, Pos: target}, cmd.Queue)
+			}
+		}
+
+	case CmdUnloadAll:
+		for _, u := range w.ownedUnits(cmd) {
+			if len(u.Cargo) > 0 {
+				u.ClearOrders()
+				u.ClearTarget()
+				w.unloadAll(u)
+			}
+		}
+
+	case CmdPlaceBuilding:
+		def := entity.BuildingDefs[cmd.BuildingType]
+		if def == nil || !w.Tech(cmd.Faction).BuildingUnlocked(def.Type) || !w.CanPlaceBuilding(target, def) {
+			return
+		}
+		pos := SnapToGrid(target)
+		w.StartBuilding(def, pos.X, pos.Y, cmd.Faction)
+
+	case CmdProduceUnit:
+		building := w.Building(cmd.BuildingID)
+		if building == nil || building.Faction != cmd.Faction || !building.CanProduce() {
+			return
+		}
+		if !w.Tech(cmd.Faction).UnitUnlocked(cmd.UnitType) {
+			return
+		}
+		building.QueueProduction(entity.UnitDefs[cmd.UnitType])
+
+	case CmdCancelProduction:
+		building := w.Building(cmd.BuildingID)
+		if building == nil || building.Faction != cmd.Faction {
+			return
+		}
+		building.RemoveFromQueue(cmd.UnitType, w.Resources(cmd.Faction))
+
+	case CmdResearch:
+		building := w.Building(cmd.BuildingID)
+		if building == nil || building.Faction != cmd.Faction || !building.CanResearch() {
+			return
+		}
+		// A project runs once per faction, after everything it requires
+		if !w.Tech(cmd.Faction).Available(cmd.Research) || w.Researching(cmd.Faction, cmd.Research) {
+			return
+		}
+		building.QueueResearch(entity.ResearchDefs[cmd.Research])
+
+	case CmdCancelResearch:
+		building := w.Building(cmd.BuildingID)
+		if building == nil || building.Faction != cmd.Faction {
+			return
+		}
+		building.CancelResearch(cmd.Research, w.Resources(cmd.Faction))
+
+	case CmdSetRallyPoint:
+		building := w.Building(cmd.BuildingID)
+		if building == nil || building.Faction != cmd.Faction {
+			return
+		}
+		building.RallyPoint = target
+		building.HasRallyPoint = true
+
+	case CmdSetStance:
+		for _, u := range w.ownedUnits(cmd) {
+			if u.CanAttack() {
+				u.Stance = cmd.Stance
+				u.Post = u.Center()
+			}
+		}
+
+	case CmdSetPowerPriority:
+		if cmd.Power < 0 || cmd.Power >= entity.NumPowerClasses {
+			return
+		}
+		w.PowerGrid(cmd.Faction).First = cmd.Power
+
+	case CmdSetPriority:
+		building := w.Building(cmd.BuildingID)
+		if building == nil || building.Faction != cmd.Faction || cmd.Priority < 0 || cmd.Priority >= entity.NumPriorities {
+			return
+		}
+		building.Priority = cmd.Priority
+
+	case CmdRecycle:
+		building := w.Building(cmd.BuildingID)
+		if building == nil || building.Faction != cmd.Faction {
+			return
+		}
+		w.recycle(building)
+
+	case CmdUseAbility:
+		w.useAbility(cmd, target)
+	}
+}
+
+// giveOrder replaces a unit's orders, or appends to them when queue is set
+func giveOrder(u *entity.Unit, o entity.Order, queue bool) {
+	if queue {
+		u.QueueOrder(o)
+	} else {
+		u.GiveOrder(o)
+	}
+}
+
+// areaRect returns the rectangle spanned by two opposite corners
+func areaRect(a, b emath.Vec2) emath.Rect {
+	return emath.Rect{
+		Pos:  emath.Vec2{X: min(a.X, b.X), Y: min(a.Y, b.Y)},
+		Size: emath.Vec2{X: math.Abs(a.X - b.X), Y: math.Abs(a.Y - b.Y)},
+	}
+}
+
+// wrecksIn returns the active wrecks touching area
+func (w *World) wrecksIn(area emath.Rect) []*entity.Wreckage {
+	var wrecks []*entity.Wreckage
+	for _, wr := range w.Wreckages {
+		if wr.Active && wr.Bounds().Intersects(area) {
+			wrecks = append(wrecks, wr)
+		}
+	}
+	return wrecks
+}
+
+// reclaimRoute orders wrecks so each one is the closest left to the one
+// before it, starting from the closest to from
+func reclaimRoute(from emath.Vec2, wrecks []*entity.Wreckage) []*entity.Wreckage {
+	left := slices.Clone(wrecks)
+	route := make([]*entity.Wreckage, 0, len(left))
+	for len(left) > 0 {
+		next := 0
+		for i, wr := range left {
+			if wr.Center().DistanceSquared(from) < left[next].Center().DistanceSquared(from) {
+				next = i
+			}
+		}
+		route = append(route, left[next])
+		from = left[next].Center()
+		left = slices.Delete(left, next, next+1)
+	}
+	return route
+}
+
+// moveUnits carries out move, attack-move and patrol orders. Groups take
+// up a formation around the target and every unit heads for its own slot.
+func (w *World) moveUnits(cmd Command, target emath.Vec2) {
+	units := w.ownedUnits(cmd)
+	if len(units) == 0 {
+		return
+	}
+	kind := entity.OrderMove
+	switch cmd.Type {
+	case CmdAttackMove:
+		kind = entity.OrderAttackMove
+	case CmdPatrol:
+		kind = entity.OrderPatrol
+	}
+
+	if len(units) == 1 {
+		u := units[0]
+		u.Formation = entity.FormationSlot{}
+		w.orderMove(u, kind, target, cmd.Queue)
+		return
+	}
+
+	facing := cmd.Facing
+	if !cmd.HasFacing {
+		facing = groupFacing(units, target)
+	}
+	w.arrangeFormation(units, cmd.Formation, target, facing)
+	speed := groupSpeed(units)
+
+	// Only a group setting off now can share flow fields, one per
+	// movement class in the group
+	var flows map[entity.MovementClass]*pathfinding.FlowField
+	if kind == entity.OrderMove && !cmd.Queue && len(units) >= flowFieldMinGroup {
+		w.refreshPathObstacles()
+		flows = make(map[entity.MovementClass]*pathfinding.FlowField)
+	}
+	for _, u := range units {
+		slot := target.Add(rotate(u.Formation.Offset, facing))
+		started := w.orderMove(u, kind, slot, cmd.Queue)
+		if !started {
+			continue
+		}
+		if grid := w.Paths[u.Movement()]; flows != nil && grid != nil {
+			flow, ok := flows[u.Movement()]
+			if !ok {
+				flow = grid.FlowTo(target)
+				flows[u.Movement()] = flow
+			}
+			u.SetFlowTarget(slot, flow)
+		}
+		u.GroupSpeed = speed
+	}
+}
+
+// orderMove gives or queues a move-like order for one unit and reports
+// whether the unit set off at once. A patrol given without queueing
+// returns to where the unit stands now.
+func (w *World) orderMove(u *entity.Unit, kind entity.OrderType, pos emath.Vec2, queue bool) bool {
+	order := entity.Order{Type: kind, Pos: pos}
+	if kind == entity.OrderPatrol {
+		if !queue || len(u.Orders) == 0 {
+			u.GiveOrder(order)
+			u.Orders = append(u.Orders, entity.Order{Type: entity.OrderPatrol, Pos: u.Center()})
+			return true
+		}
+		// Patrol from the end of the queue unless already patrolling
+		last := u.Orders[len(u.Orders)-1]
+		u.QueueOrder(order)
+		if last.Type != entity.OrderPatrol {
+			u.QueueOrder(entity.Order{Type: entity.OrderPatrol, Pos: last.Point()})
+		}
+		return false
+	}
+	if queue && len(u.Orders) > 0 {
+		u.QueueOrder(order)
+		return false
+	}
+	u.GiveOrder(order)
+	return true
+}
@@ -0,0 +1,188 @@
+package sim
+
+import (
+	"math"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// Formation is the layout of a group move. The values match the
+// multiplayer wire names.
+type Formation string
+
+const (
+	FormationLine  Formation = "line"  // One rank across the facing
+	FormationBox   Formation = "box"   // A square block
+	FormationWedge Formation = "wedge" // A V with its tip towards the facing
+)
+
+// DefaultFormation is used when a move order names no formation
+const DefaultFormation = FormationBox
+
+// Formations lists the layouts in the order the client cycles through them
+var Formations = []Formation{FormationLine, FormationBox, FormationWedge}
+
+// formationGap is the space left between neighbouring units of a formation
+const formationGap = 10.0
+
+// formationOffsets returns count slot positions around the group center.
+// +X points along the facing and +Y to its right; slot 0 is in front.
+func formationOffsets(kind Formation, count int, spacing float64) []emath.Vec2 {
+	offsets := make([]emath.Vec2, count)
+	switch kind {
+	case FormationLine:
+		for i := range offsets {
+			offsets[i] = emath.Vec2{Y: (float64(i) - float64(count-1)/2) * spacing}
+		}
+
+	case FormationWedge:
+		// Tip first, then ranks of two spreading back to either side
+		ranks := count / 2
+		for i := range offsets {
+			rank := (i + 1) / 2
+			side := 1.0
+			if i%2 == 1 {
+				side = -1
+			}
+			offsets[i] = emath.Vec2{
+				X: (float64(ranks)/2 - float64(rank)) * spacing,
+				Y: side * float64(rank) * spacing,
+			}
+		}
+
+	default:
+		cols := int(math.Ceil(math.Sqrt(float64(count))))
+		rows := (count + cols - 1) / cols
+		for i := range offsets {
+			row, col := i/cols, i%cols
+			inRow := cols
+			if row == rows-1 {
+				inRow = count - row*cols
+			}
+			offsets[i] = emath.Vec2{
+				X: (float64(rows-1)/2 - float64(row)) * spacing,
+				Y: (float64(col) - float64(inRow-1)/2) * spacing,
+			}
+		}
+	}
+	return offsets
+}
+
+// formationSpacing fits the largest unit of a group plus a gap into each slot
+func formationSpacing(units []*entity.Unit) float64 {
+	spacing := 0.0
+	for _, u := range units {
+		spacing = max(spacing, u.Size.X, u.Size.Y)
+	}
+	return spacing + formationGap
+}
+
+// rotate turns a formation offset to world space for the given facing
+func rotate(v emath.Vec2, facing float64) emath.Vec2 {
+	sin, cos := math.Sincos(facing)
+	return emath.Vec2{X: v.X*cos - v.Y*sin, Y: v.X*sin + v.Y*cos}
+}
+
+// arrangeFormation gives every unit of a move order its slot. Units that
+// already make up a whole formation of the requested kind keep their
+// offsets; otherwise slots are laid out anew and handed to the nearest
+// units so the group does not cross over itself.
+func (w *World) arrangeFormation(units []*entity.Unit, kind Formation, target emath.Vec2, facing float64) {
+	if kind == "" {
+		kind = DefaultFormation
+	}
+	if w.isWholeFormation(units, kind) {
+		return
+	}
+
+	w.nextFormationID++
+	offsets := formationOffsets(kind, len(units), formationSpacing(units))
+	taken := make([]bool, len(units))
+	for _, offset := range offsets {
+		slot := target.Add(rotate(offset, facing))
+		best := -1
+		for i, u := range units {
+			if taken[i] {
+				continue
+			}
+			if best == -1 || u.Center().DistanceSquared(slot) < units[best].Center().DistanceSquared(slot) {
+				best = i
+			}
+		}
+		taken[best] = true
+		units[best].Formation = entity.FormationSlot{
+			GroupID: w.nextFormationID,
+			Kind:    string(kind),
+			Offset:  offset,
+		}
+	}
+}
+
+// isWholeFormation reports whether units are exactly the surviving
+// members of one formation of the given kind
+func (w *World) isWholeFormation(units []*entity.Unit, kind Formation) bool {
+	group := units[0].Formation.GroupID
+	if group == 0 {
+		return false
+	}
+	for _, u := range units {
+		if u.Formation.GroupID != group || u.Formation.Kind != string(kind) {
+			return false
+		}
+	}
+	members := 0
+	for _, u := range w.Units {
+		if u.Active && u.Formation.GroupID == group {
+			members++
+		}
+	}
+	return members == len(units)
+}
+
+// groupFacing is the direction from the group's center to the target
+func groupFacing(units []*entity.Unit, target emath.Vec2) float64 {
+	var center emath.Vec2
+	for _, u := range units {
+		center = center.Add(u.Center())
+	}
+	center = center.Div(float64(len(units)))
+	dir := target.Sub(center)
+	if dir.LengthSquared() < 1 {
+		return units[0].Angle
+	}
+	return math.Atan2(dir.Y, dir.X)
+}
+
+// groupSpeed is the speed of the slowest unit
+func groupSpeed(units []*entity.Unit) float64 {
+	speed := math.Inf(1)
+	for _, u := range units {
+		speed = min(speed, u.Speed)
+	}
+	return speed
+}
+
+// PreviewFormation returns where units would stand if ordered to target
+// in the given formation, for drawing the order before it is given
+func (w *World) PreviewFormation(units []*entity.Unit, kind Formation, target emath.Vec2, facing float64) []emath.Vec2 {
+	if len(units) == 0 {
+		return nil
+	}
+	if kind == "" {
+		kind = DefaultFormation
+	}
+	var offsets []emath.Vec2
+	if w.isWholeFormation(units, kind) {
+		for _, u := range units {
+			offsets = append(offsets, u.Formation.Offset)
+		}
+	} else {
+		offsets = formationOffsets(kind, len(units), formationSpacing(units))
+	}
+	slots := make([]emath.Vec2, len(offsets))
+	for i, offset := range offsets {
+		slots[i] = target.Add(rotate(offset, facing))
+	}
+	return slots
+}
@@ -0,0 +1,168 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// spawnGroup places count units of a type in a row starting at x, y
+func spawnGroup(w *World, t entity.UnitType, count int, x, y float64) []*entity.Unit {
+	units := make([]*entity.Unit, count)
+	for i := range units {
+		units[i] = w.SpawnUnit(entity.UnitDefs[t], x+float64(i)*50, y, entity.FactionPlayer)
+	}
+	return units
+}
+
+func unitIDs(units []*entity.Unit) []uint64 {
+	ids := make([]uint64, len(units))
+	for i, u := range units {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+// runUntilStopped ticks the world until no unit has a move target left
+func runUntilStopped(t *testing.T, w *World, units []*entity.Unit, maxTicks int) {
+	t.Helper()
+	for tick := 0; tick < maxTicks; tick++ {
+		w.Update(TickRate)
+		moving := false
+		for _, u := range units {
+			moving = moving || u.HasTarget
+		}
+		if !moving {
+			return
+		}
+	}
+	t.Fatalf("units still moving after %d ticks", maxTicks)
+}
+
+func TestGroupMoveArrivesInFormation(t *testing.T) {
+	tests := []struct {
+		kind Formation
+		// Check the arrived group's layout, facing +X
+		check func(t *testing.T, centers []emath.Vec2)
+	}{
+		{FormationLine, func(t *testing.T, centers []emath.Vec2) {
+			for _, c := range centers {
+				if math.Abs(c.X-1000) > 10 {
+					t.Errorf("unit at %v, want every unit on the line x=1000", c)
+				}
+			}
+		}},
+		{FormationWedge, func(t *testing.T, centers []emath.Vec2) {
+			tip := centers[0]
+			for _, c := range centers {
+				if c.X > tip.X {
+					t.Fatalf("unit at %v is ahead of the tip of the wedge", c)
+				}
+			}
+			for _, c := range centers {
+				if c != tip && c.X > tip.X-10 {
+					t.Errorf("unit at %v stands level with the tip at %v", c, tip)
+				}
+			}
+		}},
+		{FormationBox, func(t *testing.T, centers []emath.Vec2) {
+			for _, c := range centers {
+				if c.Distance(emath.Vec2{X: 1000, Y: 1000}) > 120 {
+					t.Errorf("unit at %v is outside the block around the click", c)
+				}
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			w := newTestWorld()
+			units := spawnGroup(w, entity.UnitTypeTank, 5, 300, 1000)
+			w.Submit(Command{
+				Type: CmdMove, Faction: entity.FactionPlayer, UnitIDs: unitIDs(units),
+				TargetX: 1000, TargetY: 1000, Formation: tt.kind, HasFacing: true,
+			})
+			runUntilStopped(t, w, units, 1200)
+
+			// Order by slot, front first
+			centers := make([]emath.Vec2, len(units))
+			for i, slot := range formationOffsets(tt.kind, len(units), formationSpacing(units)) {
+				for _, u := range units {
+					if u.Formation.Offset == slot {
+						centers[i] = u.Center()
+					}
+				}
+			}
+			tt.check(t, centers)
+			for i, a := range units {
+				for _, b := range units[i+1:] {
+					if a.Bounds().Intersects(b.Bounds()) {
+						t.Errorf("units %d and %d overlap at the destination", a.ID, b.ID)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGroupMovesAtSlowestSpeed(t *testing.T) {
+	w := newTestWorld()
+	units := append(spawnGroup(w, entity.UnitTypeLightTank, 2, 300, 1000), spawnGroup(w, entity.UnitTypeArtillery, 1, 400, 1100)...)
+	start := make([]emath.Vec2, len(units))
+	for i, u := range units {
+		start[i] = u.Center()
+	}
+	w.Submit(Command{
+		Type: CmdMove, Faction: entity.FactionPlayer, UnitIDs: unitIDs(units),
+		TargetX: 1500, TargetY: 1000, Formation: FormationLine, HasFacing: true,
+	})
+
+	const ticks = 60
+	for range ticks {
+		w.Update(TickRate)
+	}
+	slowest := entity.UnitDefs[entity.UnitTypeArtillery].Speed
+	for i, u := range units {
+		if u.GroupSpeed != slowest {
+			t.Errorf("unit %d group speed %v, want %v", u.ID, u.GroupSpeed, slowest)
+		}
+		if moved := u.Center().Distance(start[i]); moved > slowest*ticks+1 {
+			t.Errorf("unit %d moved %v in %d ticks, faster than the group's %v a tick", u.ID, moved, ticks, slowest)
+		}
+	}
+}
+
+func TestFormationKeptAcrossMoves(t *testing.T) {
+	w := newTestWorld()
+	units := spawnGroup(w, entity.UnitTypeTank, 4, 300, 1000)
+	move := Command{
+		Type: CmdMove, Faction: entity.FactionPlayer, UnitIDs: unitIDs(units),
+		TargetX: 800, TargetY: 1000, Formation: FormationWedge,
+	}
+	w.Submit(move)
+	w.Update(TickRate)
+	slots := make([]entity.FormationSlot, len(units))
+	for i, u := range units {
+		slots[i] = u.Formation
+	}
+
+	// A later order in the same formation keeps each unit in its place
+	move.TargetX, move.TargetY = 800, 400
+	w.Submit(move)
+	w.Update(TickRate)
+	for i, u := range units {
+		if u.Formation != slots[i] {
+			t.Errorf("unit %d slot %+v, want it kept as %+v", u.ID, u.Formation, slots[i])
+		}
+	}
+
+	// Splitting the group lays out a new formation
+	move.UnitIDs = move.UnitIDs[:2]
+	w.Submit(move)
+	w.Update(TickRate)
+	if units[0].Formation.GroupID == slots[0].GroupID {
+		t.Error("part of a formation should be given a new one")
+	}
+}
@@ -0,0 +1,112 @@
+package sim
+
+import (
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// guardRadius is how far a guarding unit strays from the edge of what it
+// guards before closing in again
+const guardRadius = 80.0
+
+// updateOrders checks each unit's current order and moves on to the next
+// one once it is done
+func (w *World) updateOrders() {
+	for _, u := range w.Units {
+		if !u.Active {
+			continue
+		}
+		if o := u.CurrentOrder(); o != nil && w.orderDone(u, o) {
+			u.NextOrder()
+		}
+	}
+}
+
+// orderDone steers a unit through its current order and reports whether
+// the order is finished
+func (w *World) orderDone(u *entity.Unit, o *entity.Order) bool {
+	switch o.Type {
+	case entity.OrderMove:
+		return !u.HasTarget
+
+	case entity.OrderAttack:
+		if !o.TargetActive() {
+			return true
+		}
+		// The target escaped pursuit range or the unit was retargeted
+		if o.Unit != nil {
+			return u.AttackTarget != o.Unit
+		}
+		return u.BuildingAttackTarget != o.Building
+
+	case entity.OrderAttackMove, entity.OrderPatrol:
+		if u.HasAnyAttackTarget() {
+			// Stop driving so combat can close in on the enemy
+			if !o.Engaged {
+				o.Engaged = true
+				u.ClearTarget()
+			}
+			return false
+		}
+		if o.Engaged {
+			o.Engaged = false
+			u.SetTarget(o.Pos)
+			return false
+		}
+		return !u.HasTarget
+
+	case entity.OrderGuard:
+		if !o.TargetActive() {
+			return true
+		}
+		center, reach := guarded(o)
+		if u.HasAnyAttackTarget() {
+			// Fight only what threatens the guarded target
+			var enemy emath.Vec2
+			if u.AttackTarget != nil {
+				enemy = u.AttackTarget.Center()
+			} else {
+				enemy = u.BuildingAttackTarget.Center()
+			}
+			if !u.AttackOrdered && enemy.Distance(center) > reach+u.Range {
+				u.ClearAttackTarget()
+			} else if u.HasTarget {
+				u.ClearTarget()
+			}
+			return false
+		}
+		dist := u.Center().Distance(center)
+		if dist > reach && (!u.HasTarget || u.Target.Distance(center) > guardRadius/2) {
+			u.SetTarget(center)
+		} else if u.HasTarget && dist <= reach-guardRadius/2 {
+			u.ClearTarget()
+		}
+		return false
+
+	case entity.OrderReclaim:
+		return !o.TargetActive()
+
+	case entity.OrderLoad:
+		return w.loadDone(u, o)
+
+	case entity.OrderUnload:
+		if u.HasTarget {
+			return false
+		}
+		w.unloadAll(u)
+		return true
+	}
+	return true
+}
+
+// guarded returns the center of what a guard order protects and how far
+// from that center the guard may wander
+func guarded(o *entity.Order) (emath.Vec2, float64) {
+	var e *entity.Entity
+	if o.Unit != nil {
+		e = &o.Unit.Entity
+	} else {
+		e = &o.Building.Entity
+	}
+	return e.Center(), max(e.Size.X, e.Size.Y)/2 + guardRadius
+}
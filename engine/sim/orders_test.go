@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+func TestQueuedMovesRunInOrder(t *testing.T) {
+	w := newTestWorld()
+	u := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 300, 1000, entity.FactionPlayer)
+	first := emath.Vec2{X: 700, Y: 1000}
+	last := emath.Vec2{X: 700, Y: 600}
+	w.Submit(Command{Type: CmdMove, Faction: entity.FactionPlayer, UnitIDs: []uint64{u.ID}, TargetX: first.X, TargetY: first.Y})
+	w.Submit(Command{Type: CmdMove, Faction: entity.FactionPlayer, UnitIDs: []uint64{u.ID}, TargetX: last.X, TargetY: last.Y, Queue: true})
+	w.Update(TickRate)
+	if len(u.Orders) != 2 {
+		t.Fatalf("%d orders queued, want 2", len(u.Orders))
+	}
+
+	passed := false
+	for tick := 0; tick < 1000 && len(u.Orders) > 0; tick++ {
+		w.Update(TickRate)
+		passed = passed || u.Center().Distance(first) < 10
+	}
+	if !passed {
+		t.Error("unit never reached the first waypoint")
+	}
+	if d := u.Center().Distance(last); len(u.Orders) > 0 || d > 10 {
+		t.Errorf("unit stopped %v from the last waypoint with %d orders left", d, len(u.Orders))
+	}
+}
+
+func TestPatrolLoops(t *testing.T) {
+	w := newTestWorld()
+	u := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 300, 1000, entity.FactionPlayer)
+	home := u.Center()
+	far := emath.Vec2{X: 600, Y: 1000}
+	w.Submit(Command{Type: CmdPatrol, Faction: entity.FactionPlayer, UnitIDs: []uint64{u.ID}, TargetX: far.X, TargetY: far.Y})
+
+	// Count the legs: each end reached after leaving the other
+	legs := 0
+	atFar := false
+	for tick := 0; tick < 2000 && legs < 4; tick++ {
+		w.Update(TickRate)
+		if !atFar && u.Center().Distance(far) < 10 || atFar && u.Center().Distance(home) < 10 {
+			atFar = !atFar
+			legs++
+		}
+	}
+	if legs < 4 {
+		t.Errorf("patrolled %d legs, want it to keep going back and forth", legs)
+	}
+	if len(u.Orders) != 2 {
+		t.Errorf("%d patrol orders left, want both legs kept", len(u.Orders))
+	}
+}
+
+func TestGuardFollowsItsCharge(t *testing.T) {
+	w := newTestWorld()
+	charge := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 500, 1000, entity.FactionPlayer)
+	guard := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeLightTank], 400, 1000, entity.FactionPlayer)
+	w.Submit(Command{Type: CmdGuard, Faction: entity.FactionPlayer, UnitIDs: []uint64{guard.ID}, TargetID: charge.ID})
+	w.Submit(Command{Type: CmdMove, Faction: entity.FactionPlayer, UnitIDs: []uint64{charge.ID}, TargetX: 1200, TargetY: 700})
+
+	for tick := 0; tick < 600; tick++ {
+		w.Update(TickRate)
+	}
+	_, reach := guarded(&guard.Orders[0])
+	if d := guard.Center().Distance(charge.Center()); d > reach {
+		t.Errorf("guard is %v from its charge, want within %v", d, reach)
+	}
+	if len(guard.Orders) != 1 {
+		t.Errorf("guard has %d orders, want to keep guarding", len(guard.Orders))
+	}
+}
+
+func TestGuardStaysLeashed(t *testing.T) {
+	w := newTestWorld()
+	charge := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 500, 1000, entity.FactionPlayer)
+	guard := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 600, 1000, entity.FactionPlayer)
+	w.Submit(Command{Type: CmdGuard, Faction: entity.FactionPlayer, UnitIDs: []uint64{guard.ID}, TargetID: charge.ID})
+	w.Update(TickRate)
+	_, reach := guarded(&guard.Orders[0])
+
+	// An enemy in range backs away, always just out of firing range
+	enemy := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], guard.Center().X+guard.Range-40, 1000, entity.FactionEnemy)
+	enemy.Stance = entity.StanceHoldFire
+	enemy.Health, enemy.MaxHealth = 1e9, 1e9
+	engaged := false
+	for tick := 0; tick < 400; tick++ {
+		w.Update(TickRate)
+		if guard.AttackTarget == enemy {
+			engaged = true
+			if d := enemy.Center().Distance(charge.Center()); d > reach+guard.Range+30 {
+				t.Fatalf("guard still fights an enemy %v from its charge", d)
+			}
+		}
+		if engaged {
+			enemy.Position.X = guard.Center().X + guard.Range + 20 - enemy.Size.X/2
+		}
+	}
+	if !engaged {
+		t.Fatal("guard never engaged the enemy in range")
+	}
+	if d := guard.Center().Distance(charge.Center()); d > reach {
+		t.Errorf("guard ended %v from its charge, want back within %v", d, reach)
+	}
+}
+
+func TestAttackMoveEngagesThenResumes(t *testing.T) {
+	w := newTestWorld()
+	u := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 300, 1000, entity.FactionPlayer)
+	dest := emath.Vec2{X: 1400, Y: 1000}
+	enemy := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeLightTank], 800, 1100, entity.FactionEnemy)
+	enemy.Stance = entity.StanceHoldFire
+	w.Submit(Command{Type: CmdAttackMove, Faction: entity.FactionPlayer, UnitIDs: []uint64{u.ID}, TargetX: dest.X, TargetY: dest.Y})
+	w.Update(TickRate)
+
+	stopped := false
+	for tick := 0; tick < 3000 && len(u.Orders) > 0; tick++ {
+		w.Update(TickRate)
+		if o := u.CurrentOrder(); o != nil && o.Engaged {
+			stopped = stopped || !u.HasTarget
+		}
+	}
+	if !stopped {
+		t.Error("unit did not stop driving to fight the enemy on its way")
+	}
+	if enemy.Active {
+		t.Error("enemy on the way survived the attack-move")
+	}
+	if d := u.Center().Distance(dest); len(u.Orders) > 0 || d > 10 {
+		t.Errorf("unit stopped %v from its destination with %d orders left", d, len(u.Orders))
+	}
+}
@@ -0,0 +1,462 @@
+package sim
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/save"
+	"github.com/bklimczak/tanks/engine/terrain"
+)
+
+// Snapshot captures the entities and game state of the world. Projectiles
+// are short-lived and not included.
+func (w *World) Snapshot() save.WorldState {
+	state := save.WorldState{
+		Tick:             w.Tick,
+		NextUnitID:       w.NextUnitID,
+		NextBuildingID:   w.NextBuildingID,
+		NextWreckageID:   w.NextWreckageID,
+		NextProjectileID: w.NextProjectileID,
+	}
+
+	state.Units = make([]save.UnitState, 0, len(w.Units))
+	for _, u := range w.Units {
+		if !u.Active {
+			continue
+		}
+		state.Units = append(state.Units, unitState(u))
+	}
+
+	state.Buildings = make([]save.BuildingState, 0, len(w.Buildings))
+	for _, b := range w.Buildings {
+		if !b.Active {
+			continue
+		}
+		bs := save.BuildingState{
+			ID:            b.ID,
+			Type:          b.Type,
+			Faction:       b.Faction,
+			PosX:          b.Position.X,
+			PosY:          b.Position.Y,
+			Health:        b.Health,
+			Selected:      b.Selected,
+			Completed:     b.Completed,
+			BuildProgress: b.BuildProgress,
+			MetalSpent:    b.MetalSpent,
+			EnergySpent:   b.EnergySpent,
+			Priority:      b.Priority,
+			RallyPointX:   b.RallyPoint.X,
+			RallyPointY:   b.RallyPoint.Y,
+			HasRallyPoint: b.HasRallyPoint,
+			FireCooldown:  b.FireCooldown,
+		}
+
+		if b.AttackTarget != nil && b.AttackTarget.Active {
+			bs.AttackTargetID = b.AttackTarget.ID
+		}
+
+		bs.Recycling = b.Recycling
+		bs.RecycleProgress = b.RecycleProgress
+
+		bs.Producing = b.Producing
+		bs.ProductionProgress = b.ProductionProgress
+		bs.ProductionMetalSpent = b.ProductionMetalSpent
+		bs.ProductionEnergySpent = b.ProductionEnergySpent
+		if b.CurrentProduction != nil {
+			bs.CurrentProductionType = b.CurrentProduction.Type
+		}
+		if len(b.ProductionQueue) > 0 {
+			bs.ProductionQueue = make([]entity.UnitType, len(b.ProductionQueue))
+			for i, def := range b.ProductionQueue {
+				bs.ProductionQueue[i] = def.Type
+			}
+		}
+
+		bs.Researching = b.Researching
+		bs.ResearchProgress = b.ResearchProgress
+		bs.ResearchMetalSpent = b.ResearchMetalSpent
+		bs.ResearchEnergySpent = b.ResearchEnergySpent
+		if b.CurrentResearch != nil {
+			bs.CurrentResearchType = b.CurrentResearch.Type
+		}
+		for _, def := range b.ResearchQueue {
+			bs.ResearchQueue = append(bs.ResearchQueue, def.Type)
+		}
+
+		state.Buildings = append(state.Buildings, bs)
+	}
+
+	state.Wreckages = make([]save.WreckageState, 0, len(w.Wreckages))
+	for _, wr := range w.Wreckages {
+		if !wr.Active {
+			continue
+		}
+		state.Wreckages = append(state.Wreckages, save.WreckageState{
+			ID:         wr.ID,
+			PosX:       wr.Position.X,
+			PosY:       wr.Position.Y,
+			SizeX:      wr.Size.X,
+			SizeY:      wr.Size.Y,
+			MetalValue: wr.MetalValue,
+		})
+	}
+
+	for _, faction := range slices.Sorted(maps.Keys(w.tech)) {
+		if done := w.tech[faction].Completed(); len(done) > 0 {
+			state.Research = append(state.Research, save.ResearchState{Faction: faction, Done: done})
+		}
+	}
+
+	for _, faction := range slices.Sorted(maps.Keys(w.grids)) {
+		if first := w.grids[faction].First; first != entity.PowerBalanced {
+			state.Power = append(state.Power, save.PowerState{Faction: faction, First: first})
+		}
+	}
+
+	for _, e := range w.Effects {
+		state.Effects = append(state.Effects, save.EffectState{
+			Ability:   e.Def.Type,
+			Faction:   e.Faction,
+			X:         e.Center.X,
+			Y:         e.Center.Y,
+			Remaining: e.Remaining,
+		})
+	}
+
+	deposits := w.Terrain.Deposits()
+	state.Deposits = make([]save.DepositState, len(deposits))
+	for i, d := range deposits {
+		state.Deposits[i] = save.DepositState{X: d.X, Y: d.Y, Amount: d.Amount}
+	}
+
+	return state
+}
+
+// unitState captures one unit, together with the units aboard it
+func unitState(u *entity.Unit) save.UnitState {
+	us := save.UnitState{
+		ID:           u.ID,
+		Type:         u.Type,
+		Faction:      u.Faction,
+		PosX:         u.Position.X,
+		PosY:         u.Position.Y,
+		Health:       u.Health,
+		Selected:     u.Selected,
+		Angle:        u.Angle,
+		TurretAngle:  u.TurretAngle,
+		HasTarget:    u.HasTarget,
+		TargetX:      u.Target.X,
+		TargetY:      u.Target.Y,
+		FireCooldown: u.FireCooldown,
+		Stance:       u.Stance,
+		PostX:        u.Post.X,
+		PostY:        u.Post.Y,
+		Experience:   u.Experience,
+		Rank:         u.Rank,
+	}
+
+	if u.AttackTarget != nil && u.AttackTarget.Active {
+		us.AttackTargetID = u.AttackTarget.ID
+	}
+	if u.BuildingAttackTarget != nil && u.BuildingAttackTarget.Active {
+		us.BuildingAttackTargetID = u.BuildingAttackTarget.ID
+	}
+	us.AttackOrdered = u.AttackOrdered
+
+	us.HasBuildTask = u.HasBuildTask
+	if u.BuildDef != nil {
+		us.BuildDefType = u.BuildDef.Type
+	}
+	us.BuildPosX = u.BuildPos.X
+	us.BuildPosY = u.BuildPos.Y
+	if u.BuildTarget != nil {
+		us.BuildTargetID = u.BuildTarget.ID
+	}
+	us.IsBuilding = u.IsBuilding
+
+	if len(u.BuildQueue) > 0 {
+		us.BuildQueue = make([]save.BuildTaskState, len(u.BuildQueue))
+		for i, task := range u.BuildQueue {
+			us.BuildQueue[i] = save.BuildTaskState{
+				DefType: task.Def.Type,
+				PosX:    task.Pos.X,
+				PosY:    task.Pos.Y,
+			}
+		}
+	}
+
+	for _, o := range u.Orders {
+		st := save.OrderState{Type: o.Type, PosX: o.Pos.X, PosY: o.Pos.Y}
+		if o.Unit != nil {
+			st.TargetID = o.Unit.ID
+		}
+		if o.Building != nil {
+			st.TargetBuildingID = o.Building.ID
+		}
+		if o.Wreck != nil {
+			st.WreckID = o.Wreck.ID
+		}
+		us.Orders = append(us.Orders, st)
+	}
+
+	if u.RepairTarget != nil && u.RepairTarget.Active {
+		us.RepairTargetID = u.RepairTarget.ID
+	}
+
+	if u.Ability != (entity.AbilityState{}) {
+		us.Cooldowns = slices.Clone(u.Ability.Cooldowns[:])
+		us.Boost = u.Ability.Boost
+		us.BoostLeft = u.Ability.BoostLeft
+	}
+
+	for _, c := range u.Cargo {
+		us.Cargo = append(us.Cargo, unitState(c))
+	}
+	return us
+}
+
+// Restore replaces the entities and game state of the world with a
+// snapshot. Faction resources are left untouched.
+func (w *World) Restore(state *save.WorldState) {
+	w.Tick = state.Tick
+	w.NextUnitID = state.NextUnitID
+	w.NextBuildingID = state.NextBuildingID
+	w.NextWreckageID = state.NextWreckageID
+	w.NextProjectileID = state.NextProjectileID
+
+	w.Units = make([]*entity.Unit, 0, len(state.Units))
+	w.Buildings = make([]*entity.Building, 0, len(state.Buildings))
+	w.Wreckages = make([]*entity.Wreckage, 0, len(state.Wreckages))
+	w.Projectiles = nil
+	w.commands = w.commands[:0]
+
+	// Older saves keep the deposits the map was generated with
+	if state.Deposits != nil {
+		deposits := make([]terrain.Deposit, len(state.Deposits))
+		for i, d := range state.Deposits {
+			deposits[i] = terrain.Deposit{X: d.X, Y: d.Y, Amount: d.Amount}
+		}
+		w.Terrain.SetDeposits(deposits)
+	}
+
+	w.tech = make(map[entity.Faction]*entity.Tech)
+	for _, rs := range state.Research {
+		w.Tech(rs.Faction).SetCompleted(rs.Done)
+	}
+	w.grids = make(map[entity.Faction]*entity.PowerGrid)
+	for _, ps := range state.Power {
+		w.PowerGrid(ps.Faction).First = ps.First
+	}
+	w.Effects = nil
+	for _, es := range state.Effects {
+		if def := entity.AbilityDefs[es.Ability]; def != nil {
+			e := entity.NewAreaEffect(def, es.Faction, emath.Vec2{X: es.X, Y: es.Y})
+			e.Remaining = es.Remaining
+			w.Effects = append(w.Effects, e)
+		}
+	}
+
+	unitMap := make(map[uint64]*entity.Unit)
+	buildingMap := make(map[uint64]*entity.Building)
+
+	// Targets are linked once every unit and building exists; skipped
+	// entries are left out so restored[i] matches w.Units[i]
+	restoredUnits := make([]save.UnitState, 0, len(state.Units))
+	for _, us := range state.Units {
+		def := entity.UnitDefs[us.Type]
+		if def == nil {
+			continue
+		}
+		u := w.restoreUnit(us, def)
+		// Units aboard can still be guarded, boarded or hunted by others
+		for _, cs := range us.Cargo {
+			if cargoDef := entity.UnitDefs[cs.Type]; cargoDef != nil {
+				c := w.restoreUnit(cs, cargoDef)
+				u.Load(c)
+				unitMap[c.ID] = c
+			}
+		}
+
+		w.Units = append(w.Units, u)
+		restoredUnits = append(restoredUnits, us)
+		unitMap[u.ID] = u
+	}
+
+	restoredBuildings := make([]save.BuildingState, 0, len(state.Buildings))
+	for _, bs := range state.Buildings {
+		def := entity.BuildingDefs[bs.Type]
+		if def == nil {
+			continue
+		}
+		var b *entity.Building
+		if bs.Completed {
+			b = entity.NewBuilding(bs.ID, bs.PosX, bs.PosY, def)
+		} else {
+			b = entity.NewBuildingUnderConstruction(bs.ID, bs.PosX, bs.PosY, def)
+			b.BuildProgress = bs.BuildProgress
+		}
+		// What was spent decides what recycling returns
+		b.MetalSpent = bs.MetalSpent
+		b.EnergySpent = bs.EnergySpent
+		b.Recycling = bs.Recycling
+		b.RecycleProgress = bs.RecycleProgress
+		b.Faction = bs.Faction
+		b.Health = bs.Health
+		b.Selected = bs.Selected
+		b.Priority = bs.Priority
+		b.RallyPoint = emath.Vec2{X: bs.RallyPointX, Y: bs.RallyPointY}
+		b.HasRallyPoint = bs.HasRallyPoint
+		b.FireCooldown = bs.FireCooldown
+
+		b.Producing = bs.Producing
+		b.ProductionProgress = bs.ProductionProgress
+		b.ProductionMetalSpent = bs.ProductionMetalSpent
+		b.ProductionEnergySpent = bs.ProductionEnergySpent
+		if bs.Producing {
+			b.CurrentProduction = entity.UnitDefs[bs.CurrentProductionType]
+			if b.CurrentProduction != nil {
+				b.ProductionTime = b.CurrentProduction.BuildTime
+			}
+		}
+		if len(bs.ProductionQueue) > 0 {
+			b.ProductionQueue = make([]*entity.UnitDef, len(bs.ProductionQueue))
+			for i, unitType := range bs.ProductionQueue {
+				b.ProductionQueue[i] = entity.UnitDefs[unitType]
+			}
+		}
+
+		if bs.Researching {
+			b.CurrentResearch = entity.ResearchDefs[bs.CurrentResearchType]
+			b.Researching = b.CurrentResearch != nil
+			b.ResearchProgress = bs.ResearchProgress
+			b.ResearchMetalSpent = bs.ResearchMetalSpent
+			b.ResearchEnergySpent = bs.ResearchEnergySpent
+		}
+		for _, rt := range bs.ResearchQueue {
+			if def := entity.ResearchDefs[rt]; def != nil {
+				b.ResearchQueue = append(b.ResearchQueue, def)
+			}
+		}
+
+		if b.Faction != entity.FactionPlayer {
+			b.Color = entity.GetFactionTintedColor(def.Color, b.Faction)
+		}
+
+		w.Buildings = append(w.Buildings, b)
+		restoredBuildings = append(restoredBuildings, bs)
+		buildingMap[b.ID] = b
+	}
+
+	wreckMap := make(map[uint64]*entity.Wreckage)
+	for _, ws := range state.Wreckages {
+		wr := &entity.Wreckage{
+			Entity: entity.Entity{
+				ID:       ws.ID,
+				Position: emath.Vec2{X: ws.PosX, Y: ws.PosY},
+				Size:     emath.Vec2{X: ws.SizeX, Y: ws.SizeY},
+				Color:    entity.WreckageColor,
+				Active:   true,
+				Faction:  entity.FactionNeutral,
+			},
+			MetalValue: ws.MetalValue,
+		}
+		w.Wreckages = append(w.Wreckages, wr)
+		wreckMap[wr.ID] = wr
+	}
+
+	for i, us := range restoredUnits {
+		u := w.Units[i]
+		if us.AttackTargetID != 0 {
+			if target, ok := unitMap[us.AttackTargetID]; ok {
+				u.AttackTarget = target
+			}
+		}
+		if us.BuildingAttackTargetID != 0 {
+			if target, ok := buildingMap[us.BuildingAttackTargetID]; ok {
+				u.BuildingAttackTarget = target
+			}
+		}
+		u.AttackOrdered = us.AttackOrdered && u.HasAnyAttackTarget()
+		if us.BuildTargetID != 0 {
+			if target, ok := buildingMap[us.BuildTargetID]; ok {
+				u.BuildTarget = target
+			}
+		}
+		if us.RepairTargetID != 0 {
+			if target, ok := unitMap[us.RepairTargetID]; ok {
+				u.RepairTarget = target
+			}
+		}
+		// Orders whose target is gone are dropped
+		for _, st := range us.Orders {
+			o := entity.Order{Type: st.Type, Pos: emath.Vec2{X: st.PosX, Y: st.PosY}}
+			if st.TargetID != 0 {
+				if o.Unit = unitMap[st.TargetID]; o.Unit == nil {
+					continue
+				}
+			}
+			if st.TargetBuildingID != 0 {
+				if o.Building = buildingMap[st.TargetBuildingID]; o.Building == nil {
+					continue
+				}
+			}
+			if st.Type == entity.OrderReclaim {
+				if o.Wreck = wreckMap[st.WreckID]; o.Wreck == nil {
+					continue
+				}
+			}
+			u.Orders = append(u.Orders, o)
+		}
+	}
+
+	for i, bs := range restoredBuildings {
+		if bs.AttackTargetID != 0 {
+			if target, ok := unitMap[bs.AttackTargetID]; ok {
+				w.Buildings[i].AttackTarget = target
+			}
+		}
+	}
+	w.RebuildIndexes()
+}
+
+// restoreUnit creates a unit from its saved state. Targets, orders and
+// cargo refer to other entities and are linked by Restore.
+func (w *World) restoreUnit(us save.UnitState, def *entity.UnitDef) *entity.Unit {
+	u := entity.NewUnitFromDef(us.ID, us.PosX, us.PosY, def, us.Faction)
+	u.SetRank(us.Rank)
+	u.SetUpgrade(w.Tech(us.Faction).Upgrade())
+	u.Experience = us.Experience
+	u.Health = us.Health
+	u.Selected = us.Selected
+	u.Angle = us.Angle
+	u.TurretAngle = us.TurretAngle
+	if us.HasTarget {
+		// Paths are not saved, so the route is planned again
+		u.SetTarget(emath.Vec2{X: us.TargetX, Y: us.TargetY})
+	}
+	u.FireCooldown = us.FireCooldown
+	u.Stance = us.Stance
+	u.Post = emath.Vec2{X: us.PostX, Y: us.PostY}
+	u.HasBuildTask = us.HasBuildTask
+	if us.HasBuildTask {
+		u.BuildDef = entity.BuildingDefs[us.BuildDefType]
+	}
+	u.BuildPos = emath.Vec2{X: us.BuildPosX, Y: us.BuildPosY}
+	u.IsBuilding = us.IsBuilding
+	copy(u.Ability.Cooldowns[:], us.Cooldowns)
+	u.Ability.Boost = us.Boost
+	u.Ability.BoostLeft = us.BoostLeft
+
+	if len(us.BuildQueue) > 0 {
+		u.BuildQueue = make([]entity.BuildTask, len(us.BuildQueue))
+		for i, task := range us.BuildQueue {
+			u.BuildQueue[i] = entity.BuildTask{
+				Def: entity.BuildingDefs[task.DefType],
+				Pos: emath.Vec2{X: task.PosX, Y: task.PosY},
+			}
+		}
+	}
+	return u
+}
@@ -0,0 +1,74 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	"github.com/bklimczak/tanks/engine/save"
+	"github.com/bklimczak/tanks/engine/terrain"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRestoreLinksOrdersToCarriedUnits(t *testing.T) {
+	w := newTestWorld()
+	lifter := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeAirTransport], 100, 100, entity.FactionPlayer)
+	tank := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 100, 100, entity.FactionPlayer)
+	escort := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeLightTank], 300, 100, entity.FactionPlayer)
+	lifter.Load(tank)
+	escort.QueueOrder(entity.Order{Type: entity.OrderGuard, Unit: tank})
+
+	state := w.Snapshot()
+	restored := newTestWorld()
+	restored.Restore(&state)
+
+	guard := restored.Unit(escort.ID)
+	if guard == nil {
+		t.Fatal("escort not restored")
+	}
+	if len(guard.Orders) != 1 {
+		t.Fatalf("escort restored with %d orders, want its guard order", len(guard.Orders))
+	}
+	carrier := restored.Unit(lifter.ID)
+	if carrier == nil || len(carrier.Cargo) != 1 {
+		t.Fatalf("skylifter restored without its cargo")
+	}
+	if guard.Orders[0].Unit != carrier.Cargo[0] {
+		t.Error("the guard order should follow the restored tank aboard the skylifter")
+	}
+}
+
+func TestDepositsSurviveSaveRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		deposits map[[2]int]float64
+	}{
+		{"partly mined", map[[2]int]float64{{2, 2}: 40, {3, 2}: 1500}},
+		{"all mined out", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld()
+			for p, amount := range tt.deposits {
+				w.Terrain.SetMetal(p[0], p[1], amount)
+			}
+			data, err := yaml.Marshal(w.Snapshot())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var state save.WorldState
+			if err := yaml.Unmarshal(data, &state); err != nil {
+				t.Fatal(err)
+			}
+
+			// The map generated on load holds untouched deposits elsewhere
+			restored := newTestWorld()
+			restored.Terrain.SetMetal(10, 10, terrain.DefaultMetalAmount)
+			restored.Restore(&state)
+			if got, want := restored.Terrain.Deposits(), w.Terrain.Deposits(); !reflect.DeepEqual(got, want) {
+				t.Errorf("restored deposits %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package sim
+
+import (
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// Concealed reports whether u is hidden from the viewer faction: it is
+// cloaked with no detector of the viewer in range, or it stands in a smoke
+// screen. The viewer's sensor sweeps see through both. Hidden units cannot
+// be seen or fired upon.
+func (w *World) Concealed(u *entity.Unit, viewer entity.Faction) bool {
+	if u.Faction == viewer {
+		return false
+	}
+	cloaked := u.IsCloaked()
+	if !cloaked && len(w.Effects) == 0 {
+		return false
+	}
+	center := u.Center()
+	smoked := false
+	for _, e := range w.Effects {
+		if !e.Covers(center) {
+			continue
+		}
+		if e.Def.Reveals && e.Faction == viewer {
+			return false
+		}
+		if e.Def.Conceals {
+			smoked = true
+		}
+	}
+	return smoked || cloaked && !w.Detected(center, viewer)
+}
+
+// VisibleUnits returns the units the viewer faction knows of: its own and
+// every other unit not concealed from it
+func (w *World) VisibleUnits(viewer entity.Faction) []*entity.Unit {
+	units := make([]*entity.Unit, 0, len(w.Units))
+	for _, u := range w.Units {
+		if !w.Concealed(u, viewer) {
+			units = append(units, u)
+		}
+	}
+	return units
+}
+
+// Detected reports whether a detector of the faction, unit or building,
+// reveals cloaked units at p
+func (w *World) Detected(p emath.Vec2, faction entity.Faction) bool {
+	if w.maxUnitDetection > 0 {
+		// Detectors may have moved up to maxUnitSpeed since the index was built
+		w.detectorBuf = w.unitIndex.QueryRadius(p, w.maxUnitDetection+w.maxUnitSpeed, w.detectorBuf[:0])
+		for _, u := range w.detectorBuf {
+			if u.Faction == faction && u.Detects(p) {
+				return true
+			}
+		}
+	}
+	if w.maxBuildingDetection > 0 {
+		w.sensorBuf = w.buildingIndex.QueryRadius(p, w.maxBuildingDetection, w.sensorBuf[:0])
+		for _, b := range w.sensorBuf {
+			if b.Faction == faction && b.Detects(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OnRadar reports whether p lies inside the radar coverage of the faction's
+// buildings
+func (w *World) OnRadar(p emath.Vec2, faction entity.Faction) bool {
+	if w.maxRadarRange == 0 {
+		return false
+	}
+	w.sensorBuf = w.buildingIndex.QueryRadius(p, w.maxRadarRange, w.sensorBuf[:0])
+	for _, b := range w.sensorBuf {
+		if b.Faction == faction && b.OnRadar(p) {
+			return true
+		}
+	}
+	return false
+}
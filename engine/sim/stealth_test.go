@@ -0,0 +1,61 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+func TestDetectedFindsSensorsInRange(t *testing.T) {
+	p := emath.Vec2{X: 1000, Y: 1000}
+	scout := entity.UnitDefs[entity.UnitTypeScout]
+	uplink := entity.BuildingDefs[entity.BuildingDataUplink]
+
+	tests := []struct {
+		name         string
+		place        func(w *World)
+		wantDetected bool
+		wantRadar    bool
+	}{
+		{"no sensors", func(w *World) {}, false, false},
+		{"scout in range", func(w *World) {
+			w.SpawnUnit(scout, p.X-scout.DetectionRange+20, p.Y, entity.FactionPlayer)
+		}, true, false},
+		{"scout out of range", func(w *World) {
+			w.SpawnUnit(scout, p.X-scout.DetectionRange-40, p.Y, entity.FactionPlayer)
+		}, false, false},
+		{"enemy scout", func(w *World) {
+			w.SpawnUnit(scout, p.X, p.Y, entity.FactionEnemy)
+		}, false, false},
+		{"scout moved in since the index was built", func(w *World) {
+			// Just out of range when indexed, one step from in range
+			u := w.SpawnUnit(scout, 0, p.Y, entity.FactionPlayer)
+			u.Position.X = p.X - scout.DetectionRange - u.Size.X/2 - scout.Speed/2
+			w.RebuildIndexes()
+			u.Position.X += scout.Speed
+		}, true, false},
+		{"uplink detector range", func(w *World) {
+			w.SpawnBuilding(uplink, p.X-uplink.DetectionRange+40, p.Y, entity.FactionPlayer)
+		}, true, true},
+		{"uplink radar range", func(w *World) {
+			w.SpawnBuilding(uplink, p.X-uplink.RadarRange+40, p.Y, entity.FactionPlayer)
+		}, false, true},
+		{"uplink under construction", func(w *World) {
+			w.StartBuilding(uplink, p.X, p.Y, entity.FactionPlayer)
+		}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld()
+			tt.place(w)
+			if got := w.Detected(p, entity.FactionPlayer); got != tt.wantDetected {
+				t.Errorf("Detected = %v, want %v", got, tt.wantDetected)
+			}
+			if got := w.OnRadar(p, entity.FactionPlayer); got != tt.wantRadar {
+				t.Errorf("OnRadar = %v, want %v", got, tt.wantRadar)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package sim
+
+import (
+	"math"
+	"slices"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// unloadRings is how many rings of spots around a transport are tried
+// when setting a unit down; units with no free spot stay aboard
+const unloadRings = 4
+
+// loadDone steers a unit through a load order and reports whether it is
+// finished. A transport drives to the unit it picks up, a unit boarding
+// drives to its transport.
+func (w *World) loadDone(u *entity.Unit, o *entity.Order) bool {
+	transport, passenger := u, o.Unit
+	if !u.CanTransport() {
+		transport, passenger = o.Unit, u
+	}
+	if !transport.CanLoad(passenger) {
+		return true
+	}
+	if transport.IsInLoadRange(passenger) {
+		transport.Load(passenger)
+		u.ClearTarget()
+		return true
+	}
+	// Follow the other unit while it moves
+	if other := o.Unit.Center(); !u.HasTarget || u.Target.Distance(other) > guardRadius/2 {
+		u.SetTarget(other)
+	}
+	return false
+}
+
+// unloadAll sets a transport's cargo down on free ground around it
+func (w *World) unloadAll(t *entity.Unit) {
+	for _, c := range slices.Clone(t.Cargo) {
+		pos, ok := w.unloadSpot(t, c)
+		if !ok {
+			continue
+		}
+		t.Unload(c, pos)
+		// A unit that boarded this tick has not left Units yet
+		if !slices.Contains(w.Units, c) {
+			w.Units = append(w.Units, c)
+		}
+		w.unitIndex.Insert(c, c.Bounds())
+		w.maxUnitSpeed = max(w.maxUnitSpeed, c.Speed)
+	}
+}
+
+// unloadSpot finds where a unit aboard t can stand, trying rings of spots
+// around the transport from the inside out. It returns the top-left
+// corner of the unit's bounds there.
+func (w *World) unloadSpot(t, c *entity.Unit) (emath.Vec2, bool) {
+	center := t.Center()
+	step := max(c.Size.X, c.Size.Y) + 4
+	first := max(t.Size.X, t.Size.Y)/2 + step/2
+	for ring := range unloadRings {
+		radius := first + float64(ring)*step
+		spots := max(6, int(2*math.Pi*radius/step))
+		for i := range spots {
+			// Start behind the transport and work round both sides
+			side := float64((i+1)/2) * float64(1-2*(i%2))
+			angle := t.Angle + math.Pi + side*2*math.Pi/float64(spots)
+			spot := center.Add(emath.Vec2{X: math.Cos(angle) * radius, Y: math.Sin(angle) * radius}).Sub(c.Size.Mul(0.5))
+			bounds := emath.Rect{Pos: spot, Size: c.Size}
+			if w.Terrain.IsPassable(bounds, c.Movement()) && w.isClear(bounds) {
+				return spot, true
+			}
+		}
+	}
+	return emath.Vec2{}, false
+}
+
+// isClear reports whether bounds is free of ground units, buildings and
+// wrecks
+func (w *World) isClear(bounds emath.Rect) bool {
+	w.unitBuf = w.unitIndex.Query(bounds, w.unitBuf[:0])
+	for _, u := range w.unitBuf {
+		if u.Active && !u.IsAircraft() && u.Bounds().Intersects(bounds) {
+			return false
+		}
+	}
+	w.buildingBuf = w.buildingIndex.Query(bounds, w.buildingBuf[:0])
+	for _, b := range w.buildingBuf {
+		if b.Active && b.Bounds().Intersects(bounds) {
+			return false
+		}
+	}
+	w.wreckBuf = w.wreckIndex.Query(bounds, w.wreckBuf[:0])
+	for _, wr := range w.wreckBuf {
+		if wr.Active && wr.Bounds().Intersects(bounds) {
+			return false
+		}
+	}
+	return true
+}
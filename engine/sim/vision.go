@@ -0,0 +1,33 @@
+package sim
+
+import "github.com/bklimczak/tanks/engine/entity"
+
+// Lookouts returns the faction's active units that see something none of
+// the others already returned sees, give or take slack, so an army bunched
+// on one spot reveals the fog once. Slack is usually the fog tile size.
+func (w *World) Lookouts(faction entity.Faction, slack float64) []*entity.Unit {
+	clear(w.lookoutSet)
+	var lookouts []*entity.Unit
+	for _, u := range w.Units {
+		if !u.Active || u.Faction != faction || w.sightCovered(u, slack) {
+			continue
+		}
+		w.lookoutSet[u.ID] = true
+		lookouts = append(lookouts, u)
+	}
+	return lookouts
+}
+
+// sightCovered reports whether a lookout already returned stands close
+// enough to u, and sees far enough, to cover its whole sight circle
+func (w *World) sightCovered(u *entity.Unit, slack float64) bool {
+	center := u.Center()
+	// Both units may have moved since the index was built
+	w.lookoutBuf = w.unitIndex.QueryRadius(center, slack+2*w.maxUnitSpeed, w.lookoutBuf[:0])
+	for _, other := range w.lookoutBuf {
+		if w.lookoutSet[other.ID] && center.Distance(other.Center())+u.VisionRange <= other.VisionRange+slack {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,864 @@
+// Package sim contains the headless game simulation shared by the
+// single-player client and the multiplayer server. A World owns every
+// entity and all per-faction resources; callers change it only by
+// submitting commands and advancing it one tick at a time.
+package sim
+
+import (
+	"math"
+
+	"github.com/bklimczak/tanks/engine/collision"
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/pathfinding"
+	"github.com/bklimczak/tanks/engine/resource"
+	"github.com/bklimczak/tanks/engine/spatial"
+	"github.com/bklimczak/tanks/engine/terrain"
+)
+
+// TickRate is the fixed simulation step in seconds
+const TickRate = 1.0 / 60.0
+
+// BuildingGridSize is the grid building placements snap to
+const BuildingGridSize = terrain.TileSize
+
+// maxPathsPerTick limits how many paths are planned in one tick; units
+// still waiting drive straight at their target until their turn comes
+const maxPathsPerTick = 16
+
+// spatialCellSize is the bucket size of the unit and building indexes
+const spatialCellSize = 100.0
+
+// Repair cost per health point
+const (
+	repairMetalCostPerHP  = 0.5
+	repairEnergyCostPerHP = 0.25
+)
+
+// World is the authoritative game state
+type World struct {
+	Units       []*entity.Unit
+	Buildings   []*entity.Building
+	Wreckages   []*entity.Wreckage
+	Projectiles []*entity.Projectile
+	Effects     []*entity.AreaEffect // Smoke, sweeps and other lasting ability areas
+	Terrain     *terrain.Map
+	Collision   *collision.System
+	Paths       map[entity.MovementClass]*pathfinding.Grid // One grid per surface-bound movement class
+
+	NextUnitID       uint64
+	NextBuildingID   uint64
+	NextWreckageID   uint64
+	NextProjectileID uint64
+	Tick             uint64
+
+	resources map[entity.Faction]*resource.Manager
+	tech      map[entity.Faction]*entity.Tech
+	grids     map[entity.Faction]*entity.PowerGrid
+	commands  []Command
+
+	nextFormationID uint64
+
+	pathObstaclesTick uint64 // Tick the building obstacles were last copied into Paths
+	pathObstaclesSet  bool
+
+	// Spatial indexes rebuilt at the start of every tick. Units move during
+	// the tick, so unit queries are widened by maxUnitSpeed.
+	unitIndex            *spatial.Hash[*entity.Unit]
+	buildingIndex        *spatial.Hash[*entity.Building]
+	wreckIndex           *spatial.Hash[*entity.Wreckage]
+	maxUnitSpeed         float64
+	maxUnitDetection     float64 // Largest sensor ranges indexed, bounding sensor queries
+	maxBuildingDetection float64
+	maxRadarRange        float64
+	unitBuf              []*entity.Unit
+	buildingBuf          []*entity.Building
+	wreckBuf             []*entity.Wreckage
+	obstacleBuf          []emath.Rect
+	lookoutBuf           []*entity.Unit
+	lookoutSet           map[uint64]bool
+	detectorBuf          []*entity.Unit
+	sensorBuf            []*entity.Building
+}
+
+// NewWorld creates an empty world on the given terrain
+func NewWorld(terrainMap *terrain.Map) *World {
+	w := &World{
+		Terrain:       terrainMap,
+		Collision:     collision.NewSystem(terrainMap.PixelWidth, terrainMap.PixelHeight),
+		resources:     make(map[entity.Faction]*resource.Manager),
+		tech:          make(map[entity.Faction]*entity.Tech),
+		grids:         make(map[entity.Faction]*entity.PowerGrid),
+		unitIndex:     spatial.NewHash[*entity.Unit](terrainMap.PixelWidth, terrainMap.PixelHeight, spatialCellSize),
+		buildingIndex: spatial.NewHash[*entity.Building](terrainMap.PixelWidth, terrainMap.PixelHeight, spatialCellSize),
+		wreckIndex:    spatial.NewHash[*entity.Wreckage](terrainMap.PixelWidth, terrainMap.PixelHeight, spatialCellSize),
+		lookoutSet:    make(map[uint64]bool),
+	}
+	w.Collision.SetTerrain(terrainMap)
+	w.Paths = make(map[entity.MovementClass]*pathfinding.Grid)
+	for _, class := range []entity.MovementClass{entity.MovementGround, entity.MovementHover, entity.MovementNaval} {
+		w.Paths[class] = pathfinding.NewGrid(terrainMap.Width, terrainMap.Height, terrain.TileSize, func(x, y int) bool {
+			return terrainMap.Tiles[y][x].PassableFor(class)
+		})
+	}
+	return w
+}
+
+// Resources returns the resource manager of a faction, creating it if needed
+func (w *World) Resources(faction entity.Faction) *resource.Manager {
+	res, ok := w.resources[faction]
+	if !ok {
+		res = resource.NewManager()
+		w.resources[faction] = res
+	}
+	return res
+}
+
+// SetResources replaces the resource manager of a faction
+func (w *World) SetResources(faction entity.Faction, res *resource.Manager) {
+	w.resources[faction] = res
+}
+
+// Tech returns the research a faction has finished, creating it if needed
+func (w *World) Tech(faction entity.Faction) *entity.Tech {
+	tech, ok := w.tech[faction]
+	if !ok {
+		tech = &entity.Tech{}
+		w.tech[faction] = tech
+	}
+	return tech
+}
+
+// PowerGrid returns the power grid of a faction, creating it if needed
+func (w *World) PowerGrid(faction entity.Faction) *entity.PowerGrid {
+	grid, ok := w.grids[faction]
+	if !ok {
+		grid = &entity.PowerGrid{}
+		w.grids[faction] = grid
+	}
+	return grid
+}
+
+// Researching reports whether a faction is running or has queued a project
+// in any of its buildings
+func (w *World) Researching(faction entity.Faction, rt entity.ResearchType) bool {
+	for _, b := range w.Buildings {
+		if b.Active && b.Faction == faction && b.HasResearch(rt) {
+			return true
+		}
+	}
+	return false
+}
+
+// completeResearch marks a project finished for a faction and gives its
+// units the new research bonus
+func (w *World) completeResearch(faction entity.Faction, def *entity.ResearchDef) {
+	tech := w.Tech(faction)
+	tech.Complete(def.Type)
+	if def.Upgrade == (entity.Upgrade{}) {
+		return
+	}
+	up := tech.Upgrade()
+	for _, u := range w.Units {
+		if u.Active && u.Faction == faction {
+			u.SetUpgrade(up)
+			for _, c := range u.Cargo {
+				c.SetUpgrade(up)
+			}
+		}
+	}
+}
+
+// Unit finds an active unit by ID
+func (w *World) Unit(id uint64) *entity.Unit {
+	for _, u := range w.Units {
+		if u.ID == id && u.Active {
+			return u
+		}
+	}
+	return nil
+}
+
+// Building finds an active building by ID
+func (w *World) Building(id uint64) *entity.Building {
+	for _, b := range w.Buildings {
+		if b.ID == id && b.Active {
+			return b
+		}
+	}
+	return nil
+}
+
+// Wreck finds an active wreck by ID
+func (w *World) Wreck(id uint64) *entity.Wreckage {
+	for _, wr := range w.Wreckages {
+		if wr.ID == id && wr.Active {
+			return wr
+		}
+	}
+	return nil
+}
+
+// SpawnUnit creates a unit and adds it to the world
+func (w *World) SpawnUnit(def *entity.UnitDef, x, y float64, faction entity.Faction) *entity.Unit {
+	unit := entity.NewUnitFromDef(w.NextUnitID, x, y, def, faction)
+	unit.SetUpgrade(w.Tech(faction).Upgrade())
+	w.Units = append(w.Units, unit)
+	w.NextUnitID++
+	// Index right away so units spawned mid-tick are seen by later queries
+	w.unitIndex.Insert(unit, unit.Bounds())
+	w.maxUnitSpeed = max(w.maxUnitSpeed, unit.Speed)
+	w.maxUnitDetection = max(w.maxUnitDetection, unit.DetectionRange())
+	return unit
+}
+
+// SpawnBuilding creates a completed building and applies its effects
+func (w *World) SpawnBuilding(def *entity.BuildingDef, x, y float64, faction entity.Faction) *entity.Building {
+	building := entity.NewBuilding(w.NextBuildingID, x, y, def)
+	building.Faction = faction
+	if faction != entity.FactionPlayer {
+		building.Color = entity.GetFactionTintedColor(def.Color, faction)
+	}
+	w.Buildings = append(w.Buildings, building)
+	w.NextBuildingID++
+	w.indexBuilding(building)
+	w.ApplyBuildingEffects(faction, def)
+	return building
+}
+
+// StartBuilding creates a building site that is completed over time
+func (w *World) StartBuilding(def *entity.BuildingDef, x, y float64, faction entity.Faction) *entity.Building {
+	building := entity.NewBuildingUnderConstruction(w.NextBuildingID, x, y, def)
+	building.Faction = faction
+	if faction != entity.FactionPlayer {
+		building.Color = entity.GetFactionTintedColor(def.Color, faction)
+	}
+	w.Buildings = append(w.Buildings, building)
+	w.NextBuildingID++
+	w.indexBuilding(building)
+	return building
+}
+
+// ApplyBuildingEffects adds a completed building's economy to its faction.
+// Extractors yield only what their deposit holds, see updateExtraction.
+func (w *World) ApplyBuildingEffects(faction entity.Faction, def *entity.BuildingDef) {
+	res := w.Resources(faction)
+	if def.MetalProduction > 0 && !def.RequiresDeposit {
+		res.AddProduction(resource.Metal, def.MetalProduction)
+	}
+	if def.EnergyProduction > 0 {
+		res.AddProduction(resource.Energy, def.EnergyProduction)
+	}
+	if def.MetalConsumption > 0 {
+		res.AddConsumption(resource.Metal, def.MetalConsumption)
+	}
+	if def.EnergyConsumption > 0 {
+		res.AddConsumption(resource.Energy, def.EnergyConsumption)
+	}
+	if def.MetalStorage > 0 {
+		res.AddCapacity(resource.Metal, def.MetalStorage)
+	}
+	if def.EnergyStorage > 0 {
+		res.AddCapacity(resource.Energy, def.EnergyStorage)
+	}
+}
+
+// RemoveBuildingEffects takes a building's economy away from its faction
+// again, the reverse of ApplyBuildingEffects
+func (w *World) RemoveBuildingEffects(faction entity.Faction, def *entity.BuildingDef) {
+	res := w.Resources(faction)
+	if def.MetalProduction > 0 && !def.RequiresDeposit {
+		res.AddProduction(resource.Metal, -def.MetalProduction)
+	}
+	if def.EnergyProduction > 0 {
+		res.AddProduction(resource.Energy, -def.EnergyProduction)
+	}
+	if def.MetalConsumption > 0 {
+		res.AddConsumption(resource.Metal, -def.MetalConsumption)
+	}
+	if def.EnergyConsumption > 0 {
+		res.AddConsumption(resource.Energy, -def.EnergyConsumption)
+	}
+	if def.MetalStorage > 0 {
+		res.SetCapacity(resource.Metal, res.Get(resource.Metal).Capacity-def.MetalStorage)
+	}
+	if def.EnergyStorage > 0 {
+		res.SetCapacity(resource.Energy, res.Get(resource.Energy).Capacity-def.EnergyStorage)
+	}
+}
+
+// recycle starts taking a building apart. A completed building stops
+// adding to its faction's economy right away; constructors working on a
+// site leave it.
+func (w *World) recycle(b *entity.Building) {
+	if !b.StartRecycle(w.Resources(b.Faction)) {
+		return
+	}
+	if b.Completed {
+		w.RemoveBuildingEffects(b.Faction, b.Def)
+	}
+	for _, u := range w.Units {
+		if u.Active && u.BuildTarget == b {
+			u.ClearBuildTask()
+		}
+	}
+}
+
+// SnapToGrid aligns a world position to the building grid
+func SnapToGrid(pos emath.Vec2) emath.Vec2 {
+	return emath.Vec2{
+		X: math.Floor(pos.X/BuildingGridSize) * BuildingGridSize,
+		Y: math.Floor(pos.Y/BuildingGridSize) * BuildingGridSize,
+	}
+}
+
+// CanPlaceBuilding checks if a building fits at the grid cell under pos
+func (w *World) CanPlaceBuilding(pos emath.Vec2, def *entity.BuildingDef) bool {
+	if def == nil {
+		return false
+	}
+	buildingPos := SnapToGrid(pos)
+	bounds := emath.NewRect(buildingPos.X, buildingPos.Y, def.GetWidth(), def.GetHeight())
+	if !w.Terrain.IsBuildable(bounds) {
+		return false
+	}
+	if def.RequiresDeposit && !w.hasMetal(bounds) {
+		return false
+	}
+	for _, u := range w.Units {
+		if u.Active && !u.IsAircraft() && bounds.Intersects(u.Bounds()) {
+			return false
+		}
+	}
+	for _, b := range w.Buildings {
+		if b.Active && bounds.Intersects(b.Bounds()) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMetal checks if any tile under bounds is a metal deposit
+func (w *World) hasMetal(bounds emath.Rect) bool {
+	startX, startY := w.Terrain.GetTileCoords(bounds.Pos.X, bounds.Pos.Y)
+	endX, endY := w.Terrain.GetTileCoords(bounds.Pos.X+bounds.Size.X, bounds.Pos.Y+bounds.Size.Y)
+	for y := startY; y <= endY; y++ {
+		for x := startX; x <= endX; x++ {
+			if x >= 0 && x < w.Terrain.Width && y >= 0 && y < w.Terrain.Height {
+				if w.Terrain.Tiles[y][x].HasMetal {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// AliveFactions returns every faction that still owns a unit or building
+func (w *World) AliveFactions() map[entity.Faction]bool {
+	alive := make(map[entity.Faction]bool)
+	for _, u := range w.Units {
+		if u.Active {
+			alive[u.Faction] = true
+		}
+	}
+	for _, b := range w.Buildings {
+		if b.Active {
+			alive[b.Faction] = true
+		}
+	}
+	return alive
+}
+
+// Update processes pending commands and advances the world by dt
+func (w *World) Update(dt float64) {
+	w.processCommands()
+	w.updateAbilities(dt)
+	w.updatePower(dt)
+
+	// Eliminated factions no longer earn or spend
+	alive := w.AliveFactions()
+	w.updateExtraction(dt, alive)
+	for faction, res := range w.resources {
+		if !alive[faction] {
+			continue
+		}
+		res.Update(dt)
+		res.ResetDrains()
+	}
+
+	w.RebuildIndexes()
+	w.fundBuildings(dt)
+	w.updateOrders()
+	w.updateUnits(dt)
+	w.updateBuildings(dt)
+	w.updateCombat(dt)
+	w.updateProjectiles(dt)
+	w.updateBurning(dt)
+	w.cleanupDead()
+
+	w.Tick++
+}
+
+// updatePower shares each faction's energy among its buildings. Stored
+// energy keeps the grid at full power until it runs out; after that the
+// consumers get only what the faction produces.
+func (w *World) updatePower(dt float64) {
+	byFaction := make(map[entity.Faction][]*entity.Building)
+	for _, b := range w.Buildings {
+		if b.Active {
+			byFaction[b.Faction] = append(byFaction[b.Faction], b)
+		}
+	}
+	for faction, buildings := range byFaction {
+		energy := w.Resources(faction).Get(resource.Energy)
+		grid := w.PowerGrid(faction)
+		grid.Supply = energy.Production
+		if dt > 0 {
+			grid.Supply += energy.Current / dt
+		}
+		grid.Allocate(buildings)
+	}
+}
+
+// fundBuildings shares each faction's metal and energy among the buildings
+// spending them, by priority, so a short economy starves low-priority work
+// rather than whatever updates last. Each building then works at the pace
+// its scarcer resource allows; paused buildings keep their progress.
+func (w *World) fundBuildings(dt float64) {
+	type claims struct {
+		metal, energy [entity.NumPriorities]float64
+	}
+	// Constructors on site build as fast as the site itself, see
+	// updateConstructorBuildTask
+	builders := make(map[*entity.Building]int)
+	for _, u := range w.Units {
+		if u.Active && u.IsBuilding && u.BuildTarget != nil {
+			builders[u.BuildTarget]++
+		}
+	}
+	byFaction := make(map[entity.Faction]*claims)
+	for _, b := range w.Buildings {
+		if !b.Active || b.Priority == entity.PriorityPaused {
+			continue
+		}
+		metal, energy := b.Upkeep()
+		if metal == 0 && energy == 0 {
+			continue
+		}
+		c := byFaction[b.Faction]
+		if c == nil {
+			c = &claims{}
+			byFaction[b.Faction] = c
+		}
+		speed := dt * float64(1+builders[b])
+		c.metal[b.Priority] += metal * speed
+		c.energy[b.Priority] += energy * speed
+	}
+
+	// What each level is paid, as a fraction of its claims
+	paid := make(map[entity.Faction]claims, len(byFaction))
+	for faction, c := range byFaction {
+		res := w.Resources(faction)
+		paid[faction] = claims{
+			metal:  entity.ShareOut(res.Get(resource.Metal).Current, c.metal),
+			energy: entity.ShareOut(res.Get(resource.Energy).Current, c.energy),
+		}
+	}
+	for _, b := range w.Buildings {
+		if !b.Active {
+			continue
+		}
+		b.Funding = 1
+		if b.Priority == entity.PriorityPaused {
+			b.Funding = 0
+			continue
+		}
+		metal, energy := b.Upkeep()
+		share, ok := paid[b.Faction]
+		if !ok {
+			continue
+		}
+		if metal > 0 {
+			b.Funding = min(b.Funding, share.metal[b.Priority])
+		}
+		if energy > 0 {
+			b.Funding = min(b.Funding, share.energy[b.Priority])
+		}
+	}
+}
+
+// updateExtraction mines the deposit tiles under completed extractors.
+// What each faction draws this tick is credited by its resource update, so
+// extractors share what room is left in the faction's storage and leave
+// the rest in the ground.
+func (w *World) updateExtraction(dt float64, alive map[entity.Faction]bool) {
+	for _, res := range w.resources {
+		res.Get(resource.Metal).Extraction = 0
+	}
+	if dt <= 0 {
+		return
+	}
+	room := make(map[entity.Faction]float64)
+	for _, b := range w.Buildings {
+		if !b.Active || !b.Completed || b.Recycling || !b.Def.RequiresDeposit || !alive[b.Faction] {
+			continue
+		}
+		metal := w.Resources(b.Faction).Get(resource.Metal)
+		free, ok := room[b.Faction]
+		if !ok {
+			// Production and upkeep settle in the same update
+			free = max(0, metal.Capacity-metal.Current-(metal.Production-metal.Consumption)*dt)
+		}
+		bounds := b.Bounds()
+		perTile := b.Def.MetalProduction * b.Power * dt
+		if tiles, _ := w.Terrain.MetalUnder(bounds); tiles > 0 {
+			perTile = min(perTile, free/float64(tiles))
+		}
+		mined := w.Terrain.ExtractMetal(bounds, perTile)
+		room[b.Faction] = free - mined
+		b.Yield = mined / dt
+		_, b.DepositLeft = w.Terrain.MetalUnder(bounds)
+		metal.Extraction += b.Yield
+	}
+}
+
+// updateUnits runs unit tasks and movement
+func (w *World) updateUnits(dt float64) {
+	planned := 0
+	for _, u := range w.Units {
+		if !u.Active {
+			continue
+		}
+		if u.HasBuildTask {
+			w.updateConstructorBuildTask(u, dt)
+		}
+		if u.RepairTarget != nil {
+			w.updateRepairTask(u, dt)
+		}
+		if o := u.CurrentOrder(); o != nil && o.Type == entity.OrderReclaim {
+			w.updateReclaimTask(u, o.Wreck, dt)
+		}
+		if !u.HasTarget {
+			continue
+		}
+		if u.IsAircraft() {
+			u.ApplyPosition(w.Collision.ResolveFlight(u.Bounds(), u.Update()))
+			continue
+		}
+		if u.NeedsPath && planned < maxPathsPerTick {
+			w.planPath(u)
+			planned++
+		}
+		desiredPos := u.Update()
+		obstacles := w.obstaclesNear(u)
+		resolvedPos := w.Collision.ResolveMovement(u.Bounds(), desiredPos, obstacles, u.Movement())
+
+		// If stuck (resolved position is same as current), try avoidance steering
+		if resolvedPos.DistanceSquared(u.Position) < 0.1 && u.HasTarget {
+			resolvedPos = w.Collision.CalculateAvoidanceDirection(u.Bounds(), u.Waypoint(), u.MoveSpeed(), obstacles, u.Movement())
+		}
+
+		u.ApplyPosition(resolvedPos)
+	}
+}
+
+// RebuildIndexes refills the spatial indexes from the current entities.
+// Update does so every tick; callers that replace the entities outside of
+// Update must do so before querying the world.
+func (w *World) RebuildIndexes() {
+	w.unitIndex.Clear()
+	w.maxUnitSpeed = 0
+	w.maxUnitDetection = 0
+	for _, u := range w.Units {
+		if u.Active {
+			w.unitIndex.Insert(u, u.Bounds())
+			w.maxUnitSpeed = max(w.maxUnitSpeed, u.MoveSpeed())
+			w.maxUnitDetection = max(w.maxUnitDetection, u.DetectionRange())
+		}
+	}
+	w.buildingIndex.Clear()
+	w.maxBuildingDetection = 0
+	w.maxRadarRange = 0
+	for _, b := range w.Buildings {
+		if b.Active {
+			w.indexBuilding(b)
+		}
+	}
+	w.wreckIndex.Clear()
+	for _, wr := range w.Wreckages {
+		if wr.Active {
+			w.wreckIndex.Insert(wr, wr.Bounds())
+		}
+	}
+}
+
+// indexBuilding adds a building to the index. Sensor ranges are taken from
+// the def, as power can switch sensors on after the index is built.
+func (w *World) indexBuilding(b *entity.Building) {
+	w.buildingIndex.Insert(b, b.Bounds())
+	w.maxBuildingDetection = max(w.maxBuildingDetection, b.Def.DetectionRange)
+	w.maxRadarRange = max(w.maxRadarRange, b.Def.RadarRange)
+}
+
+// obstaclesNear returns the bounds of ground units, buildings and wrecks a
+// unit could touch this tick. The slice is reused by the next call.
+func (w *World) obstaclesNear(u *entity.Unit) []emath.Rect {
+	// Reach covers the unit's own step plus how far others may have moved
+	// since the index was built
+	reach := u.MoveSpeed() + w.maxUnitSpeed
+	area := u.Bounds()
+	area.Pos = area.Pos.Sub(emath.Vec2{X: reach, Y: reach})
+	area.Size = area.Size.Add(emath.Vec2{X: 2 * reach, Y: 2 * reach})
+
+	w.obstacleBuf = w.obstacleBuf[:0]
+	w.unitBuf = w.unitIndex.Query(area, w.unitBuf[:0])
+	for _, other := range w.unitBuf {
+		if other.ID != u.ID && other.Active && !other.IsAircraft() {
+			w.obstacleBuf = append(w.obstacleBuf, other.Bounds())
+		}
+	}
+	w.buildingBuf = w.buildingIndex.Query(area, w.buildingBuf[:0])
+	for _, b := range w.buildingBuf {
+		if b.Active {
+			w.obstacleBuf = append(w.obstacleBuf, b.Bounds())
+		}
+	}
+	w.wreckBuf = w.wreckIndex.Query(area, w.wreckBuf[:0])
+	for _, wr := range w.wreckBuf {
+		if wr.Active {
+			w.obstacleBuf = append(w.obstacleBuf, wr.Bounds())
+		}
+	}
+	return w.obstacleBuf
+}
+
+// planPath routes a unit around the terrain its movement class cannot
+// cross and around buildings and wrecks to its target
+func (w *World) planPath(u *entity.Unit) {
+	grid := w.Paths[u.Movement()]
+	if grid == nil {
+		u.NeedsPath = false
+		return
+	}
+	w.refreshPathObstacles()
+	path := grid.FindPath(u.Center(), u.Target, u.Size)
+	if path == nil {
+		// No route at all; drive straight and let the stuck check give up
+		u.NeedsPath = false
+		return
+	}
+	u.SetPath(path)
+}
+
+// refreshPathObstacles copies building and wreck footprints into the path
+// grids, at most once per tick
+func (w *World) refreshPathObstacles() {
+	if !w.pathObstaclesSet || w.pathObstaclesTick != w.Tick {
+		obstacles := make([]emath.Rect, 0, len(w.Buildings)+len(w.Wreckages))
+		for _, b := range w.Buildings {
+			if b.Active {
+				obstacles = append(obstacles, b.Bounds())
+			}
+		}
+		for _, wr := range w.Wreckages {
+			if wr.Active {
+				obstacles = append(obstacles, wr.Bounds())
+			}
+		}
+		for _, grid := range w.Paths {
+			grid.SetObstacles(obstacles)
+		}
+		w.pathObstaclesTick = w.Tick
+		w.pathObstaclesSet = true
+	}
+}
+
+// updateConstructorBuildTask moves a constructor to its site and builds
+func (w *World) updateConstructorBuildTask(u *entity.Unit, dt float64) {
+	if u.HasTarget {
+		return
+	}
+	if !u.IsNearBuildSite() {
+		// Target a position just below (south of) the building site, so constructor doesn't end up inside
+		buildSiteTarget := emath.Vec2{
+			X: u.BuildPos.X + u.BuildDef.Size/2,
+			Y: u.BuildPos.Y + u.BuildDef.Size + u.Size.Y/2 + 5,
+		}
+		u.SetTarget(buildSiteTarget)
+		return
+	}
+	if !u.IsBuilding {
+		u.BuildTarget = w.StartBuilding(u.BuildDef, u.BuildPos.X, u.BuildPos.Y, u.Faction)
+		u.IsBuilding = true
+	}
+	if u.BuildTarget != nil {
+		if u.BuildTarget.UpdateConstruction(dt*u.BuildTarget.Funding, w.Resources(u.Faction)) {
+			w.ApplyBuildingEffects(u.Faction, u.BuildTarget.Def)
+			u.ClearBuildTask()
+		}
+	}
+}
+
+// updateRepairTask moves a repair unit to its target and spends resources to heal it
+func (w *World) updateRepairTask(u *entity.Unit, dt float64) {
+	target := u.RepairTarget
+
+	if target == nil || !target.Active || target.Health >= target.MaxHealth {
+		u.ClearRepairTarget()
+		return
+	}
+
+	if !u.IsInRepairRange(target) {
+		if !u.HasTarget {
+			u.SetTarget(target.Center())
+		}
+		return
+	}
+
+	u.ClearTarget()
+
+	repairAmount := u.RepairRate * dt
+	healthNeeded := target.MaxHealth - target.Health
+	if repairAmount > healthNeeded {
+		repairAmount = healthNeeded
+	}
+
+	metalCost := repairAmount * repairMetalCostPerHP
+	energyCost := repairAmount * repairEnergyCostPerHP
+
+	res := w.Resources(u.Faction)
+	metalRes := res.Get(resource.Metal)
+	energyRes := res.Get(resource.Energy)
+	if metalRes.Current < metalCost || energyRes.Current < energyCost {
+		return
+	}
+
+	metalRes.Spend(metalCost)
+	energyRes.Spend(energyCost)
+	target.Health += repairAmount
+
+	if target.Health >= target.MaxHealth {
+		target.Health = target.MaxHealth
+		u.ClearRepairTarget()
+	}
+}
+
+// updateReclaimTask moves a unit to a wreck and pulls its metal into the
+// faction's storage
+func (w *World) updateReclaimTask(u *entity.Unit, wreck *entity.Wreckage, dt float64) {
+	if !wreck.Active {
+		return
+	}
+	if !u.IsInReclaimRange(wreck) {
+		if !u.HasTarget {
+			u.SetTarget(wreck.Center())
+		}
+		return
+	}
+
+	u.ClearTarget()
+
+	// With full storage the wreck is left alone until there is room again
+	metal := w.Resources(u.Faction).Get(resource.Metal)
+	room := metal.Capacity - metal.Current
+	if room <= 0 {
+		return
+	}
+	metal.Add(wreck.Reclaim(min(u.ReclaimRate*dt, room)))
+}
+
+// updateBuildings advances animation, construction, production and research
+func (w *World) updateBuildings(dt float64) {
+	for _, b := range w.Buildings {
+		if !b.Active {
+			continue
+		}
+		b.UpdateAnimation(dt)
+
+		res := w.Resources(b.Faction)
+
+		// A building being recycled does nothing else until it is gone
+		if b.Recycling {
+			b.UpdateRecycle(dt, res)
+			continue
+		}
+
+		// Auto-construction for buildings under construction
+		if !b.Completed {
+			if b.UpdateConstruction(dt*b.Funding, res) {
+				w.ApplyBuildingEffects(b.Faction, b.Def)
+			}
+		}
+
+		// Factories and labs work as fast as their power and funding allow
+		powered := dt * b.Power * b.Funding
+		if completedUnit := b.UpdateProduction(powered, res); completedUnit != nil {
+			spawnPos := b.GetSpawnPoint()
+			unit := w.SpawnUnit(completedUnit, spawnPos.X, spawnPos.Y, b.Faction)
+			if b.HasRallyPoint {
+				unit.SetTarget(b.RallyPoint)
+			}
+		}
+
+		if finished := b.UpdateResearch(powered, res); finished != nil {
+			w.completeResearch(b.Faction, finished)
+		}
+	}
+}
+
+// updateProjectiles moves projectiles and drops the ones that hit
+func (w *World) updateProjectiles(dt float64) {
+	alive := make([]*entity.Projectile, 0, len(w.Projectiles))
+	for _, p := range w.Projectiles {
+		if p.Update(dt) {
+			if p.Landed {
+				w.explode(p)
+			}
+			continue
+		}
+		alive = append(alive, p)
+	}
+	w.Projectiles = alive
+}
+
+// cleanupDead removes destroyed units and buildings, leaving wreckage
+// behind, units that boarded a transport, recycled buildings, and wrecks
+// that were reclaimed
+func (w *World) cleanupDead() {
+	aliveWrecks := w.Wreckages[:0]
+	for _, wr := range w.Wreckages {
+		if wr.Active {
+			aliveWrecks = append(aliveWrecks, wr)
+		}
+	}
+	clear(w.Wreckages[len(aliveWrecks):])
+	w.Wreckages = aliveWrecks
+
+	aliveUnits := make([]*entity.Unit, 0, len(w.Units))
+	for _, u := range w.Units {
+		switch {
+		case u.Active:
+			aliveUnits = append(aliveUnits, u)
+		case u.Carrier != nil:
+			// Boarded a transport and left the world
+		default:
+			// Units aboard go down with their transport
+			u.Cargo = nil
+			w.Wreckages = append(w.Wreckages, entity.NewWreckageFromUnit(w.NextWreckageID, u))
+			w.NextWreckageID++
+		}
+	}
+	w.Units = aliveUnits
+
+	aliveBuildings := make([]*entity.Building, 0, len(w.Buildings))
+	for _, b := range w.Buildings {
+		if b.Active {
+			aliveBuildings = append(aliveBuildings, b)
+		} else if !b.Recycled() {
+			w.Wreckages = append(w.Wreckages, entity.NewWreckageFromBuilding(w.NextWreckageID, b))
+			w.NextWreckageID++
+		}
+	}
+	w.Buildings = aliveBuildings
+}
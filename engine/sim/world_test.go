@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/resource"
+	"github.com/bklimczak/tanks/engine/terrain"
+)
+
+// newTestWorld returns a world on an open grass map
+func newTestWorld() *World {
+	m := terrain.NewMap(2000, 2000)
+	m.GenerateGrassOnly()
+	return NewWorld(m)
+}
+
+func TestEliminatedFactionStopsEarning(t *testing.T) {
+	w := newTestWorld()
+	for _, f := range []entity.Faction{entity.FactionPlayer, entity.FactionEnemy} {
+		w.Resources(f).AddProduction(resource.Metal, 10)
+	}
+	start := w.Resources(entity.FactionPlayer).Get(resource.Metal).Current
+	w.SpawnUnit(entity.UnitDefs[entity.UnitTypeTank], 100, 100, entity.FactionPlayer)
+
+	w.Update(1)
+	if got := w.Resources(entity.FactionPlayer).Get(resource.Metal).Current; got != start+10 {
+		t.Errorf("player metal = %v, want %v", got, start+10)
+	}
+	if got := w.Resources(entity.FactionEnemy).Get(resource.Metal).Current; got != start {
+		t.Errorf("a faction with nothing left should not earn, has %v metal", got)
+	}
+}
+
+func TestExtractorYieldFollowsCoveredDeposits(t *testing.T) {
+	w := newTestWorld()
+	def := entity.BuildingDefs[entity.BuildingMetalExtractor]
+	// Each extractor sits inside a 2x2 block of tiles
+	w.Terrain.SetMetal(4, 4, 100)
+	w.Terrain.SetMetal(12, 4, 100)
+	w.Terrain.SetMetal(13, 4, 100)
+	one := w.SpawnBuilding(def, 4*terrain.TileSize+5, 4*terrain.TileSize+5, entity.FactionPlayer)
+	two := w.SpawnBuilding(def, 12*terrain.TileSize+5, 4*terrain.TileSize+5, entity.FactionPlayer)
+
+	w.Update(1)
+	if one.Yield <= 0 || two.Yield != 2*one.Yield {
+		t.Errorf("yields %v and %v, want the second twice the first", one.Yield, two.Yield)
+	}
+	if one.DepositLeft != 100-one.Yield {
+		t.Errorf("deposit left %v, want %v", one.DepositLeft, 100-one.Yield)
+	}
+}
+
+func TestLookoutsSkipBunchedUnits(t *testing.T) {
+	w := newTestWorld()
+	tank := entity.UnitDefs[entity.UnitTypeTank]
+	first := w.SpawnUnit(tank, 500, 500, entity.FactionPlayer)
+	w.SpawnUnit(tank, 505, 505, entity.FactionPlayer)
+	apart := w.SpawnUnit(tank, 900, 500, entity.FactionPlayer)
+	scout := w.SpawnUnit(entity.UnitDefs[entity.UnitTypeScout], 505, 500, entity.FactionPlayer)
+	w.SpawnUnit(tank, 500, 505, entity.FactionEnemy)
+
+	got := w.Lookouts(entity.FactionPlayer, terrain.TileSize)
+	want := []*entity.Unit{first, apart, scout}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lookouts, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("lookout %d is unit %d, want %d", i, got[i].ID, want[i].ID)
+		}
+	}
+}
+
+// BenchmarkWorldUpdate ticks two armies of 300 units patrolling across a
+// full size map, in lanes too far apart to meet
+func BenchmarkWorldUpdate(b *testing.B) {
+	m := terrain.NewMap(6000, 3375)
+	m.GenerateGrassOnly()
+	w := NewWorld(m)
+	tank := entity.UnitDefs[entity.UnitTypeTank]
+	for i := 0; i < 300; i++ {
+		x, y := 200+float64(i%20)*40, 200+float64(i/20)*40
+		for _, f := range []entity.Faction{entity.FactionPlayer, entity.FactionEnemy} {
+			u := w.SpawnUnit(tank, x, y, f)
+			u.QueueOrder(entity.Order{Type: entity.OrderPatrol, Pos: emath.Vec2{X: x + 4000, Y: y}})
+			u.QueueOrder(entity.Order{Type: entity.OrderPatrol, Pos: emath.Vec2{X: x, Y: y}})
+			y += 2000
+		}
+	}
+	w.Update(TickRate)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Update(TickRate)
+	}
+}
+
+func TestExtractorsStopAtFullStorage(t *testing.T) {
+	tests := []struct {
+		name      string
+		room      float64 // Free metal storage at the start of the tick
+		wantMined float64
+	}{
+		{"full storage", 0, 0},
+		{"room shared by both extractors", 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWorld()
+			def := entity.BuildingDefs[entity.BuildingMetalExtractor]
+			w.Terrain.SetMetal(4, 4, 100)
+			w.Terrain.SetMetal(12, 4, 100)
+			w.SpawnBuilding(def, 4*terrain.TileSize+5, 4*terrain.TileSize+5, entity.FactionPlayer)
+			w.SpawnBuilding(def, 12*terrain.TileSize+5, 4*terrain.TileSize+5, entity.FactionPlayer)
+			metal := w.Resources(entity.FactionPlayer).Get(resource.Metal)
+			metal.Current = metal.Capacity - tt.room
+
+			w.Update(1)
+			left := 0.0
+			for _, d := range w.Terrain.Deposits() {
+				left += d.Amount
+			}
+			if mined := 200 - left; mined != tt.wantMined {
+				t.Errorf("mined %v, want %v", mined, tt.wantMined)
+			}
+			if metal.Current != metal.Capacity {
+				t.Errorf("metal = %v, want storage filled to %v", metal.Current, metal.Capacity)
+			}
+		})
+	}
+}
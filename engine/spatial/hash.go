@@ -0,0 +1,95 @@
+// Package spatial provides a uniform grid index for finding entities near
+// a point without scanning all of them.
+package spatial
+
+import (
+	"math"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+type entry[T any] struct {
+	item   T
+	bounds emath.Rect
+}
+
+// Hash buckets items by the cell their center falls in. It is meant to be
+// cleared and refilled every tick; bucket storage is kept between fills.
+type Hash[T any] struct {
+	cellSize   float64
+	cols, rows int
+	cells      [][]entry[T]
+	maxHalf    emath.Vec2 // Largest half-size inserted since the last Clear
+}
+
+// NewHash creates an index covering a width x height world
+func NewHash[T any](width, height, cellSize float64) *Hash[T] {
+	cols := max(1, int(math.Ceil(width/cellSize)))
+	rows := max(1, int(math.Ceil(height/cellSize)))
+	return &Hash[T]{
+		cellSize: cellSize,
+		cols:     cols,
+		rows:     rows,
+		cells:    make([][]entry[T], cols*rows),
+	}
+}
+
+// Clear empties every bucket
+func (h *Hash[T]) Clear() {
+	for i := range h.cells {
+		h.cells[i] = h.cells[i][:0]
+	}
+	h.maxHalf = emath.Vec2{}
+}
+
+// Insert adds an item with its current bounds
+func (h *Hash[T]) Insert(item T, bounds emath.Rect) {
+	cx, cy := h.cell(bounds.Center())
+	i := cy*h.cols + cx
+	h.cells[i] = append(h.cells[i], entry[T]{item: item, bounds: bounds})
+	h.maxHalf.X = max(h.maxHalf.X, bounds.Size.X/2)
+	h.maxHalf.Y = max(h.maxHalf.Y, bounds.Size.Y/2)
+}
+
+// Query appends to dst every item whose bounds, as inserted, intersect area
+func (h *Hash[T]) Query(area emath.Rect, dst []T) []T {
+	// Items are bucketed by center, so widen the search by the largest
+	// half-size to catch items reaching into area from a neighbouring cell
+	minX, minY := h.cell(area.Pos.Sub(h.maxHalf))
+	maxX, maxY := h.cell(area.Pos.Add(area.Size).Add(h.maxHalf))
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			for _, e := range h.cells[y*h.cols+x] {
+				if e.bounds.Intersects(area) {
+					dst = append(dst, e.item)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// QueryRadius appends to dst every item whose center, as inserted, lies
+// within radius of center
+func (h *Hash[T]) QueryRadius(center emath.Vec2, radius float64, dst []T) []T {
+	minX, minY := h.cell(center.Sub(emath.Vec2{X: radius, Y: radius}))
+	maxX, maxY := h.cell(center.Add(emath.Vec2{X: radius, Y: radius}))
+	r2 := radius * radius
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			for _, e := range h.cells[y*h.cols+x] {
+				if e.bounds.Center().DistanceSquared(center) <= r2 {
+					dst = append(dst, e.item)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// cell returns the bucket coordinates of a point, clamped to the grid
+func (h *Hash[T]) cell(p emath.Vec2) (int, int) {
+	x := int(math.Floor(p.X / h.cellSize))
+	y := int(math.Floor(p.Y / h.cellSize))
+	return max(0, min(x, h.cols-1)), max(0, min(y, h.rows-1))
+}
@@ -0,0 +1,138 @@
+package spatial
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+const (
+	worldW = 6000
+	worldH = 3375
+)
+
+type item struct {
+	id     int
+	bounds emath.Rect
+}
+
+func randomItems(n int, seed int64) []item {
+	r := rand.New(rand.NewSource(seed))
+	items := make([]item, n)
+	for i := range items {
+		size := 15 + r.Float64()*60
+		items[i] = item{
+			id:     i,
+			bounds: emath.NewRect(r.Float64()*(worldW-size), r.Float64()*(worldH-size), size, size),
+		}
+	}
+	return items
+}
+
+func fill(items []item) *Hash[int] {
+	h := NewHash[int](worldW, worldH, 100)
+	for _, it := range items {
+		h.Insert(it.id, it.bounds)
+	}
+	return h
+}
+
+func TestQueryMatchesBruteForce(t *testing.T) {
+	items := randomItems(800, 1)
+	h := fill(items)
+	areas := randomItems(50, 2)
+	for _, a := range areas {
+		got := h.Query(a.bounds, nil)
+		var want []int
+		for _, it := range items {
+			if it.bounds.Intersects(a.bounds) {
+				want = append(want, it.id)
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Fatalf("Query(%v) = %v, want %v", a.bounds, got, want)
+		}
+	}
+}
+
+func TestQueryRadiusMatchesBruteForce(t *testing.T) {
+	items := randomItems(800, 3)
+	h := fill(items)
+	for _, c := range randomItems(50, 4) {
+		center := c.bounds.Center()
+		got := h.QueryRadius(center, 250, nil)
+		var want []int
+		for _, it := range items {
+			if it.bounds.Center().Distance(center) <= 250 {
+				want = append(want, it.id)
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Fatalf("QueryRadius(%v) = %v, want %v", center, got, want)
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	h := fill(randomItems(100, 5))
+	h.Clear()
+	if got := h.Query(emath.NewRect(0, 0, worldW, worldH), nil); len(got) != 0 {
+		t.Errorf("cleared hash returned %d items", len(got))
+	}
+}
+
+// sink keeps benchmark results alive
+var sink int
+
+// The benchmarks mirror one simulation tick: every unit looks for
+// obstacles around itself and for enemies within weapon range
+
+func BenchmarkNeighboursBruteForce(b *testing.B) {
+	for _, n := range []int{500, 1000} {
+		items := randomItems(n, 6)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, u := range items {
+					area := emath.NewRect(u.bounds.Pos.X-5, u.bounds.Pos.Y-5, u.bounds.Size.X+10, u.bounds.Size.Y+10)
+					center := u.bounds.Center()
+					for _, o := range items {
+						if o.bounds.Intersects(area) {
+							sink++
+						}
+						if o.bounds.Center().DistanceSquared(center) <= 250*250 {
+							sink++
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNeighboursHash(b *testing.B) {
+	for _, n := range []int{500, 1000} {
+		items := randomItems(n, 6)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			h := NewHash[int](worldW, worldH, 100)
+			var buf []int
+			for i := 0; i < b.N; i++ {
+				h.Clear()
+				for _, it := range items {
+					h.Insert(it.id, it.bounds)
+				}
+				for _, u := range items {
+					area := emath.NewRect(u.bounds.Pos.X-5, u.bounds.Pos.Y-5, u.bounds.Size.X+10, u.bounds.Size.Y+10)
+					buf = h.Query(area, buf[:0])
+					sink += len(buf)
+					buf = h.QueryRadius(u.bounds.Center(), 250, buf[:0])
+					sink += len(buf)
+				}
+			}
+		})
+	}
+}
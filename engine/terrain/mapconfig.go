@@ -48,7 +48,7 @@ type WaterFeature struct {
 type MetalDeposit struct {
 	X      int     `yaml:"x"`
 	Y      int     `yaml:"y"`
-	Amount float64 `yaml:"amount,omitempty"` // Default: 2000
+	Amount float64 `yaml:"amount,omitempty"` // Default: DefaultMetalAmount
 }
 
 // FactionConfig groups all entities belonging to a faction/team
@@ -317,7 +317,7 @@ func (mc *MapConfig) applyMetalDeposits(m *Map) {
 		if metal.X >= 0 && metal.X < m.Width && metal.Y >= 0 && metal.Y < m.Height {
 			amount := metal.Amount
 			if amount <= 0 {
-				amount = 2000
+				amount = DefaultMetalAmount
 			}
 			m.Tiles[metal.Y][metal.X] = Tile{
 				Type:        TileMetal,
@@ -86,7 +86,7 @@ func (mf *MapFile) ToMap() (*Map, error) {
 			}
 			if tileType == TileMetal {
 				tile.HasMetal = true
-				tile.MetalAmount = 2000 // Default
+				tile.MetalAmount = DefaultMetalAmount
 			}
 			m.Tiles[y][x] = tile
 		}
@@ -144,7 +144,7 @@ func MapToFile(m *Map, name, description, author string) *MapFile {
 				char = 'G' // Default to grass
 			}
 			row.WriteRune(char)
-			if tile.HasMetal && tile.MetalAmount != 2000 {
+			if tile.HasMetal && tile.MetalAmount != DefaultMetalAmount {
 				metalAmounts = append(metalAmounts, fmt.Sprintf("%d,%d:%.0f", x, y, tile.MetalAmount))
 			}
 		}
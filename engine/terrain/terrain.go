@@ -5,6 +5,7 @@ import (
 	"math/rand"
 
 	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/movement"
 )
 
 type TileType int
@@ -16,14 +17,32 @@ const (
 )
 const TileSize = 25.0
 
+// DefaultMetalAmount is the metal in a deposit tile unless the map says otherwise
+const DefaultMetalAmount = 2000.0
+
 type Tile struct {
 	Type        TileType
-	Passable    bool    // Can units walk on this?
+	Passable    bool    // Can ground units drive on this?
 	Buildable   bool    // Can buildings be placed here?
 	HasMetal    bool    // Does this tile have metal deposits?
 	MetalAmount float64 // Amount of metal if HasMetal is true
 }
 
+// PassableFor reports whether a unit of the given movement class can cross
+// the tile. Passable describes ground units; water is open to hover and
+// naval units.
+func (t Tile) PassableFor(class movement.Class) bool {
+	return class.Crosses(t.Passable, t.Type == TileWater)
+}
+
+// DepositFraction returns how full a deposit tile is compared to a
+// default one, capped at 1
+func (t Tile) DepositFraction() float64 {
+	if !t.HasMetal {
+		return 0
+	}
+	return min(t.MetalAmount/DefaultMetalAmount, 1)
+}
 func TileColors(t TileType) color.Color {
 	switch t {
 	case TileGrass:
@@ -255,7 +274,10 @@ func (m *Map) GetTileCoords(pixelX, pixelY float64) (int, int) {
 func (m *Map) GetPixelCoords(tileX, tileY int) (float64, float64) {
 	return float64(tileX) * TileSize, float64(tileY) * TileSize
 }
-func (m *Map) IsPassable(bounds emath.Rect) bool {
+
+// IsPassable reports whether a unit of the given movement class can occupy
+// bounds. Everything outside the map is impassable.
+func (m *Map) IsPassable(bounds emath.Rect, class movement.Class) bool {
 	startX, startY := m.GetTileCoords(bounds.Pos.X, bounds.Pos.Y)
 	endX, endY := m.GetTileCoords(bounds.Pos.X+bounds.Size.X, bounds.Pos.Y+bounds.Size.Y)
 	for y := startY; y <= endY; y++ {
@@ -263,7 +285,7 @@ func (m *Map) IsPassable(bounds emath.Rect) bool {
 			if x < 0 || x >= m.Width || y < 0 || y >= m.Height {
 				return false
 			}
-			if !m.Tiles[y][x].Passable {
+			if !m.Tiles[y][x].PassableFor(class) {
 				return false
 			}
 		}
@@ -317,6 +339,98 @@ func (m *Map) PlaceMetalDeposit(pixelX, pixelY float64) bool {
 	tile.Passable = true
 	tile.Buildable = true
 	tile.HasMetal = true
-	tile.MetalAmount = 2000
+	tile.MetalAmount = DefaultMetalAmount
 	return true
 }
+
+// Deposit is the metal left in one deposit tile
+type Deposit struct {
+	X, Y   int
+	Amount float64
+}
+
+// Deposits lists every tile that still holds metal
+func (m *Map) Deposits() []Deposit {
+	deposits := []Deposit{}
+	for y := range m.Tiles {
+		for x, tile := range m.Tiles[y] {
+			if tile.HasMetal {
+				deposits = append(deposits, Deposit{X: x, Y: y, Amount: tile.MetalAmount})
+			}
+		}
+	}
+	return deposits
+}
+
+// SetDeposits puts the listed metal on the map. Deposit tiles that are not
+// listed count as mined out.
+func (m *Map) SetDeposits(deposits []Deposit) {
+	for y := range m.Tiles {
+		for x := range m.Tiles[y] {
+			if m.Tiles[y][x].HasMetal {
+				m.SetMetal(x, y, 0)
+			}
+		}
+	}
+	for _, d := range deposits {
+		m.SetMetal(d.X, d.Y, d.Amount)
+	}
+}
+
+// SetMetal sets the metal left in a tile. A tile with none left turns into
+// plain grass; a tile given metal becomes a deposit.
+func (m *Map) SetMetal(x, y int, amount float64) {
+	if x < 0 || x >= m.Width || y < 0 || y >= m.Height {
+		return
+	}
+	tile := &m.Tiles[y][x]
+	if amount <= 0 {
+		if tile.HasMetal {
+			tile.Type = TileGrass
+			tile.HasMetal = false
+			tile.MetalAmount = 0
+		}
+		return
+	}
+	if tile.Type == TileWater {
+		return
+	}
+	tile.Type = TileMetal
+	tile.HasMetal = true
+	tile.MetalAmount = amount
+}
+
+// MetalUnder counts the deposit tiles under bounds and the metal left in them
+func (m *Map) MetalUnder(bounds emath.Rect) (tiles int, amount float64) {
+	startX, startY := m.GetTileCoords(bounds.Pos.X, bounds.Pos.Y)
+	endX, endY := m.GetTileCoords(bounds.Pos.X+bounds.Size.X, bounds.Pos.Y+bounds.Size.Y)
+	for y := max(startY, 0); y <= min(endY, m.Height-1); y++ {
+		for x := max(startX, 0); x <= min(endX, m.Width-1); x++ {
+			if m.Tiles[y][x].HasMetal {
+				tiles++
+				amount += m.Tiles[y][x].MetalAmount
+			}
+		}
+	}
+	return tiles, amount
+}
+
+// ExtractMetal takes up to perTile metal out of every deposit tile under
+// bounds and returns the total taken. Tiles that run dry turn to grass.
+func (m *Map) ExtractMetal(bounds emath.Rect, perTile float64) float64 {
+	startX, startY := m.GetTileCoords(bounds.Pos.X, bounds.Pos.Y)
+	endX, endY := m.GetTileCoords(bounds.Pos.X+bounds.Size.X, bounds.Pos.Y+bounds.Size.Y)
+	taken := 0.0
+	for y := max(startY, 0); y <= min(endY, m.Height-1); y++ {
+		for x := max(startX, 0); x <= min(endX, m.Width-1); x++ {
+			tile := &m.Tiles[y][x]
+			if !tile.HasMetal {
+				continue
+			}
+			amount := min(perTile, tile.MetalAmount)
+			taken += amount
+			m.SetMetal(x, y, tile.MetalAmount-amount)
+		}
+	}
+	return taken
+}
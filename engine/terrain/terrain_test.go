@@ -0,0 +1,72 @@
+package terrain
+
+import (
+	"reflect"
+	"testing"
+
+	emath "github.com/bklimczak/tanks/engine/math"
+)
+
+// newGrassMap returns a small map of plain grass
+func newGrassMap() *Map {
+	m := NewMap(10*TileSize, 10*TileSize)
+	m.GenerateGrassOnly()
+	return m
+}
+
+// tileRect covers tiles x0..x1 of row y, just inside their edges
+func tileRect(x0, x1, y int) emath.Rect {
+	return emath.Rect{
+		Pos:  emath.Vec2{X: float64(x0)*TileSize + 1, Y: float64(y)*TileSize + 1},
+		Size: emath.Vec2{X: float64(x1-x0+1)*TileSize - 2, Y: TileSize - 2},
+	}
+}
+
+func TestExtractMetalScalesWithCoveredTiles(t *testing.T) {
+	m := newGrassMap()
+	m.SetMetal(2, 2, 100)
+	m.SetMetal(3, 2, 100)
+
+	if got := m.ExtractMetal(tileRect(2, 2, 2), 5); got != 5 {
+		t.Errorf("one deposit tile gave %v, want 5", got)
+	}
+	if got := m.ExtractMetal(tileRect(1, 3, 2), 5); got != 10 {
+		t.Errorf("two deposit tiles gave %v, want 10", got)
+	}
+	if tiles, amount := m.MetalUnder(tileRect(1, 3, 2)); tiles != 2 || amount != 185 {
+		t.Errorf("left %d tiles with %v metal, want 2 with 185", tiles, amount)
+	}
+}
+
+func TestMinedOutTileTurnsToGrass(t *testing.T) {
+	m := newGrassMap()
+	m.SetMetal(4, 4, 3)
+
+	if got := m.ExtractMetal(tileRect(4, 4, 4), 5); got != 3 {
+		t.Errorf("took %v, want the 3 left", got)
+	}
+	tile := m.Tiles[4][4]
+	if tile.HasMetal || tile.Type != TileGrass || tile.MetalAmount != 0 {
+		t.Errorf("mined out tile is %v with %v metal, want plain grass", tile.Type, tile.MetalAmount)
+	}
+	if got := m.ExtractMetal(tileRect(4, 4, 4), 5); got != 0 {
+		t.Errorf("a mined out tile gave %v more metal", got)
+	}
+}
+
+func TestSetDepositsRoundTrip(t *testing.T) {
+	m := newGrassMap()
+	m.SetMetal(1, 1, 50)
+	m.SetMetal(6, 3, DefaultMetalAmount)
+	saved := m.Deposits()
+
+	other := newGrassMap()
+	other.SetMetal(8, 8, 400)
+	other.SetDeposits(saved)
+	if got := other.Deposits(); !reflect.DeepEqual(got, saved) {
+		t.Errorf("restored deposits %v, want %v", got, saved)
+	}
+	if other.Tiles[8][8].HasMetal {
+		t.Error("a deposit missing from the save should count as mined out")
+	}
+}
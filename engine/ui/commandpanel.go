@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"image/color"
+	"math"
 
 	"github.com/bklimczak/tanks/engine/entity"
 	emath "github.com/bklimczak/tanks/engine/math"
@@ -35,6 +36,7 @@ type CommandButton struct {
 	Description string
 	BuildingDef *entity.BuildingDef
 	State       ButtonState
+	Locked      string // Research still needed before it can be built, empty when available
 	OnClick     func()
 }
 
@@ -53,6 +55,7 @@ type UnitButton struct {
 	Label      string
 	UnitDef    *entity.UnitDef
 	State      ButtonState
+	Locked     string // Research still needed before it can be produced, empty when available
 	QueueCount int
 }
 
@@ -74,7 +77,11 @@ func (b *UnitButton) Draw(screen *ebiten.Image, resources *resource.Manager) {
 	h := float32(b.Bounds.Size.Y)
 	var bgColor, borderColor color.Color
 	canAfford := resources.CanAfford(b.UnitDef.Cost)
-	switch b.State {
+	state := b.State
+	if b.Locked != "" {
+		state = ButtonDisabled
+	}
+	switch state {
 	case ButtonHovered:
 		if canAfford {
 			bgColor = color.RGBA{60, 60, 80, 255}
@@ -107,6 +114,10 @@ func (b *UnitButton) Draw(screen *ebiten.Image, resources *resource.Manager) {
 	labelY := int(y + 6)
 	ebitenutil.DebugPrintAt(screen, b.Label, labelX, labelY)
 	costY := int(y + 22)
+	if b.Locked != "" {
+		ebitenutil.DebugPrintAt(screen, "Needs "+b.Locked, labelX, costY)
+		return
+	}
 	costStr := ""
 	if metal, ok := b.UnitDef.Cost[resource.Metal]; ok && metal > 0 {
 		costStr += fmt.Sprintf("M:%.0f ", metal)
@@ -135,7 +146,11 @@ func (b *CommandButton) Draw(screen *ebiten.Image, resources *resource.Manager)
 	h := float32(b.Bounds.Size.Y)
 	var bgColor, borderColor color.Color
 	canAfford := resources.CanAfford(b.BuildingDef.Cost)
-	switch b.State {
+	state := b.State
+	if b.Locked != "" {
+		state = ButtonDisabled
+	}
+	switch state {
 	case ButtonHovered:
 		if canAfford {
 			bgColor = color.RGBA{60, 60, 80, 255}
@@ -168,6 +183,10 @@ func (b *CommandButton) Draw(screen *ebiten.Image, resources *resource.Manager)
 	labelY := int(y + 6)
 	ebitenutil.DebugPrintAt(screen, b.Label, labelX, labelY)
 	costY := int(y + 22)
+	if b.Locked != "" {
+		ebitenutil.DebugPrintAt(screen, "Needs "+b.Locked, labelX, costY)
+		return
+	}
 	costStr := ""
 	if metal, ok := b.BuildingDef.Cost[resource.Metal]; ok && metal > 0 {
 		costStr += fmt.Sprintf("M:%.0f ", metal)
@@ -180,10 +199,310 @@ func (b *CommandButton) Draw(screen *ebiten.Image, resources *resource.Manager)
 	ebitenutil.DebugPrintAt(screen, timeStr, int(x+w-35), costY)
 }
 
+// StanceButton sets the stance of the selected combat units
+type StanceButton struct {
+	Bounds emath.Rect
+	Stance entity.Stance
+	State  ButtonState
+	Active bool // Every selected unit already has this stance
+}
+
+func (b *StanceButton) Contains(p emath.Vec2) bool {
+	return b.Bounds.Contains(p)
+}
+func (b *StanceButton) Draw(screen *ebiten.Image) {
+	x := float32(b.Bounds.Pos.X)
+	y := float32(b.Bounds.Pos.Y)
+	w := float32(b.Bounds.Size.X)
+	h := float32(b.Bounds.Size.Y)
+	var bgColor, borderColor color.Color
+	switch {
+	case b.State == ButtonPressed:
+		bgColor = color.RGBA{40, 40, 60, 255}
+		borderColor = color.RGBA{120, 120, 140, 255}
+	case b.Active:
+		bgColor = color.RGBA{40, 70, 50, 255}
+		borderColor = color.RGBA{90, 160, 100, 255}
+	case b.State == ButtonHovered:
+		bgColor = color.RGBA{60, 60, 80, 255}
+		borderColor = color.RGBA{100, 100, 120, 255}
+	default:
+		bgColor = color.RGBA{45, 45, 60, 255}
+		borderColor = color.RGBA{70, 70, 90, 255}
+	}
+	vector.FillRect(screen, x, y, w, h, bgColor, false)
+	vector.StrokeRect(screen, x, y, w, h, 1, borderColor, false)
+	ebitenutil.DebugPrintAt(screen, b.Stance.String(), int(x+8), int(y+6))
+	ebitenutil.DebugPrintAt(screen, stanceHint(b.Stance), int(x+8), int(y+22))
+}
+
+// stanceHint is the one-line explanation under a stance button
+func stanceHint(s entity.Stance) string {
+	switch s {
+	case entity.StanceDefensive:
+		return "Chase a short way"
+	case entity.StanceHoldPosition:
+		return "Fire, never move"
+	case entity.StanceHoldFire:
+		return "Fire only on order"
+	default:
+		return "Chase any enemy"
+	}
+}
+
+// PriorityButton sets how the selected building's work is paid for. The
+// three priority buttons share one row of the panel.
+type PriorityButton struct {
+	Bounds   emath.Rect
+	Priority entity.Priority
+	State    ButtonState
+	Active   bool // The building already has this priority
+}
+
+func (b *PriorityButton) Contains(p emath.Vec2) bool {
+	return b.Bounds.Contains(p)
+}
+func (b *PriorityButton) Draw(screen *ebiten.Image) {
+	x := float32(b.Bounds.Pos.X)
+	y := float32(b.Bounds.Pos.Y)
+	w := float32(b.Bounds.Size.X)
+	h := float32(b.Bounds.Size.Y)
+	var bgColor, borderColor color.Color
+	switch {
+	case b.State == ButtonPressed:
+		bgColor = color.RGBA{40, 40, 60, 255}
+		borderColor = color.RGBA{120, 120, 140, 255}
+	case b.Active && b.Priority == entity.PriorityPaused:
+		bgColor = color.RGBA{80, 40, 40, 255}
+		borderColor = color.RGBA{170, 90, 90, 255}
+	case b.Active:
+		bgColor = color.RGBA{40, 70, 50, 255}
+		borderColor = color.RGBA{90, 160, 100, 255}
+	case b.State == ButtonHovered:
+		bgColor = color.RGBA{60, 60, 80, 255}
+		borderColor = color.RGBA{100, 100, 120, 255}
+	default:
+		bgColor = color.RGBA{45, 45, 60, 255}
+		borderColor = color.RGBA{70, 70, 90, 255}
+	}
+	vector.FillRect(screen, x, y, w, h, bgColor, false)
+	vector.StrokeRect(screen, x, y, w, h, 1, borderColor, false)
+	label := b.Priority.String()
+	ebitenutil.DebugPrintAt(screen, label, int(x+(w-float32(len(label)*6))/2), int(y+(h-16)/2))
+}
+
+// RecycleButton takes the selected building apart for a partial refund
+type RecycleButton struct {
+	Bounds emath.Rect
+	State  ButtonState
+	Metal  float64 // Refund once recycled
+	Energy float64
+}
+
+func (b *RecycleButton) Contains(p emath.Vec2) bool {
+	return b.Bounds.Contains(p)
+}
+func (b *RecycleButton) Draw(screen *ebiten.Image) {
+	x := float32(b.Bounds.Pos.X)
+	y := float32(b.Bounds.Pos.Y)
+	w := float32(b.Bounds.Size.X)
+	h := float32(b.Bounds.Size.Y)
+	var bgColor, borderColor color.Color
+	switch b.State {
+	case ButtonPressed:
+		bgColor = color.RGBA{40, 40, 60, 255}
+		borderColor = color.RGBA{120, 120, 140, 255}
+	case ButtonHovered:
+		bgColor = color.RGBA{80, 50, 40, 255}
+		borderColor = color.RGBA{160, 110, 90, 255}
+	default:
+		bgColor = color.RGBA{60, 40, 35, 255}
+		borderColor = color.RGBA{110, 80, 70, 255}
+	}
+	vector.FillRect(screen, x, y, w, h, bgColor, false)
+	vector.StrokeRect(screen, x, y, w, h, 1, borderColor, false)
+	ebitenutil.DebugPrintAt(screen, "Recycle [Del]", int(x+8), int(y+6))
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Refund M:%.0f E:%.0f", b.Metal, b.Energy), int(x+8), int(y+22))
+}
+
+// AbilityHotkeys are the keys of the ability buttons, in panel order. They
+// match the keys the input manager reports as ability slots.
+var AbilityHotkeys = []string{"Q", "E", "R", "G"}
+
+// AbilityButton uses an ability of the selected units. Point and unit
+// abilities are aimed on the map after the click.
+type AbilityButton struct {
+	Bounds   emath.Rect
+	Def      *entity.AbilityDef
+	Hotkey   string
+	State    ButtonState
+	Cooldown float64 // Seconds until the first selected unit is ready, 0 when one is
+	Aiming   bool    // The ability is waiting for a target
+}
+
+func (b *AbilityButton) Contains(p emath.Vec2) bool {
+	return b.Bounds.Contains(p)
+}
+func (b *AbilityButton) Draw(screen *ebiten.Image, resources *resource.Manager) {
+	x := float32(b.Bounds.Pos.X)
+	y := float32(b.Bounds.Pos.Y)
+	w := float32(b.Bounds.Size.X)
+	h := float32(b.Bounds.Size.Y)
+	var bgColor, borderColor color.Color
+	canAfford := resources.Get(resource.Energy).Current >= b.Def.EnergyCost
+	switch {
+	case b.Aiming:
+		bgColor = color.RGBA{40, 60, 80, 255}
+		borderColor = color.RGBA{90, 150, 210, 255}
+	case b.State == ButtonPressed:
+		bgColor = color.RGBA{40, 40, 60, 255}
+		borderColor = color.RGBA{120, 120, 140, 255}
+	case b.State == ButtonHovered:
+		bgColor = color.RGBA{60, 60, 80, 255}
+		borderColor = color.RGBA{100, 100, 120, 255}
+	case canAfford || b.Cooldown > 0:
+		bgColor = color.RGBA{45, 45, 60, 255}
+		borderColor = color.RGBA{70, 70, 90, 255}
+	default:
+		bgColor = color.RGBA{60, 35, 35, 255}
+		borderColor = color.RGBA{70, 70, 90, 255}
+	}
+	vector.FillRect(screen, x, y, w, h, bgColor, false)
+	// The cooldown overlay shrinks from the right as the ability recharges
+	if b.Cooldown > 0 && b.Def.Cooldown > 0 {
+		left := float32(min(1, b.Cooldown/b.Def.Cooldown))
+		vector.FillRect(screen, x+w*(1-left), y, w*left, h, color.RGBA{0, 0, 0, 140}, false)
+	}
+	vector.StrokeRect(screen, x, y, w, h, 1, borderColor, false)
+	labelX := int(x + 8)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%s [%s]", b.Def.Name, b.Hotkey), labelX, int(y+6))
+	statusY := int(y + 22)
+	if b.Cooldown > 0 {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Ready in %.0fs", math.Ceil(b.Cooldown)), labelX, statusY)
+	} else {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("E:%.0f", b.Def.EnergyCost), labelX, statusY)
+	}
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%.0fs", b.Def.Cooldown), int(x+w-35), statusY)
+}
+
+// ResearchButton queues a research project at the selected lab
+type ResearchButton struct {
+	Bounds   emath.Rect
+	Def      *entity.ResearchDef
+	State    ButtonState
+	Done     bool    // Already researched
+	Queued   bool    // Waiting in the lab's queue
+	Running  bool    // Being researched now
+	Progress float64 // Progress of a running project, 0 to 1
+	Locked   string  // Research still needed first, empty when available
+}
+
+func (b *ResearchButton) Contains(p emath.Vec2) bool {
+	return b.Bounds.Contains(p)
+}
+
+// Available reports whether clicking the button would queue the project
+func (b *ResearchButton) Available() bool {
+	return !b.Done && !b.Queued && !b.Running && b.Locked == ""
+}
+func (b *ResearchButton) Draw(screen *ebiten.Image, resources *resource.Manager) {
+	x := float32(b.Bounds.Pos.X)
+	y := float32(b.Bounds.Pos.Y)
+	w := float32(b.Bounds.Size.X)
+	h := float32(b.Bounds.Size.Y)
+	var bgColor, borderColor color.Color
+	canAfford := resources.CanAfford(b.Def.Cost)
+	switch {
+	case b.Done:
+		bgColor = color.RGBA{40, 70, 50, 255}
+		borderColor = color.RGBA{90, 160, 100, 255}
+	case b.Locked != "":
+		bgColor = color.RGBA{30, 30, 35, 255}
+		borderColor = color.RGBA{50, 50, 60, 255}
+	case b.State == ButtonPressed:
+		bgColor = color.RGBA{40, 40, 60, 255}
+		borderColor = color.RGBA{120, 120, 140, 255}
+	case b.State == ButtonHovered && canAfford:
+		bgColor = color.RGBA{60, 60, 80, 255}
+		borderColor = color.RGBA{100, 100, 120, 255}
+	case b.State == ButtonHovered:
+		bgColor = color.RGBA{80, 40, 40, 255}
+		borderColor = color.RGBA{100, 100, 120, 255}
+	case canAfford || b.Running || b.Queued:
+		bgColor = color.RGBA{45, 45, 60, 255}
+		borderColor = color.RGBA{70, 70, 90, 255}
+	default:
+		bgColor = color.RGBA{60, 35, 35, 255}
+		borderColor = color.RGBA{70, 70, 90, 255}
+	}
+	vector.FillRect(screen, x, y, w, h, bgColor, false)
+	vector.StrokeRect(screen, x, y, w, h, 1, borderColor, false)
+	if b.Running {
+		vector.FillRect(screen, x+1, y+h-5, (w-2)*float32(b.Progress), 4, color.RGBA{80, 200, 220, 255}, false)
+	}
+	labelX := int(x + 8)
+	ebitenutil.DebugPrintAt(screen, b.Def.Name, labelX, int(y+6))
+	statusY := int(y + 22)
+	switch {
+	case b.Done:
+		ebitenutil.DebugPrintAt(screen, "Researched", labelX, statusY)
+	case b.Running:
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Researching %.0f%%", b.Progress*100), labelX, statusY)
+	case b.Queued:
+		ebitenutil.DebugPrintAt(screen, "Queued", labelX, statusY)
+	case b.Locked != "":
+		ebitenutil.DebugPrintAt(screen, "Needs "+b.Locked, labelX, statusY)
+	default:
+		costStr := ""
+		if metal, ok := b.Def.Cost[resource.Metal]; ok && metal > 0 {
+			costStr += fmt.Sprintf("M:%.0f ", metal)
+		}
+		if energy, ok := b.Def.Cost[resource.Energy]; ok && energy > 0 {
+			costStr += fmt.Sprintf("E:%.0f", energy)
+		}
+		ebitenutil.DebugPrintAt(screen, costStr, labelX, statusY)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%.0fs", b.Def.ResearchTime), int(x+w-35), statusY)
+	}
+}
+
+// missingResearch names the first unfinished project a research project
+// requires, or returns "" when all are finished
+func missingResearch(def *entity.ResearchDef, tech *entity.Tech) string {
+	for _, req := range def.Requires {
+		if !tech.Has(req) {
+			return req.String()
+		}
+	}
+	return ""
+}
+
+// unitLock names the research a unit type still needs, or returns ""
+func unitLock(unitType entity.UnitType, tech *entity.Tech) string {
+	if tech.UnitUnlocked(unitType) {
+		return ""
+	}
+	rt, _ := entity.UnitLock(unitType)
+	return rt.String()
+}
+
+// buildingLock names the research a building type still needs, or returns ""
+func buildingLock(buildingType entity.BuildingType, tech *entity.Tech) string {
+	if tech.BuildingUnlocked(buildingType) {
+		return ""
+	}
+	rt, _ := entity.BuildingLock(buildingType)
+	return rt.String()
+}
+
 type CommandPanel struct {
 	panel           *Panel
 	buttons         []*CommandButton
 	unitButtons     []*UnitButton
+	stanceButtons   []*StanceButton
+	researchButtons []*ResearchButton
+	priorityButtons []*PriorityButton
+	recycleButton   *RecycleButton
+	abilityButtons  []*AbilityButton
 	visible         bool
 	topOffset       float64
 	title           string
@@ -212,7 +531,7 @@ func (cp *CommandPanel) Width() float64 {
 }
 
 func (cp *CommandPanel) calculateScrollBounds() {
-	buttonCount := len(cp.buttons) + len(cp.unitButtons)
+	buttonCount := cp.actionRows() + len(cp.buttons) + len(cp.unitButtons) + len(cp.stanceButtons) + len(cp.researchButtons)
 	if buttonCount == 0 {
 		cp.contentHeight = 0
 		cp.maxScrollOffset = 0
@@ -259,9 +578,35 @@ func (cp *CommandPanel) isButtonVisible(btnY, btnHeight float64) bool {
 	return btnY+btnHeight >= buttonsStartY && btnY <= buttonsEndY
 }
 
+// actionRows is the number of panel rows the ability buttons of selected
+// units and the priority and recycle buttons of a selected building take
+func (cp *CommandPanel) actionRows() int {
+	rows := len(cp.abilityButtons)
+	if len(cp.priorityButtons) > 0 {
+		rows++
+	}
+	if cp.recycleButton != nil {
+		rows++
+	}
+	return rows
+}
+
 func (cp *CommandPanel) updateButtonPositions() {
 	buttonsStartY := cp.topOffset + panelPadding + 20
 
+	for i, btn := range cp.abilityButtons {
+		btn.Bounds.Pos.Y = buttonsStartY + float64(i)*(buttonHeight+buttonMargin) - cp.scrollOffset
+	}
+	abilityRows := float64(len(cp.abilityButtons)) * (buttonHeight + buttonMargin)
+	for _, btn := range cp.priorityButtons {
+		btn.Bounds.Pos.Y = buttonsStartY + abilityRows - cp.scrollOffset
+	}
+	if cp.recycleButton != nil {
+		row := float64(cp.actionRows() - 1)
+		cp.recycleButton.Bounds.Pos.Y = buttonsStartY + row*(buttonHeight+buttonMargin) - cp.scrollOffset
+	}
+	buttonsStartY += float64(cp.actionRows()) * (buttonHeight + buttonMargin)
+
 	for i, btn := range cp.buttons {
 		baseY := buttonsStartY + float64(i)*(buttonHeight+buttonMargin)
 		btn.Bounds.Pos.Y = baseY - cp.scrollOffset
@@ -272,6 +617,18 @@ func (cp *CommandPanel) updateButtonPositions() {
 		baseY := buttonsStartY + float64(buildingCount+i)*(buttonHeight+buttonMargin)
 		btn.Bounds.Pos.Y = baseY - cp.scrollOffset
 	}
+
+	listed := len(cp.buttons) + len(cp.unitButtons)
+	for i, btn := range cp.stanceButtons {
+		baseY := buttonsStartY + float64(listed+i)*(buttonHeight+buttonMargin)
+		btn.Bounds.Pos.Y = baseY - cp.scrollOffset
+	}
+
+	listed += len(cp.stanceButtons)
+	for i, btn := range cp.researchButtons {
+		baseY := buttonsStartY + float64(listed+i)*(buttonHeight+buttonMargin)
+		btn.Bounds.Pos.Y = baseY - cp.scrollOffset
+	}
 }
 func (cp *CommandPanel) IsVisible() bool {
 	return cp.visible
@@ -281,6 +638,11 @@ func (cp *CommandPanel) SetVisible(visible bool) {
 	if !visible {
 		cp.buttons = nil
 		cp.unitButtons = nil
+		cp.stanceButtons = nil
+		cp.researchButtons = nil
+		cp.priorityButtons = nil
+		cp.recycleButton = nil
+		cp.abilityButtons = nil
 		cp.title = ""
 		cp.selectedFactory = nil
 		cp.scrollOffset = 0
@@ -289,6 +651,11 @@ func (cp *CommandPanel) SetVisible(visible bool) {
 func (cp *CommandPanel) SetBuildOptions(units []*entity.Unit) {
 	cp.buttons = nil
 	cp.unitButtons = nil
+	cp.stanceButtons = nil
+	cp.researchButtons = nil
+	cp.priorityButtons = nil
+	cp.recycleButton = nil
+	cp.abilityButtons = nil
 	cp.title = ""
 	cp.visible = false
 	cp.selectedFactory = nil
@@ -323,9 +690,14 @@ func (cp *CommandPanel) SetBuildOptions(units []*entity.Unit) {
 	cp.calculateScrollBounds()
 	cp.updateButtonPositions()
 }
-func (cp *CommandPanel) SetFactoryOptions(factory *entity.Building) {
+func (cp *CommandPanel) SetFactoryOptions(factory *entity.Building, tech *entity.Tech) {
 	cp.buttons = nil
 	cp.unitButtons = nil
+	cp.stanceButtons = nil
+	cp.researchButtons = nil
+	cp.priorityButtons = nil
+	cp.recycleButton = nil
+	cp.abilityButtons = nil
 	cp.title = ""
 	cp.visible = false
 	cp.selectedFactory = nil
@@ -346,6 +718,7 @@ func (cp *CommandPanel) SetFactoryOptions(factory *entity.Building) {
 			def,
 		)
 		btn.QueueCount = factory.GetQueueCount(def.Type)
+		btn.Locked = unitLock(def.Type, tech)
 		cp.unitButtons = append(cp.unitButtons, btn)
 		_ = i
 	}
@@ -353,9 +726,14 @@ func (cp *CommandPanel) SetFactoryOptions(factory *entity.Building) {
 	cp.updateButtonPositions()
 }
 
-func (cp *CommandPanel) SetBuildingBuildOptions(building *entity.Building) {
+func (cp *CommandPanel) SetBuildingBuildOptions(building *entity.Building, tech *entity.Tech) {
 	cp.buttons = nil
 	cp.unitButtons = nil
+	cp.stanceButtons = nil
+	cp.researchButtons = nil
+	cp.priorityButtons = nil
+	cp.recycleButton = nil
+	cp.abilityButtons = nil
 	cp.title = ""
 	cp.visible = false
 	cp.selectedFactory = nil
@@ -374,6 +752,7 @@ func (cp *CommandPanel) SetBuildingBuildOptions(building *entity.Building) {
 			buttonHeight,
 			def,
 		)
+		btn.Locked = buildingLock(def.Type, tech)
 		cp.buttons = append(cp.buttons, btn)
 		_ = i
 	}
@@ -381,6 +760,334 @@ func (cp *CommandPanel) SetBuildingBuildOptions(building *entity.Building) {
 	cp.updateButtonPositions()
 }
 
+// SetResearchOptions shows the tech tree at a lab: what is researched,
+// running, queued, still locked or ready to start
+func (cp *CommandPanel) SetResearchOptions(lab *entity.Building, tech *entity.Tech, researching func(entity.ResearchType) bool) {
+	cp.buttons = nil
+	cp.unitButtons = nil
+	cp.stanceButtons = nil
+	cp.researchButtons = nil
+	cp.priorityButtons = nil
+	cp.recycleButton = nil
+	cp.abilityButtons = nil
+	cp.title = ""
+	cp.visible = false
+	cp.selectedFactory = nil
+	if lab == nil || !lab.CanResearch() {
+		cp.scrollOffset = 0
+		return
+	}
+	cp.visible = true
+	cp.title = "RESEARCH"
+	for _, rt := range entity.AllResearchTypes {
+		def := entity.ResearchDefs[rt]
+		btn := &ResearchButton{
+			Bounds: emath.NewRect(panelPadding, 0, commandPanelWidth-panelPadding*2, buttonHeight),
+			Def:    def,
+			Done:   tech.Has(rt),
+			Locked: missingResearch(def, tech),
+		}
+		if lab.Researching && lab.CurrentResearch == def {
+			btn.Running = true
+			btn.Progress = lab.ResearchProgress
+		} else if !btn.Done && researching(rt) {
+			btn.Queued = true
+		}
+		cp.researchButtons = append(cp.researchButtons, btn)
+	}
+	cp.calculateScrollBounds()
+	cp.updateButtonPositions()
+}
+
+// UpdateResearch updates research button hover state and returns the
+// project that was clicked, if it can be queued
+func (cp *CommandPanel) UpdateResearch(mousePos emath.Vec2, leftClicked bool) *entity.ResearchDef {
+	if !cp.visible {
+		return nil
+	}
+	var clicked *entity.ResearchDef
+	for _, btn := range cp.researchButtons {
+		if !cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) || !btn.Contains(mousePos) {
+			btn.State = ButtonNormal
+			continue
+		}
+		if leftClicked && btn.Available() {
+			btn.State = ButtonPressed
+			clicked = btn.Def
+		} else {
+			btn.State = ButtonHovered
+		}
+	}
+	return clicked
+}
+
+// UpdateResearchRightClick returns the running or queued project that was
+// right-clicked to cancel it, if any
+func (cp *CommandPanel) UpdateResearchRightClick(mousePos emath.Vec2, rightClicked bool) *entity.ResearchDef {
+	if !cp.visible || !rightClicked {
+		return nil
+	}
+	for _, btn := range cp.researchButtons {
+		if !cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) {
+			continue
+		}
+		if btn.Contains(mousePos) && (btn.Running || btn.Queued) {
+			return btn.Def
+		}
+	}
+	return nil
+}
+
+// GetHoveredResearch returns the research project under the mouse, if any
+func (cp *CommandPanel) GetHoveredResearch(mousePos emath.Vec2) *entity.ResearchDef {
+	if !cp.visible {
+		return nil
+	}
+	for _, btn := range cp.researchButtons {
+		if cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) && btn.Contains(mousePos) {
+			return btn.Def
+		}
+	}
+	return nil
+}
+
+// SetStanceOptions shows the stance buttons for the selected player
+// combat units, marking the stance they all share
+func (cp *CommandPanel) SetStanceOptions(units []*entity.Unit) {
+	cp.buttons = nil
+	cp.unitButtons = nil
+	cp.stanceButtons = nil
+	cp.researchButtons = nil
+	cp.priorityButtons = nil
+	cp.recycleButton = nil
+	cp.abilityButtons = nil
+	cp.title = ""
+	cp.visible = false
+	cp.selectedFactory = nil
+	var current entity.Stance
+	count := 0
+	shared := true
+	for _, u := range units {
+		if !u.Selected || u.Faction != entity.FactionPlayer || !u.CanAttack() {
+			continue
+		}
+		if count > 0 && u.Stance != current {
+			shared = false
+		}
+		current = u.Stance
+		count++
+	}
+	if count == 0 {
+		cp.scrollOffset = 0
+		return
+	}
+	cp.visible = true
+	cp.title = "STANCE"
+	for _, stance := range entity.Stances {
+		cp.stanceButtons = append(cp.stanceButtons, &StanceButton{
+			Bounds: emath.NewRect(panelPadding, 0, commandPanelWidth-panelPadding*2, buttonHeight),
+			Stance: stance,
+			Active: shared && stance == current,
+		})
+	}
+	cp.calculateScrollBounds()
+	cp.updateButtonPositions()
+}
+
+// AddPriorityOptions puts a row of priority buttons above whatever the
+// panel shows, if the building spends resources on its work
+func (cp *CommandPanel) AddPriorityOptions(building *entity.Building) {
+	cp.priorityButtons = nil
+	if building == nil || !building.UsesResources() {
+		return
+	}
+	if !cp.visible {
+		cp.visible = true
+		cp.title = "PRIORITY"
+		cp.scrollOffset = 0
+	}
+	width := (commandPanelWidth - panelPadding*2 - buttonMargin*float64(len(entity.Priorities)-1)) / float64(len(entity.Priorities))
+	for i, priority := range entity.Priorities {
+		x := panelPadding + float64(i)*(width+buttonMargin)
+		cp.priorityButtons = append(cp.priorityButtons, &PriorityButton{
+			Bounds:   emath.NewRect(x, 0, width, buttonHeight),
+			Priority: priority,
+			Active:   building.Priority == priority,
+		})
+	}
+	cp.calculateScrollBounds()
+	cp.updateButtonPositions()
+}
+
+// AddRecycleOption puts a recycle button under the priority buttons, if
+// the building can be taken apart
+func (cp *CommandPanel) AddRecycleOption(building *entity.Building) {
+	cp.recycleButton = nil
+	if building == nil || !building.CanRecycle() {
+		return
+	}
+	if !cp.visible {
+		cp.visible = true
+		cp.title = building.Def.Name
+		cp.scrollOffset = 0
+	}
+	metal, energy := building.RecycleValue()
+	cp.recycleButton = &RecycleButton{
+		Bounds: emath.NewRect(panelPadding, 0, commandPanelWidth-panelPadding*2, buttonHeight),
+		Metal:  metal,
+		Energy: energy,
+	}
+	cp.calculateScrollBounds()
+	cp.updateButtonPositions()
+}
+
+// UpdateRecycle updates the recycle button hover state and reports
+// whether it was clicked
+func (cp *CommandPanel) UpdateRecycle(mousePos emath.Vec2, leftClicked bool) bool {
+	btn := cp.recycleButton
+	if !cp.visible || btn == nil {
+		return false
+	}
+	if !cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) || !btn.Contains(mousePos) {
+		btn.State = ButtonNormal
+		return false
+	}
+	if leftClicked {
+		btn.State = ButtonPressed
+		return true
+	}
+	btn.State = ButtonHovered
+	return false
+}
+
+// AddAbilityOptions puts a button for each ability of the selected player
+// units at the top of the panel. aiming is the ability waiting for a
+// target, if any.
+func (cp *CommandPanel) AddAbilityOptions(units []*entity.Unit, aiming *entity.AbilityDef) {
+	cp.abilityButtons = nil
+	abilities := SelectedAbilities(units)
+	if len(abilities) == 0 {
+		return
+	}
+	if !cp.visible {
+		cp.visible = true
+		cp.title = "ABILITIES"
+		cp.scrollOffset = 0
+	}
+	for i, at := range abilities {
+		def := entity.AbilityDefs[at]
+		cooldown := -1.0
+		for _, u := range units {
+			if u.Selected && u.Faction == entity.FactionPlayer && u.HasAbility(at) {
+				if cooldown < 0 || u.Ability.Cooldowns[at] < cooldown {
+					cooldown = u.Ability.Cooldowns[at]
+				}
+			}
+		}
+		cp.abilityButtons = append(cp.abilityButtons, &AbilityButton{
+			Bounds:   emath.NewRect(panelPadding, 0, commandPanelWidth-panelPadding*2, buttonHeight),
+			Def:      def,
+			Hotkey:   AbilityHotkeys[i],
+			Cooldown: cooldown,
+			Aiming:   def == aiming,
+		})
+	}
+	cp.calculateScrollBounds()
+	cp.updateButtonPositions()
+}
+
+// SelectedAbilities lists the abilities of the selected player units in
+// the order their buttons are shown, at most one per hotkey
+func SelectedAbilities(units []*entity.Unit) []entity.AbilityType {
+	var has [entity.NumAbilityTypes]bool
+	for _, u := range units {
+		if u.Selected && u.Faction == entity.FactionPlayer {
+			for _, at := range u.Abilities() {
+				has[at] = true
+			}
+		}
+	}
+	var abilities []entity.AbilityType
+	for at, ok := range has {
+		if ok && len(abilities) < len(AbilityHotkeys) {
+			abilities = append(abilities, entity.AbilityType(at))
+		}
+	}
+	return abilities
+}
+
+// UpdateAbility updates ability button hover state and returns the
+// ability that was clicked, if any
+func (cp *CommandPanel) UpdateAbility(mousePos emath.Vec2, leftClicked bool) *entity.AbilityDef {
+	if !cp.visible {
+		return nil
+	}
+	var clicked *entity.AbilityDef
+	for _, btn := range cp.abilityButtons {
+		if !cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) || !btn.Contains(mousePos) {
+			btn.State = ButtonNormal
+			continue
+		}
+		if leftClicked {
+			btn.State = ButtonPressed
+			clicked = btn.Def
+		} else {
+			btn.State = ButtonHovered
+		}
+	}
+	return clicked
+}
+
+// UpdatePriority updates priority button hover state and returns the
+// priority that was clicked, if any
+func (cp *CommandPanel) UpdatePriority(mousePos emath.Vec2, leftClicked bool) (entity.Priority, bool) {
+	if !cp.visible {
+		return 0, false
+	}
+	var clicked entity.Priority
+	found := false
+	for _, btn := range cp.priorityButtons {
+		if !cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) || !btn.Contains(mousePos) {
+			btn.State = ButtonNormal
+			continue
+		}
+		if leftClicked {
+			btn.State = ButtonPressed
+			clicked, found = btn.Priority, true
+		} else {
+			btn.State = ButtonHovered
+		}
+	}
+	return clicked, found
+}
+
+// UpdateStance updates stance button hover state and returns the stance
+// that was clicked, if any
+func (cp *CommandPanel) UpdateStance(mousePos emath.Vec2, leftClicked bool) (entity.Stance, bool) {
+	if !cp.visible {
+		return 0, false
+	}
+	var clicked entity.Stance
+	found := false
+	for _, btn := range cp.stanceButtons {
+		if !cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) {
+			btn.State = ButtonNormal
+			continue
+		}
+		if btn.Contains(mousePos) {
+			if leftClicked {
+				btn.State = ButtonPressed
+				clicked, found = btn.Stance, true
+			} else {
+				btn.State = ButtonHovered
+			}
+		} else {
+			btn.State = ButtonNormal
+		}
+	}
+	return clicked, found
+}
+
 func (cp *CommandPanel) UpdateQueueCounts() {
 	if cp.selectedFactory == nil {
 		return
@@ -400,7 +1107,7 @@ func (cp *CommandPanel) Update(mousePos emath.Vec2, leftClicked bool) *entity.Bu
 			continue
 		}
 		if btn.Contains(mousePos) {
-			if leftClicked {
+			if leftClicked && btn.Locked == "" {
 				btn.State = ButtonPressed
 				clickedDef = btn.BuildingDef
 			} else {
@@ -423,6 +1130,13 @@ func (cp *CommandPanel) Update(mousePos emath.Vec2, leftClicked bool) *entity.Bu
 			btn.State = ButtonNormal
 		}
 	}
+	if !leftClicked {
+		cp.UpdateStance(mousePos, false)
+		cp.UpdateResearch(mousePos, false)
+		cp.UpdatePriority(mousePos, false)
+		cp.UpdateRecycle(mousePos, false)
+		cp.UpdateAbility(mousePos, false)
+	}
 	return clickedDef
 }
 
@@ -476,6 +1190,14 @@ func (cp *CommandPanel) GetHoveredButtonBounds(mousePos emath.Vec2) *emath.Rect
 			return &btn.Bounds
 		}
 	}
+	for _, btn := range cp.researchButtons {
+		if !cp.isButtonVisible(btn.Bounds.Pos.Y, btn.Bounds.Size.Y) {
+			continue
+		}
+		if btn.Contains(mousePos) {
+			return &btn.Bounds
+		}
+	}
 	return nil
 }
 func (cp *CommandPanel) UpdateUnit(mousePos emath.Vec2, leftClicked bool) *entity.UnitDef {
@@ -489,7 +1211,7 @@ func (cp *CommandPanel) UpdateUnit(mousePos emath.Vec2, leftClicked bool) *entit
 			continue
 		}
 		if btn.Contains(mousePos) {
-			if leftClicked {
+			if leftClicked && btn.Locked == "" {
 				btn.State = ButtonPressed
 				clickedDef = btn.UnitDef
 			} else {
@@ -528,6 +1250,22 @@ func (cp *CommandPanel) Draw(screen *ebiten.Image, resources *resource.Manager)
 	cp.panel.Draw(screen)
 	ebitenutil.DebugPrintAt(screen, cp.title, int(panelPadding), int(cp.topOffset+panelPadding))
 
+	for _, btn := range cp.abilityButtons {
+		if cp.isButtonVisible(btn.Bounds.Pos.Y, buttonHeight) {
+			btn.Draw(screen, resources)
+		}
+	}
+
+	for _, btn := range cp.priorityButtons {
+		if cp.isButtonVisible(btn.Bounds.Pos.Y, buttonHeight) {
+			btn.Draw(screen)
+		}
+	}
+
+	if btn := cp.recycleButton; btn != nil && cp.isButtonVisible(btn.Bounds.Pos.Y, buttonHeight) {
+		btn.Draw(screen)
+	}
+
 	for _, btn := range cp.buttons {
 		if cp.isButtonVisible(btn.Bounds.Pos.Y, buttonHeight) {
 			btn.Draw(screen, resources)
@@ -540,6 +1278,18 @@ func (cp *CommandPanel) Draw(screen *ebiten.Image, resources *resource.Manager)
 		}
 	}
 
+	for _, btn := range cp.stanceButtons {
+		if cp.isButtonVisible(btn.Bounds.Pos.Y, buttonHeight) {
+			btn.Draw(screen)
+		}
+	}
+
+	for _, btn := range cp.researchButtons {
+		if cp.isButtonVisible(btn.Bounds.Pos.Y, buttonHeight) {
+			btn.Draw(screen, resources)
+		}
+	}
+
 	if cp.maxScrollOffset > 0 {
 		buttonsStartY := cp.topOffset + panelPadding + 20
 		cp.drawScrollIndicator(screen, buttonsStartY)
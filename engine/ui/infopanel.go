@@ -116,7 +116,16 @@ func (ip *InfoPanel) Draw(screen *ebiten.Image) {
 		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("+%.0f Energy/s", def.EnergyProduction), x, y)
 		y += infoLineHeight
 	}
-	if def.MetalProduction > 0 {
+	if def.RequiresDeposit {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("+%.1f Metal/s", b.Yield), x, y)
+		y += infoLineHeight
+		deposit := "Deposit exhausted"
+		if b.DepositLeft > 0 {
+			deposit = fmt.Sprintf("Deposit: %.0f metal left", b.DepositLeft)
+		}
+		ebitenutil.DebugPrintAt(screen, deposit, x, y)
+		y += infoLineHeight
+	} else if def.MetalProduction > 0 {
 		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("+%.0f Metal/s", def.MetalProduction), x, y)
 		y += infoLineHeight
 	}
@@ -124,6 +133,12 @@ func (ip *InfoPanel) Draw(screen *ebiten.Image) {
 	if def.EnergyConsumption > 0 {
 		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("-%.0f Energy/s", def.EnergyConsumption), x, y)
 		y += infoLineHeight
+		power := fmt.Sprintf("Power: %.0f%% (%s)", b.Power*100, def.PowerClass())
+		if b.Offline() {
+			power = "Power: OFFLINE"
+		}
+		ebitenutil.DebugPrintAt(screen, power, x, y)
+		y += infoLineHeight
 	}
 	if def.MetalConsumption > 0 {
 		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("-%.0f Metal/s", def.MetalConsumption), x, y)
@@ -135,6 +150,27 @@ func (ip *InfoPanel) Draw(screen *ebiten.Image) {
 		y += infoLineHeight
 	}
 
+	if b.Recycling {
+		metal, energy := b.RecycleValue()
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Recycling: %.0f%%", b.RecycleProgress*100), x, y)
+		y += infoLineHeight
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Returns M:%.0f E:%.0f", metal, energy), x, y)
+		y += infoLineHeight
+	}
+	if b.UsesResources() {
+		switch {
+		case b.Priority == entity.PriorityPaused:
+			ebitenutil.DebugPrintAt(screen, "PAUSED", x, y)
+			y += infoLineHeight
+		case b.Funding < 1:
+			ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Stalled: %.0f%% funded", b.Funding*100), x, y)
+			y += infoLineHeight
+		case b.Priority == entity.PriorityHigh:
+			ebitenutil.DebugPrintAt(screen, "High priority", x, y)
+			y += infoLineHeight
+		}
+	}
+
 	if def.IsFactory && b.Producing {
 		ebitenutil.DebugPrintAt(screen, "Producing...", x, y)
 	}
@@ -152,7 +188,9 @@ func GetBuildingStats(def *entity.BuildingDef) string {
 	if def.EnergyProduction > 0 {
 		stats += fmt.Sprintf("+%.0fE/s ", def.EnergyProduction)
 	}
-	if def.MetalProduction > 0 {
+	if def.MetalProduction > 0 && def.RequiresDeposit {
+		stats += fmt.Sprintf("+%.0fM/s/tile ", def.MetalProduction)
+	} else if def.MetalProduction > 0 {
 		stats += fmt.Sprintf("+%.0fM/s ", def.MetalProduction)
 	}
 	if def.EnergyConsumption > 0 {
@@ -15,6 +15,7 @@ type MinimapEntity struct {
 	Position emath.Vec2
 	Size     emath.Vec2
 	Color    color.Color
+	Blip     bool // Radar contact out of sight, drawn as a ring at its position
 }
 type Minimap struct {
 	bounds       emath.Rect
@@ -38,6 +39,12 @@ func (m *Minimap) SetPosition(x, y float64) {
 func (m *Minimap) SetWorldSize(width, height float64) {
 	m.worldSize = emath.Vec2{X: width, Y: height}
 }
+
+// InvalidateTerrain drops the cached terrain so the next draw renders it
+// from the map again
+func (m *Minimap) InvalidateTerrain() {
+	m.terrainCache = nil
+}
 func (m *Minimap) WorldSize() emath.Vec2 {
 	return m.worldSize
 }
@@ -98,6 +105,10 @@ func (m *Minimap) Draw(screen *ebiten.Image, cam *camera.Camera, terrainMap *ter
 			pos.X > m.bounds.Pos.X+m.bounds.Size.X || pos.Y > m.bounds.Pos.Y+m.bounds.Size.Y {
 			continue
 		}
+		if ent.Blip {
+			vector.StrokeCircle(screen, float32(pos.X+size.X/2), float32(pos.Y+size.Y/2), 3, 1, ent.Color, true)
+			continue
+		}
 		vector.FillRect(
 			screen,
 			float32(pos.X),
@@ -164,7 +175,7 @@ func (m *Minimap) drawTerrainWithFog(screen *ebiten.Image, terrainMap *terrain.M
 	cacheWidth := int(m.bounds.Size.X)
 	cacheHeight := int(m.bounds.Size.Y)
 
-	// Build base terrain cache once (never changes)
+	// Build base terrain cache once; only deposits change later
 	if m.terrainCache == nil {
 		m.terrainCache = ebiten.NewImage(cacheWidth, cacheHeight)
 		scaleX := m.bounds.Size.X / m.worldSize.X
@@ -183,7 +194,12 @@ func (m *Minimap) drawTerrainWithFog(screen *ebiten.Image, terrainMap *terrain.M
 				tile := terrainMap.Tiles[y][x]
 				screenX := float64(x) * tileScreenWidth
 				screenY := float64(y) * tileScreenHeight
-				tileColor := terrain.TileColors(tile.Type).(color.RGBA)
+				// Deposits are drawn every frame as they are mined out
+				tileType := tile.Type
+				if tile.HasMetal {
+					tileType = terrain.TileGrass
+				}
+				tileColor := terrain.TileColors(tileType).(color.RGBA)
 				vector.DrawFilledRect(
 					m.terrainCache,
 					float32(screenX),
@@ -202,7 +218,6 @@ func (m *Minimap) drawTerrainWithFog(screen *ebiten.Image, terrainMap *terrain.M
 	op.GeoM.Translate(m.bounds.Pos.X, m.bounds.Pos.Y)
 	screen.DrawImage(m.terrainCache, op)
 
-	// Draw fog overlay directly (no caching - simpler and fog changes every frame anyway)
 	scaleX := m.bounds.Size.X / m.worldSize.X
 	scaleY := m.bounds.Size.Y / m.worldSize.Y
 	tileScreenWidth := terrain.TileSize * scaleX
@@ -214,6 +229,29 @@ func (m *Minimap) drawTerrainWithFog(screen *ebiten.Image, terrainMap *terrain.M
 		tileScreenHeight = 1
 	}
 
+	// Draw deposits, fading towards grass as they are mined out
+	grass := terrain.TileColors(terrain.TileGrass).(color.RGBA)
+	metal := color.RGBA{200, 200, 230, 255}
+	for y := 0; y < terrainMap.Height; y++ {
+		for x := 0; x < terrainMap.Width; x++ {
+			tile := terrainMap.Tiles[y][x]
+			if !tile.HasMetal {
+				continue
+			}
+			vector.FillRect(
+				screen,
+				float32(m.bounds.Pos.X+float64(x)*tileScreenWidth),
+				float32(m.bounds.Pos.Y+float64(y)*tileScreenHeight),
+				float32(tileScreenWidth)+1,
+				float32(tileScreenHeight)+1,
+				lerpColor(grass, metal, 0.3+0.7*tile.DepositFraction()),
+				false,
+			)
+		}
+	}
+
+	// Draw fog overlay directly (no caching - simpler and fog changes every frame anyway)
+
 	for y := 0; y < terrainMap.Height; y++ {
 		for x := 0; x < terrainMap.Width; x++ {
 			fogState := fogOfWar.GetTileStateAt(x, y)
@@ -243,3 +281,11 @@ func (m *Minimap) drawTerrainWithFog(screen *ebiten.Image, terrainMap *terrain.M
 		}
 	}
 }
+
+// lerpColor blends from a to b by t in [0, 1]
+func lerpColor(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
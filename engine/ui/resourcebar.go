@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image/color"
 
+	"github.com/bklimczak/tanks/engine/entity"
 	emath "github.com/bklimczak/tanks/engine/math"
 	"github.com/bklimczak/tanks/engine/resource"
 	"github.com/hajimehoshi/ebiten/v2"
@@ -12,8 +13,12 @@ import (
 )
 
 var (
-	MetalColor  = color.RGBA{180, 180, 200, 255}
-	EnergyColor = color.RGBA{80, 180, 255, 255}
+	MetalColor     = color.RGBA{180, 180, 200, 255}
+	EnergyColor    = color.RGBA{80, 180, 255, 255}
+	PowerColor     = color.RGBA{80, 200, 80, 255}
+	LowPowerColor  = color.RGBA{230, 160, 40, 255}
+	NoPowerColor   = color.RGBA{200, 50, 50, 255}
+	PowerHoverFill = color.RGBA{50, 50, 70, 255}
 )
 
 type ResourceDisplay struct {
@@ -91,10 +96,62 @@ func (rd *ResourceDisplay) Width() float64 {
 	return rd.bounds.Size.X
 }
 
+// PowerDisplay shows how much of the energy demand the grid covers and
+// which consumers it powers first. Clicking it cycles the priority.
+type PowerDisplay struct {
+	bounds emath.Rect
+}
+
+func NewPowerDisplay(x, y, width float64) *PowerDisplay {
+	return &PowerDisplay{bounds: emath.NewRect(x, y, width, 40)}
+}
+
+// Contains reports whether pos is over the display
+func (pd *PowerDisplay) Contains(pos emath.Vec2) bool {
+	return pd.bounds.Contains(pos)
+}
+
+func (pd *PowerDisplay) Draw(screen *ebiten.Image, grid *entity.PowerGrid, hovered bool) {
+	x := pd.bounds.Pos.X
+	y := pd.bounds.Pos.Y
+	w := pd.bounds.Size.X
+	if hovered {
+		vector.FillRect(screen, float32(x-4), float32(y-2), float32(w), float32(pd.bounds.Size.Y), PowerHoverFill, false)
+	}
+
+	ebitenutil.DebugPrintAt(screen, "POWER", int(x), int(y))
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("First: %s [O]", grid.First), int(x+45), int(y))
+
+	level := grid.Level()
+	barColor := PowerColor
+	switch {
+	case level < entity.MinDefensePower:
+		barColor = NoPowerColor
+	case level < 1:
+		barColor = LowPowerColor
+	}
+	barY := y + 14
+	barWidth := w - 10
+	vector.FillRect(screen, float32(x), float32(barY), float32(barWidth), 8, BarBackgroundColor, false)
+	vector.FillRect(screen, float32(x), float32(barY), float32(barWidth*level), 8, barColor, false)
+	vector.StrokeRect(screen, float32(x), float32(barY), float32(barWidth), 8, 1, BorderColor, false)
+
+	statsY := int(barY + 12)
+	if level < 1 {
+		text := fmt.Sprintf("LOW POWER %.0f%%", level*100)
+		vector.FillRect(screen, float32(x), float32(statsY), float32(len(text)*6+4), 14, barColor, false)
+		ebitenutil.DebugPrintAt(screen, text, int(x+2), statsY-1)
+		return
+	}
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%.1f / %.1f", grid.Demand, grid.Supply), int(x), statsY)
+}
+
 type ResourceBar struct {
 	panel    *Panel
 	displays []*ResourceDisplay
+	power    *PowerDisplay
 	height   float64
+	hovered  bool
 }
 
 func NewResourceBar(screenWidth float64) *ResourceBar {
@@ -110,6 +167,7 @@ func NewResourceBar(screenWidth float64) *ResourceBar {
 
 	rb.displays = append(rb.displays, NewResourceDisplay(resource.Metal, startX, 5, displayWidth))
 	rb.displays = append(rb.displays, NewResourceDisplay(resource.Energy, startX+displayWidth+spacing, 5, displayWidth))
+	rb.power = NewPowerDisplay(startX+2*(displayWidth+spacing), 5, displayWidth)
 
 	return rb
 }
@@ -122,8 +180,16 @@ func (rb *ResourceBar) UpdateWidth(screenWidth float64) {
 	rb.panel.Bounds.Size.X = screenWidth
 }
 
-func (rb *ResourceBar) Draw(screen *ebiten.Image, resources *resource.Manager) {
+// UpdatePower tracks the mouse over the power display and reports whether
+// it was clicked
+func (rb *ResourceBar) UpdatePower(mousePos emath.Vec2, clicked bool) bool {
+	rb.hovered = rb.power.Contains(mousePos)
+	return rb.hovered && clicked
+}
+
+func (rb *ResourceBar) Draw(screen *ebiten.Image, resources *resource.Manager, grid *entity.PowerGrid) {
 	rb.panel.Draw(screen)
+	rb.power.Draw(screen, grid, rb.hovered)
 	const tickRate = 1.0 / 60.0
 	for _, display := range rb.displays {
 		res := resources.Get(display.resourceType)
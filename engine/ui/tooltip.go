@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"image/color"
+	"slices"
 	"strings"
 
 	"github.com/bklimczak/tanks/engine/entity"
@@ -23,6 +24,7 @@ type Tooltip struct {
 	visible      bool
 	buildingDef  *entity.BuildingDef
 	unitDef      *entity.UnitDef
+	researchDef  *entity.ResearchDef
 	position     emath.Vec2
 	screenWidth  float64
 	screenHeight float64
@@ -40,6 +42,7 @@ func (t *Tooltip) SetScreenSize(w, h float64) {
 func (t *Tooltip) ShowBuilding(def *entity.BuildingDef, x, y float64) {
 	t.buildingDef = def
 	t.unitDef = nil
+	t.researchDef = nil
 	t.position = emath.Vec2{X: x, Y: y}
 	t.visible = true
 }
@@ -47,6 +50,16 @@ func (t *Tooltip) ShowBuilding(def *entity.BuildingDef, x, y float64) {
 func (t *Tooltip) ShowUnit(def *entity.UnitDef, x, y float64) {
 	t.unitDef = def
 	t.buildingDef = nil
+	t.researchDef = nil
+	t.position = emath.Vec2{X: x, Y: y}
+	t.visible = true
+}
+
+// ShowResearch shows what a research project needs and unlocks
+func (t *Tooltip) ShowResearch(def *entity.ResearchDef, x, y float64) {
+	t.researchDef = def
+	t.buildingDef = nil
+	t.unitDef = nil
 	t.position = emath.Vec2{X: x, Y: y}
 	t.visible = true
 }
@@ -55,6 +68,7 @@ func (t *Tooltip) Hide() {
 	t.visible = false
 	t.buildingDef = nil
 	t.unitDef = nil
+	t.researchDef = nil
 }
 
 func (t *Tooltip) IsVisible() bool {
@@ -71,6 +85,8 @@ func (t *Tooltip) Draw(screen *ebiten.Image) {
 		lines = t.getBuildingLines()
 	} else if t.unitDef != nil {
 		lines = t.getUnitLines()
+	} else if t.researchDef != nil {
+		lines = t.getResearchLines()
 	} else {
 		return
 	}
@@ -117,7 +133,9 @@ func (t *Tooltip) getBuildingLines() []string {
 	if def.EnergyProduction > 0 {
 		lines = append(lines, fmt.Sprintf("Produces: +%.0f Energy/s", def.EnergyProduction))
 	}
-	if def.MetalProduction > 0 {
+	if def.MetalProduction > 0 && def.RequiresDeposit {
+		lines = append(lines, fmt.Sprintf("Produces: +%.0f Metal/s per deposit tile", def.MetalProduction))
+	} else if def.MetalProduction > 0 {
 		lines = append(lines, fmt.Sprintf("Produces: +%.0f Metal/s", def.MetalProduction))
 	}
 
@@ -142,13 +160,21 @@ func (t *Tooltip) getBuildingLines() []string {
 	if def.IsFactory {
 		lines = append(lines, "Can produce units")
 	}
+	if def.IsLab {
+		lines = append(lines, "Runs research projects")
+	}
 
 	if def.CanAttack {
-		lines = append(lines, fmt.Sprintf("Damage: %.0f  Range: %.0f", def.Damage, def.AttackRange))
+		lines = append(lines, fmt.Sprintf("Damage: %.0f %s  Range: %.0f", def.Damage, def.DamageType, def.AttackRange))
+		lines = append(lines, matchupLines(def.DamageType, def.AntiAir, def.AntiGround)...)
 	}
+	lines = append(lines, armorLines(def.Armor)...)
 
 	if def.RequiresDeposit {
-		lines = append(lines, "Must be placed on deposit")
+		lines = append(lines, "Must be placed on deposit", "Deposits run out as they are mined")
+	}
+	if rt, locked := entity.BuildingLock(def.Type); locked {
+		lines = append(lines, "Requires research: "+rt.String())
 	}
 
 	lines = append(lines, "")
@@ -176,8 +202,10 @@ func (t *Tooltip) getUnitLines() []string {
 	lines = append(lines, fmt.Sprintf("Health: %.0f  Speed: %.0f", def.Health, def.Speed))
 
 	if def.CanAttack() {
-		lines = append(lines, fmt.Sprintf("Damage: %.0f  Range: %.0f", def.GetDamage(), def.GetRange()))
+		lines = append(lines, fmt.Sprintf("Damage: %.0f %s  Range: %.0f", def.GetDamage(), def.Combat.DamageType, def.GetRange()))
+		lines = append(lines, matchupLines(def.Combat.DamageType, def.Combat.AntiAir, def.Combat.AntiGround)...)
 	}
+	lines = append(lines, armorLines(def.Armor)...)
 
 	if def.CanConstruct() {
 		lines = append(lines, "Can construct buildings")
@@ -186,6 +214,9 @@ func (t *Tooltip) getUnitLines() []string {
 	if def.CanRepairUnits() {
 		lines = append(lines, "Can repair units")
 	}
+	if rt, locked := entity.UnitLock(def.Type); locked {
+		lines = append(lines, "Requires research: "+rt.String())
+	}
 
 	lines = append(lines, "")
 	costStr := "Cost:"
@@ -201,6 +232,93 @@ func (t *Tooltip) getUnitLines() []string {
 	return lines
 }
 
+func (t *Tooltip) getResearchLines() []string {
+	def := t.researchDef
+	lines := []string{def.Name}
+
+	descLines := wrapText(def.Description, 30)
+	lines = append(lines, descLines...)
+	lines = append(lines, "")
+
+	if len(def.Requires) > 0 {
+		lines = append(lines, wrapText("Requires: "+joinNames(def.Requires), 30)...)
+	}
+	unlocks := make([]string, 0, len(def.UnlocksUnits)+len(def.UnlocksBuildings))
+	for _, ut := range def.UnlocksUnits {
+		unlocks = append(unlocks, ut.String())
+	}
+	for _, bt := range def.UnlocksBuildings {
+		unlocks = append(unlocks, bt.String())
+	}
+	if len(unlocks) > 0 {
+		lines = append(lines, wrapText("Unlocks: "+strings.Join(unlocks, ", "), 30)...)
+	}
+	if def.Upgrade.Plating > 0 {
+		lines = append(lines, fmt.Sprintf("All units: -%.0f%% damage taken", def.Upgrade.Plating*100))
+	}
+	if def.Upgrade.Range > 0 {
+		lines = append(lines, fmt.Sprintf("All units: +%.0f%% weapon range", def.Upgrade.Range*100))
+	}
+
+	lines = append(lines, "")
+	costStr := "Cost:"
+	if m, ok := def.Cost[resource.Metal]; ok && m > 0 {
+		costStr += fmt.Sprintf(" %.0fM", m)
+	}
+	if e, ok := def.Cost[resource.Energy]; ok && e > 0 {
+		costStr += fmt.Sprintf(" %.0fE", e)
+	}
+	lines = append(lines, costStr)
+	lines = append(lines, fmt.Sprintf("Research Time: %.0fs", def.ResearchTime))
+
+	return lines
+}
+
+// matchupLines lists the armor classes a weapon is strong and weak
+// against, among the targets it can actually shoot at
+func matchupLines(damage entity.DamageType, antiAir, antiGround bool) []string {
+	canHit := func(a entity.ArmorClass) bool {
+		if a == entity.ArmorAir {
+			return antiAir
+		}
+		return antiGround
+	}
+	strong, weak := entity.Effectiveness.Against(damage)
+	strong = slices.DeleteFunc(strong, func(a entity.ArmorClass) bool { return !canHit(a) })
+	weak = slices.DeleteFunc(weak, func(a entity.ArmorClass) bool { return !canHit(a) })
+
+	var lines []string
+	if len(strong) > 0 {
+		lines = append(lines, wrapText("Strong vs: "+joinNames(strong), 30)...)
+	}
+	if len(weak) > 0 {
+		lines = append(lines, wrapText("Weak vs: "+joinNames(weak), 30)...)
+	}
+	return lines
+}
+
+// armorLines names an armor class and the damage types it is vulnerable
+// to and resists
+func armorLines(armor entity.ArmorClass) []string {
+	lines := []string{"Armor: " + armor.String()}
+	vulnerable, resists := entity.Effectiveness.Threats(armor)
+	if len(vulnerable) > 0 {
+		lines = append(lines, wrapText("Vulnerable to: "+joinNames(vulnerable), 30)...)
+	}
+	if len(resists) > 0 {
+		lines = append(lines, wrapText("Resists: "+joinNames(resists), 30)...)
+	}
+	return lines
+}
+
+func joinNames[T fmt.Stringer](items []T) string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.String()
+	}
+	return strings.Join(names, ", ")
+}
+
 func wrapText(text string, maxChars int) []string {
 	words := strings.Fields(text)
 	var lines []string
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
+	"github.com/bklimczak/tanks/engine/protocol"
 	"github.com/google/uuid"
 )
 
@@ -35,9 +37,19 @@ type Lobby struct {
 	Game       *Simulation
 	gameCancel context.CancelFunc
 
+	// seats maps session tokens to the players of a match in progress, so
+	// a player who lost the connection can rejoin the same slot
+	seats map[string]seat
+
 	mu sync.RWMutex
 }
 
+// seat is a player's place in a match in progress
+type seat struct {
+	PlayerID string
+	Slot     int
+}
+
 // NewLobby creates a new lobby
 func NewLobby(name string, host *Player, maxPlayers int) *Lobby {
 	if maxPlayers < MinPlayers {
@@ -174,6 +186,7 @@ func (l *Lobby) Start() error {
 
 	// Create game simulation
 	playerSetups := make([]PlayerSetup, 0, len(l.Players))
+	l.seats = make(map[string]seat, len(l.Players))
 	for _, playerID := range l.PlayerOrder {
 		if player, ok := l.Players[playerID]; ok {
 			playerSetups = append(playerSetups, PlayerSetup{
@@ -181,16 +194,111 @@ func (l *Lobby) Start() error {
 				Name:     player.GetName(),
 				Slot:     player.Slot,
 			})
+			l.seats[player.SessionToken] = seat{PlayerID: player.ID, Slot: player.Slot}
 		}
 	}
 
 	l.Game = NewSimulation(playerSetups)
 	l.State = LobbyPlaying
+	l.runGame()
 
-	// Start game loop in background
+	return nil
+}
+
+// runGame starts the game loop in the background. Callers hold l.mu.
+func (l *Lobby) runGame() {
 	ctx, cancel := context.WithCancel(context.Background())
 	l.gameCancel = cancel
 	go l.Game.Run(ctx, l)
+}
+
+// restoreLobby recreates a lobby and its running match from a snapshot.
+// The lobby starts without connected players; they come back through Rejoin.
+func restoreLobby(snap *matchSnapshot) *Lobby {
+	l := &Lobby{
+		ID:          snap.LobbyID,
+		Name:        snap.LobbyName,
+		State:       LobbyPlaying,
+		HostID:      snap.HostID,
+		Players:     make(map[string]*Player),
+		PlayerOrder: make([]string, 0, snap.MaxPlayers),
+		MaxPlayers:  snap.MaxPlayers,
+		seats:       make(map[string]seat, len(snap.Players)),
+	}
+	for _, p := range snap.Players {
+		l.seats[p.SessionToken] = seat{PlayerID: p.PlayerID, Slot: p.Slot}
+	}
+
+	l.Game = restoreSimulation(snap.Players, &snap.World)
+	l.runGame()
+	return l
+}
+
+// Snapshot captures the running match, or returns nil if none is running
+func (l *Lobby) Snapshot() *matchSnapshot {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.State != LobbyPlaying || l.Game == nil {
+		return nil
+	}
+
+	players, world := l.Game.Snapshot()
+	for i := range players {
+		for token, st := range l.seats {
+			if st.PlayerID == players[i].PlayerID {
+				players[i].SessionToken = token
+				break
+			}
+		}
+	}
+
+	return &matchSnapshot{
+		Version:    MatchSnapshotVersion,
+		Timestamp:  time.Now(),
+		LobbyID:    l.ID,
+		LobbyName:  l.Name,
+		HostID:     l.HostID,
+		MaxPlayers: l.MaxPlayers,
+		Players:    players,
+		World:      world,
+	}
+}
+
+// seatFor returns the seat held by a session token in a running match
+func (l *Lobby) seatFor(token string) (seat, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.State != LobbyPlaying || token == "" {
+		return seat{}, false
+	}
+	st, ok := l.seats[token]
+	return st, ok
+}
+
+// Rejoin puts a reconnected player back into their slot of the running match
+func (l *Lobby) Rejoin(p *Player) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.State != LobbyPlaying {
+		return errors.New("match is no longer running")
+	}
+
+	st, ok := l.seats[p.SessionToken]
+	if !ok || st.PlayerID != p.ID {
+		return errors.New("no seat in this match")
+	}
+
+	if _, exists := l.Players[p.ID]; exists {
+		return errors.New("player already in lobby")
+	}
+
+	p.Slot = st.Slot
+	p.Ready = true
+	l.Players[p.ID] = p
+	l.PlayerOrder = append(l.PlayerOrder, p.ID)
 
 	return nil
 }
@@ -209,7 +317,7 @@ func (l *Lobby) Stop() {
 }
 
 // Broadcast sends a message to all players in the lobby
-func (l *Lobby) Broadcast(msg Message) {
+func (l *Lobby) Broadcast(msg protocol.Message) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
@@ -219,14 +327,83 @@ func (l *Lobby) Broadcast(msg Message) {
 }
 
 // BroadcastPayload sends a message with the given type and payload to all players
-func (l *Lobby) BroadcastPayload(msgType MessageType, payload interface{}) {
-	msg, err := NewMessage(msgType, payload)
+func (l *Lobby) BroadcastPayload(msgType protocol.MessageType, payload interface{}) {
+	msg, err := protocol.NewMessage(msgType, payload)
 	if err != nil {
 		return
 	}
 	l.Broadcast(msg)
 }
 
+// BroadcastGameState sends the game state to all players, each in the
+// encoding negotiated during their handshake. What hidden[slot] lists is
+// left out of what the player in that slot is sent.
+func (l *Lobby) BroadcastGameState(state protocol.GameStatePayload, hidden map[int]*hiddenSet) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var jsonMsg *protocol.Message
+	var binaryFrame []byte
+
+	for _, p := range l.Players {
+		if h := hidden[p.Slot]; h != nil {
+			sendGameState(p, withoutHidden(state, h))
+			continue
+		}
+		if p.Encoding == protocol.EncodingBinary {
+			if binaryFrame == nil {
+				binaryFrame = protocol.EncodeGameState(&state)
+			}
+			p.SendBinary(binaryFrame)
+			continue
+		}
+		if jsonMsg == nil {
+			msg, err := protocol.NewMessage(protocol.MsgGameState, state)
+			if err != nil {
+				return
+			}
+			jsonMsg = &msg
+		}
+		p.Send(*jsonMsg)
+	}
+}
+
+// sendGameState sends a state meant for p alone in p's encoding
+func sendGameState(p *Player, state protocol.GameStatePayload) {
+	if p.Encoding == protocol.EncodingBinary {
+		p.SendBinary(protocol.EncodeGameState(&state))
+		return
+	}
+	msg, err := protocol.NewMessage(protocol.MsgGameState, state)
+	if err != nil {
+		return
+	}
+	p.Send(msg)
+}
+
+// withoutHidden returns a copy of state that leaves out the hidden units
+// and where the hidden projectiles are headed
+func withoutHidden(state protocol.GameStatePayload, h *hiddenSet) protocol.GameStatePayload {
+	units := make([]protocol.UnitState, 0, len(state.Units))
+	for _, u := range state.Units {
+		if !h.units[u.ID] {
+			units = append(units, u)
+		}
+	}
+	state.Units = units
+	if len(h.projectiles) > 0 {
+		projectiles := make([]protocol.ProjectileState, len(state.Projectiles))
+		for i, p := range state.Projectiles {
+			if h.projectiles[p.ID] {
+				p.TargetX, p.TargetY = 0, 0
+			}
+			projectiles[i] = p
+		}
+		state.Projectiles = projectiles
+	}
+	return state
+}
+
 // GetPlayerSlot returns the slot number for a player
 func (l *Lobby) GetPlayerSlot(playerID string) int {
 	l.mu.RLock()
@@ -239,11 +416,11 @@ func (l *Lobby) GetPlayerSlot(playerID string) int {
 }
 
 // ToLobbyInfo converts the lobby to a LobbyInfo for sending to clients
-func (l *Lobby) ToLobbyInfo() LobbyInfo {
+func (l *Lobby) ToLobbyInfo() protocol.LobbyInfo {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	players := make([]PlayerInfo, 0, len(l.Players))
+	players := make([]protocol.PlayerInfo, 0, len(l.Players))
 	for _, p := range l.Players {
 		players = append(players, p.ToPlayerInfo())
 	}
@@ -253,7 +430,7 @@ func (l *Lobby) ToLobbyInfo() LobbyInfo {
 		hostName = host.GetName()
 	}
 
-	return LobbyInfo{
+	return protocol.LobbyInfo{
 		ID:         l.ID,
 		Name:       l.Name,
 		HostID:     l.HostID,
@@ -359,6 +536,72 @@ func (m *LobbyManager) LeaveLobby(playerID string) (*Lobby, error) {
 	return lobby, nil
 }
 
+// FindSeat looks up the player ID behind a session token in any running match
+func (m *LobbyManager) FindSeat(token string) (playerID string, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, lobby := range m.lobbies {
+		if st, ok := lobby.seatFor(token); ok {
+			return st.PlayerID, true
+		}
+	}
+	return "", false
+}
+
+// RejoinLobby returns a reconnected player to the match they have a seat in
+func (m *LobbyManager) RejoinLobby(player *Player) (*Lobby, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, inLobby := m.playerMap[player.ID]; inLobby {
+		return nil, errors.New("player is already in a lobby")
+	}
+
+	for id, lobby := range m.lobbies {
+		if _, ok := lobby.seatFor(player.SessionToken); !ok {
+			continue
+		}
+		if err := lobby.Rejoin(player); err != nil {
+			return nil, err
+		}
+		m.playerMap[player.ID] = id
+		return lobby, nil
+	}
+
+	return nil, errors.New("no match to rejoin")
+}
+
+// Snapshots captures every running match
+func (m *LobbyManager) Snapshots() []*matchSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshots := make([]*matchSnapshot, 0, len(m.lobbies))
+	for _, lobby := range m.lobbies {
+		if snap := lobby.Snapshot(); snap != nil {
+			snapshots = append(snapshots, snap)
+		}
+	}
+	return snapshots
+}
+
+// Restore brings back matches from snapshots and returns how many were restored
+func (m *LobbyManager) Restore(snapshots []*matchSnapshot) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restored := 0
+	for _, snap := range snapshots {
+		if _, exists := m.lobbies[snap.LobbyID]; exists {
+			continue
+		}
+		m.lobbies[snap.LobbyID] = restoreLobby(snap)
+		restored++
+	}
+	return restored
+}
+
 // GetLobby returns a lobby by ID
 func (m *LobbyManager) GetLobby(lobbyID string) (*Lobby, bool) {
 	m.mu.RLock()
@@ -383,11 +626,11 @@ func (m *LobbyManager) GetPlayerLobby(playerID string) (*Lobby, bool) {
 }
 
 // ListLobbies returns all lobbies that are waiting for players
-func (m *LobbyManager) ListLobbies() []LobbyInfo {
+func (m *LobbyManager) ListLobbies() []protocol.LobbyInfo {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	lobbies := make([]LobbyInfo, 0)
+	lobbies := make([]protocol.LobbyInfo, 0)
 	for _, lobby := range m.lobbies {
 		if lobby.State == LobbyWaiting {
 			lobbies = append(lobbies, lobby.ToLobbyInfo())
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/bklimczak/tanks/engine/protocol"
+)
+
+const (
+	// MaxPausesPerPlayer is how many times each player may pause a match
+	MaxPausesPerPlayer = 3
+
+	// ResumeCountdown is the warning given before a paused match resumes
+	ResumeCountdown = 3 * time.Second
+
+	// MaxPauseDuration is how long a pause lasts before the countdown starts on its own
+	MaxPauseDuration = 2 * time.Minute
+
+	// pauseHeartbeatTicks is how often, in server ticks, state is broadcast while paused
+	pauseHeartbeatTicks = 15
+
+	// serverSlot marks a pause held by the server itself, e.g. after a restore
+	serverSlot = -1
+)
+
+// pauseState tracks the current pause of a simulation. Durations are
+// counted in server ticks so the pause timer follows the game loop.
+type pauseState struct {
+	paused      bool
+	bySlot      int
+	ticks       int          // Ticks spent in the current pause
+	countdown   int          // Ticks left until resume, 0 while waiting for votes
+	resumeVotes map[int]bool // Slot -> voted to resume
+	remaining   map[int]int  // Slot -> pauses left
+}
+
+func newPauseState() *pauseState {
+	return &pauseState{
+		resumeVotes: make(map[int]bool),
+		remaining:   make(map[int]int),
+	}
+}
+
+// request pauses the match on behalf of slot. A pause during a resume
+// countdown cancels the countdown and counts as a new pause.
+func (p *pauseState) request(slot int) error {
+	if p.paused && p.countdown == 0 {
+		return errors.New("game is already paused")
+	}
+	if p.remaining[slot] <= 0 {
+		return errors.New("no pauses left")
+	}
+	p.remaining[slot]--
+	p.begin(slot)
+	return nil
+}
+
+// hold pauses the match on behalf of the server, without using up any
+// player's pauses
+func (p *pauseState) hold() {
+	p.begin(serverSlot)
+}
+
+func (p *pauseState) begin(slot int) {
+	p.paused = true
+	p.bySlot = slot
+	p.ticks = 0
+	p.countdown = 0
+	p.resumeVotes = make(map[int]bool)
+}
+
+// voteResume records a resume vote from slot. The countdown starts when
+// the player who paused votes or a majority of alive players agree.
+func (p *pauseState) voteResume(slot int, alive map[int]bool) error {
+	if !p.paused {
+		return errors.New("game is not paused")
+	}
+	if p.countdown > 0 {
+		return nil
+	}
+	p.resumeVotes[slot] = true
+
+	votes, voters := 0, 0
+	for s, isAlive := range alive {
+		if !isAlive {
+			continue
+		}
+		voters++
+		if p.resumeVotes[s] {
+			votes++
+		}
+	}
+	if slot == p.bySlot || votes*2 > voters {
+		p.startCountdown()
+	}
+	return nil
+}
+
+func (p *pauseState) startCountdown() {
+	p.countdown = int(ResumeCountdown / TickDuration)
+}
+
+// tick advances the pause timers by one server tick and reports whether
+// the simulation should stay paused
+func (p *pauseState) tick() bool {
+	if !p.paused {
+		return false
+	}
+	p.ticks++
+	if p.countdown == 0 && time.Duration(p.ticks)*TickDuration >= MaxPauseDuration {
+		p.startCountdown()
+	}
+	if p.countdown > 0 {
+		p.countdown--
+		if p.countdown == 0 {
+			p.paused = false
+			return false
+		}
+	}
+	return true
+}
+
+// toNet converts the pause to its wire form, or nil when not paused
+func (p *pauseState) toNet(names map[int]string) *protocol.PauseState {
+	if !p.paused {
+		return nil
+	}
+	votes := make([]int, 0, len(p.resumeVotes))
+	for slot := range p.resumeVotes {
+		votes = append(votes, slot)
+	}
+	sort.Ints(votes)
+	byName := names[p.bySlot]
+	if p.bySlot == serverSlot {
+		byName = "server"
+	}
+	return &protocol.PauseState{
+		BySlot:      p.bySlot,
+		ByName:      byName,
+		Elapsed:     (time.Duration(p.ticks) * TickDuration).Seconds(),
+		ResumeIn:    (time.Duration(p.countdown) * TickDuration).Seconds(),
+		ResumeVotes: votes,
+	}
+}
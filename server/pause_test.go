@@ -0,0 +1,139 @@
+package server
+
+import "testing"
+
+// pausedBy returns a pause held by slot, with every slot's pauses unused
+func pausedBy(slot int) *pauseState {
+	p := newPauseState()
+	for s := 0; s < 4; s++ {
+		p.remaining[s] = MaxPausesPerPlayer
+	}
+	p.begin(slot)
+	return p
+}
+
+func TestPauseRequest(t *testing.T) {
+	countdown := int(ResumeCountdown / TickDuration)
+
+	tests := []struct {
+		name          string
+		state         func() *pauseState
+		slot          int
+		wantErr       bool
+		wantRemaining int
+	}{
+		{"first pause", func() *pauseState {
+			p := newPauseState()
+			p.remaining[1] = MaxPausesPerPlayer
+			return p
+		}, 1, false, MaxPausesPerPlayer - 1},
+		{"already paused", func() *pauseState { return pausedBy(0) }, 1, true, MaxPausesPerPlayer},
+		{"no pauses left", func() *pauseState { return newPauseState() }, 1, true, 0},
+		{"during the resume countdown", func() *pauseState {
+			p := pausedBy(0)
+			p.countdown = countdown
+			return p
+		}, 1, false, MaxPausesPerPlayer - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.state()
+			err := p.request(tt.slot)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("request error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := p.remaining[tt.slot]; got != tt.wantRemaining {
+				t.Errorf("pauses left = %d, want %d", got, tt.wantRemaining)
+			}
+			if !tt.wantErr && (!p.paused || p.bySlot != tt.slot || p.countdown != 0) {
+				t.Errorf("paused %v by %d, countdown %d; want a fresh pause by %d", p.paused, p.bySlot, p.countdown, tt.slot)
+			}
+		})
+	}
+}
+
+func TestPauseVoteResume(t *testing.T) {
+	allAlive := map[int]bool{0: true, 1: true, 2: true}
+
+	tests := []struct {
+		name          string
+		paused        bool
+		alive         map[int]bool
+		votes         []int
+		wantErr       bool
+		wantCountdown bool
+	}{
+		{"not paused", false, allAlive, []int{1}, true, false},
+		{"the pausing player resumes", true, allAlive, []int{0}, false, true},
+		{"one vote of three", true, allAlive, []int{1}, false, false},
+		{"majority", true, allAlive, []int{1, 2}, false, true},
+		{"eliminated players do not count", true, map[int]bool{0: true, 1: true, 2: true, 3: false}, []int{3, 1}, false, false},
+		{"majority of the alive", true, map[int]bool{0: true, 1: true, 2: false}, []int{1}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPauseState()
+			if tt.paused {
+				p = pausedBy(0)
+			}
+			var err error
+			for _, slot := range tt.votes {
+				err = p.voteResume(slot, tt.alive)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("vote error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := p.countdown > 0; got != tt.wantCountdown {
+				t.Errorf("countdown started = %v, want %v", got, tt.wantCountdown)
+			}
+		})
+	}
+}
+
+func TestPauseTick(t *testing.T) {
+	countdown := int(ResumeCountdown / TickDuration)
+	// Ticks until the pause reaches its longest, rounded up
+	maxTicks := int((MaxPauseDuration + TickDuration - 1) / TickDuration)
+
+	tests := []struct {
+		name          string
+		state         func() *pauseState
+		wantPaused    bool
+		wantCountdown int
+	}{
+		{"not paused", newPauseState, false, 0},
+		{"waiting for votes", func() *pauseState { return pausedBy(0) }, true, 0},
+		{"counting down", func() *pauseState {
+			p := pausedBy(0)
+			p.countdown = 5
+			return p
+		}, true, 4},
+		{"countdown runs out", func() *pauseState {
+			p := pausedBy(0)
+			p.countdown = 1
+			return p
+		}, false, 0},
+		{"pause runs too long", func() *pauseState {
+			p := pausedBy(0)
+			p.ticks = maxTicks - 1
+			return p
+		}, true, countdown - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.state()
+			if got := p.tick(); got != tt.wantPaused {
+				t.Errorf("tick = %v, want %v", got, tt.wantPaused)
+			}
+			if p.paused != tt.wantPaused {
+				t.Errorf("paused = %v, want %v", p.paused, tt.wantPaused)
+			}
+			if p.countdown != tt.wantCountdown {
+				t.Errorf("countdown = %d, want %d", p.countdown, tt.wantCountdown)
+			}
+		})
+	}
+}
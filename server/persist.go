@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bklimczak/tanks/engine/save"
+	"gopkg.in/yaml.v3"
+)
+
+// MatchSnapshotVersion is bumped when the snapshot layout changes
+const MatchSnapshotVersion = 1
+
+// matchSnapshot is a running match as written to disk
+type matchSnapshot struct {
+	Version    int              `yaml:"version"`
+	Timestamp  time.Time        `yaml:"timestamp"`
+	LobbyID    string           `yaml:"lobby_id"`
+	LobbyName  string           `yaml:"lobby_name"`
+	HostID     string           `yaml:"host_id"`
+	MaxPlayers int              `yaml:"max_players"`
+	Players    []playerSnapshot `yaml:"players"`
+	World      save.WorldState  `yaml:"world"`
+}
+
+// playerSnapshot is one seat of a persisted match
+type playerSnapshot struct {
+	PlayerID     string              `yaml:"player_id"`
+	SessionToken string              `yaml:"session_token"`
+	Name         string              `yaml:"name"`
+	Slot         int                 `yaml:"slot"`
+	Alive        bool                `yaml:"alive"`
+	PausesLeft   int                 `yaml:"pauses_left"`
+	Resources    save.ResourcesState `yaml:"resources"`
+}
+
+// MatchStore keeps one snapshot file per running match in a directory
+type MatchStore struct {
+	dir string
+}
+
+// NewMatchStore opens dir for match snapshots, creating it if needed
+func NewMatchStore(dir string) (*MatchStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &MatchStore{dir: dir}, nil
+}
+
+func (m *MatchStore) filename(lobbyID string) string {
+	return filepath.Join(m.dir, lobbyID+".yaml")
+}
+
+// Sync writes every snapshot and removes files of matches that are no
+// longer running
+func (m *MatchStore) Sync(snapshots []*matchSnapshot) error {
+	keep := make(map[string]bool, len(snapshots))
+	for _, snap := range snapshots {
+		if err := m.write(snap); err != nil {
+			return err
+		}
+		keep[m.filename(snap.LobbyID)] = true
+	}
+
+	files, err := filepath.Glob(filepath.Join(m.dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !keep[file] {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove stale snapshot: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// write stores a snapshot through a temporary file so a crash mid-write
+// never leaves a truncated snapshot behind
+func (m *MatchStore) write(snap *matchSnapshot) error {
+	data, err := yaml.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to serialize match %s: %w", snap.LobbyID, err)
+	}
+
+	filename := m.filename(snap.LobbyID)
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write match %s: %w", snap.LobbyID, err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return fmt.Errorf("failed to write match %s: %w", snap.LobbyID, err)
+	}
+	return nil
+}
+
+// Load reads every stored snapshot. Unreadable files are reported and skipped.
+func (m *MatchStore) Load() ([]*matchSnapshot, []error) {
+	files, err := filepath.Glob(filepath.Join(m.dir, "*.yaml"))
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var snapshots []*matchSnapshot
+	var errs []error
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", file, err))
+			continue
+		}
+
+		var snap matchSnapshot
+		if err := yaml.Unmarshal(data, &snap); err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse %s: %w", file, err))
+			continue
+		}
+		if snap.Version > MatchSnapshotVersion {
+			errs = append(errs, fmt.Errorf("%s: snapshot version %d is newer than supported version %d", file, snap.Version, MatchSnapshotVersion))
+			continue
+		}
+		if snap.LobbyID == "" || strings.ContainsAny(snap.LobbyID, `/\`) {
+			errs = append(errs, fmt.Errorf("%s: invalid lobby ID %q", file, snap.LobbyID))
+			continue
+		}
+
+		snapshots = append(snapshots, &snap)
+	}
+	return snapshots, errs
+}
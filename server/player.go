@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bklimczak/tanks/engine/protocol"
 	"github.com/gorilla/websocket"
 )
 
@@ -17,15 +18,26 @@ type Player struct {
 	Slot      int // Player slot 0-3 in game (assigned when game starts)
 	Ready     bool
 	Connected bool
-	Alive     bool // In-game status
+	Alive     bool   // In-game status
+	Encoding  string // Game state encoding negotiated in the handshake
 
-	sendChan  chan Message
+	// SessionToken is handed out in Welcome; presenting it on a later
+	// connection rejoins the match this player has a seat in
+	SessionToken string
+
+	sendChan  chan outgoing
 	closeChan chan struct{}
 	closeOnce sync.Once
 
 	mu sync.RWMutex
 }
 
+// outgoing is a queued frame: a JSON message, or a prebuilt binary frame
+type outgoing struct {
+	msg    protocol.Message
+	binary []byte
+}
+
 // NewPlayer creates a new player session
 func NewPlayer(id string, conn *websocket.Conn) *Player {
 	p := &Player{
@@ -34,7 +46,8 @@ func NewPlayer(id string, conn *websocket.Conn) *Player {
 		Conn:      conn,
 		Connected: true,
 		Alive:     true,
-		sendChan:  make(chan Message, 64),
+		Encoding:  protocol.EncodingJSON,
+		sendChan:  make(chan outgoing, 64),
 		closeChan: make(chan struct{}),
 	}
 
@@ -74,9 +87,19 @@ func (p *Player) IsReady() bool {
 }
 
 // Send queues a message to be sent to the player
-func (p *Player) Send(msg Message) error {
+func (p *Player) Send(msg protocol.Message) error {
+	return p.enqueue(outgoing{msg: msg})
+}
+
+// SendBinary queues a binary frame to be sent to the player
+func (p *Player) SendBinary(frame []byte) error {
+	return p.enqueue(outgoing{binary: frame})
+}
+
+// enqueue hands a frame to the write pump without blocking
+func (p *Player) enqueue(out outgoing) error {
 	select {
-	case p.sendChan <- msg:
+	case p.sendChan <- out:
 		return nil
 	case <-p.closeChan:
 		return websocket.ErrCloseSent
@@ -88,8 +111,8 @@ func (p *Player) Send(msg Message) error {
 }
 
 // SendPayload sends a message with the given type and payload
-func (p *Player) SendPayload(msgType MessageType, payload interface{}) error {
-	msg, err := NewMessage(msgType, payload)
+func (p *Player) SendPayload(msgType protocol.MessageType, payload interface{}) error {
+	msg, err := protocol.NewMessage(msgType, payload)
 	if err != nil {
 		return err
 	}
@@ -98,7 +121,7 @@ func (p *Player) SendPayload(msgType MessageType, payload interface{}) error {
 
 // SendError sends an error message to the player
 func (p *Player) SendError(message string) error {
-	return p.SendPayload(MsgError, ErrorPayload{Message: message})
+	return p.SendPayload(protocol.MsgError, protocol.ErrorPayload{Message: message})
 }
 
 // Close closes the player's connection
@@ -131,7 +154,7 @@ func (p *Player) writePump() {
 
 	for {
 		select {
-		case msg, ok := <-p.sendChan:
+		case out, ok := <-p.sendChan:
 			if !ok {
 				p.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
@@ -139,13 +162,19 @@ func (p *Player) writePump() {
 
 			p.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 
-			data, err := json.Marshal(msg)
-			if err != nil {
-				log.Printf("Error marshaling message: %v", err)
-				continue
+			frameType := websocket.BinaryMessage
+			data := out.binary
+			if data == nil {
+				var err error
+				data, err = json.Marshal(out.msg)
+				if err != nil {
+					log.Printf("Error marshaling message: %v", err)
+					continue
+				}
+				frameType = websocket.TextMessage
 			}
 
-			if err := p.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			if err := p.Conn.WriteMessage(frameType, data); err != nil {
 				log.Printf("Error writing to WebSocket: %v", err)
 				return
 			}
@@ -163,25 +192,25 @@ func (p *Player) writePump() {
 }
 
 // ReadMessage reads the next message from the player's WebSocket
-func (p *Player) ReadMessage() (Message, error) {
+func (p *Player) ReadMessage() (protocol.Message, error) {
 	_, data, err := p.Conn.ReadMessage()
 	if err != nil {
-		return Message{}, err
+		return protocol.Message{}, err
 	}
 
-	var msg Message
+	var msg protocol.Message
 	if err := json.Unmarshal(data, &msg); err != nil {
-		return Message{}, err
+		return protocol.Message{}, err
 	}
 
 	return msg, nil
 }
 
 // ToPlayerInfo converts the player to a PlayerInfo for sending to clients
-func (p *Player) ToPlayerInfo() PlayerInfo {
+func (p *Player) ToPlayerInfo() protocol.PlayerInfo {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return PlayerInfo{
+	return protocol.PlayerInfo{
 		ID:      p.ID,
 		Name:    p.Name,
 		Ready:   p.Ready,
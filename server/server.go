@@ -4,14 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/bklimczak/tanks/engine/protocol"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// handshakeTimeout bounds how long a new connection may take to send Hello
+const handshakeTimeout = 10 * time.Second
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for development
@@ -26,6 +31,11 @@ type Server struct {
 	players      map[string]*Player // Connection ID -> Player
 	httpServer   *http.Server
 
+	// Match persistence, nil when disabled
+	store       *MatchStore
+	persistStop chan struct{}
+	persistDone chan struct{}
+
 	mu sync.RWMutex
 }
 
@@ -45,23 +55,133 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create player
+	hello, err := s.handshake(conn)
+	if err != nil {
+		log.Printf("Handshake rejected for %s: %v", r.RemoteAddr, err)
+		conn.Close()
+		return
+	}
+
+	// Create player, reusing the identity of a seat the client presents a token for
 	playerID := uuid.New().String()[:8]
+	token := uuid.New().String()
+	rejoining := false
+	if id, ok := s.lobbyManager.FindSeat(hello.SessionToken); ok && !s.isConnected(id) {
+		playerID, token = id, hello.SessionToken
+		rejoining = true
+	}
+
 	player := NewPlayer(playerID, conn)
+	player.SetName(hello.Name)
+	player.Encoding = protocol.NegotiateEncoding(hello.Encodings)
+	player.SessionToken = token
 
 	s.mu.Lock()
 	s.players[playerID] = player
 	s.mu.Unlock()
 
-	log.Printf("Player connected: %s", playerID)
+	log.Printf("Player connected: %s (protocol v%d, %s)", playerID, hello.Version, player.Encoding)
 
 	// Send welcome message
-	player.SendPayload(MsgWelcome, WelcomePayload{PlayerID: playerID})
+	player.SendPayload(protocol.MsgWelcome, protocol.WelcomePayload{
+		PlayerID:     playerID,
+		Version:      protocol.Version,
+		Encoding:     player.Encoding,
+		SessionToken: token,
+	})
+
+	if rejoining {
+		s.rejoinMatch(player)
+	}
 
 	// Handle messages
 	go s.handlePlayer(player)
 }
 
+// isConnected reports whether a player ID has a live connection
+func (s *Server) isConnected(playerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.players[playerID]
+	return ok
+}
+
+// rejoinMatch returns a reconnected player to their slot and tells them
+// the game is on
+func (s *Server) rejoinMatch(player *Player) {
+	lobby, err := s.lobbyManager.RejoinLobby(player)
+	if err != nil {
+		log.Printf("Player %s could not rejoin: %v", player.ID, err)
+		return
+	}
+
+	log.Printf("Player %s rejoined lobby %s in slot %d", player.ID, lobby.ID, player.Slot)
+
+	lobbyInfo := lobby.ToLobbyInfo()
+	player.SendPayload(protocol.MsgGameStarting, protocol.GameStartingPayload{
+		Lobby:    lobbyInfo,
+		YourSlot: player.Slot,
+	})
+	lobby.BroadcastPayload(protocol.MsgLobbyUpdate, protocol.LobbyUpdatePayload{Lobby: lobbyInfo})
+}
+
+// handshake waits for the client's Hello and rejects it with a Welcome
+// carrying the reason when the protocol versions do not match
+func (s *Server) handshake(conn *websocket.Conn) (protocol.HelloPayload, error) {
+	var hello protocol.HelloPayload
+
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		// A client that never says Hello is too old to know the handshake;
+		// tell it which version to get before hanging up
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			err = protocol.CheckVersion(0)
+			rejectHandshake(conn, err)
+		}
+		return hello, err
+	}
+
+	var msg protocol.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return hello, err
+	}
+
+	// Clients that predate the handshake open with set_name; treat them as version 0
+	if msg.Type == protocol.MsgHello {
+		if err := msg.Decode(&hello); err != nil {
+			return hello, err
+		}
+	}
+
+	if err := protocol.CheckVersion(hello.Version); err != nil {
+		rejectHandshake(conn, err)
+		return hello, err
+	}
+
+	return hello, nil
+}
+
+// rejectHandshake sends a Welcome carrying the reason the connection is
+// refused, then closes it
+func rejectHandshake(conn *websocket.Conn, reason error) {
+	reject, err := protocol.NewMessage(protocol.MsgWelcome, protocol.WelcomePayload{
+		Version: protocol.Version,
+		Reason:  reason.Error(),
+	})
+	if err != nil {
+		return
+	}
+	if data, err := json.Marshal(reject); err == nil {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		conn.WriteMessage(websocket.TextMessage, data)
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "protocol version mismatch"))
+	}
+}
+
 // handlePlayer handles messages from a player
 func (s *Server) handlePlayer(player *Player) {
 	defer func() {
@@ -82,10 +202,10 @@ func (s *Server) handlePlayer(player *Player) {
 }
 
 // handleMessage handles a single message from a player
-func (s *Server) handleMessage(player *Player, msg Message) {
+func (s *Server) handleMessage(player *Player, msg protocol.Message) {
 	switch msg.Type {
-	case MsgSetName:
-		var payload SetNamePayload
+	case protocol.MsgSetName:
+		var payload protocol.SetNamePayload
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 			player.SendError("Invalid payload")
 			return
@@ -93,12 +213,12 @@ func (s *Server) handleMessage(player *Player, msg Message) {
 		player.SetName(payload.Name)
 		log.Printf("Player %s set name to: %s", player.ID, payload.Name)
 
-	case MsgListLobbies:
+	case protocol.MsgListLobbies:
 		lobbies := s.lobbyManager.ListLobbies()
-		player.SendPayload(MsgLobbyList, LobbyListPayload{Lobbies: lobbies})
+		player.SendPayload(protocol.MsgLobbyList, protocol.LobbyListPayload{Lobbies: lobbies})
 
-	case MsgCreateLobby:
-		var payload CreateLobbyPayload
+	case protocol.MsgCreateLobby:
+		var payload protocol.CreateLobbyPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 			player.SendError("Invalid payload")
 			return
@@ -111,10 +231,10 @@ func (s *Server) handleMessage(player *Player, msg Message) {
 		}
 
 		log.Printf("Player %s created lobby: %s", player.ID, lobby.ID)
-		player.SendPayload(MsgLobbyCreated, LobbyCreatedPayload{Lobby: lobby.ToLobbyInfo()})
+		player.SendPayload(protocol.MsgLobbyCreated, protocol.LobbyCreatedPayload{Lobby: lobby.ToLobbyInfo()})
 
-	case MsgJoinLobby:
-		var payload JoinLobbyPayload
+	case protocol.MsgJoinLobby:
+		var payload protocol.JoinLobbyPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 			player.SendError("Invalid payload")
 			return
@@ -127,12 +247,12 @@ func (s *Server) handleMessage(player *Player, msg Message) {
 		}
 
 		log.Printf("Player %s joined lobby: %s", player.ID, lobby.ID)
-		player.SendPayload(MsgLobbyJoined, LobbyJoinedPayload{Lobby: lobby.ToLobbyInfo()})
+		player.SendPayload(protocol.MsgLobbyJoined, protocol.LobbyJoinedPayload{Lobby: lobby.ToLobbyInfo()})
 
 		// Notify other players
-		lobby.BroadcastPayload(MsgLobbyUpdate, LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
+		lobby.BroadcastPayload(protocol.MsgLobbyUpdate, protocol.LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
 
-	case MsgLeaveLobby:
+	case protocol.MsgLeaveLobby:
 		lobby, err := s.lobbyManager.LeaveLobby(player.ID)
 		if err != nil {
 			player.SendError(err.Error())
@@ -140,15 +260,15 @@ func (s *Server) handleMessage(player *Player, msg Message) {
 		}
 
 		log.Printf("Player %s left lobby", player.ID)
-		player.SendPayload(MsgLobbyLeft, nil)
+		player.SendPayload(protocol.MsgLobbyLeft, nil)
 
 		// Notify remaining players
 		if lobby != nil {
-			lobby.BroadcastPayload(MsgLobbyUpdate, LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
+			lobby.BroadcastPayload(protocol.MsgLobbyUpdate, protocol.LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
 		}
 
-	case MsgSetReady:
-		var payload SetReadyPayload
+	case protocol.MsgSetReady:
+		var payload protocol.SetReadyPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 			player.SendError("Invalid payload")
 			return
@@ -168,9 +288,9 @@ func (s *Server) handleMessage(player *Player, msg Message) {
 		log.Printf("Player %s set ready: %v", player.ID, payload.Ready)
 
 		// Notify all players
-		lobby.BroadcastPayload(MsgLobbyUpdate, LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
+		lobby.BroadcastPayload(protocol.MsgLobbyUpdate, protocol.LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
 
-	case MsgStartGame:
+	case protocol.MsgStartGame:
 		lobby, ok := s.lobbyManager.GetPlayerLobby(player.ID)
 		if !ok {
 			player.SendError("Not in a lobby")
@@ -197,14 +317,14 @@ func (s *Server) handleMessage(player *Player, msg Message) {
 		// Notify all players with their slot
 		lobbyInfo := lobby.ToLobbyInfo()
 		for _, p := range lobby.Players {
-			p.SendPayload(MsgGameStarting, GameStartingPayload{
+			p.SendPayload(protocol.MsgGameStarting, protocol.GameStartingPayload{
 				Lobby:    lobbyInfo,
 				YourSlot: p.Slot,
 			})
 		}
 
-	case MsgGameCommand:
-		var payload GameCommandPayload
+	case protocol.MsgGameCommand:
+		var payload protocol.GameCommandPayload
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 			player.SendError("Invalid payload")
 			return
@@ -216,7 +336,31 @@ func (s *Server) handleMessage(player *Player, msg Message) {
 		}
 
 		// Enqueue command for processing
-		lobby.Game.EnqueueCommand(player.ID, player.Slot, payload.Command)
+		if err := lobby.Game.EnqueueCommand(player.ID, player.Slot, payload.Command); err != nil {
+			player.SendError(err.Error())
+		}
+
+	case protocol.MsgPauseGame:
+		lobby, ok := s.lobbyManager.GetPlayerLobby(player.ID)
+		if !ok || lobby.State != LobbyPlaying || lobby.Game == nil {
+			player.SendError("Not in a running game")
+			return
+		}
+
+		if err := lobby.Game.RequestPause(player.Slot); err != nil {
+			player.SendError(err.Error())
+		}
+
+	case protocol.MsgResumeGame:
+		lobby, ok := s.lobbyManager.GetPlayerLobby(player.ID)
+		if !ok || lobby.State != LobbyPlaying || lobby.Game == nil {
+			player.SendError("Not in a running game")
+			return
+		}
+
+		if err := lobby.Game.RequestResume(player.Slot); err != nil {
+			player.SendError(err.Error())
+		}
 
 	default:
 		log.Printf("Unknown message type: %s", msg.Type)
@@ -232,7 +376,7 @@ func (s *Server) handleDisconnect(player *Player) {
 	// Remove from lobby
 	lobby, _ := s.lobbyManager.LeaveLobby(player.ID)
 	if lobby != nil {
-		lobby.BroadcastPayload(MsgLobbyUpdate, LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
+		lobby.BroadcastPayload(protocol.MsgLobbyUpdate, protocol.LobbyUpdatePayload{Lobby: lobby.ToLobbyInfo()})
 	}
 
 	player.Close()
@@ -256,7 +400,54 @@ func (s *Server) HandleLobbies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	lobbies := s.lobbyManager.ListLobbies()
-	json.NewEncoder(w).Encode(LobbyListPayload{Lobbies: lobbies})
+	json.NewEncoder(w).Encode(protocol.LobbyListPayload{Lobbies: lobbies})
+}
+
+// EnablePersistence restores the matches snapshotted in dir and keeps
+// snapshotting running matches there every interval and on shutdown
+func (s *Server) EnablePersistence(dir string, interval time.Duration) error {
+	store, err := NewMatchStore(dir)
+	if err != nil {
+		return err
+	}
+
+	snapshots, errs := store.Load()
+	for _, err := range errs {
+		log.Printf("Skipping match snapshot: %v", err)
+	}
+	restored := s.lobbyManager.Restore(snapshots)
+	log.Printf("Restored %d running matches from %s", restored, dir)
+
+	s.store = store
+	s.persistStop = make(chan struct{})
+	s.persistDone = make(chan struct{})
+	go s.persistLoop(interval)
+
+	return nil
+}
+
+// persistLoop snapshots running matches until persistStop is closed
+func (s *Server) persistLoop(interval time.Duration) {
+	defer close(s.persistDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.persistStop:
+			return
+		case <-ticker.C:
+			s.saveMatches()
+		}
+	}
+}
+
+// saveMatches writes a snapshot of every running match
+func (s *Server) saveMatches() {
+	if err := s.store.Sync(s.lobbyManager.Snapshots()); err != nil {
+		log.Printf("Failed to snapshot matches: %v", err)
+	}
 }
 
 // Start starts the server on the given address
@@ -281,6 +472,14 @@ func (s *Server) Start(addr string) error {
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Server shutting down...")
 
+	// Snapshot running matches before players disconnect and their lobbies stop
+	if s.store != nil {
+		close(s.persistStop)
+		<-s.persistDone
+		s.saveMatches()
+		log.Println("Running matches saved")
+	}
+
 	// Close all player connections
 	s.mu.Lock()
 	for _, player := range s.players {
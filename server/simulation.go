@@ -2,19 +2,23 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/bklimczak/tanks/engine/collision"
 	"github.com/bklimczak/tanks/engine/entity"
 	emath "github.com/bklimczak/tanks/engine/math"
+	"github.com/bklimczak/tanks/engine/protocol"
 	"github.com/bklimczak/tanks/engine/resource"
+	"github.com/bklimczak/tanks/engine/save"
+	"github.com/bklimczak/tanks/engine/sim"
 	"github.com/bklimczak/tanks/engine/terrain"
 )
 
 const (
-	TickRate     = 1.0 / 60.0 // 60 FPS
+	TickRate     = sim.TickRate
 	TickDuration = time.Second / 60
 )
 
@@ -37,32 +41,22 @@ type PlayerSetup struct {
 type PlayerCommand struct {
 	PlayerID string
 	Slot     int
-	Command  GameCommand
+	Command  protocol.GameCommand
 }
 
 // Simulation runs the game logic on the server
 type Simulation struct {
-	units       []*entity.Unit
-	buildings   []*entity.Building
-	projectiles []*entity.Projectile
-	terrainMap  *terrain.Map
-	collision   *collision.System
+	world *sim.World
 
 	// Per-player state
-	playerResources map[int]*resource.Manager // Slot -> Resources
-	playerAlive     map[int]bool              // Slot -> Alive
-	playerIDs       map[int]string            // Slot -> PlayerID
-	playerNames     map[int]string            // Slot -> Name
-	numPlayers      int
-
-	// Entity ID generation
-	nextUnitID       uint64
-	nextBuildingID   uint64
-	nextProjectileID uint64
+	playerAlive map[int]bool   // Slot -> Alive
+	playerIDs   map[int]string // Slot -> PlayerID
+	playerNames map[int]string // Slot -> Name
+	numPlayers  int
 
 	// Simulation state
-	tick    uint64
 	running bool
+	pause   *pauseState
 
 	// Command queue
 	commandQueue chan PlayerCommand
@@ -72,6 +66,36 @@ type Simulation struct {
 
 // NewSimulation creates a new game simulation
 func NewSimulation(players []PlayerSetup) *Simulation {
+	slots := make([]int, 0, len(players))
+	for _, setup := range players {
+		slots = append(slots, setup.Slot)
+	}
+	s := newSimulation(newMatchTerrain(slots), len(players))
+
+	// Spawn each player's base
+	for _, setup := range players {
+		s.spawnPlayerBase(setup)
+	}
+
+	return s
+}
+
+// newSimulation creates a simulation with no players on the given terrain
+func newSimulation(terrainMap *terrain.Map, numPlayers int) *Simulation {
+	return &Simulation{
+		world:        sim.NewWorld(terrainMap),
+		playerAlive:  make(map[int]bool),
+		playerIDs:    make(map[int]string),
+		playerNames:  make(map[int]string),
+		numPlayers:   numPlayers,
+		pause:        newPauseState(),
+		commandQueue: make(chan PlayerCommand, 256),
+	}
+}
+
+// newMatchTerrain builds the match map. It is fully determined by the
+// occupied slots, so a restored match gets the same map back.
+func newMatchTerrain(slots []int) *terrain.Map {
 	// Create terrain (simple grass map for now)
 	terrainMap := terrain.NewMap(6400, 3600)
 	terrainMap.GenerateGrassOnly()
@@ -87,28 +111,18 @@ func NewSimulation(players []PlayerSetup) *Simulation {
 	terrainMap.PlaceMetalDeposit(centerX, centerY+50)
 	terrainMap.PlaceMetalDeposit(centerX, centerY-50)
 
-	s := &Simulation{
-		units:           make([]*entity.Unit, 0),
-		buildings:       make([]*entity.Building, 0),
-		projectiles:     make([]*entity.Projectile, 0),
-		terrainMap:      terrainMap,
-		collision:       collision.NewSystem(float64(terrainMap.PixelWidth), float64(terrainMap.PixelHeight)),
-		playerResources: make(map[int]*resource.Manager),
-		playerAlive:     make(map[int]bool),
-		playerIDs:       make(map[int]string),
-		playerNames:     make(map[int]string),
-		numPlayers:      len(players),
-		commandQueue:    make(chan PlayerCommand, 256),
-	}
-
-	s.collision.SetTerrain(terrainMap)
-
-	// Spawn each player's base
-	for _, setup := range players {
-		s.spawnPlayerBase(setup)
+	// Place metal deposits near each spawn
+	for _, slot := range slots {
+		spawn := SpawnPositions[slot]
+		metalX := spawn.X - 100
+		metalY := spawn.Y - 100
+		if metalX > 0 && metalY > 0 {
+			terrainMap.PlaceMetalDeposit(metalX, metalY)
+			terrainMap.PlaceMetalDeposit(metalX+50, metalY)
+		}
 	}
 
-	return s
+	return terrainMap
 }
 
 // spawnPlayerBase creates starting units and buildings for a player
@@ -121,6 +135,7 @@ func (s *Simulation) spawnPlayerBase(setup PlayerSetup) {
 	s.playerIDs[slot] = setup.PlayerID
 	s.playerNames[slot] = setup.Name
 	s.playerAlive[slot] = true
+	s.pause.remaining[slot] = MaxPausesPerPlayer
 
 	// Initialize resources
 	res := resource.NewManager()
@@ -128,51 +143,71 @@ func (s *Simulation) spawnPlayerBase(setup PlayerSetup) {
 	res.Get(resource.Metal).Capacity = 2000
 	res.Get(resource.Energy).Current = 100
 	res.Get(resource.Energy).Capacity = 200
-	s.playerResources[slot] = res
+	s.world.SetResources(faction, res)
 
 	// Spawn Command Nexus
 	nexusDef := entity.BuildingDefs[entity.BuildingCommandNexus]
-	nexus := entity.NewBuilding(s.nextBuildingID, spawn.X, spawn.Y, nexusDef)
-	nexus.Faction = faction
-	nexus.Completed = true
-	nexus.BuildProgress = 1.0
-	s.buildings = append(s.buildings, nexus)
-	s.nextBuildingID++
-	s.applyBuildingEffects(slot, nexusDef)
+	s.world.SpawnBuilding(nexusDef, spawn.X, spawn.Y, faction)
 
 	// Spawn 2 tanks
 	tankDef := entity.UnitDefs[entity.UnitTypeTank]
 	for i := 0; i < 2; i++ {
 		tx := spawn.X + float64(i)*50 - 25
 		ty := spawn.Y + nexusDef.Size + 40
-		tank := entity.NewUnitFromDef(s.nextUnitID, tx, ty, tankDef, faction)
-		s.units = append(s.units, tank)
-		s.nextUnitID++
+		s.world.SpawnUnit(tankDef, tx, ty, faction)
 	}
 
 	// Spawn 1 scout
 	scoutDef := entity.UnitDefs[entity.UnitTypeScout]
-	scout := entity.NewUnitFromDef(s.nextUnitID, spawn.X+80, spawn.Y+nexusDef.Size+40, scoutDef, faction)
-	s.units = append(s.units, scout)
-	s.nextUnitID++
+	s.world.SpawnUnit(scoutDef, spawn.X+80, spawn.Y+nexusDef.Size+40, faction)
 
 	// Spawn Solar Array for starting energy production
 	solarDef := entity.BuildingDefs[entity.BuildingSolarArray]
-	solar := entity.NewBuilding(s.nextBuildingID, spawn.X+nexusDef.Size+20, spawn.Y, solarDef)
-	solar.Faction = faction
-	solar.Completed = true
-	solar.BuildProgress = 1.0
-	s.buildings = append(s.buildings, solar)
-	s.nextBuildingID++
-	s.applyBuildingEffects(slot, solarDef)
-
-	// Place metal deposits near spawn
-	metalX := spawn.X - 100
-	metalY := spawn.Y - 100
-	if metalX > 0 && metalY > 0 {
-		s.terrainMap.PlaceMetalDeposit(metalX, metalY)
-		s.terrainMap.PlaceMetalDeposit(metalX+50, metalY)
+	s.world.SpawnBuilding(solarDef, spawn.X+nexusDef.Size+20, spawn.Y, faction)
+}
+
+// Snapshot captures the players and the world of the running match
+func (s *Simulation) Snapshot() ([]playerSnapshot, save.WorldState) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	players := make([]playerSnapshot, 0, s.numPlayers)
+	for slot := 0; slot < s.numPlayers; slot++ {
+		players = append(players, playerSnapshot{
+			PlayerID:   s.playerIDs[slot],
+			Name:       s.playerNames[slot],
+			Slot:       slot,
+			Alive:      s.playerAlive[slot],
+			PausesLeft: s.pause.remaining[slot],
+			Resources:  save.NewResourcesStateFromManager(s.world.Resources(slotToFaction(slot))),
+		})
+	}
+	return players, s.world.Snapshot()
+}
+
+// restoreSimulation rebuilds a match from a snapshot. It starts paused so
+// nothing happens until the players are back.
+func restoreSimulation(players []playerSnapshot, world *save.WorldState) *Simulation {
+	slots := make([]int, 0, len(players))
+	for _, p := range players {
+		slots = append(slots, p.Slot)
 	}
+	s := newSimulation(newMatchTerrain(slots), len(players))
+
+	for _, p := range players {
+		s.playerIDs[p.Slot] = p.PlayerID
+		s.playerNames[p.Slot] = p.Name
+		s.playerAlive[p.Slot] = p.Alive
+		s.pause.remaining[p.Slot] = p.PausesLeft
+
+		res := resource.NewManager()
+		p.Resources.ApplyToManager(res)
+		s.world.SetResources(slotToFaction(p.Slot), res)
+	}
+	s.world.Restore(world)
+	s.pause.hold()
+
+	return s
 }
 
 // slotToFaction converts a player slot to a faction
@@ -203,33 +238,6 @@ func factionToSlot(faction entity.Faction) int {
 	}
 }
 
-// applyBuildingEffects applies resource production/consumption effects
-func (s *Simulation) applyBuildingEffects(slot int, def *entity.BuildingDef) {
-	res := s.playerResources[slot]
-	if res == nil {
-		return
-	}
-
-	if def.MetalProduction > 0 {
-		res.AddProduction(resource.Metal, def.MetalProduction)
-	}
-	if def.EnergyProduction > 0 {
-		res.AddProduction(resource.Energy, def.EnergyProduction)
-	}
-	if def.MetalConsumption > 0 {
-		res.AddConsumption(resource.Metal, def.MetalConsumption)
-	}
-	if def.EnergyConsumption > 0 {
-		res.AddConsumption(resource.Energy, def.EnergyConsumption)
-	}
-	if def.MetalStorage > 0 {
-		res.AddCapacity(resource.Metal, def.MetalStorage)
-	}
-	if def.EnergyStorage > 0 {
-		res.AddCapacity(resource.Energy, def.EnergyStorage)
-	}
-}
-
 // Run starts the game simulation loop
 func (s *Simulation) Run(ctx context.Context, lobby *Lobby) {
 	s.mu.Lock()
@@ -253,29 +261,41 @@ func (s *Simulation) Run(ctx context.Context, lobby *Lobby) {
 		case <-ticker.C:
 			s.mu.Lock()
 
+			if s.pause.tick() {
+				// Orders queued just before the pause are dropped, not replayed on resume
+				s.discardCommands()
+				// The first paused tick is always sent so everyone sees the pause at once
+				heartbeat := s.pause.ticks%pauseHeartbeatTicks == 1
+				var state protocol.GameStatePayload
+				var hidden map[int]*hiddenSet
+				if heartbeat {
+					state, hidden = s.getGameState(), s.hidden()
+				}
+				s.mu.Unlock()
+
+				if heartbeat {
+					lobby.BroadcastGameState(state, hidden)
+				}
+				continue
+			}
+
 			// Process commands
 			s.processCommands()
 
 			// Update game state
-			s.updateResources()
-			s.updateUnits()
-			s.updateBuildings()
-			s.updateCombat()
-			s.updateProjectiles()
-			s.cleanupDead()
-
-			s.tick++
+			s.world.Update(sim.TickRate)
 
 			// Check victory conditions
 			finished, winnerSlot := s.checkVictory()
 
 			// Get game state
 			state := s.getGameState()
+			hidden := s.hidden()
 
 			s.mu.Unlock()
 
 			// Broadcast state to all players
-			lobby.BroadcastPayload(MsgGameState, state)
+			lobby.BroadcastGameState(state, hidden)
 
 			// Handle game end
 			if finished {
@@ -283,7 +303,7 @@ func (s *Simulation) Run(ctx context.Context, lobby *Lobby) {
 				if name, ok := s.playerNames[winnerSlot]; ok {
 					winnerName = name
 				}
-				lobby.BroadcastPayload(MsgGameEnd, GameEndPayload{
+				lobby.BroadcastPayload(protocol.MsgGameEnd, protocol.GameEndPayload{
 					WinnerSlot: winnerSlot,
 					WinnerName: winnerName,
 					Reason:     "last_standing",
@@ -295,13 +315,22 @@ func (s *Simulation) Run(ctx context.Context, lobby *Lobby) {
 	}
 }
 
-// EnqueueCommand adds a command to the processing queue
-func (s *Simulation) EnqueueCommand(playerID string, slot int, cmd GameCommand) {
+// EnqueueCommand adds a command to the processing queue. Orders given
+// while the game is paused are refused rather than kept for later.
+func (s *Simulation) EnqueueCommand(playerID string, slot int, cmd protocol.GameCommand) error {
+	s.mu.Lock()
+	paused := s.pause.paused
+	s.mu.Unlock()
+	if paused {
+		return errors.New("game is paused, order not carried out")
+	}
+
 	select {
 	case s.commandQueue <- PlayerCommand{PlayerID: playerID, Slot: slot, Command: cmd}:
 	default:
 		log.Printf("Command queue full, dropping command from player %s", playerID)
 	}
+	return nil
 }
 
 // processCommands processes all queued commands
@@ -316,391 +345,81 @@ func (s *Simulation) processCommands() {
 	}
 }
 
-// executeCommand executes a single player command
-func (s *Simulation) executeCommand(pc PlayerCommand) {
-	slot := pc.Slot
-	cmd := pc.Command
-	faction := slotToFaction(slot)
-
-	switch cmd.Type {
-	case CmdMove:
-		for _, unitID := range cmd.UnitIDs {
-			if unit := s.getUnit(unitID); unit != nil && unit.Faction == faction {
-				unit.SetTarget(emath.Vec2{X: cmd.TargetX, Y: cmd.TargetY})
-				unit.ClearAttackTarget()
-			}
-		}
-
-	case CmdAttack:
-		target := s.getUnit(cmd.TargetID)
-		targetBuilding := s.getBuilding(cmd.TargetID)
-
-		for _, unitID := range cmd.UnitIDs {
-			if unit := s.getUnit(unitID); unit != nil && unit.Faction == faction {
-				if target != nil && target.Faction != faction {
-					unit.SetAttackTarget(target)
-				} else if targetBuilding != nil && targetBuilding.Faction != faction {
-					unit.SetBuildingAttackTarget(targetBuilding)
-				}
-			}
-		}
-
-	case CmdStop:
-		for _, unitID := range cmd.UnitIDs {
-			if unit := s.getUnit(unitID); unit != nil && unit.Faction == faction {
-				unit.ClearTarget()
-				unit.ClearAttackTarget()
-			}
-		}
-
-	case CmdPlaceBuilding:
-		buildingType := entity.BuildingType(cmd.BuildingType)
-		def := entity.BuildingDefs[buildingType]
-		if def == nil {
-			return
-		}
-
-		pos := emath.Vec2{X: cmd.TargetX, Y: cmd.TargetY}
-		if s.canPlaceBuilding(pos, def) {
-			res := s.playerResources[slot]
-			if res != nil && res.CanAfford(def.Cost) {
-				building := entity.NewBuildingUnderConstruction(s.nextBuildingID, pos.X, pos.Y, def)
-				building.Faction = faction
-				s.buildings = append(s.buildings, building)
-				s.nextBuildingID++
-			}
-		}
-
-	case CmdProduceUnit:
-		building := s.getBuilding(cmd.BuildingID)
-		if building == nil || building.Faction != faction || !building.CanProduce() {
-			return
-		}
-
-		unitType := entity.UnitType(cmd.UnitType)
-		unitDef := entity.UnitDefs[unitType]
-		if unitDef == nil {
-			return
-		}
-
-		building.QueueProduction(unitDef)
-
-	case CmdCancelProduction:
-		building := s.getBuilding(cmd.BuildingID)
-		if building == nil || building.Faction != faction {
-			return
-		}
-
-		unitType := entity.UnitType(cmd.UnitType)
-		building.RemoveFromQueue(unitType, s.playerResources[slot])
-
-	case CmdSetRallyPoint:
-		building := s.getBuilding(cmd.BuildingID)
-		if building == nil || building.Faction != faction {
+// discardCommands empties the command queue without executing anything
+func (s *Simulation) discardCommands() {
+	for {
+		select {
+		case <-s.commandQueue:
+		default:
 			return
 		}
-
-		building.RallyPoint = emath.Vec2{X: cmd.TargetX, Y: cmd.TargetY}
-		building.HasRallyPoint = true
-	}
-}
-
-// getUnit finds a unit by ID
-func (s *Simulation) getUnit(id uint64) *entity.Unit {
-	for _, u := range s.units {
-		if u.ID == id && u.Active {
-			return u
-		}
-	}
-	return nil
-}
-
-// getBuilding finds a building by ID
-func (s *Simulation) getBuilding(id uint64) *entity.Building {
-	for _, b := range s.buildings {
-		if b.ID == id && b.Active {
-			return b
-		}
-	}
-	return nil
-}
-
-// canPlaceBuilding checks if a building can be placed at the given position
-func (s *Simulation) canPlaceBuilding(pos emath.Vec2, def *entity.BuildingDef) bool {
-	bounds := emath.NewRect(pos.X, pos.Y, def.Size, def.Size)
-
-	if !s.terrainMap.IsBuildable(bounds) {
-		return false
-	}
-
-	// Check for metal deposit requirement
-	if def.RequiresDeposit {
-		if !s.hasMetal(bounds) {
-			return false
-		}
-	}
-
-	// Check unit collisions
-	for _, u := range s.units {
-		if u.Active && bounds.Intersects(u.Bounds()) {
-			return false
-		}
-	}
-
-	// Check building collisions
-	for _, b := range s.buildings {
-		if b.Active && bounds.Intersects(b.Bounds()) {
-			return false
-		}
-	}
-
-	return true
-}
-
-// hasMetal checks if a location has metal deposits
-func (s *Simulation) hasMetal(bounds emath.Rect) bool {
-	startX, startY := s.terrainMap.GetTileCoords(bounds.Pos.X, bounds.Pos.Y)
-	endX, endY := s.terrainMap.GetTileCoords(bounds.Pos.X+bounds.Size.X, bounds.Pos.Y+bounds.Size.Y)
-
-	for y := startY; y <= endY; y++ {
-		for x := startX; x <= endX; x++ {
-			if x >= 0 && x < s.terrainMap.Width && y >= 0 && y < s.terrainMap.Height {
-				if s.terrainMap.Tiles[y][x].HasMetal {
-					return true
-				}
-			}
-		}
 	}
-	return false
 }
 
-// updateResources updates resource production for all players
-func (s *Simulation) updateResources() {
-	for slot, res := range s.playerResources {
-		if s.playerAlive[slot] {
-			res.Update(TickRate)
-		}
-	}
-}
-
-// updateUnits updates all unit positions and states
-func (s *Simulation) updateUnits() {
-	for _, u := range s.units {
-		if !u.Active || !u.HasTarget {
-			continue
-		}
-
-		desiredPos := u.Update()
-
-		// Gather obstacles
-		obstacles := make([]emath.Rect, 0, len(s.units)+len(s.buildings)-1)
-		for _, other := range s.units {
-			if other.ID != u.ID && other.Active {
-				obstacles = append(obstacles, other.Bounds())
-			}
-		}
-		for _, b := range s.buildings {
-			if b.Active {
-				obstacles = append(obstacles, b.Bounds())
-			}
-		}
+// RequestPause pauses the game on behalf of a player slot
+func (s *Simulation) RequestPause(slot int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		// Resolve movement
-		resolvedPos := s.collision.ResolveMovement(u.Bounds(), desiredPos, obstacles)
-		u.ApplyPosition(resolvedPos)
+	if !s.playerAlive[slot] {
+		return errors.New("eliminated players cannot pause")
 	}
-}
-
-// updateBuildings updates building construction and production
-func (s *Simulation) updateBuildings() {
-	for _, b := range s.buildings {
-		if !b.Active {
-			continue
-		}
-
-		slot := factionToSlot(b.Faction)
-		res := s.playerResources[slot]
-
-		// Construction
-		if !b.Completed {
-			if b.UpdateConstruction(TickRate, res) {
-				s.applyBuildingEffects(slot, b.Def)
-			}
-		}
-
-		// Production
-		if completedUnit := b.UpdateProduction(TickRate, res); completedUnit != nil {
-			spawnPos := b.GetSpawnPoint()
-			unit := entity.NewUnitFromDef(s.nextUnitID, spawnPos.X, spawnPos.Y, completedUnit, b.Faction)
-			s.units = append(s.units, unit)
-			s.nextUnitID++
-
-			if b.HasRallyPoint {
-				unit.SetTarget(b.RallyPoint)
-			}
-		}
+	if err := s.pause.request(slot); err != nil {
+		return err
 	}
+	log.Printf("Game paused by slot %d (%d pauses left)", slot, s.pause.remaining[slot])
+	return nil
 }
 
-// updateCombat handles combat between units
-func (s *Simulation) updateCombat() {
-	for _, u := range s.units {
-		if !u.Active || !u.CanAttack() {
-			continue
-		}
-
-		// Clear invalid targets
-		if u.AttackTarget != nil && !u.AttackTarget.Active {
-			u.AttackTarget = nil
-		}
-		if u.BuildingAttackTarget != nil && !u.BuildingAttackTarget.Active {
-			u.BuildingAttackTarget = nil
-		}
-
-		// Auto-acquire targets
-		if !u.HasAnyAttackTarget() {
-			var nearestEnemy *entity.Unit
-			var nearestBuilding *entity.Building
-			nearestUnitDist := u.Range + 1
-			nearestBuildingDist := u.Range + 1
-
-			for _, other := range s.units {
-				if other.Active && other.Faction != u.Faction {
-					dist := u.Center().Distance(other.Center())
-					if dist <= u.Range && dist < nearestUnitDist {
-						nearestUnitDist = dist
-						nearestEnemy = other
-					}
-				}
-			}
-
-			for _, b := range s.buildings {
-				if b.Active && b.Faction != u.Faction {
-					dist := u.Center().Distance(b.Center())
-					if dist <= u.Range && dist < nearestBuildingDist {
-						nearestBuildingDist = dist
-						nearestBuilding = b
-					}
-				}
-			}
-
-			if nearestEnemy != nil {
-				u.SetAttackTarget(nearestEnemy)
-			} else if nearestBuilding != nil {
-				u.SetBuildingAttackTarget(nearestBuilding)
-			}
-		}
-
-		// Fire projectile
-		if u.UpdateCombat(TickRate) {
-			if u.AttackTarget != nil && u.AttackTarget.Active {
-				projectile := entity.NewProjectile(s.nextProjectileID, u, u.AttackTarget)
-				s.projectiles = append(s.projectiles, projectile)
-				s.nextProjectileID++
-			} else if u.BuildingAttackTarget != nil && u.BuildingAttackTarget.Active {
-				projectile := entity.NewProjectileAtBuilding(s.nextProjectileID, u, u.BuildingAttackTarget)
-				s.projectiles = append(s.projectiles, projectile)
-				s.nextProjectileID++
-			}
-		}
-	}
-
-	// Building combat
-	for _, b := range s.buildings {
-		if !b.Active || !b.CanAttack() {
-			continue
-		}
-
-		if b.FireCooldown > 0 {
-			b.FireCooldown -= TickRate
-		}
-
-		if b.AttackTarget != nil && (!b.AttackTarget.Active || !b.IsInAttackRange(b.AttackTarget)) {
-			b.AttackTarget = nil
-		}
-
-		if b.AttackTarget == nil {
-			var nearestEnemy *entity.Unit
-			nearestDist := b.Def.AttackRange + 1
-
-			for _, u := range s.units {
-				if u.Active && u.Faction != b.Faction {
-					dist := b.Center().Distance(u.Center())
-					if dist <= b.Def.AttackRange && dist < nearestDist {
-						nearestDist = dist
-						nearestEnemy = u
-					}
-				}
-			}
-
-			if nearestEnemy != nil {
-				b.SetAttackTarget(nearestEnemy)
-			}
-		}
-
-		if b.FireCooldown <= 0 && b.AttackTarget != nil && b.AttackTarget.Active {
-			slot := factionToSlot(b.Faction)
-			res := s.playerResources[slot]
-			energyRes := res.Get(resource.Energy)
-
-			if energyRes.Current >= b.Def.EnergyPerShot {
-				energyRes.Spend(b.Def.EnergyPerShot)
-				projectile := entity.NewProjectileFromBuilding(s.nextProjectileID, b, b.AttackTarget)
-				s.projectiles = append(s.projectiles, projectile)
-				s.nextProjectileID++
-				b.FireCooldown = 1.0 / b.Def.FireRate
-			}
-		}
-	}
-}
+// RequestResume casts a player's vote to resume a paused game
+func (s *Simulation) RequestResume(slot int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-// updateProjectiles updates projectile movement
-func (s *Simulation) updateProjectiles() {
-	alive := make([]*entity.Projectile, 0, len(s.projectiles))
-	for _, p := range s.projectiles {
-		if !p.Update(TickRate) {
-			alive = append(alive, p)
-		}
-	}
-	s.projectiles = alive
+	return s.pause.voteResume(slot, s.playerAlive)
 }
 
-// cleanupDead removes dead units and buildings
-func (s *Simulation) cleanupDead() {
-	alive := make([]*entity.Unit, 0, len(s.units))
-	for _, u := range s.units {
-		if u.Active {
-			alive = append(alive, u)
-		}
-	}
-	s.units = alive
-
-	aliveBuildings := make([]*entity.Building, 0, len(s.buildings))
-	for _, b := range s.buildings {
-		if b.Active {
-			aliveBuildings = append(aliveBuildings, b)
+// executeCommand hands a single player command to the world
+func (s *Simulation) executeCommand(pc PlayerCommand) {
+	cmd := pc.Command
+	faction := slotToFaction(pc.Slot)
+	// In a match a building can only be placed once its full cost is in store
+	if cmd.Type == protocol.CmdPlaceBuilding {
+		def := entity.BuildingDefs[entity.BuildingType(cmd.BuildingType)]
+		if def == nil || !s.world.Resources(faction).CanAfford(def.Cost) {
+			return
 		}
 	}
-	s.buildings = aliveBuildings
+	s.world.Submit(sim.Command{
+		Type:         sim.CommandType(cmd.Type),
+		Faction:      faction,
+		UnitIDs:      cmd.UnitIDs,
+		TargetX:      cmd.TargetX,
+		TargetY:      cmd.TargetY,
+		EndX:         cmd.EndX,
+		EndY:         cmd.EndY,
+		TargetID:     cmd.TargetID,
+		BuildingID:   cmd.BuildingID,
+		BuildingType: entity.BuildingType(cmd.BuildingType),
+		UnitType:     entity.UnitType(cmd.UnitType),
+		Research:     entity.ResearchType(cmd.Research),
+		Formation:    sim.Formation(cmd.Formation),
+		Facing:       cmd.Facing,
+		HasFacing:    cmd.HasFacing,
+		Stance:       entity.Stance(cmd.Stance),
+		Power:        entity.PowerClass(cmd.Power),
+		Priority:     entity.Priority(cmd.Priority),
+		Ability:      entity.AbilityType(cmd.Ability),
+		Queue:        cmd.Queue,
+	})
 }
 
 // checkVictory checks if the game has ended
 func (s *Simulation) checkVictory() (finished bool, winnerSlot int) {
 	// Count alive players
 	alivePlayers := make(map[int]bool)
-
-	for _, u := range s.units {
-		if u.Active {
-			slot := factionToSlot(u.Faction)
-			alivePlayers[slot] = true
-		}
-	}
-
-	for _, b := range s.buildings {
-		if b.Active {
-			slot := factionToSlot(b.Faction)
-			alivePlayers[slot] = true
-		}
+	for faction := range s.world.AliveFactions() {
+		alivePlayers[factionToSlot(faction)] = true
 	}
 
 	// Update player alive status
@@ -724,38 +443,46 @@ func (s *Simulation) checkVictory() (finished bool, winnerSlot int) {
 }
 
 // getGameState returns the current game state for broadcasting
-func (s *Simulation) getGameState() GameStatePayload {
-	players := make([]PlayerGameState, 0, s.numPlayers)
+func (s *Simulation) getGameState() protocol.GameStatePayload {
+	players := make([]protocol.PlayerGameState, 0, s.numPlayers)
 	for slot := 0; slot < s.numPlayers; slot++ {
-		res := s.playerResources[slot]
-		var resState ResourceStateNet
-		if res != nil {
-			metal := res.Get(resource.Metal)
-			energy := res.Get(resource.Energy)
-			resState = ResourceStateNet{
-				Metal:      metal.Current,
-				MetalCap:   metal.Capacity,
-				MetalProd:  metal.NetFlow(),
-				Energy:     energy.Current,
-				EnergyCap:  energy.Capacity,
-				EnergyProd: energy.NetFlow(),
-			}
-		}
-
-		players = append(players, PlayerGameState{
-			Slot:      slot,
-			Name:      s.playerNames[slot],
-			Alive:     s.playerAlive[slot],
-			Resources: resState,
+		res := s.world.Resources(slotToFaction(slot))
+		metal := res.Get(resource.Metal)
+		energy := res.Get(resource.Energy)
+		resState := protocol.ResourceStateNet{
+			Metal:      metal.Current,
+			MetalCap:   metal.Capacity,
+			MetalProd:  metal.NetFlow(),
+			Energy:     energy.Current,
+			EnergyCap:  energy.Capacity,
+			EnergyProd: energy.NetFlow(),
+		}
+
+		var research []int
+		for _, rt := range s.world.Tech(slotToFaction(slot)).Completed() {
+			research = append(research, int(rt))
+		}
+
+		grid := s.world.PowerGrid(slotToFaction(slot))
+
+		players = append(players, protocol.PlayerGameState{
+			Slot:       slot,
+			Name:       s.playerNames[slot],
+			Alive:      s.playerAlive[slot],
+			PausesLeft: s.pause.remaining[slot],
+			Resources:  resState,
+			Research:   research,
+			PowerFirst: int(grid.First),
+			PowerLevel: grid.Level(),
 		})
 	}
 
-	units := make([]UnitState, 0, len(s.units))
-	for _, u := range s.units {
+	units := make([]protocol.UnitState, 0, len(s.world.Units))
+	for _, u := range s.world.Units {
 		if !u.Active {
 			continue
 		}
-		units = append(units, UnitState{
+		units = append(units, protocol.UnitState{
 			ID:          u.ID,
 			Type:        int(u.Type),
 			OwnerSlot:   factionToSlot(u.Faction),
@@ -768,11 +495,17 @@ func (s *Simulation) getGameState() GameStatePayload {
 			HasTarget:   u.HasTarget,
 			TargetX:     u.Target.X,
 			TargetY:     u.Target.Y,
+			Stance:      int(u.Stance),
+			Rank:        int(u.Rank),
+			Orders:      orderStates(u.Orders),
+			Cargo:       cargoStates(u.Cargo),
+			Cooldowns:   cooldowns(u),
+			BoostLeft:   u.Ability.BoostLeft,
 		})
 	}
 
-	buildings := make([]BuildingState, 0, len(s.buildings))
-	for _, b := range s.buildings {
+	buildings := make([]protocol.BuildingState, 0, len(s.world.Buildings))
+	for _, b := range s.world.Buildings {
 		if !b.Active {
 			continue
 		}
@@ -781,8 +514,12 @@ func (s *Simulation) getGameState() GameStatePayload {
 		if b.CurrentProduction != nil {
 			prodType = int(b.CurrentProduction.Type)
 		}
+		var resType int
+		if b.CurrentResearch != nil {
+			resType = int(b.CurrentResearch.Type)
+		}
 
-		buildings = append(buildings, BuildingState{
+		buildings = append(buildings, protocol.BuildingState{
 			ID:            b.ID,
 			Type:          int(b.Type),
 			OwnerSlot:     factionToSlot(b.Faction),
@@ -795,17 +532,29 @@ func (s *Simulation) getGameState() GameStatePayload {
 			Producing:     b.Producing,
 			ProdProgress:  b.ProductionProgress,
 			ProdType:      prodType,
+			Yield:         b.Yield,
+			Deposit:       b.DepositLeft,
+			Researching:   b.Researching,
+			ResProgress:   b.ResearchProgress,
+			ResType:       resType,
+			PowerDeficit:  1 - b.Power,
+			Priority:      int(b.Priority),
+			Recycling:     b.Recycling,
+			RecycleProg:   b.RecycleProgress,
 		})
 	}
 
-	projectiles := make([]ProjectileState, 0, len(s.projectiles))
-	for _, p := range s.projectiles {
+	projectiles := make([]protocol.ProjectileState, 0, len(s.world.Projectiles))
+	for _, p := range s.world.Projectiles {
 		if !p.Active {
 			continue
 		}
 
 		var targetX, targetY float64
-		if p.Target != nil {
+		if p.Weapon == entity.WeaponBallistic || p.Weapon == entity.WeaponFlame {
+			targetX = p.Aim.X
+			targetY = p.Aim.Y
+		} else if p.Target != nil {
 			targetX = p.Target.Position.X
 			targetY = p.Target.Position.Y
 		} else if p.BuildingTarget != nil {
@@ -813,21 +562,147 @@ func (s *Simulation) getGameState() GameStatePayload {
 			targetY = p.BuildingTarget.Position.Y
 		}
 
-		projectiles = append(projectiles, ProjectileState{
+		projectiles = append(projectiles, protocol.ProjectileState{
 			ID:        p.ID,
 			OwnerSlot: factionToSlot(p.Faction),
 			PosX:      p.Position.X,
 			PosY:      p.Position.Y,
 			TargetX:   targetX,
 			TargetY:   targetY,
+			Weapon:    int(p.Weapon),
+		})
+	}
+
+	wreckages := make([]protocol.WreckageState, 0, len(s.world.Wreckages))
+	for _, wr := range s.world.Wreckages {
+		if !wr.Active {
+			continue
+		}
+		wreckages = append(wreckages, protocol.WreckageState{
+			ID:     wr.ID,
+			PosX:   wr.Position.X,
+			PosY:   wr.Position.Y,
+			Width:  wr.Size.X,
+			Height: wr.Size.Y,
+			Metal:  wr.MetalValue,
 		})
 	}
 
-	return GameStatePayload{
-		Tick:        s.tick,
+	terrainDeposits := s.world.Terrain.Deposits()
+	deposits := make([]protocol.DepositState, len(terrainDeposits))
+	for i, d := range terrainDeposits {
+		deposits[i] = protocol.DepositState{X: d.X, Y: d.Y, Amount: d.Amount}
+	}
+
+	effects := make([]protocol.EffectState, len(s.world.Effects))
+	for i, e := range s.world.Effects {
+		effects[i] = protocol.EffectState{
+			Ability:   int(e.Def.Type),
+			OwnerSlot: factionToSlot(e.Faction),
+			X:         e.Center.X,
+			Y:         e.Center.Y,
+			Remaining: e.Remaining,
+		}
+	}
+
+	return protocol.GameStatePayload{
+		Tick:        s.world.Tick,
+		Pause:       s.pause.toNet(s.playerNames),
 		Players:     players,
 		Units:       units,
 		Buildings:   buildings,
 		Projectiles: projectiles,
+		Wreckages:   wreckages,
+		Deposits:    deposits,
+		Effects:     effects,
+	}
+}
+
+// hiddenSet is what one player must not be sent: the enemy units concealed
+// from them and the projectiles whose state would track those units
+type hiddenSet struct {
+	units       map[uint64]bool
+	projectiles map[uint64]bool
+}
+
+// hidden returns, for each player slot, what is concealed from that player.
+// It is left out of what the player is sent, so a client cannot show what
+// its player should not see.
+func (s *Simulation) hidden() map[int]*hiddenSet {
+	var hidden map[int]*hiddenSet
+	for _, u := range s.world.Units {
+		if !u.Active {
+			continue
+		}
+		for slot := 0; slot < s.numPlayers; slot++ {
+			if !s.world.Concealed(u, slotToFaction(slot)) {
+				continue
+			}
+			if hidden == nil {
+				hidden = make(map[int]*hiddenSet)
+			}
+			if hidden[slot] == nil {
+				hidden[slot] = &hiddenSet{units: make(map[uint64]bool), projectiles: make(map[uint64]bool)}
+			}
+			hidden[slot].units[u.ID] = true
+		}
+	}
+	if hidden == nil {
+		return nil
+	}
+	// A rocket fired before its target vanished still homes in on it
+	for _, p := range s.world.Projectiles {
+		if !p.Active || !tracksTarget(p) {
+			continue
+		}
+		for _, h := range hidden {
+			if h.units[p.Target.ID] {
+				h.projectiles[p.ID] = true
+			}
+		}
+	}
+	return hidden
+}
+
+// tracksTarget reports whether a projectile's state carries the position of
+// its target unit, as it does for homing shots
+func tracksTarget(p *entity.Projectile) bool {
+	return p.Target != nil && p.Weapon != entity.WeaponBallistic && p.Weapon != entity.WeaponFlame
+}
+
+// cooldowns returns a unit's ability cooldowns for the wire, nil when
+// every ability is ready
+func cooldowns(u *entity.Unit) []float64 {
+	for _, cd := range u.Ability.Cooldowns {
+		if cd > 0 {
+			return slices.Clone(u.Ability.Cooldowns[:])
+		}
+	}
+	return nil
+}
+
+// cargoStates converts the units aboard a transport for the wire
+func cargoStates(cargo []*entity.Unit) []protocol.CargoState {
+	if len(cargo) == 0 {
+		return nil
+	}
+	states := make([]protocol.CargoState, len(cargo))
+	for i, c := range cargo {
+		states[i] = protocol.CargoState{ID: c.ID, Type: int(c.Type), Health: c.Health, MaxHealth: c.MaxHealth}
+	}
+	return states
+}
+
+// orderStates converts a unit's order queue for the wire, placing attack,
+// guard and reclaim orders at their target
+func orderStates(orders []entity.Order) []protocol.OrderState {
+	if len(orders) == 0 {
+		return nil
+	}
+	states := make([]protocol.OrderState, len(orders))
+	for i := range orders {
+		p := orders[i].Point()
+		states[i] = protocol.OrderState{Type: int(orders[i].Type), X: p.X, Y: p.Y}
 	}
+	return states
 }
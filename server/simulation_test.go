@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bklimczak/tanks/engine/entity"
+	"github.com/bklimczak/tanks/engine/terrain"
+)
+
+func TestConcealedUnitLeftOutOfEnemyState(t *testing.T) {
+	m := terrain.NewMap(2000, 2000)
+	m.GenerateGrassOnly()
+	s := newSimulation(m, 2)
+	// A position no other field of the state holds
+	phantom := s.world.SpawnUnit(entity.UnitDefs[entity.UnitTypePhantomTank], 1234.5, 876.25, slotToFaction(1))
+	shooter := s.world.SpawnUnit(entity.UnitDefs[entity.UnitTypeRocketTank], 600, 600, slotToFaction(0))
+	// Fired before its target cloaked, the rocket still homes in on it
+	rocket := entity.NewProjectile(s.world.NextProjectileID, shooter, phantom)
+	rocket.Weapon = entity.WeaponRocket
+	s.world.Projectiles = append(s.world.Projectiles, rocket)
+
+	state, hidden := s.getGameState(), s.hidden()
+	tests := []struct {
+		name     string
+		slot     int
+		wantSeen bool
+	}{
+		{"enemy", 0, false},
+		{"owner", 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := state
+			if h := hidden[tt.slot]; h != nil {
+				sent = withoutHidden(state, h)
+			}
+			data, err := json.Marshal(sent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen := strings.Contains(string(data), "1234.5"); seen != tt.wantSeen {
+				t.Errorf("state carries the phantom's position = %v, want %v", seen, tt.wantSeen)
+			}
+			if len(sent.Projectiles) != 1 {
+				t.Errorf("sent %d projectiles, want the rocket still shown", len(sent.Projectiles))
+			}
+		})
+	}
+	if state.Projectiles[0].TargetX != phantom.Position.X {
+		t.Error("filtering one player's state should leave the shared state alone")
+	}
+}